@@ -14,5 +14,6 @@ func NewCommand() *cobra.Command {
 
 	cmd.AddCommand(newRunPipelineCommand())
 	cmd.AddCommand(newStartCommand())
+	cmd.AddCommand(newIssuersCommand())
 	return cmd
 }
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	cliutils "bilalekrem.com/certstore/cmd/cli/utils"
+	wrk "bilalekrem.com/certstore/internal/cluster/agent"
+	"github.com/spf13/cobra"
+)
+
+func newIssuersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "issuers",
+		Short: "list issuers of server with their capabilities",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, _ := cmd.Flags().GetString("config")
+
+			// -----
+
+			issuers, err := wrk.ListIssuersFromFile(context.Background(), configPath)
+			cliutils.ValidateNotError(err)
+
+			// ---
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "NAME\tTYPE\tKEY TYPES\tVALIDITY DAYS\tFIELDS")
+			for _, issuer := range issuers {
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+					issuer.Name,
+					issuer.Type,
+					strings.Join(issuer.KeyTypes, ","),
+					validityDays(issuer.MinValidityDays, issuer.MaxValidityDays),
+					strings.Join(issuer.SupportedFields, ","))
+			}
+			writer.Flush()
+		},
+	}
+
+	// ----
+
+	cmd.Flags().String("config", "", "agent config file path")
+	cmd.MarkFlagRequired("config")
+
+	return cmd
+}
+
+func validityDays(min int32, max int32) string {
+	if max == 0 {
+		return fmt.Sprintf("%d-", min)
+	} else if min == max {
+		return fmt.Sprintf("%d", min)
+	}
+
+	return fmt.Sprintf("%d-%d", min, max)
+}
@@ -1,10 +1,9 @@
 package agent
 
 import (
-	"runtime"
-
 	cliutils "bilalekrem.com/certstore/cmd/cli/utils"
 	wrk "bilalekrem.com/certstore/internal/cluster/agent"
+	"bilalekrem.com/certstore/internal/lifecycle"
 	"github.com/spf13/cobra"
 )
 
@@ -17,12 +16,14 @@ func newStartCommand() *cobra.Command {
 
 			// -----
 
-			_, err := wrk.NewFromFile(configPath)
+			agent, err := wrk.NewFromFile(configPath)
 			cliutils.ValidateNotError(err)
 
 			// ---
 
-			runtime.Goexit()
+			// jobs run in background until a stop signal
+			err = lifecycle.Run(nil, agent.Stop, agent.ShutdownTimeout())
+			cliutils.ValidateNotError(err)
 		},
 	}
 
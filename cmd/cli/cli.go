@@ -6,6 +6,7 @@ import (
 
 	"bilalekrem.com/certstore/cmd/cli/cluster"
 	"bilalekrem.com/certstore/cmd/cli/server"
+	"bilalekrem.com/certstore/cmd/cli/transparency"
 	"bilalekrem.com/certstore/cmd/cli/agent"
 	"bilalekrem.com/certstore/internal/logging"
 )
@@ -31,4 +32,5 @@ func addCommands() {
 	rootCmd.AddCommand(cluster.NewCommand())
 	rootCmd.AddCommand(agent.NewCommand())
 	rootCmd.AddCommand(server.NewCommand())
+	rootCmd.AddCommand(transparency.NewVerifyInclusionCommand())
 }
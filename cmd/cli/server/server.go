@@ -13,5 +13,8 @@ func NewCommand() *cobra.Command {
 	// ----
 
 	cmd.AddCommand(newStartCommand())
+	cmd.AddCommand(newReloadCommand())
+	cmd.AddCommand(newHealthCommand())
+	cmd.AddCommand(newApprovalsCommand())
 	return cmd
 }
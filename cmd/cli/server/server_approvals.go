@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cliutils "bilalekrem.com/certstore/cmd/cli/utils"
+	wrk "bilalekrem.com/certstore/internal/cluster/agent"
+	"github.com/spf13/cobra"
+)
+
+func newApprovalsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "approvals",
+		Short: "list, approve or deny requests of issuers requiring approval",
+	}
+
+	// ----
+
+	cmd.AddCommand(newApprovalsListCommand())
+	cmd.AddCommand(newApproveCommand())
+	cmd.AddCommand(newDenyCommand())
+	return cmd
+}
+
+func newApprovalsListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list requests waiting for approval",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, _ := cmd.Flags().GetString("config")
+			issuer, _ := cmd.Flags().GetString("issuer")
+
+			// -----
+
+			requests, err := wrk.ListApprovalsFromFile(context.Background(), configPath, issuer)
+			cliutils.ValidateNotError(err)
+
+			// ---
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "OPERATION\tISSUER\tREQUESTED BY\tREQUESTED AT\tCOMMON NAME\tSANS\tVALIDITY")
+			for _, request := range requests {
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%v\n",
+					request.OperationId,
+					request.Issuer,
+					request.RequestedBy,
+					request.RequestedAt.AsTime().Format(time.RFC3339),
+					request.CommonName,
+					strings.Join(request.SANs, ","),
+					time.Duration(request.ValiditySeconds)*time.Second)
+			}
+			writer.Flush()
+		},
+	}
+
+	// ----
+
+	cmd.Flags().String("config", "", "agent config file path, to connect the server")
+	cmd.Flags().String("issuer", "", "only the requests of the issuer, every issuer if empty")
+	cmd.MarkFlagRequired("config")
+	return cmd
+}
+
+func newApproveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "approve",
+		Short: "approve a request, certificate is issued and handed to the requesting agent",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, _ := cmd.Flags().GetString("config")
+			operationID, _ := cmd.Flags().GetString("operation")
+
+			// -----
+
+			operation, err := wrk.ApproveRequestFromFile(context.Background(), configPath, operationID)
+			cliutils.ValidateNotError(err)
+
+			fmt.Printf("approved request [%s] of issuer [%s], state: %s\n", operation.Id, operation.Issuer, operation.State)
+		},
+	}
+
+	// ----
+
+	cmd.Flags().String("config", "", "agent config file path, to connect the server")
+	cmd.Flags().String("operation", "", "operation id of the request")
+	cmd.MarkFlagRequired("config")
+	cmd.MarkFlagRequired("operation")
+	return cmd
+}
+
+func newDenyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deny",
+		Short: "deny a request, its operation fails with the reason",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, _ := cmd.Flags().GetString("config")
+			operationID, _ := cmd.Flags().GetString("operation")
+			reason, _ := cmd.Flags().GetString("reason")
+
+			// -----
+
+			operation, err := wrk.DenyRequestFromFile(context.Background(), configPath, operationID, reason)
+			cliutils.ValidateNotError(err)
+
+			fmt.Printf("denied request [%s] of issuer [%s], %s\n", operation.Id, operation.Issuer, operation.Error)
+		},
+	}
+
+	// ----
+
+	cmd.Flags().String("config", "", "agent config file path, to connect the server")
+	cmd.Flags().String("operation", "", "operation id of the request")
+	cmd.Flags().String("reason", "", "reason of the denial, passed to the requesting agent")
+	cmd.MarkFlagRequired("config")
+	cmd.MarkFlagRequired("operation")
+	cmd.MarkFlagRequired("reason")
+	return cmd
+}
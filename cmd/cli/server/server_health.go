@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	cliutils "bilalekrem.com/certstore/cmd/cli/utils"
+	wrk "bilalekrem.com/certstore/internal/cluster/agent"
+	"github.com/spf13/cobra"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newHealthCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "check health of a running server, exits with 1 if a component is not serving",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, _ := cmd.Flags().GetString("config")
+
+			// ----
+
+			ctx, cancel := context.WithTimeout(context.Background(), wrk.HEALTH_PROBE_TIMEOUT)
+			defer cancel()
+
+			components, err := wrk.ServerHealthFromFile(ctx, configPath)
+			cliutils.ValidateNotError(err)
+
+			serving := true
+			for _, component := range components {
+				fmt.Printf("%-32s %s\n", wrk.DisplayService(component.Service), component.Status)
+				if component.Status == healthpb.HealthCheckResponse_NOT_SERVING {
+					serving = false
+				}
+			}
+
+			if !serving {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String("config", "", "agent config file path, to connect the server")
+	cmd.MarkFlagRequired("config")
+	return cmd
+}
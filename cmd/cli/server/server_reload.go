@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cliutils "bilalekrem.com/certstore/cmd/cli/utils"
+	wrk "bilalekrem.com/certstore/internal/cluster/agent"
+	"github.com/spf13/cobra"
+)
+
+func newReloadCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reload",
+		Short: "reload config of a running server, same as sending SIGHUP to it",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, _ := cmd.Flags().GetString("config")
+
+			// ----
+
+			resp, err := wrk.ReloadServerFromFile(context.Background(), configPath)
+			cliutils.ValidateNotError(err)
+
+			fmt.Printf("added issuers: [%s]\n", strings.Join(resp.AddedIssuers, ", "))
+			fmt.Printf("updated issuers: [%s]\n", strings.Join(resp.UpdatedIssuers, ", "))
+			fmt.Printf("removed issuers: [%s]\n", strings.Join(resp.RemovedIssuers, ", "))
+		},
+	}
+
+	cmd.Flags().String("config", "", "agent config file path, to connect the server")
+	cmd.MarkFlagRequired("config")
+	return cmd
+}
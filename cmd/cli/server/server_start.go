@@ -3,6 +3,7 @@ package server
 import (
 	cliutils "bilalekrem.com/certstore/cmd/cli/utils"
 	cluster_server_pkg "bilalekrem.com/certstore/internal/cluster/server"
+	"bilalekrem.com/certstore/internal/lifecycle"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +19,8 @@ func newStartCommand() *cobra.Command {
 			server, err := cluster_server_pkg.NewFromFile(configPath)
 			cliutils.ValidateNotError(err)
 
-			server.Serve()
+			err = lifecycle.Run(server.Serve, server.Stop, server.ShutdownTimeout())
+			cliutils.ValidateNotError(err)
 		},
 	}
 
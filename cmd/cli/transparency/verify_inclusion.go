@@ -0,0 +1,60 @@
+package transparency
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+
+	cliutils "bilalekrem.com/certstore/cmd/cli/utils"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
+	wrk "bilalekrem.com/certstore/internal/cluster/agent"
+	"github.com/spf13/cobra"
+)
+
+func NewVerifyInclusionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-inclusion",
+		Short: "verify a certificate is in the transparency log of server, exits with 1 if it is not",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, _ := cmd.Flags().GetString("config")
+			certificatePath, _ := cmd.Flags().GetString("certificate")
+			publicKeyPath, _ := cmd.Flags().GetString("public-key")
+			treeHeadPath, _ := cmd.Flags().GetString("tree-head")
+
+			// ----
+
+			certificatePem, err := ioutil.ReadFile(certificatePath)
+			cliutils.ValidateNotError(err)
+
+			publicKeyPem, err := ioutil.ReadFile(publicKeyPath)
+			cliutils.ValidateNotError(err)
+			publicKey, err := transparency.ParsePublicKey(publicKeyPem)
+			cliutils.ValidateNotError(err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), wrk.VERIFY_INCLUSION_TIMEOUT)
+			defer cancel()
+
+			inclusion, err := wrk.VerifyInclusionFromFile(ctx, configPath, certificatePem, publicKey, treeHeadPath)
+			cliutils.ValidateNotError(err)
+
+			// ----
+
+			fmt.Printf("certificate is included at index %d\n", inclusion.LeafIndex)
+			fmt.Printf("tree size: %d, root hash: %s, signed at: %s\n", inclusion.TreeHead.TreeSize,
+				hex.EncodeToString(inclusion.TreeHead.RootHash), inclusion.TreeHead.Timestamp)
+			if inclusion.TrustedTreeHead != nil {
+				fmt.Printf("consistent with trusted tree size: %d\n", inclusion.TrustedTreeHead.TreeSize)
+			}
+		},
+	}
+
+	cmd.Flags().String("config", "", "agent config file path, to connect the server")
+	cmd.Flags().String("certificate", "", "PEM encoded certificate file path")
+	cmd.Flags().String("public-key", "", "PEM encoded public key of the transparency log")
+	cmd.Flags().String("tree-head", "", "file keeping the last verified tree head, new tree head must be consistent with it")
+	cmd.MarkFlagRequired("config")
+	cmd.MarkFlagRequired("certificate")
+	cmd.MarkFlagRequired("public-key")
+	return cmd
+}
@@ -4,14 +4,24 @@ go 1.17
 
 require (
 	github.com/go-acme/lego/v4 v4.6.0
+	github.com/prometheus/client_golang v1.10.0
 	google.golang.org/grpc v1.38.0
 	google.golang.org/protobuf v1.27.1
 )
 
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_model v0.2.0 // indirect
+	github.com/prometheus/common v0.18.0 // indirect
+	github.com/prometheus/procfs v0.7.3 // indirect
+)
+
 require (
 	github.com/cenkalti/backoff/v4 v4.1.1 // indirect
-	github.com/miekg/dns v1.1.43 // indirect
-	golang.org/x/crypto v0.0.0-20210616213533-5ff15b29337e // indirect
+	github.com/miekg/dns v1.1.43
+	golang.org/x/crypto v0.0.0-20210616213533-5ff15b29337e
 	gopkg.in/square/go-jose.v2 v2.6.0 // indirect
 )
 
@@ -24,9 +34,9 @@ require (
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.7.0 // indirect
 	go.uber.org/zap v1.19.1
-	golang.org/x/net v0.0.0-20210510120150-4163338589ed // indirect
+	golang.org/x/net v0.0.0-20210510120150-4163338589ed
 	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
 	golang.org/x/text v0.3.6 // indirect
-	google.golang.org/genproto v0.0.0-20210602131652-f16073e35f0c // indirect
+	google.golang.org/genproto v0.0.0-20210602131652-f16073e35f0c
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	POLICY_CAA        = "caa"
+	POLICY_RATE_LIMIT = "rate-limit"
+	POLICY_REUSE      = "reuse"
+	POLICY_APPROVAL   = "approval"
+
+	DECISION_ALLOWED = "allowed"
+	DECISION_DENIED  = "denied"
+	DECISION_WARNED  = "warned"
+	DECISION_REUSED  = "reused"
+	DECISION_PENDING = "pending"
+
+	// actions are named by the rpc requesting them, except the ones below
+	ACTION_RELOAD          = "Reload"
+	ACTION_ISSUE_SUBMITTED = "IssueSubmittedCertificate"
+
+	// identity of the actions not requested by a peer, e.g. reload on SIGHUP
+	LOCAL_IDENTITY = "local"
+)
+
+// Entry is written as a single json line once the action is completed
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+
+	// common name and serial number of the peer certificate
+	Identity       string   `json:"identity"`
+	IdentitySerial string   `json:"identity-serial,omitempty"`
+	RemoteAddress  string   `json:"remote-address,omitempty"`
+	Issuer         string   `json:"issuer,omitempty"`
+	Names          []string `json:"names,omitempty"`
+
+	// hex encoded serial number of the issued or revoked certificate
+	SerialNumber string `json:"serial-number,omitempty"`
+	OperationID  string `json:"operation-id,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+
+	Decisions []Decision `json:"decisions,omitempty"`
+
+	// empty if action succeeded, kind is the error kind of typed errors e.g. rate-limited, canceled,
+	// deadline-exceeded or error
+	ErrorKind string `json:"error-kind,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Decision is a policy evaluated for the action, e.g. caa denied or reuse reused
+type Decision struct {
+	Policy string `json:"policy"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ----
+
+// Logger writes entries to a sink, safe for concurrent use
+type Logger struct {
+	mutex        sync.Mutex
+	sink         sink
+	timeProvider func() time.Time
+}
+
+// New returns nil if audit is not enabled
+func New(conf *Config) (*Logger, error) {
+	if !conf.Enabled() {
+		return nil, nil
+	}
+
+	err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSink(conf)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("creating audit sink failed, %v", err))
+	}
+
+	return newLogger(s), nil
+}
+
+// ReadEntries decodes the json lines written by a logger, in the order they are written
+func ReadEntries(reader io.Reader) ([]*Entry, error) {
+	entries := []*Entry{}
+	decoder := json.NewDecoder(reader)
+	for {
+		entry := &Entry{}
+		err := decoder.Decode(entry)
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, errors.New(fmt.Sprintf("decoding audit entry failed, %v", err))
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+// NewWriterLogger writes entries to writer as json lines, writer is not closed with the logger
+func NewWriterLogger(writer io.Writer) *Logger {
+	return newLogger(&writerSink{writer: writer})
+}
+
+func newLogger(s sink) *Logger {
+	return &Logger{sink: s, timeProvider: time.Now}
+}
+
+// Log writes the entry, failures are logged since the action is completed already
+func (l *Logger) Log(entry *Entry) {
+	if l == nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logging.GetLogger().Errorf("Encoding audit entry failed, action: [%s], %v", entry.Action, err)
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err = l.sink.Write(line)
+	if err != nil {
+		logging.GetLogger().Errorf("Writing audit entry failed, action: [%s], %v", entry.Action, err)
+	}
+}
+
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.sink.Close()
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestTrail(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := NewWriterLogger(buffer)
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	logger.timeProvider = func() time.Time { return now }
+
+	trail := logger.Start(Entry{Action: "IssueCertificate", Identity: "agent", Issuer: "issuer", Names: []string{"certstore.com"}})
+	ctx := NewContext(context.Background(), trail)
+	Decide(ctx, POLICY_CAA, DECISION_ALLOWED, "certstore.com")
+	SetSerialNumber(ctx, "0a")
+	trail.Finish("", nil)
+
+	// finished already
+	trail.Finish("error", errors.New("failed"))
+
+	// ----
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, 1, len(lines))
+
+	entry := &Entry{}
+	err := json.Unmarshal([]byte(lines[0]), entry)
+	assert.NotError(t, err, "decoding entry failed")
+
+	assert.True(t, now.Equal(entry.Time))
+	assert.Equal(t, "IssueCertificate", entry.Action)
+	assert.Equal(t, "agent", entry.Identity)
+	assert.Equal(t, "0a", entry.SerialNumber)
+	assert.DeepEqual(t, []Decision{{Policy: POLICY_CAA, Result: DECISION_ALLOWED, Detail: "certstore.com"}}, entry.Decisions)
+	assert.Equal(t, "", entry.Error)
+}
+
+func TestTrailFailed(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	trail := NewWriterLogger(buffer).Start(Entry{Action: "RevokeCertificate"})
+	trail.Finish("not-found", errors.New("Certificate not found"))
+
+	entry := &Entry{}
+	err := json.Unmarshal(buffer.Bytes(), entry)
+	assert.NotError(t, err, "decoding entry failed")
+	assert.Equal(t, "not-found", entry.ErrorKind)
+	assert.Equal(t, "Certificate not found", entry.Error)
+}
+
+func TestNilTrail(t *testing.T) {
+	var logger *Logger
+	trail := logger.Start(Entry{Action: "IssueCertificate"})
+	assert.Nil(t, trail)
+
+	ctx := NewContext(context.Background(), trail)
+	Decide(ctx, POLICY_REUSE, DECISION_REUSED, "")
+	SetSerialNumber(ctx, "0a")
+	trail.Finish("", nil)
+
+	detached, detachedTrail := Detach(ctx, ACTION_ISSUE_SUBMITTED)
+	assert.Nil(t, detachedTrail)
+	assert.Nil(t, FromContext(detached))
+	assert.NotError(t, logger.Close(), "closing nil logger failed")
+}
+
+func TestDetach(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	trail := NewWriterLogger(buffer).Start(Entry{Action: "SubmitCertificateRequest", Identity: "agent", Issuer: "issuer"})
+	ctx, cancel := context.WithCancel(NewContext(context.Background(), trail))
+
+	detached, detachedTrail := Detach(ctx, ACTION_ISSUE_SUBMITTED)
+	detachedTrail.SetOperationID("operation")
+	cancel()
+	trail.Finish("", nil)
+
+	assert.Nil(t, detached.Err())
+	Decide(detached, POLICY_RATE_LIMIT, DECISION_ALLOWED, "")
+	detachedTrail.Finish("", nil)
+
+	// ----
+
+	entries, err := ReadEntries(buffer)
+	assert.NotError(t, err, "reading entries failed")
+	assert.Equal(t, 2, len(entries))
+
+	entry := entries[1]
+	assert.Equal(t, ACTION_ISSUE_SUBMITTED, entry.Action)
+	assert.Equal(t, "agent", entry.Identity)
+	assert.Equal(t, "issuer", entry.Issuer)
+	assert.Equal(t, "operation", entry.OperationID)
+	assert.Equal(t, 1, len(entry.Decisions))
+}
+
+func TestReadEntriesNotValid(t *testing.T) {
+	_, err := ReadEntries(strings.NewReader("{\"action\": \"Reload\"}\nnot json\n"))
+	assert.ErrorContains(t, err, "decoding audit entry failed")
+}
+
+func TestValidateConfig(t *testing.T) {
+	assert.NotError(t, (&Config{}).Validate(), "validating disabled audit failed")
+	assert.NotError(t, (&Config{Sink: Stdout}).Validate(), "validating stdout audit failed")
+
+	assert.Error(t, (&Config{Sink: File}).Validate(), "validation failed: missing path")
+	assert.Error(t, (&Config{Sink: "kafka"}).Validate(), "validation failed: unknown sink")
+	assert.Error(t, (&Config{Sink: File, Path: "audit.log", MaxBackups: -1}).Validate(), "validation failed: negative backups")
+}
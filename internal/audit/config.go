@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+)
+
+type SinkType string
+
+const (
+	File   SinkType = "file"
+	Stdout SinkType = "stdout"
+	Syslog SinkType = "syslog"
+
+	DEFAULT_MAX_SIZE_MB = 100
+	DEFAULT_MAX_BACKUPS = 10
+	DEFAULT_SYSLOG_TAG  = "certstore"
+)
+
+// audit is disabled if sink is empty
+type Config struct {
+	Sink SinkType `yaml:"sink"`
+
+	// file is rotated once it exceeds max size, path.1 being the latest of max backups
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max-size-mb"`
+	MaxBackups int    `yaml:"max-backups"`
+
+	// local syslog is used if address is empty, network is e.g. udp or tcp
+	SyslogNetwork string `yaml:"syslog-network"`
+	SyslogAddress string `yaml:"syslog-address"`
+	SyslogTag     string `yaml:"syslog-tag"`
+}
+
+func (c *Config) Enabled() bool {
+	return c.Sink != ""
+}
+
+func (c *Config) Validate() error {
+	switch c.Sink {
+	case "", Stdout, Syslog:
+	case File:
+		if c.Path == "" {
+			return errors.New("path of audit file is required")
+		}
+	default:
+		return errors.New(fmt.Sprintf("unknown audit sink: [%s], one of file, stdout or syslog expected", c.Sink))
+	}
+
+	if c.MaxSizeMB < 0 || c.MaxBackups < 0 {
+		return errors.New(fmt.Sprintf("audit file max size and backups can not be negative, %d, %d", c.MaxSizeMB, c.MaxBackups))
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+)
+
+// fileSink appends lines to a file, and rotates it once it exceeds max size
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func newFileSink(path string, maxSizeMB int, maxBackups int) (*fileSink, error) {
+	if maxSizeMB == 0 {
+		maxSizeMB = DEFAULT_MAX_SIZE_MB
+	}
+	if maxBackups == 0 {
+		maxBackups = DEFAULT_MAX_BACKUPS
+	}
+
+	s := &fileSink{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+
+	err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *fileSink) Write(line []byte) error {
+	line = append(line, '\n')
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		err := s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts backups by one, the oldest one is removed
+func (s *fileSink) rotate() error {
+	err := s.file.Close()
+	if err != nil {
+		return err
+	}
+
+	for index := s.maxBackups - 1; index > 0; index-- {
+		err = os.Rename(backupPath(s.path, index), backupPath(s.path, index+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	err = os.Rename(s.path, backupPath(s.path, 1))
+	if err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func backupPath(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
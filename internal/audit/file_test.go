@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_audit_file")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	path := fmt.Sprintf("%s/audit.log", dir)
+	s, err := newFileSink(path, 1, 2)
+	assert.NotError(t, err, "creating file sink failed")
+	s.maxSize = 10
+
+	for _, line := range []string{"first", "second", "third", "fourth"} {
+		err = s.Write([]byte(line))
+		assert.NotError(t, err, "writing line failed")
+	}
+	assert.NotError(t, s.Close(), "closing file sink failed")
+
+	// ----
+
+	assertContent(t, path, "fourth\n")
+	assertContent(t, backupPath(path, 1), "third\n")
+	assertContent(t, backupPath(path, 2), "second\n")
+
+	_, err = os.Stat(backupPath(path, 3))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_audit_file")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	path := fmt.Sprintf("%s/audit.log", dir)
+	for _, line := range []string{"first", "second"} {
+		s, err := newFileSink(path, 0, 0)
+		assert.NotError(t, err, "creating file sink failed")
+		assert.NotError(t, s.Write([]byte(line)), "writing line failed")
+		assert.NotError(t, s.Close(), "closing file sink failed")
+	}
+
+	assertContent(t, path, "first\nsecond\n")
+
+	info, err := os.Stat(path)
+	assert.NotError(t, err, "reading file info failed")
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func assertContent(t *testing.T, path string, expected string) {
+	content, err := ioutil.ReadFile(path)
+	assert.NotError(t, err, "reading file failed")
+	assert.Equal(t, expected, string(content))
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"io"
+	"os"
+)
+
+// sink writes a json line, newline is added by the sink if needed
+type sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+func newSink(conf *Config) (sink, error) {
+	switch conf.Sink {
+	case File:
+		return newFileSink(conf.Path, conf.MaxSizeMB, conf.MaxBackups)
+	case Syslog:
+		return newSyslogSink(conf.SyslogNetwork, conf.SyslogAddress, conf.SyslogTag)
+	default:
+		return &writerSink{writer: os.Stdout}, nil
+	}
+}
+
+// writerSink writes lines to a writer, e.g. stdout, writer is not closed
+type writerSink struct {
+	writer io.Writer
+}
+
+func (s *writerSink) Write(line []byte) error {
+	_, err := s.writer.Write(append(line, '\n'))
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package audit
+
+import (
+	"errors"
+)
+
+func newSyslogSink(network string, address string, tag string) (sink, error) {
+	return nil, errors.New("syslog audit sink is not supported on this platform")
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+type trailKey struct{}
+
+// Trail collects an entry while the action is in progress, methods of a nil trail do nothing so that callers do
+// not need to know whether audit is enabled
+type Trail struct {
+	logger *Logger
+
+	mutex    sync.Mutex
+	entry    Entry
+	finished bool
+}
+
+// Start returns the trail of an action started now, entry is written when the trail is finished
+func (l *Logger) Start(entry Entry) *Trail {
+	if l == nil {
+		return nil
+	}
+
+	entry.Time = l.timeProvider()
+	return &Trail{logger: l, entry: entry}
+}
+
+func (t *Trail) Decide(policy string, result string, detail string) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.entry.Decisions = append(t.entry.Decisions, Decision{Policy: policy, Result: result, Detail: detail})
+}
+
+func (t *Trail) SetSerialNumber(serialNumber string) {
+	t.update(func(entry *Entry) { entry.SerialNumber = serialNumber })
+}
+
+func (t *Trail) SetOperationID(id string) {
+	t.update(func(entry *Entry) { entry.OperationID = id })
+}
+
+func (t *Trail) SetDetail(detail string) {
+	t.update(func(entry *Entry) { entry.Detail = detail })
+}
+
+func (t *Trail) SetIssuer(issuer string) {
+	t.update(func(entry *Entry) { entry.Issuer = issuer })
+}
+
+func (t *Trail) SetNames(names []string) {
+	t.update(func(entry *Entry) { entry.Names = names })
+}
+
+// Finish writes the entry, with the error and its kind if the action failed. Later calls are ignored
+func (t *Trail) Finish(errorKind string, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	if t.finished {
+		t.mutex.Unlock()
+		return
+	}
+	t.finished = true
+
+	entry := t.entry
+	entry.Decisions = append([]Decision{}, t.entry.Decisions...)
+	if err != nil {
+		entry.ErrorKind = errorKind
+		entry.Error = err.Error()
+	}
+	t.mutex.Unlock()
+
+	t.logger.Log(&entry)
+}
+
+func (t *Trail) update(fn func(*Entry)) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	fn(&t.entry)
+}
+
+// ----
+
+func NewContext(ctx context.Context, trail *Trail) context.Context {
+	if trail == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, trailKey{}, trail)
+}
+
+// FromContext returns nil if the context has no trail
+func FromContext(ctx context.Context) *Trail {
+	trail, _ := ctx.Value(trailKey{}).(*Trail)
+	return trail
+}
+
+// Decide records a policy decision on the trail of the context, if there is one
+func Decide(ctx context.Context, policy string, result string, detail string) {
+	FromContext(ctx).Decide(policy, result, detail)
+}
+
+func SetSerialNumber(ctx context.Context, serialNumber string) {
+	FromContext(ctx).SetSerialNumber(serialNumber)
+}
+
+// Detach returns a context for work outliving the request of ctx, e.g. a submitted certificate request. It is not
+// canceled with ctx, and carries a new trail of the same requester for the given action, nil trail if ctx has none
+func Detach(ctx context.Context, action string) (context.Context, *Trail) {
+	trail := FromContext(ctx)
+	if trail == nil {
+		return context.Background(), nil
+	}
+
+	trail.mutex.Lock()
+	entry := Entry{
+		Action:         action,
+		Identity:       trail.entry.Identity,
+		IdentitySerial: trail.entry.IdentitySerial,
+		RemoteAddress:  trail.entry.RemoteAddress,
+		Issuer:         trail.entry.Issuer,
+		Names:          trail.entry.Names,
+	}
+	trail.mutex.Unlock()
+
+	detached := trail.logger.Start(entry)
+	return NewContext(context.Background(), detached), detached
+}
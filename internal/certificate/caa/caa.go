@@ -0,0 +1,208 @@
+package caa
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"bilalekrem.com/certstore/internal/logging"
+	"github.com/miekg/dns"
+)
+
+type Mode string
+
+const (
+	Enforce Mode = "enforce"
+	Warn    Mode = "warn"
+
+	TAG_ISSUE      string = "issue"
+	TAG_ISSUE_WILD string = "issuewild"
+	TAG_IODEF      string = "iodef"
+
+	// issuer critical flag, see RFC 8659 section 4.1
+	FLAG_CRITICAL uint8 = 128
+
+	DEFAULT_RESOLVER_CONFIG_PATH               = "/etc/resolv.conf"
+	DEFAULT_TIMEOUT              time.Duration = 5 * time.Second
+)
+
+// lookup errors are wrapping this error, they do not mean issuance is denied
+var ErrLookupFailed = errors.New("CAA lookup failed")
+
+// known property tags, an unknown tag with critical flag prevents issuance
+var knownTags = map[string]bool{
+	TAG_ISSUE:      true,
+	TAG_ISSUE_WILD: true,
+	TAG_IODEF:      true,
+	"issuemail":    true,
+	"contactemail": true,
+	"contactphone": true,
+}
+
+type Checker struct {
+	// empty if no resolver is configured and system resolver is not found, lookups fail then
+	resolver string
+	client   *dns.Client
+}
+
+// NewChecker uses the system resolver if resolver is empty. There is no fallback to a public resolver, queries
+// leaving the network are only sent if they are configured
+func NewChecker(resolver string) *Checker {
+	if resolver == "" {
+		systemResolver, err := getSystemResolver()
+		if err != nil {
+			logging.GetLogger().Errorf("reading system resolver failed, CAA lookups fail until caa-resolver is set, %v", err)
+		}
+		resolver = systemResolver
+	}
+
+	return &Checker{
+		resolver: resolver,
+		client:   &dns.Client{Timeout: DEFAULT_TIMEOUT},
+	}
+}
+
+// Check evaluates CAA record set of the domain with tree-climbing algorithm described in RFC 8659, returns
+// an error if the issuer identity is not authorized to issue certificates for the domain
+func (c *Checker) Check(domain string, identity string) error {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" {
+		return errors.New("CAA check failed: domain can not be empty")
+	}
+
+	wildcard := strings.HasPrefix(domain, "*.")
+	if wildcard {
+		domain = strings.TrimPrefix(domain, "*.")
+	}
+
+	if net.ParseIP(domain) != nil {
+		logging.GetLogger().Debugf("CAA check is skipped for ip address: [%s]", domain)
+		return nil
+	}
+
+	// ----
+
+	records, owner, err := c.findRelevantRecordSet(domain)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		logging.GetLogger().Debugf("no CAA record found for [%s], any issuer is authorized", domain)
+		return nil
+	}
+
+	logging.GetLogger().Debugf("relevant CAA record set found for [%s] at [%s]: %v", domain, owner, records)
+	return evaluate(domain, owner, records, identity, wildcard)
+}
+
+// ----
+
+func (c *Checker) findRelevantRecordSet(domain string) ([]*dns.CAA, string, error) {
+	labels := dns.SplitDomainName(domain)
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+
+		records, err := c.lookup(candidate)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if len(records) > 0 {
+			return records, candidate, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+func (c *Checker) lookup(name string) ([]*dns.CAA, error) {
+	if c.resolver == "" {
+		return nil, fmt.Errorf("%w for [%s], no resolver is configured", ErrLookupFailed, name)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeCAA)
+	msg.RecursionDesired = true
+
+	response, _, err := c.client.Exchange(msg, c.resolver)
+	if err != nil {
+		logging.GetLogger().Errorf("CAA lookup failed for [%s], %v", name, err)
+		return nil, fmt.Errorf("%w for [%s], %v", ErrLookupFailed, name, err)
+	}
+
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%w for [%s], rcode: %s", ErrLookupFailed, name, dns.RcodeToString[response.Rcode])
+	}
+
+	records := []*dns.CAA{}
+	for _, rr := range response.Answer {
+		record, ok := rr.(*dns.CAA)
+		if ok {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+func evaluate(domain string, owner string, records []*dns.CAA, identity string, wildcard bool) error {
+	for _, record := range records {
+		tag := strings.ToLower(record.Tag)
+		if record.Flag&FLAG_CRITICAL != 0 && !knownTags[tag] {
+			return fmt.Errorf("CAA record at [%s] has unknown critical property [%s], issuance is not allowed",
+				owner, record.Tag)
+		}
+	}
+
+	tag := TAG_ISSUE
+	if wildcard && hasTag(records, TAG_ISSUE_WILD) {
+		tag = TAG_ISSUE_WILD
+	}
+
+	if !hasTag(records, tag) {
+		// record set only contains properties unrelated to issuance, e.g. iodef
+		return nil
+	}
+
+	for _, record := range records {
+		if strings.ToLower(record.Tag) != tag {
+			continue
+		}
+
+		if issuerDomainName(record.Value) == strings.ToLower(identity) && identity != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("CAA records at [%s] do not authorize issuer [%s] for [%s]", owner, identity, domain)
+}
+
+func hasTag(records []*dns.CAA, tag string) bool {
+	for _, record := range records {
+		if strings.ToLower(record.Tag) == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// issue property value is in format "issuer-domain-name; key=value", only issuer domain name is relevant
+func issuerDomainName(value string) string {
+	issuer := strings.SplitN(value, ";", 2)[0]
+	return strings.ToLower(strings.TrimSpace(issuer))
+}
+
+func getSystemResolver() (string, error) {
+	conf, err := dns.ClientConfigFromFile(DEFAULT_RESOLVER_CONFIG_PATH)
+	if err != nil {
+		return "", err
+	} else if len(conf.Servers) == 0 {
+		return "", errors.New(fmt.Sprintf("no nameserver in [%s]", DEFAULT_RESOLVER_CONFIG_PATH))
+	}
+
+	return net.JoinHostPort(conf.Servers[0], conf.Port), nil
+}
@@ -0,0 +1,151 @@
+package caa
+
+import (
+	"errors"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"github.com/miekg/dns"
+)
+
+func TestNoRecordAllowsAnyIssuer(t *testing.T) {
+	checker := createChecker(t, nil)
+
+	err := checker.Check("www.certstore.com", "letsencrypt.org")
+	assert.NotError(t, err, "issuance should be allowed without CAA records")
+}
+
+func TestIssueRecordAuthorizesIssuer(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "letsencrypt.org")},
+	})
+
+	err := checker.Check("certstore.com", "letsencrypt.org")
+	assert.NotError(t, err, "issuer should be authorized")
+}
+
+func TestIssueRecordDeniesOtherIssuer(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "letsencrypt.org")},
+	})
+
+	err := checker.Check("certstore.com", "ca.certstore.internal")
+	assert.ErrorContains(t, err, "do not authorize issuer")
+}
+
+func TestTreeClimbing(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "letsencrypt.org")},
+	})
+
+	err := checker.Check("a.b.certstore.com", "letsencrypt.org")
+	assert.NotError(t, err, "issuer should be authorized by parent domain")
+
+	err = checker.Check("a.b.certstore.com", "other.org")
+	assert.ErrorContains(t, err, "do not authorize issuer")
+}
+
+func TestClosestRecordSetWins(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com":     {NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "letsencrypt.org")},
+		"dev.certstore.com": {NewTestCAARecord("dev.certstore.com", 0, TAG_ISSUE, "ca.certstore.internal")},
+	})
+
+	err := checker.Check("api.dev.certstore.com", "ca.certstore.internal")
+	assert.NotError(t, err, "closest record set should authorize issuer")
+
+	err = checker.Check("api.dev.certstore.com", "letsencrypt.org")
+	assert.ErrorContains(t, err, "do not authorize issuer")
+}
+
+func TestIssuerValueWithParameters(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "LetsEncrypt.org; accounturi=https://acme/1")},
+	})
+
+	err := checker.Check("certstore.com", "letsencrypt.org")
+	assert.NotError(t, err, "issuer should be authorized")
+}
+
+func TestEmptyIssueValueDeniesAll(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {NewTestCAARecord("certstore.com", 0, TAG_ISSUE, ";")},
+	})
+
+	err := checker.Check("certstore.com", "letsencrypt.org")
+	assert.ErrorContains(t, err, "do not authorize issuer")
+}
+
+func TestWildcardUsesIssueWild(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {
+			NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "letsencrypt.org"),
+			NewTestCAARecord("certstore.com", 0, TAG_ISSUE_WILD, "ca.certstore.internal"),
+		},
+	})
+
+	err := checker.Check("*.certstore.com", "ca.certstore.internal")
+	assert.NotError(t, err, "issuewild should authorize wildcard")
+
+	err = checker.Check("*.certstore.com", "letsencrypt.org")
+	assert.ErrorContains(t, err, "do not authorize issuer")
+}
+
+func TestWildcardFallsBackToIssue(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "letsencrypt.org")},
+	})
+
+	err := checker.Check("*.certstore.com", "letsencrypt.org")
+	assert.NotError(t, err, "issue property should be used for wildcard")
+}
+
+func TestOnlyIodefRecordAllowsAnyIssuer(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {NewTestCAARecord("certstore.com", 0, TAG_IODEF, "mailto:security@certstore.com")},
+	})
+
+	err := checker.Check("certstore.com", "letsencrypt.org")
+	assert.NotError(t, err, "iodef should not restrict issuance")
+}
+
+func TestUnknownCriticalTagDenies(t *testing.T) {
+	checker := createChecker(t, map[string][]*dns.CAA{
+		"certstore.com": {
+			NewTestCAARecord("certstore.com", 0, TAG_ISSUE, "letsencrypt.org"),
+			NewTestCAARecord("certstore.com", FLAG_CRITICAL, "tbs", "unknown"),
+		},
+	})
+
+	err := checker.Check("certstore.com", "letsencrypt.org")
+	assert.ErrorContains(t, err, "unknown critical property")
+}
+
+func TestLookupFailureDenies(t *testing.T) {
+	checker := createChecker(t, nil)
+
+	err := checker.Check("servfail.test", "letsencrypt.org")
+	assert.ErrorContains(t, err, "CAA lookup failed")
+}
+
+func TestLookupWithoutResolverFails(t *testing.T) {
+	checker := &Checker{client: &dns.Client{Timeout: DEFAULT_TIMEOUT}}
+
+	err := checker.Check("certstore.com", "letsencrypt.org")
+	assert.TrueM(t, errors.Is(err, ErrLookupFailed), "lookup without a resolver should fail")
+	assert.ErrorContains(t, err, "no resolver is configured")
+}
+
+func TestIPAddressIsSkipped(t *testing.T) {
+	checker := NewChecker("127.0.0.1:1")
+
+	err := checker.Check("10.0.0.1", "letsencrypt.org")
+	assert.NotError(t, err, "ip addresses should be skipped")
+}
+
+// ----
+
+func createChecker(t *testing.T, records map[string][]*dns.CAA) *Checker {
+	server := NewTestDNSServer(t, records)
+	return NewChecker(server.Address)
+}
@@ -0,0 +1,77 @@
+package caa
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// in-process dns server serving CAA records, records are keyed by domain name without trailing dot
+type TestDNSServer struct {
+	Address string
+
+	server  *dns.Server
+	records map[string][]*dns.CAA
+}
+
+func NewTestDNSServer(t *testing.T, records map[string][]*dns.CAA) *TestDNSServer {
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening udp for test dns server failed, %v", err)
+	}
+
+	testServer := &TestDNSServer{
+		Address: packetConn.LocalAddr().String(),
+		records: records,
+	}
+
+	started := make(chan struct{})
+	testServer.server = &dns.Server{
+		PacketConn:        packetConn,
+		Handler:           dns.HandlerFunc(testServer.handle),
+		NotifyStartedFunc: func() { close(started) },
+	}
+
+	go testServer.server.ActivateAndServe()
+	<-started
+
+	t.Cleanup(func() {
+		testServer.server.Shutdown()
+	})
+
+	return testServer
+}
+
+func NewTestCAARecord(domain string, flag uint8, tag string, value string) *dns.CAA {
+	return &dns.CAA{
+		Hdr:   dns.RR_Header{Name: dns.Fqdn(domain), Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 60},
+		Flag:  flag,
+		Tag:   tag,
+		Value: value,
+	}
+}
+
+func (s *TestDNSServer) handle(w dns.ResponseWriter, req *dns.Msg) {
+	response := new(dns.Msg)
+	response.SetReply(req)
+
+	for _, question := range req.Question {
+		name := strings.TrimSuffix(strings.ToLower(question.Name), ".")
+		if name == "servfail.test" {
+			response.Rcode = dns.RcodeServerFailure
+			break
+		}
+
+		if question.Qtype != dns.TypeCAA {
+			continue
+		}
+
+		for _, record := range s.records[name] {
+			response.Answer = append(response.Answer, record)
+		}
+	}
+
+	w.WriteMsg(response)
+}
@@ -0,0 +1,184 @@
+package keypool
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	DEFAULT_CONCURRENCY = 1
+
+	// refill is paused for a while if generating a key fails
+	RETRY_DELAY = 5 * time.Second
+)
+
+var keyTypeBits = map[string]int{
+	service.KEY_TYPE_RSA_2048: 2048,
+	service.KEY_TYPE_RSA_4096: 4096,
+}
+
+type Config struct {
+	KeyType string `yaml:"key-type"`
+
+	// number of keys kept ready
+	Size int `yaml:"size"`
+
+	// number of keys generated at the same time while refilling the pool
+	Concurrency int `yaml:"concurrency"`
+}
+
+func (c Config) Validate() error {
+	if _, exist := keyTypeBits[c.KeyType]; !exist {
+		return errors.New(fmt.Sprintf("key pool key type is unknown: [%s]", c.KeyType))
+	}
+
+	if c.Size < 1 {
+		return errors.New(fmt.Sprintf("key pool size must be positive, [%s]", c.KeyType))
+	}
+
+	if c.Concurrency < 0 {
+		return errors.New(fmt.Sprintf("key pool concurrency can not be negative, [%s]", c.KeyType))
+	}
+
+	return nil
+}
+
+type Stats struct {
+	KeyType string
+
+	// keys ready in the pool
+	Available int
+
+	// requests served from the pool, and generated inline since the pool was empty
+	Hits   uint64
+	Misses uint64
+
+	Generated             uint64
+	AverageGenerationTime time.Duration
+}
+
+// Pool keeps pre-generated keys of a key type, and refills itself in background as they are drawn
+type Pool struct {
+	keyType   string
+	keys      chan *rsa.PrivateKey
+	generator service.KeyGenerator
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	hits            uint64
+	misses          uint64
+	generated       uint64
+	generationNanos int64
+}
+
+func New(conf Config) (*Pool, error) {
+	return NewWithGenerator(conf, service.NewRSAKeyGenerator(keyTypeBits[conf.KeyType]))
+}
+
+func NewWithGenerator(conf Config, generator service.KeyGenerator) (*Pool, error) {
+	err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := conf.Concurrency
+	if concurrency == 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+
+	pool := &Pool{
+		keyType:   conf.KeyType,
+		keys:      make(chan *rsa.PrivateKey, conf.Size),
+		generator: generator,
+		stop:      make(chan struct{}),
+	}
+
+	logging.GetLogger().Infof("Starting key pool [%s], size: [%d], concurrency: [%d]", conf.KeyType, conf.Size, concurrency)
+	for i := 0; i < concurrency; i++ {
+		go pool.refill()
+	}
+
+	return pool, nil
+}
+
+// GenerateKey returns a key from the pool, or generates one if the pool is empty
+func (p *Pool) GenerateKey(ctx context.Context) (*rsa.PrivateKey, error) {
+	select {
+	case key := <-p.keys:
+		atomic.AddUint64(&p.hits, 1)
+		return key, nil
+	default:
+	}
+
+	atomic.AddUint64(&p.misses, 1)
+	logging.GetLogger().Debugf("key pool [%s] is empty, generating key inline", p.keyType)
+	return p.generate(ctx)
+}
+
+func (p *Pool) Stats() Stats {
+	stats := Stats{
+		KeyType:   p.keyType,
+		Available: len(p.keys),
+		Hits:      atomic.LoadUint64(&p.hits),
+		Misses:    atomic.LoadUint64(&p.misses),
+		Generated: atomic.LoadUint64(&p.generated),
+	}
+
+	if stats.Generated > 0 {
+		stats.AverageGenerationTime = time.Duration(atomic.LoadInt64(&p.generationNanos) / int64(stats.Generated))
+	}
+
+	return stats
+}
+
+// Stop stops refilling the pool, keys left in the pool are still served
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// ----
+
+func (p *Pool) refill() {
+	for {
+		key, err := p.generate(context.Background())
+		if err != nil {
+			logging.GetLogger().Errorf("generating key of key pool [%s] failed, %v", p.keyType, err)
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(RETRY_DELAY):
+			}
+
+			continue
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case p.keys <- key:
+		}
+	}
+}
+
+func (p *Pool) generate(ctx context.Context) (*rsa.PrivateKey, error) {
+	start := time.Now()
+	key, err := p.generator.GenerateKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.AddUint64(&p.generated, 1)
+	atomic.AddInt64(&p.generationNanos, int64(time.Since(start)))
+	return key, nil
+}
@@ -0,0 +1,120 @@
+package keypool
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+)
+
+func TestPoolIsFilled(t *testing.T) {
+	generator := newTestGenerator(t)
+	pool, err := NewWithGenerator(Config{KeyType: service.KEY_TYPE_RSA_2048, Size: 3, Concurrency: 2}, generator)
+	assert.NotError(t, err, "creating key pool failed")
+	defer pool.Stop()
+
+	waitAvailable(t, pool, 3)
+
+	// ----
+
+	key, err := pool.GenerateKey(context.Background())
+	assert.NotError(t, err, "getting key from pool failed")
+	assert.NotNil(t, key)
+
+	stats := pool.Stats()
+	assert.Equal(t, service.KEY_TYPE_RSA_2048, stats.KeyType)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(0), stats.Misses)
+
+	// drawn key is replaced
+	waitAvailable(t, pool, 3)
+	assert.True(t, pool.Stats().Generated >= 4)
+}
+
+func TestPoolMiss(t *testing.T) {
+	generator := newTestGenerator(t)
+
+	// pool without refill
+	pool := &Pool{
+		keyType:   service.KEY_TYPE_RSA_2048,
+		keys:      make(chan *rsa.PrivateKey, 1),
+		generator: generator,
+		stop:      make(chan struct{}),
+	}
+
+	// ----
+
+	_, err := pool.GenerateKey(context.Background())
+	assert.NotError(t, err, "generating key inline failed")
+
+	pool.keys <- generator.key
+	_, err = pool.GenerateKey(context.Background())
+	assert.NotError(t, err, "getting key from pool failed")
+
+	stats := pool.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Generated)
+	assert.True(t, stats.AverageGenerationTime > 0)
+}
+
+func TestPoolGenerationFails(t *testing.T) {
+	generator := &testGenerator{err: errors.New("no entropy")}
+	pool, err := NewWithGenerator(Config{KeyType: service.KEY_TYPE_RSA_2048, Size: 1}, generator)
+	assert.NotError(t, err, "creating key pool failed")
+	defer pool.Stop()
+
+	_, err = pool.GenerateKey(context.Background())
+	assert.ErrorContains(t, err, "no entropy")
+}
+
+func TestConfigValidate(t *testing.T) {
+	err := Config{KeyType: "DSA-1024", Size: 1}.Validate()
+	assert.ErrorContains(t, err, "key type is unknown")
+
+	err = Config{KeyType: service.KEY_TYPE_RSA_4096, Size: 0}.Validate()
+	assert.ErrorContains(t, err, "size must be positive")
+
+	err = Config{KeyType: service.KEY_TYPE_RSA_4096, Size: 1, Concurrency: -1}.Validate()
+	assert.ErrorContains(t, err, "concurrency can not be negative")
+}
+
+// -----
+
+// testGenerator returns the same key, generating RSA keys is slow
+type testGenerator struct {
+	key   *rsa.PrivateKey
+	err   error
+	calls int32
+}
+
+func newTestGenerator(t *testing.T) *testGenerator {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NotError(t, err, "generating key failed")
+
+	return &testGenerator{key: key}
+}
+
+func (g *testGenerator) GenerateKey(_ context.Context) (*rsa.PrivateKey, error) {
+	atomic.AddInt32(&g.calls, 1)
+	time.Sleep(time.Millisecond)
+	return g.key, g.err
+}
+
+func waitAvailable(t *testing.T, pool *Pool, available int) {
+	for i := 0; i < 100; i++ {
+		if pool.Stats().Available == available {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("key pool is not filled, available: [%d]", pool.Stats().Available)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// issuing with a ca expiring in less than these days is refused
+	CA_MIN_REMAINING_DAYS = 30
+)
+
+// validateCA checks the ca is able to sign certificates with the private key
+func validateCA(ca *x509.Certificate, caPrivateKey *rsa.PrivateKey, now time.Time) error {
+	publicKey, ok := ca.PublicKey.(*rsa.PublicKey)
+	if !ok || !publicKey.Equal(&caPrivateKey.PublicKey) {
+		return errors.New("ca certificate and private key do not match")
+	}
+
+	if !ca.BasicConstraintsValid || !ca.IsCA {
+		return errors.New(fmt.Sprintf("certificate is not a ca: [%s]", ca.Subject))
+	}
+
+	if ca.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.New(fmt.Sprintf("ca certificate does not have cert sign key usage: [%s]", ca.Subject))
+	}
+
+	return validateCAExpiration(ca, now)
+}
+
+func validateCAExpiration(ca *x509.Certificate, now time.Time) error {
+	if now.Before(ca.NotBefore) {
+		return errors.New(fmt.Sprintf("ca certificate is not valid before [%v]: [%s]", ca.NotBefore, ca.Subject))
+	}
+
+	if now.AddDate(0, 0, CA_MIN_REMAINING_DAYS).After(ca.NotAfter) {
+		return errors.New(fmt.Sprintf("ca certificate expires in less than %d days, at [%v]: [%s]",
+			CA_MIN_REMAINING_DAYS, ca.NotAfter, ca.Subject))
+	}
+
+	return nil
+}
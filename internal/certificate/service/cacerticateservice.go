@@ -4,17 +4,39 @@ import (
 	"bilalekrem.com/certstore/internal/certificate/x509utils"
 	"bilalekrem.com/certstore/internal/logging"
 
+	"context"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
-	"crypto/x509/pkix"
 	"time"
 )
 
 type CACertificateService struct {
+	// zero if certificates are valid from the time they are issued
+	NotBeforeBackdate time.Duration
+
+	// keys are generated when they are requested if nil
+	KeyGenerator KeyGenerator
+}
+
+// ca certificates do not have subject alternative names
+func (service *CACertificateService) Capabilities() Capabilities {
+	return Capabilities{
+		KeyTypes:        []string{KEY_TYPE_RSA_4096},
+		MinValidityDays: 1,
+		SupportedFields: append(append([]string{}, subjectFields...), FIELD_EXPIRATION_DAYS, FIELD_VALIDITY),
+	}
+}
+
+// HealthCheck always succeeds, ca certificates are self signed
+func (service *CACertificateService) HealthCheck(_ context.Context) error {
+	return nil
 }
 
-func (service *CACertificateService) CreateCertificate(request *NewCertificateRequest) (*NewCertificateResponse, error) {
+func (service *CACertificateService) CreateCertificate(ctx context.Context, request *NewCertificateRequest) (*NewCertificateResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	err := validateCertificateRequest(request)
 	if err != nil {
 		logging.GetLogger().Debug("validating ca certificate request failed: [%v]", err)
@@ -29,15 +51,13 @@ func (service *CACertificateService) CreateCertificate(request *NewCertificateRe
 		return nil, err
 	}
 
+	now := time.Now()
 	ca := &x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			CommonName:   request.CommonName,
-			Organization: request.Organization,
-		},
+		SerialNumber:          serialNumber,
+		Subject:               request.Subject(),
 		EmailAddresses:        request.Email,
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().AddDate(0, 0, request.ExpirationDays),
+		NotBefore:             now.Add(-service.NotBeforeBackdate),
+		NotAfter:              now.Add(request.ValidityDuration()),
 		IsCA:                  true,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
@@ -46,7 +66,12 @@ func (service *CACertificateService) CreateCertificate(request *NewCertificateRe
 
 	// ----
 	logging.GetLogger().Debug("Generating private key for CA")
-	caPrivateKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	keyGenerator := service.KeyGenerator
+	if keyGenerator == nil {
+		keyGenerator = NewRSAKeyGenerator(4096)
+	}
+
+	caPrivateKey, err := keyGenerator.GenerateKey(ctx)
 	if err != nil {
 		logging.GetLogger().Debug("generating ca private key failed: [%v]", err)
 		return nil, err
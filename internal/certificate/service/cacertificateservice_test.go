@@ -78,6 +78,16 @@ func TestCA_NotProvidedExpirationDate(t *testing.T) {
 	testNotProvidedExpirationDate(t, &service)
 }
 
+func TestCA_Validity(t *testing.T) {
+	var service CertificateService = createCACertificateService()
+	testValidity(t, &service)
+}
+
+func TestCA_NotValidValidity(t *testing.T) {
+	var service CertificateService = createCACertificateService()
+	testNotValidValidity(t, &service)
+}
+
 func TestCA_StartDate(t *testing.T) {
 	var service CertificateService = createCACertificateService()
 	testStartDate(t, &service)
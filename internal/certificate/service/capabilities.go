@@ -0,0 +1,105 @@
+package service
+
+const (
+	FIELD_COMMON_NAME         = "commonName"
+	FIELD_EMAIL               = "email"
+	FIELD_ORGANIZATION        = "organization"
+	FIELD_ORGANIZATIONAL_UNIT = "organizationalUnit"
+	FIELD_LOCALITY            = "locality"
+	FIELD_PROVINCE            = "province"
+	FIELD_COUNTRY             = "country"
+	FIELD_STREET_ADDRESS      = "streetAddress"
+	FIELD_POSTAL_CODE         = "postalCode"
+	FIELD_SERIAL_NUMBER       = "serialNumber"
+	FIELD_EXPIRATION_DAYS     = "expirationDays"
+	FIELD_VALIDITY            = "validity"
+	FIELD_SANS                = "sans"
+
+	KEY_TYPE_RSA_2048 = "RSA-2048"
+	KEY_TYPE_RSA_4096 = "RSA-4096"
+)
+
+// Capabilities describes what an issuer can issue, so that clients can validate their requests up front
+type Capabilities struct {
+	KeyTypes []string
+
+	// zero if there is no limit, equal if issuer has a fixed validity
+	MinValidityDays int
+	MaxValidityDays int
+
+	// request fields honoured by the issuer, others are ignored
+	SupportedFields []string
+}
+
+func (c Capabilities) Supports(field string) bool {
+	for _, supported := range c.SupportedFields {
+		if supported == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IgnoredFields returns the fields set in request but not honoured by the issuer
+func (c Capabilities) IgnoredFields(request *NewCertificateRequest) []string {
+	setFields := map[string]bool{
+		FIELD_COMMON_NAME:         request.CommonName != "",
+		FIELD_EMAIL:               len(request.Email) > 0,
+		FIELD_ORGANIZATION:        len(request.Organization) > 0,
+		FIELD_ORGANIZATIONAL_UNIT: len(request.OrganizationalUnit) > 0,
+		FIELD_LOCALITY:            len(request.Locality) > 0,
+		FIELD_PROVINCE:            len(request.Province) > 0,
+		FIELD_COUNTRY:             len(request.Country) > 0,
+		FIELD_STREET_ADDRESS:      len(request.StreetAddress) > 0,
+		FIELD_POSTAL_CODE:         len(request.PostalCode) > 0,
+		FIELD_SERIAL_NUMBER:       request.SerialNumber != "",
+		FIELD_EXPIRATION_DAYS:     request.ExpirationDays != 0,
+		FIELD_VALIDITY:            request.Validity != 0,
+		FIELD_SANS:                len(request.SubjectAlternativeNames) > 0,
+	}
+
+	ignored := []string{}
+	for _, field := range allFields {
+		if setFields[field] && !c.Supports(field) {
+			ignored = append(ignored, field)
+		}
+	}
+
+	return ignored
+}
+
+// AllFields returns every request field, in the order of certificate request
+func AllFields() []string {
+	return append([]string{}, allFields...)
+}
+
+// in the order of certificate request
+var allFields = []string{
+	FIELD_COMMON_NAME,
+	FIELD_EMAIL,
+	FIELD_ORGANIZATION,
+	FIELD_ORGANIZATIONAL_UNIT,
+	FIELD_LOCALITY,
+	FIELD_PROVINCE,
+	FIELD_COUNTRY,
+	FIELD_STREET_ADDRESS,
+	FIELD_POSTAL_CODE,
+	FIELD_SERIAL_NUMBER,
+	FIELD_EXPIRATION_DAYS,
+	FIELD_VALIDITY,
+	FIELD_SANS,
+}
+
+var subjectFields = []string{
+	FIELD_COMMON_NAME,
+	FIELD_EMAIL,
+	FIELD_ORGANIZATION,
+	FIELD_ORGANIZATIONAL_UNIT,
+	FIELD_LOCALITY,
+	FIELD_PROVINCE,
+	FIELD_COUNTRY,
+	FIELD_STREET_ADDRESS,
+	FIELD_POSTAL_CODE,
+	FIELD_SERIAL_NUMBER,
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestIgnoredFields(t *testing.T) {
+	capabilities := Capabilities{SupportedFields: []string{FIELD_COMMON_NAME, FIELD_SANS}}
+
+	request := &NewCertificateRequest{
+		CommonName:              "certstore.com",
+		Email:                   []string{"certstore@certstore.com"},
+		ExpirationDays:          30,
+		SubjectAlternativeNames: []string{"www.certstore.com"},
+	}
+
+	ignored := capabilities.IgnoredFields(request)
+	assert.DeepEqual(t, []string{FIELD_EMAIL, FIELD_EXPIRATION_DAYS}, ignored)
+}
+
+func TestIgnoredFieldsNone(t *testing.T) {
+	capabilities := (&certificateServiceImpl{}).Capabilities()
+
+	request := &NewCertificateRequest{
+		CommonName:              "certstore.com",
+		Organization:            []string{"certstore"},
+		ExpirationDays:          30,
+		SubjectAlternativeNames: []string{"www.certstore.com"},
+	}
+
+	assert.Equal(t, 0, len(capabilities.IgnoredFields(request)))
+}
+
+func TestCACertificateServiceIgnoresSANs(t *testing.T) {
+	capabilities := (&CACertificateService{}).Capabilities()
+
+	assert.False(t, capabilities.Supports(FIELD_SANS))
+	assert.True(t, capabilities.Supports(FIELD_EXPIRATION_DAYS))
+}
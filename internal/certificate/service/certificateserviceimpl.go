@@ -4,10 +4,10 @@ import (
 	"bilalekrem.com/certstore/internal/certificate/x509utils"
 	"bilalekrem.com/certstore/internal/logging"
 
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
-	"crypto/x509/pkix"
 	"errors"
 	"time"
 )
@@ -15,9 +15,22 @@ import (
 type certificateServiceImpl struct {
 	ca           *x509.Certificate
 	caPrivateKey *rsa.PrivateKey
+
+	notBeforeBackdate time.Duration
+	keyGenerator      KeyGenerator
 }
 
 func New(privateKeyPem []byte, caPem []byte) (*certificateServiceImpl, error) {
+	return NewWithBackdate(privateKeyPem, caPem, DEFAULT_NOT_BEFORE_BACKDATE)
+}
+
+func NewWithBackdate(privateKeyPem []byte, caPem []byte, notBeforeBackdate time.Duration) (*certificateServiceImpl, error) {
+	return NewWithKeyGenerator(privateKeyPem, caPem, notBeforeBackdate, NewRSAKeyGenerator(4096))
+}
+
+func NewWithKeyGenerator(privateKeyPem []byte, caPem []byte, notBeforeBackdate time.Duration,
+	keyGenerator KeyGenerator) (*certificateServiceImpl, error) {
+
 	caCert, err := x509utils.ParsePemCertificate(caPem)
 	if err != nil {
 		return nil, err
@@ -28,13 +41,47 @@ func New(privateKeyPem []byte, caPem []byte) (*certificateServiceImpl, error) {
 		return nil, err
 	}
 
+	err = validateCA(caCert, caKey, time.Now())
+	if err != nil {
+		logging.GetLogger().Errorf("validating ca failed, %v", err)
+		return nil, err
+	}
+
 	return &certificateServiceImpl{
-		ca:           caCert,
-		caPrivateKey: caKey,
+		ca:                caCert,
+		caPrivateKey:      caKey,
+		notBeforeBackdate: notBeforeBackdate,
+		keyGenerator:      keyGenerator,
 	}, nil
 }
 
-func (service *certificateServiceImpl) CreateCertificate(request *NewCertificateRequest) (*NewCertificateResponse, error) {
+func (service *certificateServiceImpl) Capabilities() Capabilities {
+	return Capabilities{
+		KeyTypes:        []string{KEY_TYPE_RSA_4096},
+		MinValidityDays: 1,
+		SupportedFields: append(append([]string{}, subjectFields...), FIELD_EXPIRATION_DAYS, FIELD_VALIDITY, FIELD_SANS),
+	}
+}
+
+func (service *certificateServiceImpl) CACertificate() *x509.Certificate {
+	return service.ca
+}
+
+// HealthCheck fails when the ca gets close to its expiration
+func (service *certificateServiceImpl) HealthCheck(_ context.Context) error {
+	err := service.validate()
+	if err != nil {
+		return err
+	}
+
+	return validateCAExpiration(service.ca, time.Now())
+}
+
+func (service *certificateServiceImpl) CreateCertificate(ctx context.Context, request *NewCertificateRequest) (*NewCertificateResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	err := service.validate()
 	if err != nil {
 		logging.GetLogger().Debug("validating certificate service failed: [%v]", err)
@@ -55,21 +102,19 @@ func (service *certificateServiceImpl) CreateCertificate(request *NewCertificate
 		return nil, err
 	}
 
+	now := time.Now()
 	cert := &x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			CommonName:   request.CommonName,
-			Organization: request.Organization,
-		},
+		SerialNumber:   serialNumber,
+		Subject:        request.Subject(),
 		EmailAddresses: request.Email,
 		DNSNames:       request.SubjectAlternativeNames,
-		NotBefore:      time.Now(),
-		NotAfter:       time.Now().AddDate(0, 0, request.ExpirationDays),
+		NotBefore:      now.Add(-service.notBeforeBackdate),
+		NotAfter:       now.Add(request.ValidityDuration()),
 		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:       x509.KeyUsageDigitalSignature,
 	}
 
-	certPrivateKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	certPrivateKey, err := service.keyGenerator.GenerateKey(ctx)
 	if err != nil {
 		logging.GetLogger().Debug("generating private key failed: [%v]", err)
 		return nil, err
@@ -86,6 +131,7 @@ func (service *certificateServiceImpl) CreateCertificate(request *NewCertificate
 	response := &NewCertificateResponse{
 		Certificate: certPem.Bytes(),
 		PrivateKey:  certPrivateKeyPem.Bytes(),
+		Chain:       x509utils.EncodePEMCert(service.ca.Raw).Bytes(),
 	}
 
 	return response, nil
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"crypto/x509"
 
@@ -74,6 +76,20 @@ func TestDefault_VerifySignedWithCA(t *testing.T) {
 	assert.NotError(t, err, "verification of CA is failed\n")
 }
 
+func TestDefault_Chain(t *testing.T) {
+	service := createCertificateServiceImpl(t)
+	var polymorphicService CertificateService = service
+
+	request := &NewCertificateRequest{
+		CommonName:     "my-site",
+		ExpirationDays: 5,
+	}
+	response := createCert(t, &polymorphicService, request)
+
+	chain := parsePEMToX509Certificate(t, response.Chain)
+	assert.True(t, chain.Equal(service.ca))
+}
+
 // ----- common certificate service tests
 
 func TestDefault_Email(t *testing.T) {
@@ -116,6 +132,34 @@ func TestDefault_NotProvidedExpirationDate(t *testing.T) {
 	testNotProvidedExpirationDate(t, &service)
 }
 
+func TestDefault_Validity(t *testing.T) {
+	var service CertificateService = createCertificateServiceImpl(t)
+	testValidity(t, &service)
+}
+
+func TestDefault_NotValidValidity(t *testing.T) {
+	var service CertificateService = createCertificateServiceImpl(t)
+	testNotValidValidity(t, &service)
+}
+
+func TestDefault_NotBeforeBackdate(t *testing.T) {
+	service := createCertificateServiceImpl(t)
+	service.notBeforeBackdate = time.Hour
+
+	request := &NewCertificateRequest{
+		CommonName: "my-ca",
+		Validity:   time.Hour,
+	}
+	var certService CertificateService = service
+	response := createCert(t, &certService, request)
+	cert := parsePEMToX509Certificate(t, response.Certificate)
+
+	// certificate is valid for backdate and requested validity
+	assert.True(t, cert.NotBefore.Before(time.Now().Add(-59*time.Minute)))
+	assert.True(t, cert.NotAfter.Sub(cert.NotBefore) <= 2*time.Hour)
+	assert.True(t, cert.NotAfter.Sub(cert.NotBefore) >= 2*time.Hour-time.Second)
+}
+
 func TestDefault_StartDate(t *testing.T) {
 	var service CertificateService = createCertificateServiceImpl(t)
 	testStartDate(t, &service)
@@ -131,6 +175,17 @@ func TestDefault_RSAPrivateKey(t *testing.T) {
 	testRSAPrivateKey(t, &service)
 }
 
+func TestDefault_HealthCheck(t *testing.T) {
+	service := createCertificateServiceImpl(t)
+	err := service.HealthCheck(context.Background())
+	assert.NotError(t, err, "health check failed")
+
+	// ca expires in less than min remaining days
+	service.ca.NotAfter = time.Now().AddDate(0, 0, CA_MIN_REMAINING_DAYS-1)
+	err = service.HealthCheck(context.Background())
+	assert.ErrorContains(t, err, "ca certificate expires in less than")
+}
+
 // ------
 
 func createCertificateServiceImpl(t *testing.T) *certificateServiceImpl {
@@ -139,7 +194,7 @@ func createCertificateServiceImpl(t *testing.T) *certificateServiceImpl {
 		CommonName:     "my-ca",
 		ExpirationDays: 365,
 	}
-	caResponse, _ := caCertService.CreateCertificate(caRequest)
+	caResponse, _ := caCertService.CreateCertificate(context.Background(), caRequest)
 
 	service, err := New(caResponse.PrivateKey, caResponse.Certificate)
 	assert.NotError(t, err, "creating certificate service failed")
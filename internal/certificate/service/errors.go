@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type ErrorKind string
+
+const (
+	// request is not valid, retrying the same request does not help
+	ValidationErrorKind ErrorKind = "validation"
+	NotFoundErrorKind   ErrorKind = "not-found"
+
+	// request is valid, but refused by a policy, e.g. CAA records
+	PolicyDeniedErrorKind ErrorKind = "policy-denied"
+
+	// issuer backend failed temporarily, request can be retried
+	BackendUnavailableErrorKind ErrorKind = "backend-unavailable"
+	RateLimitedErrorKind        ErrorKind = "rate-limited"
+)
+
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// Error is a typed error of certificate services, kind tells if the request can be retried
+type Error struct {
+	Kind    ErrorKind
+	Message string
+
+	// set for validation errors
+	FieldViolations []FieldViolation
+
+	// zero if it is not known when to retry
+	RetryAfter time.Duration
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s, %v", e.Message, e.cause)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// ----
+
+func NewValidationError(field string, description string) *Error {
+	return &Error{
+		Kind:            ValidationErrorKind,
+		Message:         "Validation error: " + description,
+		FieldViolations: []FieldViolation{{Field: field, Description: description}},
+	}
+}
+
+func NewNotFoundError(message string, cause error) *Error {
+	return &Error{Kind: NotFoundErrorKind, Message: message, cause: cause}
+}
+
+func NewPolicyDeniedError(message string, cause error) *Error {
+	return &Error{Kind: PolicyDeniedErrorKind, Message: message, cause: cause}
+}
+
+func NewBackendUnavailableError(message string, cause error) *Error {
+	return &Error{Kind: BackendUnavailableErrorKind, Message: message, cause: cause}
+}
+
+func NewRateLimitedError(message string, retryAfter time.Duration, cause error) *Error {
+	return &Error{Kind: RateLimitedErrorKind, Message: message, RetryAfter: retryAfter, cause: cause}
+}
+
+// AsError returns the typed error in err's chain, nil if there is none
+func AsError(err error) *Error {
+	var serviceError *Error
+	if errors.As(err, &serviceError) {
+		return serviceError
+	}
+
+	return nil
+}
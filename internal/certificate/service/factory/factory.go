@@ -1,10 +1,18 @@
 package factory
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
 
 	"bilalekrem.com/certstore/internal/certificate/service"
 	"bilalekrem.com/certstore/internal/certificate/service/letsencrypt"
+	"bilalekrem.com/certstore/internal/certificate/service/plugin"
+	"bilalekrem.com/certstore/internal/certificate/service/relay"
+	"bilalekrem.com/certstore/internal/certificate/service/sshca"
 	"bilalekrem.com/certstore/internal/logging"
 )
 
@@ -14,10 +22,31 @@ const (
 	Simple               ServiceType = "Simple"
 	CertificateAuthority             = "CertificateAuthority"
 	LetsEncrypt                      = "LetsEncrypt"
+	Plugin                           = "Plugin"
+	Certstore                        = "Certstore"
+	SSHCA                            = "SSHCA"
 	Unknown                          = "Unknown"
 )
 
-func NewService(t ServiceType, args map[string]string) service.CertificateService {
+// CAA issuer domain names of well known certificate authorities
+var defaultCAAIdentities = map[ServiceType]string{
+	LetsEncrypt: "letsencrypt.org",
+}
+
+func DefaultCAAIdentity(t ServiceType) string {
+	return defaultCAAIdentities[t]
+}
+
+// NewService returns an error if the service can not issue certificates with the args, e.g. unreadable files
+func NewService(t ServiceType, args map[string]string) (service.CertificateService, error) {
+	return NewServiceWithKeyGenerators(t, args, nil)
+}
+
+// NewServiceWithKeyGenerators creates services drawing private keys from the generators of their key types,
+// keys of other key types are generated when they are requested
+func NewServiceWithKeyGenerators(t ServiceType, args map[string]string,
+	keyGenerators map[string]service.KeyGenerator) (service.CertificateService, error) {
+
 	logging.GetLogger().Debugf("Creating new service with type [%s], with args: [%v]", t, args)
 
 	switch t {
@@ -28,50 +57,206 @@ func NewService(t ServiceType, args map[string]string) service.CertificateServic
 		caPrivateKey, err := ioutil.ReadFile(caPrivateKeyPath)
 		if err != nil {
 			logging.GetLogger().Errorf("reading private key failed, %v", err)
+			return nil, err
 		}
 		caCertificate, err := ioutil.ReadFile(caCertificatePath)
 		if err != nil {
 			logging.GetLogger().Errorf("reading certificate failed, %v", err)
+			return nil, err
+		}
+
+		backdate, err := notBeforeBackdate(args)
+		if err != nil {
+			return nil, err
 		}
 
-		svc, err := service.New([]byte(caPrivateKey), []byte(caCertificate))
+		keyGenerator := rsa4096KeyGenerator(keyGenerators)
+		svc, err := service.NewWithKeyGenerator([]byte(caPrivateKey), []byte(caCertificate), backdate, keyGenerator)
 		if err != nil {
 			logging.GetLogger().Errorf("error occurred while creating new certificate service, %v", err)
-			return nil
+			return nil, err
 		}
-		return svc
+		return svc, nil
 	case CertificateAuthority:
-		svc := &service.CACertificateService{}
-		return svc
+		backdate, err := notBeforeBackdate(args)
+		if err != nil {
+			return nil, err
+		}
+
+		svc := &service.CACertificateService{
+			NotBeforeBackdate: backdate,
+			KeyGenerator:      rsa4096KeyGenerator(keyGenerators),
+		}
+		return svc, nil
 	case LetsEncrypt:
 		userEmail := args["email"]
 		if userEmail == "" {
 			logging.GetLogger().Errorf("email is required field for lets encrypt service")
-			return nil
+			return nil, errors.New("email is required field for lets encrypt service")
 		}
 
 		userPrivateKeyPath := args["private-key"]
 		if userPrivateKeyPath == "" {
 			logging.GetLogger().Errorf("private-key is required field for lets encrypt service")
-			return nil
+			return nil, errors.New("private-key is required field for lets encrypt service")
 		}
 
 		provider := args["provider"]
 		if provider == "" {
 			logging.GetLogger().Errorf("provider is required field for lets encrypt service")
-			return nil
+			return nil, errors.New("provider is required field for lets encrypt service")
 		}
 
 		svc, err := letsencrypt.New(userEmail, userPrivateKeyPath, provider)
 		if err != nil {
 			logging.GetLogger().Errorf("error occurred while creating new lets encrypt certificate service, %v", err)
-			return nil
+			return nil, err
+		}
+
+		return svc, nil
+	case Plugin:
+		command := args["command"]
+		if command == "" {
+			logging.GetLogger().Errorf("command is required field for plugin service")
+			return nil, errors.New("command is required field for plugin service")
+		}
+
+		// other args are passed to plugin
+		config := make(map[string]string)
+		for key, value := range args {
+			if key != "command" && key != "args" {
+				config[key] = value
+			}
+		}
+
+		svc, err := plugin.New(command, pluginArgs(args["args"]), config)
+		if err != nil {
+			logging.GetLogger().Errorf("error occurred while creating new plugin certificate service, %v", err)
+			return nil, err
+		}
+
+		return svc, nil
+	case Certstore:
+		conf, err := relayConfig(args)
+		if err != nil {
+			logging.GetLogger().Errorf("relay service args are not valid, %v", err)
+			return nil, err
+		}
+
+		svc, err := relay.New(conf)
+		if err != nil {
+			logging.GetLogger().Errorf("error occurred while creating new relay certificate service, %v", err)
+			return nil, err
+		}
+
+		return svc, nil
+	case SSHCA:
+		caPrivateKeyPath := args["private-key"]
+		if caPrivateKeyPath == "" {
+			logging.GetLogger().Errorf("private-key is required field for ssh ca service")
+			return nil, errors.New("private-key is required field for ssh ca service")
+		}
+
+		caPrivateKey, err := ioutil.ReadFile(caPrivateKeyPath)
+		if err != nil {
+			logging.GetLogger().Errorf("reading private key failed, %v", err)
+			return nil, err
+		}
+
+		var maxValidity time.Duration
+		maxValidityStr := args["max-validity"]
+		if maxValidityStr != "" {
+			maxValidity, err = time.ParseDuration(maxValidityStr)
+			if err != nil || maxValidity < 0 {
+				return nil, errors.New(fmt.Sprintf("max-validity is not valid: [%s]", maxValidityStr))
+			}
 		}
 
-		return svc
-	case Unknown:
-	default:
+		backdate, err := notBeforeBackdate(args)
+		if err != nil {
+			return nil, err
+		}
+
+		var allowedPrincipals []string
+		if args["allowed-principals"] != "" {
+			allowedPrincipals = strings.Split(args["allowed-principals"], ";")
+		}
+
+		svc, err := sshca.New(caPrivateKey, maxValidity, backdate, allowedPrincipals)
+		if err != nil {
+			logging.GetLogger().Errorf("error occurred while creating new ssh ca service, %v", err)
+			return nil, err
+		}
+
+		return svc, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("unknown certificate service type: [%s]", t))
+}
+
+func rsa4096KeyGenerator(keyGenerators map[string]service.KeyGenerator) service.KeyGenerator {
+	keyGenerator, exist := keyGenerators[service.KEY_TYPE_RSA_4096]
+	if !exist {
+		return service.NewRSAKeyGenerator(4096)
+	}
+
+	return keyGenerator
+}
+
+// notBeforeBackdate returns how long before issuance certificates of the service are valid
+func notBeforeBackdate(args map[string]string) (time.Duration, error) {
+	backdateStr := args["not-before-backdate"]
+	if backdateStr == "" {
+		return service.DEFAULT_NOT_BEFORE_BACKDATE, nil
+	}
+
+	backdate, err := time.ParseDuration(backdateStr)
+	if err != nil || backdate < 0 {
+		logging.GetLogger().Errorf("not-before-backdate is not valid: [%s]", backdateStr)
+		return 0, errors.New(fmt.Sprintf("not-before-backdate is not valid: [%s]", backdateStr))
+	}
+
+	return backdate, nil
+}
+
+// pluginArgs splits args separated by semicolon, args are passed as is, e.g. with spaces
+func pluginArgs(value string) []string {
+	if value == "" {
 		return nil
 	}
-	return nil
+
+	return strings.Split(value, ";")
+}
+
+func relayConfig(args map[string]string) (relay.Config, error) {
+	for _, required := range []string{"server-address", "issuer", "tls-ca-cert", "tls-cert", "tls-cert-key"} {
+		if args[required] == "" {
+			return relay.Config{}, errors.New(fmt.Sprintf("%s is required field for certstore service", required))
+		}
+	}
+
+	conf := relay.Config{
+		ServerAddress: args["server-address"],
+		Issuer:        args["issuer"],
+		TlsCACert:     args["tls-ca-cert"],
+		TlsCert:       args["tls-cert"],
+		TlsCertKey:    args["tls-cert-key"],
+	}
+
+	allowedDomains := args["allowed-domains"]
+	if allowedDomains != "" {
+		conf.Policy.AllowedDomains = strings.Split(allowedDomains, ";")
+	}
+
+	maxExpirationDaysStr := args["max-expiration-days"]
+	if maxExpirationDaysStr != "" {
+		maxExpirationDays, err := strconv.Atoi(maxExpirationDaysStr)
+		if err != nil || maxExpirationDays < 0 {
+			return relay.Config{}, errors.New(fmt.Sprintf("max-expiration-days is not valid: [%s]", maxExpirationDaysStr))
+		}
+
+		conf.Policy.MaxExpirationDays = maxExpirationDays
+	}
+
+	return conf, nil
 }
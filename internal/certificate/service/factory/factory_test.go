@@ -1,13 +1,17 @@
 package factory
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
 
 	"bilalekrem.com/certstore/internal/assert"
-	"bilalekrem.com/certstore/internal/testutils"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certificate/x509utils"
 )
 
 func TestNewSimpleCertificateService(t *testing.T) {
@@ -17,29 +21,90 @@ func TestNewSimpleCertificateService(t *testing.T) {
 
 	// ------
 
-	privateKeyPath := fmt.Sprintf("%s/ca.key", dir)
-	privateKey := testutils.GetCAPrivateKey()
-	ioutil.WriteFile(privateKeyPath, []byte(privateKey), 0666)
-
-	certPath := fmt.Sprintf("%s/ca.crt", dir)
-	certPem := testutils.GetCAPem()
-	ioutil.WriteFile(certPath, []byte(certPem), 0666)
+	ca := createTestCA(t, 365)
+	args := writeTestCA(t, dir, ca.Certificate, ca.PrivateKey)
 
 	// -----
 
+	service, err := NewService(Simple, args)
+	assert.NotError(t, err, "creating simple certificate service failed")
+	assert.NotNil(t, service)
+}
+
+func TestNewSimpleCertificateServiceMissingFiles(t *testing.T) {
 	args := make(map[string]string)
-	args["private-key"] = privateKeyPath
-	args["certificate"] = certPath
+	args["private-key"] = "/tmp/certstore-missing-ca.key"
+	args["certificate"] = "/tmp/certstore-missing-ca.crt"
 
-	service := NewService(Simple, args)
-	assert.NotNil(t, service)
+	service, err := NewService(Simple, args)
+	assert.Error(t, err, "reading missing files should fail")
+	assert.Nil(t, service)
+}
+
+func TestNewSimpleCertificateServiceNotValidCA(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_new_cert_service")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	// ------
+
+	ca := createTestCA(t, 365)
+	otherCA := createTestCA(t, 365)
+	args := writeTestCA(t, dir, ca.Certificate, otherCA.PrivateKey)
+
+	_, err = NewService(Simple, args)
+	assert.ErrorContains(t, err, "ca certificate and private key do not match")
+
+	// -----
+
+	expiringCA := createTestCA(t, 10)
+	args = writeTestCA(t, dir, expiringCA.Certificate, expiringCA.PrivateKey)
+
+	_, err = NewService(Simple, args)
+	assert.ErrorContains(t, err, "ca certificate expires in less than")
+
+	// -----
+
+	// certificates issued by a ca are not ca
+	caService, err := service.New(ca.PrivateKey, ca.Certificate)
+	assert.NotError(t, err, "creating certificate service failed")
+	leaf, err := caService.CreateCertificate(context.Background(), &service.NewCertificateRequest{
+		CommonName:     "certstore.com",
+		ExpirationDays: 365,
+	})
+	assert.NotError(t, err, "creating certificate failed")
+	args = writeTestCA(t, dir, leaf.Certificate, leaf.PrivateKey)
+
+	_, err = NewService(Simple, args)
+	assert.ErrorContains(t, err, "certificate is not a ca")
 }
 
 func TestCACertificateService(t *testing.T) {
-	service := NewService(CertificateAuthority, nil)
+	service, err := NewService(CertificateAuthority, nil)
+	assert.NotError(t, err, "creating ca certificate service failed")
 	assert.NotNil(t, service)
 }
 
+func TestCACertificateServiceWithKeyGenerator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NotError(t, err, "generating key failed")
+	keyGenerators := map[string]service.KeyGenerator{service.KEY_TYPE_RSA_4096: testKeyGenerator{key: key}}
+
+	caService, err := NewServiceWithKeyGenerators(CertificateAuthority, nil, keyGenerators)
+	assert.NotError(t, err, "creating ca certificate service failed")
+
+	response, err := caService.CreateCertificate(context.Background(), &service.NewCertificateRequest{
+		CommonName:     "certstore.com",
+		ExpirationDays: 365,
+	})
+	assert.NotError(t, err, "creating certificate failed")
+
+	// key is drawn from generator of the key type
+	privateKey, err := x509utils.ParsePemPrivateKey(response.PrivateKey)
+	assert.NotError(t, err, "parsing private key failed")
+	assert.True(t, key.Equal(privateKey))
+}
+
 func TestLetsEncrypt(t *testing.T) {
 	dir, err := ioutil.TempDir("/tmp", "test_new_cert_service")
 	assert.NotError(t, err, "creating temp dir failed")
@@ -54,7 +119,8 @@ func TestLetsEncrypt(t *testing.T) {
 	args["email"] = "test@certstore.com"
 	args["provider"] = "mock"
 
-	service := NewService(LetsEncrypt, args)
+	service, err := NewService(LetsEncrypt, args)
+	assert.NotError(t, err, "creating lets encrypt certificate service failed")
 	assert.NotNil(t, service)
 }
 
@@ -64,8 +130,8 @@ func TestLetsEncryptMissingFields(t *testing.T) {
 	args["private-key"] = "private key path"
 	args["provider"] = "mock"
 
-	service := NewService(LetsEncrypt, nil)
-	assert.Nil(t, service)
+	_, err := NewService(LetsEncrypt, args)
+	assert.ErrorContains(t, err, "email is required")
 
 	// -----
 
@@ -74,8 +140,8 @@ func TestLetsEncryptMissingFields(t *testing.T) {
 	args["email"] = "test@certstore.com"
 	args["provider"] = "mock"
 
-	service = NewService(LetsEncrypt, nil)
-	assert.Nil(t, service)
+	_, err = NewService(LetsEncrypt, args)
+	assert.ErrorContains(t, err, "private-key is required")
 
 	// -----
 
@@ -84,16 +150,122 @@ func TestLetsEncryptMissingFields(t *testing.T) {
 	args["private-key"] = "private key path"
 	args["email"] = "test@certstore.com"
 
-	service = NewService(LetsEncrypt, nil)
-	assert.Nil(t, service)
+	_, err = NewService(LetsEncrypt, args)
+	assert.ErrorContains(t, err, "provider is required")
+}
+
+func TestPluginMissingFields(t *testing.T) {
+	args := make(map[string]string)
+	args["args"] = "--verbose"
+
+	_, err := NewService(Plugin, args)
+	assert.ErrorContains(t, err, "command is required")
+
+	// -----
+
+	args["command"] = "certstore-missing-plugin"
+	_, err = NewService(Plugin, args)
+	assert.Error(t, err, "missing plugin executable should fail")
+}
+
+func TestPluginArgs(t *testing.T) {
+	assert.Nil(t, pluginArgs(""))
+	assert.DeepEqual(t, []string{"--profile", "web server"}, pluginArgs("--profile;web server"))
 }
 
-func TestUnknownServiceShouldBeNil(t *testing.T) {
-	service := NewService(Unknown, nil)
+func TestCertstoreMissingFields(t *testing.T) {
+	args := make(map[string]string)
+	args["server-address"] = "central.certstore.com:10000"
+	args["issuer"] = "central issuer"
+
+	_, err := NewService(Certstore, args)
+	assert.ErrorContains(t, err, "tls-ca-cert is required")
+
+	// -----
+
+	args["tls-ca-cert"] = "/tmp/certstore-missing-ca.crt"
+	args["tls-cert"] = "/tmp/certstore-missing.crt"
+	args["tls-cert-key"] = "/tmp/certstore-missing.key"
+	args["max-expiration-days"] = "thirty"
+	_, err = NewService(Certstore, args)
+	assert.ErrorContains(t, err, "max-expiration-days is not valid")
+
+	// -----
+
+	delete(args, "max-expiration-days")
+	_, err = NewService(Certstore, args)
+	assert.Error(t, err, "missing tls files should fail")
+}
+
+func TestSSHCAMissingFields(t *testing.T) {
+	args := make(map[string]string)
+	args["max-validity"] = "24h"
+
+	_, err := NewService(SSHCA, args)
+	assert.ErrorContains(t, err, "private-key is required")
+
+	// -----
+
+	args["private-key"] = "/tmp/certstore-missing-ssh-ca"
+	_, err = NewService(SSHCA, args)
+	assert.Error(t, err, "reading missing ssh ca key should fail")
+}
+
+func TestNotBeforeBackdateNotValid(t *testing.T) {
+	args := map[string]string{"not-before-backdate": "-5m"}
+
+	_, err := NewService(CertificateAuthority, args)
+	assert.ErrorContains(t, err, "not-before-backdate is not valid")
+
+	args["not-before-backdate"] = "1h"
+	_, err = NewService(CertificateAuthority, args)
+	assert.NotError(t, err, "creating ca service failed")
+}
+
+func TestUnknownServiceShouldFail(t *testing.T) {
+	service, err := NewService(Unknown, nil)
+	assert.ErrorContains(t, err, "unknown certificate service type")
 	assert.Nil(t, service)
 }
 
-func TestUnrelatedServiceShouldBeNil(t *testing.T) {
-	service := NewService("test", nil)
+func TestUnrelatedServiceShouldFail(t *testing.T) {
+	service, err := NewService("test", nil)
+	assert.ErrorContains(t, err, "unknown certificate service type: [test]")
 	assert.Nil(t, service)
 }
+
+// -----
+
+func createTestCA(t *testing.T, expirationDays int) *service.NewCertificateResponse {
+	caService := &service.CACertificateService{}
+	ca, err := caService.CreateCertificate(context.Background(), &service.NewCertificateRequest{
+		CommonName:     "test-ca",
+		ExpirationDays: expirationDays,
+	})
+	assert.NotError(t, err, "creating ca failed")
+
+	return ca
+}
+
+func writeTestCA(t *testing.T, dir string, certificate []byte, privateKey []byte) map[string]string {
+	privateKeyPath := fmt.Sprintf("%s/ca.key", dir)
+	err := ioutil.WriteFile(privateKeyPath, privateKey, 0666)
+	assert.NotError(t, err, "saving ca private key failed")
+
+	certPath := fmt.Sprintf("%s/ca.crt", dir)
+	err = ioutil.WriteFile(certPath, certificate, 0666)
+	assert.NotError(t, err, "saving ca failed")
+
+	args := make(map[string]string)
+	args["private-key"] = privateKeyPath
+	args["certificate"] = certPath
+	return args
+}
+
+type testKeyGenerator struct {
+	key *rsa.PrivateKey
+}
+
+func (g testKeyGenerator) GenerateKey(_ context.Context) (*rsa.PrivateKey, error) {
+	return g.key, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"bilalekrem.com/certstore/internal/metrics"
+)
+
+// KeyGenerator generates private keys of issued certificates, e.g. from a pool of pre-generated keys
+type KeyGenerator interface {
+	GenerateKey(context.Context) (*rsa.PrivateKey, error)
+}
+
+type rsaKeyGenerator struct {
+	bits int
+}
+
+// NewRSAKeyGenerator returns a generator generating keys when they are requested
+func NewRSAKeyGenerator(bits int) KeyGenerator {
+	return rsaKeyGenerator{bits: bits}
+}
+
+func (g rsaKeyGenerator) GenerateKey(ctx context.Context) (*rsa.PrivateKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	key, err := rsa.GenerateKey(rand.Reader, g.bits)
+	if err == nil {
+		metrics.KeyGenerationDuration.WithLabelValues(fmt.Sprintf("RSA-%d", g.bits)).Observe(time.Since(start).Seconds())
+	}
+
+	return key, err
+}
@@ -1,7 +1,10 @@
 package letsencrypt
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
 
 	"bilalekrem.com/certstore/internal/certificate/service"
@@ -9,13 +12,23 @@ import (
 	"bilalekrem.com/certstore/internal/lego/providers/mock"
 	"bilalekrem.com/certstore/internal/lego/providers/windns"
 	"bilalekrem.com/certstore/internal/logging"
+	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/certificate"
 	"github.com/go-acme/lego/v4/challenge"
 	real_lego "github.com/go-acme/lego/v4/lego"
 )
 
+const (
+	RATE_LIMITED_PROBLEM = "urn:ietf:params:acme:error:rateLimited"
+
+	CERTIFICATE_VALIDITY_DAYS = 90
+)
+
 type letsEncryptCertificateService struct {
 	lego lego.LegoAdapter
+
+	// acme directory, requested by health checks
+	directoryURL string
 }
 
 func New(email string, privateKeyPath string, providerName string) (*letsEncryptCertificateService, error) {
@@ -47,12 +60,16 @@ func New(email string, privateKeyPath string, providerName string) (*letsEncrypt
 		}
 	}
 
-	return &letsEncryptCertificateService{lego: adapter}, nil
+	return &letsEncryptCertificateService{lego: adapter, directoryURL: real_lego.LEDirectoryProduction}, nil
 }
 
-func (c *letsEncryptCertificateService) CreateCertificate(request *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
+func (c *letsEncryptCertificateService) CreateCertificate(ctx context.Context, request *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
 	logging.GetLogger().Info("Creating certificate with lets encrypt service")
-	logging.GetLogger().Warnf("Lets encrpyt certificate service ignores 'email', 'organization', 'expiration days' fields")
+
+	ignoredFields := c.Capabilities().IgnoredFields(request)
+	if len(ignoredFields) > 0 {
+		logging.GetLogger().Warnf("Lets encrypt certificate service ignores fields of the request: %v", ignoredFields)
+	}
 
 	// ----
 
@@ -74,9 +91,9 @@ func (c *letsEncryptCertificateService) CreateCertificate(request *service.NewCe
 		Bundle:  true,
 	}
 
-	obtainResource, err := c.lego.Obtain(obtainRequest)
+	obtainResource, err := c.lego.Obtain(ctx, obtainRequest)
 	if err != nil {
-		return nil, err
+		return nil, convertACMEError(err, "Obtaining certificate from lets encrypt failed")
 	}
 	cert := obtainResource.Certificate
 	privateKey := obtainResource.PrivateKey
@@ -84,24 +101,87 @@ func (c *letsEncryptCertificateService) CreateCertificate(request *service.NewCe
 	return &service.NewCertificateResponse{
 		Certificate: cert,
 		PrivateKey:  privateKey,
+		Chain:       obtainResource.IssuerCertificate,
 	}, nil
 }
 
+// lets encrypt issues domain validated certificates, subject is the common name and validity is fixed
+func (c *letsEncryptCertificateService) Capabilities() service.Capabilities {
+	return service.Capabilities{
+		KeyTypes:        []string{service.KEY_TYPE_RSA_2048},
+		MinValidityDays: CERTIFICATE_VALIDITY_DAYS,
+		MaxValidityDays: CERTIFICATE_VALIDITY_DAYS,
+		SupportedFields: []string{service.FIELD_COMMON_NAME, service.FIELD_SANS},
+	}
+}
+
+// HealthCheck fails if acme directory of lets encrypt is not reachable
+func (c *letsEncryptCertificateService) HealthCheck(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.directoryURL, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return service.NewBackendUnavailableError("Lets encrypt directory is not reachable", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return service.NewBackendUnavailableError(
+			fmt.Sprintf("Lets encrypt directory returned status: [%d]", response.StatusCode), nil)
+	}
+
+	return nil
+}
+
+// RevokeCertificate revokes the certificate at lets encrypt, with the account issued it
+func (c *letsEncryptCertificateService) RevokeCertificate(ctx context.Context, certificate []byte,
+	reason service.RevocationReason) error {
+
+	err := c.lego.Revoke(ctx, certificate, uint(reason))
+	if err != nil {
+		return convertACMEError(err, "Revoking certificate at lets encrypt failed")
+	}
+
+	return nil
+}
+
 // ---
 
 func validateCertificateRequest(req *service.NewCertificateRequest) error {
 	if req.CommonName == "" {
-		return errors.New("Validation error: common name can not be empty")
+		return service.NewValidationError(service.FIELD_COMMON_NAME, "common name can not be empty")
 	}
 
 	return nil
 }
 
+// acme problems other than server errors are permanent, e.g. unauthorized or rejected identifier. failure is the
+// message of other errors
+func convertACMEError(err error, failure string) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var problem *acme.ProblemDetails
+	if errors.As(err, &problem) {
+		if problem.Type == RATE_LIMITED_PROBLEM {
+			return service.NewRateLimitedError("Lets encrypt rate limit exceeded", 0, err)
+		} else if problem.HTTPStatus > 0 && problem.HTTPStatus < 500 {
+			return service.NewPolicyDeniedError("Lets encrypt refused the request", err)
+		}
+	}
+
+	return service.NewBackendUnavailableError(failure, err)
+}
+
 func getProvider(providerName string) (challenge.Provider, error) {
 	if providerName == "mock" {
-		return &mock.MockDNSProvider{}, nil
+		return lego.NewTimedProvider(providerName, &mock.MockDNSProvider{}), nil
 	} else if providerName == "windns" {
-		return windns.NewWinDnsProvider(), nil
+		return lego.NewTimedProvider(providerName, windns.NewWinDnsProvider()), nil
 	}
 
 	logging.GetLogger().Errorf("lego challenge not found with name, %s", providerName)
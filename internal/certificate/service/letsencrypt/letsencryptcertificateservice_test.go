@@ -1,11 +1,17 @@
 package letsencrypt
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"bilalekrem.com/certstore/internal/assert"
 	"bilalekrem.com/certstore/internal/certificate/service"
 	"bilalekrem.com/certstore/internal/lego"
+	"github.com/go-acme/lego/v4/acme"
 	"github.com/go-acme/lego/v4/certificate"
 	"github.com/golang/mock/gomock"
 )
@@ -25,29 +31,32 @@ func TestCreateCertificate(t *testing.T) {
 	commonName := "certstore.com"
 	responseCert := []byte("test certificate content")
 	responsePrivateKey := []byte("test private key content")
+	responseIssuerCert := []byte("test issuer certificate content")
 
 	adapter.
 		EXPECT().
-		Obtain(gomock.Any()).
-		DoAndReturn(func(req certificate.ObtainRequest) (*certificate.Resource, error) {
+		Obtain(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req certificate.ObtainRequest) (*certificate.Resource, error) {
 			assert.Equal(t, 1, len(req.Domains))
 			assert.Equal(t, req.Domains[0], commonName)
 
 			return &certificate.Resource{
-				Certificate: responseCert,
-				PrivateKey:  responsePrivateKey,
+				Certificate:       responseCert,
+				PrivateKey:        responsePrivateKey,
+				IssuerCertificate: responseIssuerCert,
 			}, nil
 		})
 
 	request := &service.NewCertificateRequest{
 		CommonName: commonName,
 	}
-	response, err := leService.CreateCertificate(request)
+	response, err := leService.CreateCertificate(context.Background(), request)
 	assert.NotNil(t, response)
 	assert.NotError(t, err, "creating lets encrypt cert failed")
 
 	assert.DeepEqual(t, responseCert, response.Certificate)
 	assert.DeepEqual(t, responsePrivateKey, response.PrivateKey)
+	assert.DeepEqual(t, responseIssuerCert, response.Chain)
 }
 
 func TestCreateCertificateWithSans(t *testing.T) {
@@ -64,8 +73,8 @@ func TestCreateCertificateWithSans(t *testing.T) {
 
 	adapter.
 		EXPECT().
-		Obtain(gomock.Any()).
-		DoAndReturn(func(req certificate.ObtainRequest) (*certificate.Resource, error) {
+		Obtain(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req certificate.ObtainRequest) (*certificate.Resource, error) {
 			assert.Equal(t, 3, len(req.Domains))
 			assert.Equal(t, req.Domains[0], commonName)
 			assert.Equal(t, req.Domains[1], sans[0])
@@ -81,7 +90,7 @@ func TestCreateCertificateWithSans(t *testing.T) {
 		CommonName:              commonName,
 		SubjectAlternativeNames: sans,
 	}
-	response, err := leService.CreateCertificate(request)
+	response, err := leService.CreateCertificate(context.Background(), request)
 	assert.NotNil(t, response)
 	assert.NotError(t, err, "creating lets encrypt cert failed")
 
@@ -95,6 +104,69 @@ func TestCreateCertificateMissingCommonName(t *testing.T) {
 	request := &service.NewCertificateRequest{
 		SubjectAlternativeNames: []string{},
 	}
-	_, err := leService.CreateCertificate(request)
+	_, err := leService.CreateCertificate(context.Background(), request)
 	assert.ErrorContains(t, err, "Validation error: common name")
+	assert.Equal(t, service.ValidationErrorKind, service.AsError(err).Kind)
+}
+
+func TestRevokeCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	adapter := lego.NewMockLegoAdapter(ctrl)
+	adapter.
+		EXPECT().
+		Revoke(gomock.Any(), gomock.Eq([]byte("certificate")), gomock.Eq(uint(1))).
+		Return(nil)
+	adapter.
+		EXPECT().
+		Revoke(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&acme.ProblemDetails{Type: "urn:ietf:params:acme:error:alreadyRevoked", HTTPStatus: 400})
+
+	leService := &letsEncryptCertificateService{lego: adapter}
+
+	err := leService.RevokeCertificate(context.Background(), []byte("certificate"), service.RevocationKeyCompromise)
+	assert.NotError(t, err, "revoking certificate failed")
+
+	err = leService.RevokeCertificate(context.Background(), []byte("certificate"), service.RevocationUnspecified)
+	assert.Equal(t, service.PolicyDeniedErrorKind, service.AsError(err).Kind)
+}
+
+func TestConvertACMEError(t *testing.T) {
+	rateLimited := &acme.ProblemDetails{Type: RATE_LIMITED_PROBLEM, HTTPStatus: 429}
+	err := convertACMEError(fmt.Errorf("acme: error, %w", rateLimited), "obtaining failed")
+	assert.Equal(t, service.RateLimitedErrorKind, service.AsError(err).Kind)
+
+	rejected := &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:rejectedIdentifier", HTTPStatus: 400}
+	err = convertACMEError(rejected, "obtaining failed")
+	assert.Equal(t, service.PolicyDeniedErrorKind, service.AsError(err).Kind)
+
+	serverError := &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:serverInternal", HTTPStatus: 500}
+	err = convertACMEError(serverError, "obtaining failed")
+	assert.Equal(t, service.BackendUnavailableErrorKind, service.AsError(err).Kind)
+
+	err = convertACMEError(errors.New("connection refused"), "obtaining failed")
+	assert.Equal(t, service.BackendUnavailableErrorKind, service.AsError(err).Kind)
+	assert.ErrorContains(t, err, "obtaining failed")
+
+	err = convertACMEError(context.DeadlineExceeded, "obtaining failed")
+	assert.Nil(t, service.AsError(err))
+}
+
+func TestHealthCheck(t *testing.T) {
+	status := http.StatusOK
+	directory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer directory.Close()
+
+	leService := &letsEncryptCertificateService{directoryURL: directory.URL}
+	err := leService.HealthCheck(context.Background())
+	assert.NotError(t, err, "health check failed")
+
+	// ----
+
+	status = http.StatusServiceUnavailable
+	err = leService.HealthCheck(context.Background())
+	assert.Equal(t, service.BackendUnavailableErrorKind, service.AsError(err).Kind)
 }
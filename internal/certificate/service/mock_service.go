@@ -5,6 +5,8 @@
 package service
 
 import (
+	context "context"
+	x509 "crypto/x509"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -33,17 +35,119 @@ func (m *MockCertificateService) EXPECT() *MockCertificateServiceMockRecorder {
 	return m.recorder
 }
 
+// Capabilities mocks base method.
+func (m *MockCertificateService) Capabilities() Capabilities {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Capabilities")
+	ret0, _ := ret[0].(Capabilities)
+	return ret0
+}
+
+// Capabilities indicates an expected call of Capabilities.
+func (mr *MockCertificateServiceMockRecorder) Capabilities() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capabilities", reflect.TypeOf((*MockCertificateService)(nil).Capabilities))
+}
+
 // CreateCertificate mocks base method.
-func (m *MockCertificateService) CreateCertificate(arg0 *NewCertificateRequest) (*NewCertificateResponse, error) {
+func (m *MockCertificateService) CreateCertificate(arg0 context.Context, arg1 *NewCertificateRequest) (*NewCertificateResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateCertificate", arg0)
+	ret := m.ctrl.Call(m, "CreateCertificate", arg0, arg1)
 	ret0, _ := ret[0].(*NewCertificateResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateCertificate indicates an expected call of CreateCertificate.
-func (mr *MockCertificateServiceMockRecorder) CreateCertificate(arg0 interface{}) *gomock.Call {
+func (mr *MockCertificateServiceMockRecorder) CreateCertificate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCertificate", reflect.TypeOf((*MockCertificateService)(nil).CreateCertificate), arg0, arg1)
+}
+
+// HealthCheck mocks base method.
+func (m *MockCertificateService) HealthCheck(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HealthCheck", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HealthCheck indicates an expected call of HealthCheck.
+func (mr *MockCertificateServiceMockRecorder) HealthCheck(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockCertificateService)(nil).HealthCheck), arg0)
+}
+
+// MockCAHolder is a mock of CAHolder interface.
+type MockCAHolder struct {
+	ctrl     *gomock.Controller
+	recorder *MockCAHolderMockRecorder
+}
+
+// MockCAHolderMockRecorder is the mock recorder for MockCAHolder.
+type MockCAHolderMockRecorder struct {
+	mock *MockCAHolder
+}
+
+// NewMockCAHolder creates a new mock instance.
+func NewMockCAHolder(ctrl *gomock.Controller) *MockCAHolder {
+	mock := &MockCAHolder{ctrl: ctrl}
+	mock.recorder = &MockCAHolderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCAHolder) EXPECT() *MockCAHolderMockRecorder {
+	return m.recorder
+}
+
+// CACertificate mocks base method.
+func (m *MockCAHolder) CACertificate() *x509.Certificate {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CACertificate")
+	ret0, _ := ret[0].(*x509.Certificate)
+	return ret0
+}
+
+// CACertificate indicates an expected call of CACertificate.
+func (mr *MockCAHolderMockRecorder) CACertificate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CACertificate", reflect.TypeOf((*MockCAHolder)(nil).CACertificate))
+}
+
+// MockRevoker is a mock of Revoker interface.
+type MockRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevokerMockRecorder
+}
+
+// MockRevokerMockRecorder is the mock recorder for MockRevoker.
+type MockRevokerMockRecorder struct {
+	mock *MockRevoker
+}
+
+// NewMockRevoker creates a new mock instance.
+func NewMockRevoker(ctrl *gomock.Controller) *MockRevoker {
+	mock := &MockRevoker{ctrl: ctrl}
+	mock.recorder = &MockRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevoker) EXPECT() *MockRevokerMockRecorder {
+	return m.recorder
+}
+
+// RevokeCertificate mocks base method.
+func (m *MockRevoker) RevokeCertificate(ctx context.Context, certificate []byte, reason RevocationReason) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeCertificate", ctx, certificate, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeCertificate indicates an expected call of RevokeCertificate.
+func (mr *MockRevokerMockRecorder) RevokeCertificate(ctx, certificate, reason interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCertificate", reflect.TypeOf((*MockCertificateService)(nil).CreateCertificate), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeCertificate", reflect.TypeOf((*MockRevoker)(nil).RevokeCertificate), ctx, certificate, reason)
 }
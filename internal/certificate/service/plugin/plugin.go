@@ -0,0 +1,303 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	// capabilities are queried once while creating the service
+	CAPABILITIES_TIMEOUT = 10 * time.Second
+
+	// output of an exited plugin is read at most this long, its children may keep output open
+	WAIT_DELAY = 5 * time.Second
+
+	// call fails if plugin writes a larger response, stderr beyond its limit is discarded
+	MAX_RESPONSE_SIZE = 1024 * 1024
+	MAX_STDERR_SIZE   = 64 * 1024
+)
+
+var errUnsupported = errors.New("method is not supported by plugin")
+
+// pluginCertificateService issues certificates by running an external executable, see protocol.go
+type pluginCertificateService struct {
+	command string
+	args    []string
+
+	// passed to the plugin as is in each request
+	config map[string]string
+
+	capabilities service.Capabilities
+}
+
+func New(command string, args []string, config map[string]string) (*pluginCertificateService, error) {
+	path, err := exec.LookPath(command)
+	if err != nil {
+		logging.GetLogger().Errorf("plugin executable not found: [%s], %v", command, err)
+		return nil, err
+	}
+
+	svc := &pluginCertificateService{
+		command: path,
+		args:    args,
+		config:  config,
+	}
+
+	// ----
+
+	ctx, cancel := context.WithTimeout(context.Background(), CAPABILITIES_TIMEOUT)
+	defer cancel()
+
+	response, err := svc.call(ctx, &pluginRequest{Method: METHOD_CAPABILITIES})
+	if errors.Is(err, errUnsupported) {
+		logging.GetLogger().Infof("plugin [%s] does not report capabilities, all fields are assumed supported", command)
+		svc.capabilities = service.Capabilities{SupportedFields: service.AllFields()}
+		return svc, nil
+	} else if err != nil {
+		logging.GetLogger().Errorf("querying capabilities of plugin [%s] failed, %v", command, err)
+		return nil, err
+	}
+
+	if response.Capabilities != nil {
+		svc.capabilities = service.Capabilities{
+			KeyTypes:        response.Capabilities.KeyTypes,
+			MinValidityDays: response.Capabilities.MinValidityDays,
+			MaxValidityDays: response.Capabilities.MaxValidityDays,
+			SupportedFields: response.Capabilities.SupportedFields,
+		}
+	}
+
+	return svc, nil
+}
+
+func (p *pluginCertificateService) CreateCertificate(ctx context.Context, request *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	response, err := p.call(ctx, &pluginRequest{
+		Method: METHOD_CREATE_CERTIFICATE,
+		Request: &certificateRequest{
+			CommonName:         request.CommonName,
+			Email:              request.Email,
+			Organization:       request.Organization,
+			OrganizationalUnit: request.OrganizationalUnit,
+			Locality:           request.Locality,
+			Province:           request.Province,
+			Country:            request.Country,
+			StreetAddress:      request.StreetAddress,
+			PostalCode:         request.PostalCode,
+			SerialNumber:       request.SerialNumber,
+			ExpirationDays:     request.ExpirationDays,
+			ValiditySeconds:    int64(request.Validity / time.Second),
+			SANs:               request.SubjectAlternativeNames,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Certificate == "" {
+		return nil, service.NewBackendUnavailableError(fmt.Sprintf("Plugin [%s] returned no certificate", p.command), nil)
+	}
+
+	err = validateCertificate([]byte(response.Certificate))
+	if err != nil {
+		message := fmt.Sprintf("Plugin [%s] returned a certificate not valid", p.command)
+		return nil, service.NewBackendUnavailableError(message, err)
+	}
+
+	return &service.NewCertificateResponse{
+		Certificate: []byte(response.Certificate),
+		PrivateKey:  []byte(response.PrivateKey),
+		Chain:       []byte(response.Chain),
+	}, nil
+}
+
+func (p *pluginCertificateService) Capabilities() service.Capabilities {
+	return p.capabilities
+}
+
+// HealthCheck succeeds if plugin does not implement health checks
+func (p *pluginCertificateService) HealthCheck(ctx context.Context) error {
+	_, err := p.call(ctx, &pluginRequest{Method: METHOD_HEALTH_CHECK})
+	if errors.Is(err, errUnsupported) {
+		return nil
+	}
+
+	return err
+}
+
+// ----
+
+func (p *pluginCertificateService) call(ctx context.Context, request *pluginRequest) (*pluginResponse, error) {
+	request.Version = PROTOCOL_VERSION
+	request.Config = p.config
+
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.GetLogger().Debugf("running plugin [%s], method: [%s]", p.command, request.Method)
+	stdout, stderr, runErr := p.run(ctx, requestBytes)
+	if stderr.Len() > 0 {
+		logging.GetLogger().Infof("plugin [%s] stderr: %s", p.command, stderr.String())
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	// ----
+
+	if stdout.Len() == 0 {
+		message := fmt.Sprintf("Plugin [%s] returned no response", p.command)
+		return nil, service.NewBackendUnavailableError(message, runErr)
+	} else if stdout.Len() > MAX_RESPONSE_SIZE {
+		message := fmt.Sprintf("Plugin [%s] returned a response larger than %d bytes", p.command, MAX_RESPONSE_SIZE)
+		return nil, service.NewBackendUnavailableError(message, runErr)
+	}
+
+	response := &pluginResponse{}
+	err = json.Unmarshal(stdout.Bytes(), response)
+	if err != nil {
+		message := fmt.Sprintf("Plugin [%s] returned a response not valid", p.command)
+		return nil, service.NewBackendUnavailableError(message, err)
+	}
+
+	if response.Error != nil {
+		return nil, convertError(response.Error)
+	} else if runErr != nil {
+		message := fmt.Sprintf("Plugin [%s] failed", p.command)
+		return nil, service.NewBackendUnavailableError(message, runErr)
+	}
+
+	return response, nil
+}
+
+// run kills the plugin and the processes it started once ctx is done. Output is read until plugin exits and
+// pipes are closed, pipes kept open by its children are abandoned after WAIT_DELAY. At most one byte more than
+// MAX_RESPONSE_SIZE of stdout is kept, so that caller can tell it is exceeded
+func (p *pluginCertificateService) run(ctx context.Context, input []byte) (*bytes.Buffer, *bytes.Buffer, error) {
+	var stdout, stderr bytes.Buffer
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return &stdout, &stderr, err
+	}
+	defer stdoutReader.Close()
+
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		stdoutWriter.Close()
+		return &stdout, &stderr, err
+	}
+	defer stderrReader.Close()
+
+	cmd := exec.Command(p.command, p.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	setProcessGroup(cmd)
+
+	err = cmd.Start()
+	// writers are inherited by plugin, readers get EOF once plugin and its children close them
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	if err != nil {
+		return &stdout, &stderr, err
+	}
+
+	// ----
+
+	readDone := make(chan struct{}, 2)
+	go func() {
+		readLimited(&stdout, stdoutReader, MAX_RESPONSE_SIZE+1)
+		readDone <- struct{}{}
+	}()
+	go func() {
+		readLimited(&stderr, stderrReader, MAX_STDERR_SIZE)
+		readDone <- struct{}{}
+	}()
+
+	waitDone := make(chan error, 1)
+	go func() {
+		waitDone <- cmd.Wait()
+	}()
+
+	var runErr error
+	select {
+	case runErr = <-waitDone:
+	case <-ctx.Done():
+		killErr := killProcessGroup(cmd)
+		if killErr != nil {
+			logging.GetLogger().Errorf("killing plugin [%s] failed, %v", p.command, killErr)
+		}
+		runErr = <-waitDone
+	}
+
+	timer := time.NewTimer(WAIT_DELAY)
+	defer timer.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-readDone:
+		case <-timer.C:
+			logging.GetLogger().Warnf("plugin [%s] exited but its output is still open, it is not read anymore", p.command)
+			stdoutReader.Close()
+			stderrReader.Close()
+			<-readDone
+		}
+	}
+
+	return &stdout, &stderr, runErr
+}
+
+// readLimited keeps at most limit bytes of reader, rest is discarded so that plugin is not blocked on a full pipe
+func readLimited(buffer *bytes.Buffer, reader io.Reader, limit int64) {
+	io.Copy(buffer, io.LimitReader(reader, limit))
+	io.Copy(io.Discard, reader)
+}
+
+// validateCertificate returns an error if certificate is not a PEM encoded x509 certificate
+func validateCertificate(certificate []byte) error {
+	block, _ := pem.Decode(certificate)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("certificate is not in PEM format")
+	}
+
+	_, err := x509.ParseCertificate(block.Bytes)
+	return err
+}
+
+func convertError(pluginErr *pluginError) error {
+	switch pluginErr.Kind {
+	case UNSUPPORTED_ERROR_KIND:
+		return errUnsupported
+	case service.ValidationErrorKind:
+		return service.NewValidationError(pluginErr.Field, pluginErr.Message)
+	case service.NotFoundErrorKind:
+		return service.NewNotFoundError(pluginErr.Message, nil)
+	case service.PolicyDeniedErrorKind:
+		return service.NewPolicyDeniedError(pluginErr.Message, nil)
+	case service.BackendUnavailableErrorKind:
+		return service.NewBackendUnavailableError(pluginErr.Message, nil)
+	case service.RateLimitedErrorKind:
+		retryAfter := time.Duration(pluginErr.RetryAfterSeconds) * time.Second
+		return service.NewRateLimitedError(pluginErr.Message, retryAfter, nil)
+	}
+
+	return errors.New(pluginErr.Message)
+}
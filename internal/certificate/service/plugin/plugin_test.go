@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+)
+
+const HELPER_PLUGIN_ENV = "CERTSTORE_TEST_HELPER_PLUGIN"
+
+func TestCreateCertificate(t *testing.T) {
+	plugin := createHelperPlugin(t, "issue")
+
+	request := &service.NewCertificateRequest{CommonName: "certstore.com", ExpirationDays: 30}
+	response, err := plugin.CreateCertificate(context.Background(), request)
+	assert.NotError(t, err, "creating certificate failed")
+
+	block, _ := pem.Decode(response.Certificate)
+	assert.NotNil(t, block)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	assert.NotError(t, err, "parsing certificate failed")
+	assert.Equal(t, "certstore.com", certificate.Subject.CommonName)
+	assert.Equal(t, "private key", string(response.PrivateKey))
+	assert.Equal(t, "chain", string(response.Chain))
+}
+
+func TestCapabilities(t *testing.T) {
+	plugin := createHelperPlugin(t, "issue")
+
+	capabilities := plugin.Capabilities()
+	assert.DeepEqual(t, []string{service.KEY_TYPE_RSA_2048}, capabilities.KeyTypes)
+	assert.Equal(t, 365, capabilities.MaxValidityDays)
+	assert.DeepEqual(t, []string{service.FIELD_COMMON_NAME, service.FIELD_SANS}, capabilities.SupportedFields)
+
+	err := plugin.HealthCheck(context.Background())
+	assert.NotError(t, err, "health check failed")
+}
+
+func TestOptionalMethodsNotSupported(t *testing.T) {
+	plugin := createHelperPlugin(t, "minimal")
+
+	assert.DeepEqual(t, service.AllFields(), plugin.Capabilities().SupportedFields)
+
+	err := plugin.HealthCheck(context.Background())
+	assert.NotError(t, err, "health check should succeed if plugin does not support it")
+}
+
+func TestPluginError(t *testing.T) {
+	plugin := createHelperPlugin(t, "rate-limited")
+
+	request := &service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := plugin.CreateCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "too many certificates")
+
+	serviceError := service.AsError(err)
+	assert.Equal(t, service.RateLimitedErrorKind, serviceError.Kind)
+	assert.Equal(t, time.Minute, serviceError.RetryAfter)
+}
+
+func TestPluginCrash(t *testing.T) {
+	plugin := createHelperPlugin(t, "minimal")
+	plugin.config["behavior"] = "crash"
+
+	request := &service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := plugin.CreateCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "returned no response")
+	assert.Equal(t, service.BackendUnavailableErrorKind, service.AsError(err).Kind)
+}
+
+func TestPluginTimeout(t *testing.T) {
+	plugin := createHelperPlugin(t, "minimal")
+	plugin.config["behavior"] = "hang"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	request := &service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := plugin.CreateCertificate(ctx, request)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestPluginChildrenKilledOnTimeout(t *testing.T) {
+	plugin := createHelperPlugin(t, "minimal")
+	plugin.config["behavior"] = "hang-with-child"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	request := &service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := plugin.CreateCertificate(ctx, request)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.TrueM(t, time.Since(start) < WAIT_DELAY, "children of plugin should be killed")
+}
+
+func TestPluginCertificateNotValid(t *testing.T) {
+	plugin := createHelperPlugin(t, "minimal")
+	plugin.config["behavior"] = "invalid-certificate"
+
+	request := &service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := plugin.CreateCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "returned a certificate not valid")
+	assert.Equal(t, service.BackendUnavailableErrorKind, service.AsError(err).Kind)
+}
+
+func TestPluginResponseTooLarge(t *testing.T) {
+	plugin := createHelperPlugin(t, "large-response")
+
+	request := &service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := plugin.CreateCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "larger than")
+	assert.Equal(t, service.BackendUnavailableErrorKind, service.AsError(err).Kind)
+}
+
+func TestPluginNotFound(t *testing.T) {
+	_, err := New("certstore-missing-plugin", nil, nil)
+	assert.Error(t, err, "missing plugin executable should fail")
+}
+
+// TestHelperPlugin is not a real test, it is run as plugin executable by other tests
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv(HELPER_PLUGIN_ENV) != "1" {
+		return
+	}
+
+	request := &pluginRequest{}
+	err := json.NewDecoder(os.Stdin).Decode(request)
+	if err != nil {
+		os.Exit(2)
+	}
+
+	response := helperPluginResponse(request)
+	if response == nil {
+		os.Exit(1)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(response)
+	os.Exit(0)
+}
+
+// -----
+
+func helperPluginResponse(request *pluginRequest) *pluginResponse {
+	behavior := request.Config["behavior"]
+	switch {
+	case behavior == "crash":
+		fmt.Fprintln(os.Stderr, "plugin crashed")
+		return nil
+	case behavior == "hang":
+		time.Sleep(time.Minute)
+		return nil
+	case behavior == "hang-with-child":
+		// child inherits stdout and keeps it open after plugin is killed, unless it is killed too
+		child := exec.Command(os.Args[0], os.Args[1:]...)
+		child.Stdin = strings.NewReader(`{"method": "create-certificate", "config": {"behavior": "hang"}}`)
+		child.Stdout = os.Stdout
+		child.Start()
+		time.Sleep(time.Minute)
+		return nil
+	case request.Method == METHOD_CREATE_CERTIFICATE && behavior == "invalid-certificate":
+		return &pluginResponse{Certificate: "certificate of " + request.Request.CommonName}
+	case request.Method == METHOD_CREATE_CERTIFICATE && behavior == "large-response":
+		return &pluginResponse{
+			Certificate: helperCertificate(request.Request.CommonName),
+			Chain:       strings.Repeat("c", MAX_RESPONSE_SIZE),
+		}
+	case request.Method == METHOD_CREATE_CERTIFICATE && behavior == "rate-limited":
+		return &pluginResponse{Error: &pluginError{
+			Kind:              service.RateLimitedErrorKind,
+			Message:           "too many certificates",
+			RetryAfterSeconds: 60,
+		}}
+	case request.Method == METHOD_CREATE_CERTIFICATE:
+		return &pluginResponse{
+			Certificate: helperCertificate(request.Request.CommonName),
+			PrivateKey:  "private key",
+			Chain:       "chain",
+		}
+	case behavior == "issue" && request.Method == METHOD_CAPABILITIES:
+		return &pluginResponse{Capabilities: &capabilities{
+			KeyTypes:        []string{service.KEY_TYPE_RSA_2048},
+			MaxValidityDays: 365,
+			SupportedFields: []string{service.FIELD_COMMON_NAME, service.FIELD_SANS},
+		}}
+	case behavior == "issue" && request.Method == METHOD_HEALTH_CHECK:
+		return &pluginResponse{}
+	}
+
+	return &pluginResponse{Error: &pluginError{Kind: UNSUPPORTED_ERROR_KIND, Message: request.Method}}
+}
+
+func helperCertificate(commonName string) string {
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+
+	certificate, _ := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}))
+}
+
+func createHelperPlugin(t *testing.T, behavior string) *pluginCertificateService {
+	t.Setenv(HELPER_PLUGIN_ENV, "1")
+
+	args := []string{"-test.run=TestHelperPlugin"}
+	plugin, err := New(os.Args[0], args, map[string]string{"behavior": behavior})
+	assert.NotError(t, err, "creating plugin failed")
+
+	return plugin
+}
@@ -0,0 +1,16 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package plugin
+
+import (
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// only plugin process is killed, output pipes kept open by its children are closed after WAIT_DELAY
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
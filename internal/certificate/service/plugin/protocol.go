@@ -0,0 +1,90 @@
+package plugin
+
+import "bilalekrem.com/certstore/internal/certificate/service"
+
+/*
+plugin protocol, JSON over stdio:
+
+plugin executable is run for each call. A single request is written to its stdin, and a single
+response is read from its stdout, at most MAX_RESPONSE_SIZE bytes. Anything written to stderr is logged.
+
+request:
+{
+  "version": 1,
+  "method": "create-certificate",
+  "config": {"endpoint": "https://ca.corp.internal"},
+  "request": {"common-name": "certstore.com", "expiration-days": 30, "sans": ["www.certstore.com"]}
+}
+
+response, certificates are in PEM format:
+{"certificate": "...", "private-key": "...", "chain": "..."}
+
+or a failure, kind is one of service error kinds, e.g. validation, policy-denied, backend-unavailable:
+{"error": {"kind": "backend-unavailable", "message": "ca is in maintenance", "retry-after-seconds": 60}}
+
+revoking and renewing certificates are not part of the protocol, plugin service does not implement
+service.Revoker, so revocations are kept in inventory only. Certificates are renewed by issuing a new one.
+*/
+
+const (
+	PROTOCOL_VERSION = 1
+
+	METHOD_CREATE_CERTIFICATE = "create-certificate"
+
+	// optional methods, plugins not implementing them respond with unsupported error kind
+	METHOD_CAPABILITIES = "capabilities"
+	METHOD_HEALTH_CHECK = "health-check"
+
+	UNSUPPORTED_ERROR_KIND service.ErrorKind = "unsupported"
+)
+
+type pluginRequest struct {
+	Version int               `json:"version"`
+	Method  string            `json:"method"`
+	Config  map[string]string `json:"config,omitempty"`
+
+	// set only for create-certificate
+	Request *certificateRequest `json:"request,omitempty"`
+}
+
+type certificateRequest struct {
+	CommonName         string   `json:"common-name"`
+	Email              []string `json:"email,omitempty"`
+	Organization       []string `json:"organization,omitempty"`
+	OrganizationalUnit []string `json:"organizational-unit,omitempty"`
+	Locality           []string `json:"locality,omitempty"`
+	Province           []string `json:"province,omitempty"`
+	Country            []string `json:"country,omitempty"`
+	StreetAddress      []string `json:"street-address,omitempty"`
+	PostalCode         []string `json:"postal-code,omitempty"`
+	SerialNumber       string   `json:"serial-number,omitempty"`
+	ExpirationDays     int      `json:"expiration-days,omitempty"`
+	ValiditySeconds    int64    `json:"validity-seconds,omitempty"`
+	SANs               []string `json:"sans,omitempty"`
+}
+
+type pluginResponse struct {
+	Error *pluginError `json:"error,omitempty"`
+
+	// create-certificate result
+	Certificate string `json:"certificate,omitempty"`
+	PrivateKey  string `json:"private-key,omitempty"`
+	Chain       string `json:"chain,omitempty"`
+
+	// capabilities result
+	Capabilities *capabilities `json:"capabilities,omitempty"`
+}
+
+type pluginError struct {
+	Kind              service.ErrorKind `json:"kind"`
+	Message           string            `json:"message"`
+	Field             string            `json:"field,omitempty"`
+	RetryAfterSeconds int               `json:"retry-after-seconds,omitempty"`
+}
+
+type capabilities struct {
+	KeyTypes        []string `json:"key-types,omitempty"`
+	MinValidityDays int      `json:"min-validity-days,omitempty"`
+	MaxValidityDays int      `json:"max-validity-days,omitempty"`
+	SupportedFields []string `json:"supported-fields,omitempty"`
+}
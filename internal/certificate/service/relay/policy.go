@@ -0,0 +1,51 @@
+package relay
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+)
+
+// Policy filters requests before they are relayed to upstream server
+type Policy struct {
+	// requested domains must be one of these domains or their subdomains, any domain is allowed if empty
+	AllowedDomains []string
+
+	// zero if there is no limit other than upstream issuer's
+	MaxExpirationDays int
+}
+
+func (p Policy) check(request *service.NewCertificateRequest) error {
+	maxValidity := time.Duration(p.MaxExpirationDays) * 24 * time.Hour
+	if p.MaxExpirationDays > 0 && request.ValidityDuration() > maxValidity {
+		return service.NewPolicyDeniedError(
+			fmt.Sprintf("Validity [%v] exceeds relay limit: [%d days]", request.ValidityDuration(), p.MaxExpirationDays), nil)
+	}
+
+	if len(p.AllowedDomains) == 0 {
+		return nil
+	}
+
+	domains := append([]string{request.CommonName}, request.SubjectAlternativeNames...)
+	for _, domain := range domains {
+		if !p.allowed(domain) {
+			return service.NewPolicyDeniedError(fmt.Sprintf("Domain is not allowed by relay: [%s]", domain), nil)
+		}
+	}
+
+	return nil
+}
+
+func (p Policy) allowed(domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), "*.")
+	for _, allowed := range p.AllowedDomains {
+		allowed = strings.ToLower(allowed)
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
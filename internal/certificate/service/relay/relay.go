@@ -0,0 +1,338 @@
+package relay
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// capabilities of upstream issuer are queried while creating the service, and refreshed by health checks
+	LIST_ISSUERS_TIMEOUT = 10 * time.Second
+)
+
+type Config struct {
+	ServerAddress string
+
+	// mTLS identity of this server, signed by upstream cluster CA
+	TlsCACert  string
+	TlsCert    string
+	TlsCertKey string
+
+	// issuer name in upstream server
+	Issuer string
+
+	Policy Policy
+}
+
+// relayCertificateService issues certificates by relaying requests to an issuer of an upstream certstore server
+type relayCertificateService struct {
+	client gen.CertificateServiceClient
+	issuer string
+	policy Policy
+
+	// connection of client, nil if client is given
+	conn io.Closer
+
+	mutex        sync.RWMutex
+	capabilities service.Capabilities
+
+	// calls in progress, connection is closed once they are completed after Close
+	calls  int
+	closed bool
+}
+
+func New(conf Config) (*relayCertificateService, error) {
+	if conf.ServerAddress == "" || conf.Issuer == "" {
+		return nil, errors.New("server address and issuer are required for relay service")
+	}
+
+	tlsConfig, err := createTlsConfig(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("creating tls config of relay service failed, %v", err)
+		return nil, err
+	}
+
+	conn, err := grpc.Dial(conf.ServerAddress, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		logging.GetLogger().Errorf("connecting upstream server [%s] failed, %v", conf.ServerAddress, err)
+		return nil, err
+	}
+
+	svc := newWithClient(gen.NewCertificateServiceClient(conn), conf.Issuer, conf.Policy)
+	svc.conn = conn
+
+	return svc, nil
+}
+
+func newWithClient(client gen.CertificateServiceClient, issuer string, policy Policy) *relayCertificateService {
+	svc := &relayCertificateService{
+		client: client,
+		issuer: issuer,
+		policy: policy,
+
+		// until upstream capabilities are known
+		capabilities: service.Capabilities{SupportedFields: service.AllFields()},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), LIST_ISSUERS_TIMEOUT)
+	defer cancel()
+
+	// upstream might not be reachable yet, relay server should still start
+	err := svc.refreshCapabilities(ctx)
+	if err != nil {
+		logging.GetLogger().Warnf("getting capabilities of upstream issuer [%s] failed, %v", issuer, err)
+	}
+
+	return svc
+}
+
+func (r *relayCertificateService) CreateCertificate(ctx context.Context, request *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err := r.policy.check(request)
+	if err != nil {
+		logging.GetLogger().Infof("request is denied by relay policy, %v", err)
+		return nil, err
+	}
+
+	err = r.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer r.end()
+
+	// ----
+
+	response, err := r.client.IssueCertificateV2(ctx, &gen.CertificateRequestV2{
+		Issuer: r.issuer,
+		Subject: &gen.Subject{
+			CommonName:         request.CommonName,
+			Organization:       request.Organization,
+			OrganizationalUnit: request.OrganizationalUnit,
+			Locality:           request.Locality,
+			Province:           request.Province,
+			Country:            request.Country,
+			StreetAddress:      request.StreetAddress,
+			PostalCode:         request.PostalCode,
+			SerialNumber:       request.SerialNumber,
+		},
+		Emails:          request.Email,
+		SANs:            request.SubjectAlternativeNames,
+		ExpirationDays:  int32(request.ExpirationDays),
+		ValiditySeconds: int64(request.Validity / time.Second),
+		ForceNew:        request.ForceNew,
+		Priority:        convertPriority(request.Priority),
+	})
+	if err != nil {
+		logging.GetLogger().Debugf("relaying certificate request to upstream issuer [%s] failed, %v", r.issuer, err)
+		return nil, convertStatusError(err)
+	}
+
+	return &service.NewCertificateResponse{
+		Certificate: response.Certificate,
+		PrivateKey:  response.PrivateKey,
+		Chain:       response.Chain,
+	}, nil
+}
+
+func (r *relayCertificateService) Capabilities() service.Capabilities {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.capabilities
+}
+
+// HealthCheck fails if upstream server is not reachable or it does not have the issuer
+func (r *relayCertificateService) HealthCheck(ctx context.Context) error {
+	err := r.begin()
+	if err != nil {
+		return err
+	}
+	defer r.end()
+
+	return r.refreshCapabilities(ctx)
+}
+
+// Close refuses new calls, e.g. once the service is replaced by a reload. Connection to upstream server is closed
+// right away, or once calls in progress are completed
+func (r *relayCertificateService) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+	if r.calls == 0 {
+		return r.closeConn()
+	}
+
+	return nil
+}
+
+// ----
+
+func (r *relayCertificateService) begin() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return service.NewBackendUnavailableError(fmt.Sprintf("Relay service of upstream issuer [%s] is closed", r.issuer), nil)
+	}
+
+	r.calls++
+	return nil
+}
+
+func (r *relayCertificateService) end() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.calls--
+	if r.closed && r.calls == 0 {
+		r.closeConn()
+	}
+}
+
+// must be called with mutex held
+func (r *relayCertificateService) closeConn() error {
+	if r.conn == nil {
+		return nil
+	}
+
+	err := r.conn.Close()
+	if err != nil {
+		logging.GetLogger().Warnf("closing connection of upstream issuer [%s] failed, %v", r.issuer, err)
+	}
+
+	return err
+}
+
+func (r *relayCertificateService) refreshCapabilities(ctx context.Context) error {
+	resp, err := r.client.ListIssuers(ctx, &gen.ListIssuersRequest{})
+	if err != nil {
+		return convertStatusError(err)
+	}
+
+	for _, issuer := range resp.Issuers {
+		if issuer.Name != r.issuer {
+			continue
+		}
+
+		capabilities := service.Capabilities{
+			KeyTypes:        issuer.KeyTypes,
+			MinValidityDays: int(issuer.MinValidityDays),
+			MaxValidityDays: int(issuer.MaxValidityDays),
+			SupportedFields: issuer.SupportedFields,
+		}
+
+		maxExpirationDays := r.policy.MaxExpirationDays
+		if maxExpirationDays > 0 && (capabilities.MaxValidityDays == 0 || maxExpirationDays < capabilities.MaxValidityDays) {
+			capabilities.MaxValidityDays = maxExpirationDays
+		}
+
+		r.mutex.Lock()
+		r.capabilities = capabilities
+		r.mutex.Unlock()
+		return nil
+	}
+
+	return service.NewNotFoundError(fmt.Sprintf("Issuer not found in upstream server: [%s]", r.issuer), nil)
+}
+
+func convertPriority(priority service.Priority) gen.Priority {
+	switch priority {
+	case service.PriorityHigh:
+		return gen.Priority_HIGH
+	case service.PriorityLow:
+		return gen.Priority_LOW
+	}
+
+	return gen.Priority_NORMAL
+}
+
+var codeErrorKinds = map[codes.Code]service.ErrorKind{
+	codes.InvalidArgument:    service.ValidationErrorKind,
+	codes.NotFound:           service.NotFoundErrorKind,
+	codes.FailedPrecondition: service.PolicyDeniedErrorKind,
+	codes.Unavailable:        service.BackendUnavailableErrorKind,
+	codes.ResourceExhausted:  service.RateLimitedErrorKind,
+}
+
+// convertStatusError converts status errors of upstream server back to typed errors, so that they are relayed
+// to agents with the same code and details
+func convertStatusError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	case codes.Canceled:
+		return context.Canceled
+	}
+
+	kind, exist := codeErrorKinds[st.Code()]
+	if !exist {
+		return err
+	}
+
+	serviceError := &service.Error{Kind: kind, Message: "Upstream: " + st.Message()}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, violation := range d.FieldViolations {
+				serviceError.FieldViolations = append(serviceError.FieldViolations, service.FieldViolation{
+					Field:       violation.Field,
+					Description: violation.Description,
+				})
+			}
+		case *errdetails.RetryInfo:
+			serviceError.RetryAfter = d.RetryDelay.AsDuration()
+		}
+	}
+
+	return serviceError
+}
+
+func createTlsConfig(conf Config) (*tls.Config, error) {
+	caCertPem, err := ioutil.ReadFile(conf.TlsCACert)
+	if err != nil {
+		return nil, err
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCertPem) {
+		return nil, fmt.Errorf("could not add ca cert to cert pool")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(conf.TlsCert, conf.TlsCertKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      caPool,
+	}, nil
+}
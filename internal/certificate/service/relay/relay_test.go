@@ -0,0 +1,211 @@
+package relay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestCreateCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	expectListIssuers(client)
+	client.
+		EXPECT().
+		IssueCertificateV2(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *gen.CertificateRequestV2, _ ...interface{}) (*gen.CertificateResponseV2, error) {
+			// issuer name is mapped to upstream issuer
+			assert.Equal(t, "central issuer", req.Issuer)
+			assert.Equal(t, "branch.certstore.com", req.Subject.CommonName)
+			assert.Equal(t, int32(30), req.ExpirationDays)
+
+			return &gen.CertificateResponseV2{Certificate: []byte("certificate"), Chain: []byte("chain")}, nil
+		})
+
+	relay := newWithClient(client, "central issuer", Policy{})
+
+	// ----
+
+	request := &service.NewCertificateRequest{CommonName: "branch.certstore.com", ExpirationDays: 30}
+	response, err := relay.CreateCertificate(context.Background(), request)
+	assert.NotError(t, err, "relaying certificate request failed")
+	assert.Equal(t, "certificate", string(response.Certificate))
+	assert.Equal(t, "chain", string(response.Chain))
+}
+
+func TestCreateCertificateDeniedByPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// requests denied by policy are not relayed
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	expectListIssuers(client)
+
+	relay := newWithClient(client, "central issuer", Policy{
+		AllowedDomains:    []string{"branch.certstore.com"},
+		MaxExpirationDays: 90,
+	})
+
+	// ----
+
+	request := &service.NewCertificateRequest{
+		CommonName:              "web.branch.certstore.com",
+		SubjectAlternativeNames: []string{"certstore.com"},
+		ExpirationDays:          30,
+	}
+	_, err := relay.CreateCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "Domain is not allowed by relay: [certstore.com]")
+	assert.Equal(t, service.PolicyDeniedErrorKind, service.AsError(err).Kind)
+
+	request = &service.NewCertificateRequest{CommonName: "branch.certstore.com", ExpirationDays: 365}
+	_, err = relay.CreateCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "exceeds relay limit")
+}
+
+func TestCreateCertificateUpstreamError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st, _ := status.New(codes.ResourceExhausted, "rate limit exceeded").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Hour)})
+
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	expectListIssuers(client)
+	client.
+		EXPECT().
+		IssueCertificateV2(gomock.Any(), gomock.Any()).
+		Return(nil, st.Err())
+
+	relay := newWithClient(client, "central issuer", Policy{})
+
+	// ----
+
+	request := &service.NewCertificateRequest{CommonName: "branch.certstore.com", ExpirationDays: 30}
+	_, err := relay.CreateCertificate(context.Background(), request)
+
+	serviceError := service.AsError(err)
+	assert.Equal(t, service.RateLimitedErrorKind, serviceError.Kind)
+	assert.Equal(t, time.Hour, serviceError.RetryAfter)
+	assert.ErrorContains(t, err, "Upstream: rate limit exceeded")
+}
+
+func TestCapabilities(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	expectListIssuers(client)
+
+	relay := newWithClient(client, "central issuer", Policy{MaxExpirationDays: 90})
+
+	capabilities := relay.Capabilities()
+	assert.Equal(t, 90, capabilities.MaxValidityDays)
+	assert.DeepEqual(t, []string{service.FIELD_COMMON_NAME, service.FIELD_EXPIRATION_DAYS}, capabilities.SupportedFields)
+}
+
+func TestHealthCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	gomock.InOrder(
+		client.
+			EXPECT().
+			ListIssuers(gomock.Any(), gomock.Any()).
+			Return(nil, status.Error(codes.Unavailable, "connection refused")),
+		client.
+			EXPECT().
+			ListIssuers(gomock.Any(), gomock.Any()).
+			Return(&gen.ListIssuersResponse{}, nil),
+	)
+
+	// upstream is not reachable at start, capabilities are not known yet
+	relay := newWithClient(client, "central issuer", Policy{})
+	assert.DeepEqual(t, service.AllFields(), relay.Capabilities().SupportedFields)
+
+	err := relay.HealthCheck(context.Background())
+	assert.ErrorContains(t, err, "Issuer not found in upstream server")
+}
+
+func TestClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	expectListIssuers(client)
+
+	conn := &testConn{}
+	relay := newWithClient(client, "central issuer", Policy{})
+	relay.conn = conn
+
+	client.
+		EXPECT().
+		IssueCertificateV2(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *gen.CertificateRequestV2, _ ...interface{}) (*gen.CertificateResponseV2, error) {
+			// call in progress keeps connection open
+			err := relay.Close()
+			assert.NotError(t, err, "closing relay failed")
+			assert.False(t, conn.closed)
+
+			return &gen.CertificateResponseV2{Certificate: []byte("certificate")}, nil
+		})
+
+	// ----
+
+	request := &service.NewCertificateRequest{CommonName: "branch.certstore.com"}
+	_, err := relay.CreateCertificate(context.Background(), request)
+	assert.NotError(t, err, "relaying certificate request failed")
+	assert.True(t, conn.closed)
+
+	_, err = relay.CreateCertificate(context.Background(), request)
+	assert.Equal(t, service.BackendUnavailableErrorKind, service.AsError(err).Kind)
+
+	err = relay.HealthCheck(context.Background())
+	assert.ErrorContains(t, err, "is closed")
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	policy := Policy{AllowedDomains: []string{"certstore.com"}}
+
+	assert.True(t, policy.allowed("certstore.com"))
+	assert.True(t, policy.allowed("www.Certstore.com"))
+	assert.True(t, policy.allowed("*.certstore.com"))
+	assert.False(t, policy.allowed("notcertstore.com"))
+	assert.False(t, policy.allowed("certstore.com.evil.com"))
+}
+
+// -----
+
+func expectListIssuers(client *gen.MockCertificateServiceClient) {
+	client.
+		EXPECT().
+		ListIssuers(gomock.Any(), gomock.Any()).
+		Return(&gen.ListIssuersResponse{Issuers: []*gen.Issuer{
+			{Name: "other issuer"},
+			{
+				Name:            "central issuer",
+				MaxValidityDays: 365,
+				SupportedFields: []string{service.FIELD_COMMON_NAME, service.FIELD_EXPIRATION_DAYS},
+			},
+		}}, nil)
+}
+
+type testConn struct {
+	closed bool
+}
+
+func (c *testConn) Close() error {
+	c.closed = true
+	return nil
+}
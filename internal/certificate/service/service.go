@@ -1,20 +1,112 @@
 package service
 
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+)
+
+const (
+	// certificates are valid a bit before they are issued, to tolerate clocks of hosts slightly behind
+	DEFAULT_NOT_BEFORE_BACKDATE = 5 * time.Minute
+
+	MIN_VALIDITY = time.Minute
+)
+
+// Priority orders requests waiting in the work queue of an issuer
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
 type NewCertificateRequest struct {
 	CommonName     string
 	Email          []string
 	Organization   []string
 	ExpirationDays int
 
+	// takes precedence over expiration days if set, for certificates valid shorter than a day
+	Validity time.Duration
+
+	OrganizationalUnit []string
+	Locality           []string
+	Province           []string
+	Country            []string
+	StreetAddress      []string
+	PostalCode         []string
+	SerialNumber       string
+
 	SubjectAlternativeNames []string
+
+	// skips returning an existing certificate for identical requests, see certstore reuse policy
+	ForceNew bool
+
+	// normal priority if empty
+	Priority Priority
 }
 
 type NewCertificateResponse struct {
 	// both, certificate and private key, is encoded in PEM format.
 	Certificate []byte
 	PrivateKey  []byte
+
+	// PEM encoded issuer certificates, empty for self signed certificates
+	Chain []byte
+}
+
+// ValidityDuration returns how long the requested certificate is valid
+func (r *NewCertificateRequest) ValidityDuration() time.Duration {
+	if r.Validity > 0 {
+		return r.Validity
+	}
+
+	return time.Duration(r.ExpirationDays) * 24 * time.Hour
+}
+
+func (r *NewCertificateRequest) Subject() pkix.Name {
+	return pkix.Name{
+		CommonName:         r.CommonName,
+		Organization:       r.Organization,
+		OrganizationalUnit: r.OrganizationalUnit,
+		Locality:           r.Locality,
+		Province:           r.Province,
+		Country:            r.Country,
+		StreetAddress:      r.StreetAddress,
+		PostalCode:         r.PostalCode,
+		SerialNumber:       r.SerialNumber,
+	}
 }
 
 type CertificateService interface {
-	CreateCertificate(*NewCertificateRequest) (*NewCertificateResponse, error)
+	CreateCertificate(context.Context, *NewCertificateRequest) (*NewCertificateResponse, error)
+	Capabilities() Capabilities
+
+	// checks the service is able to issue certificates, without issuing one
+	HealthCheck(context.Context) error
+}
+
+// CAHolder is implemented by services signing certificates with a CA certificate of their own
+type CAHolder interface {
+	CACertificate() *x509.Certificate
+}
+
+// RevocationReason is the reason code of RFC 5280, only the codes accepted by ACME are listed
+type RevocationReason int
+
+const (
+	RevocationUnspecified          RevocationReason = 0
+	RevocationKeyCompromise        RevocationReason = 1
+	RevocationAffiliationChanged   RevocationReason = 3
+	RevocationSuperseded           RevocationReason = 4
+	RevocationCessationOfOperation RevocationReason = 5
+)
+
+// Revoker is implemented by services able to revoke the certificates they issued at their CA
+type Revoker interface {
+	// certificate is PEM encoded
+	RevokeCertificate(ctx context.Context, certificate []byte, reason RevocationReason) error
 }
@@ -1,25 +1,28 @@
 package service
 
 import (
-	"errors"
 	"fmt"
 	"net/mail"
 )
 
 func validateCertificateRequest(req *NewCertificateRequest) error {
 	if req.CommonName == "" {
-		return errors.New("Validation error: common name can not be empty")
+		return NewValidationError(FIELD_COMMON_NAME, "common name can not be empty")
 	}
 
 	for _, email := range req.Email {
 		_, err := mail.ParseAddress(email)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Validation error: email is not valid: [%s]", email))
+			return NewValidationError(FIELD_EMAIL, fmt.Sprintf("email is not valid: [%s]", email))
 		}
 	}
 
-	if req.ExpirationDays < 1 {
-		return errors.New("Validation error: expiration days must be bigger than 1")
+	if req.Validity != 0 {
+		if req.Validity < MIN_VALIDITY {
+			return NewValidationError(FIELD_VALIDITY, fmt.Sprintf("validity must be at least %v", MIN_VALIDITY))
+		}
+	} else if req.ExpirationDays < 1 {
+		return NewValidationError(FIELD_EXPIRATION_DAYS, "expiration days must be bigger than 1")
 	}
 
 	return nil
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+type SSHCertificateType string
+
+const (
+	SSHUserCertificate SSHCertificateType = "user"
+	SSHHostCertificate SSHCertificateType = "host"
+
+	FIELD_PUBLIC_KEY       = "publicKey"
+	FIELD_CERTIFICATE_TYPE = "certificateType"
+	FIELD_KEY_ID           = "keyId"
+	FIELD_PRINCIPALS       = "principals"
+	FIELD_CRITICAL_OPTIONS = "criticalOptions"
+	FIELD_EXTENSIONS       = "extensions"
+)
+
+type NewSSHCertificateRequest struct {
+	// public key to be signed, in authorized_keys format. Private key never leaves the requester
+	PublicKey []byte
+
+	Type       SSHCertificateType
+	KeyID      string
+	Principals []string
+	Validity   time.Duration
+
+	// e.g. force-command, source-address
+	CriticalOptions map[string]string
+
+	// e.g. permit-pty, permit-port-forwarding. Values are mostly empty
+	Extensions map[string]string
+}
+
+type NewSSHCertificateResponse struct {
+	// in authorized_keys format, i.e. content of *-cert.pub file
+	Certificate []byte
+	Serial      uint64
+	ValidAfter  time.Time
+	ValidBefore time.Time
+}
+
+// SSHCertificateService is implemented by services also signing OpenSSH certificates
+type SSHCertificateService interface {
+	CreateSSHCertificate(context.Context, *NewSSHCertificateRequest) (*NewSSHCertificateResponse, error)
+}
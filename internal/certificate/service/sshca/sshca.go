@@ -0,0 +1,259 @@
+package sshca
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/logging"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// used if request does not have a validity, at most max validity of issuer
+	DEFAULT_VALIDITY = 8 * time.Hour
+
+	// used if issuer does not set a max validity
+	DEFAULT_MAX_VALIDITY = 7 * 24 * time.Hour
+)
+
+// critical options known by OpenSSH, certificates with unknown critical options are refused by sshd
+var knownCriticalOptions = map[string]bool{
+	"force-command":   true,
+	"source-address":  true,
+	"verify-required": true,
+}
+
+// sshCAService signs OpenSSH user and host certificates with its ca key
+type sshCAService struct {
+	signer ssh.Signer
+
+	maxValidity time.Duration
+
+	notBeforeBackdate time.Duration
+
+	// requested principals must match one of these patterns
+	allowedPrincipals []string
+}
+
+// New refuses to create a ca without allowed principals, any user or host, e.g. root, could be requested
+// otherwise. DEFAULT_MAX_VALIDITY is used if max validity is zero
+func New(privateKeyPem []byte, maxValidity time.Duration, notBeforeBackdate time.Duration,
+	allowedPrincipals []string) (*sshCAService, error) {
+	if len(allowedPrincipals) == 0 {
+		return nil, errors.New("allowed principals are required for ssh ca, certificates could be requested for any user or host otherwise")
+	}
+
+	for _, pattern := range allowedPrincipals {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.New(fmt.Sprintf("allowed principal pattern is not valid: [%s]", pattern))
+		}
+	}
+
+	signer, err := ssh.ParsePrivateKey(privateKeyPem)
+	if err != nil {
+		logging.GetLogger().Errorf("parsing ssh ca private key failed, %v", err)
+		return nil, err
+	}
+
+	// ssh-rsa signatures use SHA-1, which is refused by recent OpenSSH versions
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signer = rsaSHA512Signer{algorithmSigner}
+	}
+
+	logging.GetLogger().Infof("SSH CA key loaded, fingerprint: [%s]", ssh.FingerprintSHA256(signer.PublicKey()))
+
+	if maxValidity == 0 {
+		maxValidity = DEFAULT_MAX_VALIDITY
+	}
+
+	return &sshCAService{
+		signer:            signer,
+		maxValidity:       maxValidity,
+		notBeforeBackdate: notBeforeBackdate,
+		allowedPrincipals: allowedPrincipals,
+	}, nil
+}
+
+func (s *sshCAService) Capabilities() service.Capabilities {
+	return service.Capabilities{
+		SupportedFields: []string{
+			service.FIELD_PUBLIC_KEY,
+			service.FIELD_CERTIFICATE_TYPE,
+			service.FIELD_KEY_ID,
+			service.FIELD_PRINCIPALS,
+			service.FIELD_VALIDITY,
+			service.FIELD_CRITICAL_OPTIONS,
+			service.FIELD_EXTENSIONS,
+		},
+	}
+}
+
+// HealthCheck always succeeds, ca key is loaded on start
+func (s *sshCAService) HealthCheck(_ context.Context) error {
+	return nil
+}
+
+func (s *sshCAService) CreateCertificate(_ context.Context, _ *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
+	return nil, service.NewValidationError("issuer", "issuer signs only SSH certificates")
+}
+
+func (s *sshCAService) CreateSSHCertificate(ctx context.Context, request *service.NewSSHCertificateRequest) (*service.NewSSHCertificateResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := s.validate(request)
+	if err != nil {
+		logging.GetLogger().Debugf("validating ssh certificate request failed, %v", err)
+		return nil, err
+	}
+
+	certType := uint32(ssh.UserCert)
+	if request.Type == service.SSHHostCertificate {
+		certType = ssh.HostCert
+	}
+
+	validity := request.Validity
+	if validity == 0 {
+		validity = DEFAULT_VALIDITY
+		if validity > s.maxValidity {
+			validity = s.maxValidity
+		}
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		logging.GetLogger().Errorf("creating ssh certificate serial failed, %v", err)
+		return nil, err
+	}
+
+	// -----
+
+	now := time.Now()
+	certificate := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          serial,
+		CertType:        certType,
+		KeyId:           request.KeyID,
+		ValidPrincipals: request.Principals,
+		ValidAfter:      uint64(now.Add(-s.notBeforeBackdate).Unix()),
+		ValidBefore:     uint64(now.Add(validity).Unix()),
+		Permissions: ssh.Permissions{
+			CriticalOptions: copyMap(request.CriticalOptions),
+			Extensions:      copyMap(request.Extensions),
+		},
+	}
+
+	err = certificate.SignCert(rand.Reader, s.signer)
+	if err != nil {
+		logging.GetLogger().Errorf("signing ssh certificate failed, %v", err)
+		return nil, err
+	}
+
+	logging.GetLogger().Infof("Signed ssh %s certificate, key id: [%s], serial: [%d], principals: %v",
+		request.Type, request.KeyID, serial, request.Principals)
+
+	return &service.NewSSHCertificateResponse{
+		Certificate: ssh.MarshalAuthorizedKey(certificate),
+		Serial:      serial,
+		ValidAfter:  time.Unix(int64(certificate.ValidAfter), 0),
+		ValidBefore: time.Unix(int64(certificate.ValidBefore), 0),
+	}, nil
+}
+
+// ----
+
+func (s *sshCAService) validate(request *service.NewSSHCertificateRequest) (ssh.PublicKey, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(request.PublicKey)
+	if err != nil {
+		return nil, service.NewValidationError(service.FIELD_PUBLIC_KEY, "public key is not in authorized_keys format")
+	}
+
+	if _, isCertificate := publicKey.(*ssh.Certificate); isCertificate {
+		return nil, service.NewValidationError(service.FIELD_PUBLIC_KEY, "public key can not be a certificate")
+	}
+
+	if request.Type != service.SSHUserCertificate && request.Type != service.SSHHostCertificate {
+		return nil, service.NewValidationError(service.FIELD_CERTIFICATE_TYPE,
+			fmt.Sprintf("certificate type must be user or host: [%s]", request.Type))
+	}
+
+	// certificates without principals are valid for any user or host
+	if len(request.Principals) == 0 {
+		return nil, service.NewValidationError(service.FIELD_PRINCIPALS, "at least one principal is required")
+	}
+
+	for _, principal := range request.Principals {
+		if !s.principalAllowed(principal) {
+			return nil, service.NewPolicyDeniedError(fmt.Sprintf("Principal is not allowed by issuer: [%s]", principal), nil)
+		}
+	}
+
+	if request.Validity < 0 {
+		return nil, service.NewValidationError(service.FIELD_VALIDITY, "validity can not be negative")
+	} else if request.Validity > s.maxValidity {
+		return nil, service.NewValidationError(service.FIELD_VALIDITY,
+			fmt.Sprintf("validity [%v] is more than maximum of issuer: [%v]", request.Validity, s.maxValidity))
+	}
+
+	if request.Type == service.SSHHostCertificate && len(request.CriticalOptions) > 0 {
+		return nil, service.NewValidationError(service.FIELD_CRITICAL_OPTIONS, "host certificates can not have critical options")
+	} else if request.Type == service.SSHHostCertificate && len(request.Extensions) > 0 {
+		return nil, service.NewValidationError(service.FIELD_EXTENSIONS, "host certificates can not have extensions")
+	}
+
+	for option := range request.CriticalOptions {
+		if !knownCriticalOptions[option] {
+			return nil, service.NewValidationError(service.FIELD_CRITICAL_OPTIONS,
+				fmt.Sprintf("critical option is not known: [%s]", option))
+		}
+	}
+
+	return publicKey, nil
+}
+
+func (s *sshCAService) principalAllowed(principal string) bool {
+	for _, pattern := range s.allowedPrincipals {
+		if matched, _ := path.Match(pattern, principal); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func randomSerial() (uint64, error) {
+	serialBytes := make([]byte, 8)
+	_, err := io.ReadFull(rand.Reader, serialBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(serialBytes), nil
+}
+
+func copyMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+// ----
+
+type rsaSHA512Signer struct {
+	ssh.AlgorithmSigner
+}
+
+func (s rsaSHA512Signer) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, ssh.SigAlgoRSASHA2512)
+}
@@ -0,0 +1,231 @@
+package sshca
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCreateSSHUserCertificate(t *testing.T) {
+	sshCA := createTestSSHCA(t, 0)
+	request := createTestRequest(t)
+
+	response, err := sshCA.CreateSSHCertificate(context.Background(), request)
+	assert.NotError(t, err, "creating ssh certificate failed")
+
+	// -----
+
+	certificate := parseCertificate(t, response.Certificate)
+	assert.Equal(t, uint32(ssh.UserCert), certificate.CertType)
+	assert.Equal(t, "alice@laptop", certificate.KeyId)
+	assert.Equal(t, response.Serial, certificate.Serial)
+	assert.DeepEqual(t, []string{"alice", "deploy"}, certificate.ValidPrincipals)
+	assert.Equal(t, "10.0.0.0/8", certificate.CriticalOptions["source-address"])
+	assert.Equal(t, "", certificate.Extensions["permit-pty"])
+	assert.Equal(t, time.Hour, response.ValidBefore.Sub(response.ValidAfter))
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(sshCA.signer.PublicKey().Marshal())
+		},
+	}
+	_, err = checker.Authenticate(testConnMetadata{user: "alice"}, certificate)
+	assert.NotError(t, err, "ssh certificate is not valid for principal")
+
+	_, err = checker.Authenticate(testConnMetadata{user: "bob"}, certificate)
+	assert.Error(t, err, "ssh certificate should not be valid for other users")
+}
+
+func TestCreateSSHHostCertificate(t *testing.T) {
+	sshCA := createTestSSHCA(t, 0)
+	request := createTestRequest(t)
+	request.Type = service.SSHHostCertificate
+	request.Principals = []string{"web.certstore.com"}
+	request.Validity = 0
+
+	// host certificates do not have options
+	_, err := sshCA.CreateSSHCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "host certificates can not have critical options")
+
+	request.CriticalOptions = nil
+	request.Extensions = nil
+	response, err := sshCA.CreateSSHCertificate(context.Background(), request)
+	assert.NotError(t, err, "creating ssh certificate failed")
+
+	certificate := parseCertificate(t, response.Certificate)
+	assert.Equal(t, uint32(ssh.HostCert), certificate.CertType)
+	assert.Equal(t, DEFAULT_VALIDITY, response.ValidBefore.Sub(response.ValidAfter))
+}
+
+func TestCreateSSHCertificateWithRSACA(t *testing.T) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NotError(t, err, "generating ca key failed")
+	caKeyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caKey)})
+
+	sshCA, err := New(caKeyPem, 0, 0, []string{"*"})
+	assert.NotError(t, err, "creating ssh ca failed")
+
+	response, err := sshCA.CreateSSHCertificate(context.Background(), createTestRequest(t))
+	assert.NotError(t, err, "creating ssh certificate failed")
+
+	// SHA-1 signatures are refused by OpenSSH
+	certificate := parseCertificate(t, response.Certificate)
+	assert.Equal(t, ssh.SigAlgoRSASHA2512, certificate.Signature.Format)
+}
+
+func TestCreateSSHCertificateValidation(t *testing.T) {
+	sshCA := createTestSSHCA(t, 12*time.Hour)
+
+	tests := []struct {
+		field  string
+		update func(*service.NewSSHCertificateRequest)
+	}{
+		{service.FIELD_PUBLIC_KEY, func(r *service.NewSSHCertificateRequest) { r.PublicKey = []byte("not a key") }},
+		{service.FIELD_CERTIFICATE_TYPE, func(r *service.NewSSHCertificateRequest) { r.Type = "server" }},
+		{service.FIELD_PRINCIPALS, func(r *service.NewSSHCertificateRequest) { r.Principals = nil }},
+		{service.FIELD_VALIDITY, func(r *service.NewSSHCertificateRequest) { r.Validity = 24 * time.Hour }},
+		{service.FIELD_CRITICAL_OPTIONS, func(r *service.NewSSHCertificateRequest) {
+			r.CriticalOptions = map[string]string{"no-touch-required": ""}
+		}},
+	}
+
+	for _, test := range tests {
+		request := createTestRequest(t)
+		test.update(request)
+
+		_, err := sshCA.CreateSSHCertificate(context.Background(), request)
+
+		serviceError := service.AsError(err)
+		assert.NotNil(t, serviceError)
+		assert.Equal(t, service.ValidationErrorKind, serviceError.Kind)
+		assert.Equal(t, test.field, serviceError.FieldViolations[0].Field)
+	}
+}
+
+func TestAllowedPrincipals(t *testing.T) {
+	sshCA := createTestSSHCA(t, 0)
+	sshCA.allowedPrincipals = []string{"alice", "deploy-*"}
+
+	request := createTestRequest(t)
+	request.Principals = []string{"alice", "deploy-web"}
+	_, err := sshCA.CreateSSHCertificate(context.Background(), request)
+	assert.NotError(t, err, "allowed principals should be signed")
+
+	request.Principals = []string{"alice", "root"}
+	_, err = sshCA.CreateSSHCertificate(context.Background(), request)
+	assert.ErrorContains(t, err, "Principal is not allowed by issuer: [root]")
+	assert.Equal(t, service.PolicyDeniedErrorKind, service.AsError(err).Kind)
+}
+
+func TestAllowedPrincipalsRequired(t *testing.T) {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NotError(t, err, "generating ca key failed")
+
+	caKeyBytes, err := x509.MarshalPKCS8PrivateKey(caKey)
+	assert.NotError(t, err, "marshalling ca key failed")
+
+	_, err = New(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: caKeyBytes}), 0, 0, nil)
+	assert.ErrorContains(t, err, "allowed principals are required")
+}
+
+func TestDefaultMaxValidity(t *testing.T) {
+	sshCA := createTestSSHCA(t, 0)
+	assert.Equal(t, DEFAULT_MAX_VALIDITY, sshCA.maxValidity)
+
+	request := createTestRequest(t)
+	request.Validity = DEFAULT_MAX_VALIDITY + time.Hour
+	_, err := sshCA.CreateSSHCertificate(context.Background(), request)
+	assert.Equal(t, service.ValidationErrorKind, service.AsError(err).Kind)
+
+	// default validity is not more than max validity of issuer
+	sshCA = createTestSSHCA(t, time.Hour)
+	request = createTestRequest(t)
+	request.Validity = 0
+	response, err := sshCA.CreateSSHCertificate(context.Background(), request)
+	assert.NotError(t, err, "creating ssh certificate failed")
+	assert.Equal(t, time.Hour, response.ValidBefore.Sub(response.ValidAfter))
+}
+
+func TestAllowedPrincipalPatternNotValid(t *testing.T) {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NotError(t, err, "generating ca key failed")
+
+	caKeyBytes, err := x509.MarshalPKCS8PrivateKey(caKey)
+	assert.NotError(t, err, "marshalling ca key failed")
+
+	_, err = New(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: caKeyBytes}), 0, 0, []string{"deploy-["})
+	assert.ErrorContains(t, err, "allowed principal pattern is not valid")
+}
+
+func TestCreateCertificateShouldFail(t *testing.T) {
+	sshCA := createTestSSHCA(t, 0)
+
+	_, err := sshCA.CreateCertificate(context.Background(), &service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.ErrorContains(t, err, "issuer signs only SSH certificates")
+}
+
+// -----
+
+func createTestSSHCA(t *testing.T, maxValidity time.Duration) *sshCAService {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NotError(t, err, "generating ca key failed")
+
+	caKeyBytes, err := x509.MarshalPKCS8PrivateKey(caKey)
+	assert.NotError(t, err, "marshalling ca key failed")
+
+	sshCA, err := New(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: caKeyBytes}), maxValidity, 0, []string{"*"})
+	assert.NotError(t, err, "creating ssh ca failed")
+
+	return sshCA
+}
+
+func createTestRequest(t *testing.T) *service.NewSSHCertificateRequest {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NotError(t, err, "generating key failed")
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	assert.NotError(t, err, "converting public key failed")
+
+	return &service.NewSSHCertificateRequest{
+		PublicKey:       ssh.MarshalAuthorizedKey(sshPublicKey),
+		Type:            service.SSHUserCertificate,
+		KeyID:           "alice@laptop",
+		Principals:      []string{"alice", "deploy"},
+		Validity:        time.Hour,
+		CriticalOptions: map[string]string{"source-address": "10.0.0.0/8"},
+		Extensions:      map[string]string{"permit-pty": ""},
+	}
+}
+
+func parseCertificate(t *testing.T, certificateBytes []byte) *ssh.Certificate {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(certificateBytes)
+	assert.NotError(t, err, "parsing ssh certificate failed")
+
+	certificate, ok := publicKey.(*ssh.Certificate)
+	assert.True(t, ok)
+
+	return certificate
+}
+
+type testConnMetadata struct {
+	ssh.ConnMetadata
+	user string
+}
+
+func (m testConnMetadata) User() string {
+	return m.user
+}
+
+func (m testConnMetadata) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+}
@@ -0,0 +1,165 @@
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	// requests waiting for a decision, by operation id. Decided requests are removed, their result is kept in
+	// the operation
+	STORAGE_BUCKET = "approvals"
+)
+
+// Request is a submitted certificate request of an issuer requiring approval, it is issued once an approver
+// approves it
+type Request struct {
+	OperationID string                         `json:"operation-id"`
+	Issuer      string                         `json:"issuer"`
+	Request     *service.NewCertificateRequest `json:"request"`
+
+	// empty if requester is not known
+	RequestedBy string    `json:"requested-by,omitempty"`
+	RequestedAt time.Time `json:"requested-at"`
+}
+
+// Policy of an issuer requiring approval
+type Policy struct {
+	// identities allowed to decide requests, nobody decides if empty
+	Approvers []string
+}
+
+// Allowed tells if approver may decide the request, nobody decides their own request
+func (p *Policy) Allowed(approver string, pending *Request) bool {
+	if approver != "" && approver == pending.RequestedBy {
+		return false
+	}
+
+	for _, allowed := range p.Approvers {
+		if allowed == approver {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ----
+
+type TimeProvider func() time.Time
+
+// Store keeps pending requests in server storage, so that they survive restarts
+type Store struct {
+	mutex        sync.Mutex
+	storage      storage.Storage
+	timeProvider TimeProvider
+}
+
+func NewStore(storage storage.Storage) *Store {
+	return NewStoreWithTimeProvider(storage, time.Now)
+}
+
+func NewStoreWithTimeProvider(storage storage.Storage, timeProvider TimeProvider) *Store {
+	return &Store{
+		storage:      storage,
+		timeProvider: timeProvider,
+	}
+}
+
+func (s *Store) Add(operationID string, issuer string, request *service.NewCertificateRequest, requestedBy string) (*Request, error) {
+	pending := &Request{
+		OperationID: operationID,
+		Issuer:      issuer,
+		Request:     request,
+		RequestedBy: requestedBy,
+		RequestedAt: s.timeProvider(),
+	}
+
+	value, err := json.Marshal(pending)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.storage.Put(STORAGE_BUCKET, operationID, value)
+	if err != nil {
+		logging.GetLogger().Errorf("saving approval request failed, operation: [%s], %v", operationID, err)
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// Get returns storage.ErrNotFound if there is no pending request of the operation
+func (s *Store) Get(operationID string) (*Request, error) {
+	value, err := s.storage.Get(STORAGE_BUCKET, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := &Request{}
+	err = json.Unmarshal(value, pending)
+	if err != nil {
+		logging.GetLogger().Errorf("decoding approval request failed, operation: [%s], %v", operationID, err)
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// List returns pending requests ordered by request time, only the ones of the issuer if it is not empty
+func (s *Store) List(issuer string) ([]*Request, error) {
+	values, err := s.storage.List(STORAGE_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []*Request{}
+	for key, value := range values {
+		pending := &Request{}
+		err = json.Unmarshal(value, pending)
+		if err != nil {
+			logging.GetLogger().Warnf("decoding approval request failed, key: [%s], %v", key, err)
+			continue
+		}
+
+		if issuer == "" || pending.Issuer == issuer {
+			requests = append(requests, pending)
+		}
+	}
+
+	sort.Slice(requests, func(a, b int) bool {
+		return requests[a].RequestedAt.Before(requests[b].RequestedAt)
+	})
+	return requests, nil
+}
+
+// Take removes the pending request and returns it, so that a request is decided only once. Returns
+// storage.ErrNotFound if it is decided already
+func (s *Store) Take(operationID string) (*Request, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending, err := s.Get(operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.storage.Delete(STORAGE_BUCKET, operationID)
+	if err != nil {
+		logging.GetLogger().Errorf("removing approval request failed, operation: [%s], %v", operationID, err)
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, storage.ErrNotFound)
+}
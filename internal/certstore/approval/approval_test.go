@@ -0,0 +1,96 @@
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+)
+
+func TestAddAndGet(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	request := &service.NewCertificateRequest{CommonName: "certstore.com", SubjectAlternativeNames: []string{"www.certstore.com"}}
+	_, err := store.Add("operation", "test issuer", request, "test agent")
+	assert.NotError(t, err, "adding approval request failed")
+
+	pending, err := store.Get("operation")
+	assert.NotError(t, err, "getting approval request failed")
+	assert.Equal(t, "test issuer", pending.Issuer)
+	assert.Equal(t, "test agent", pending.RequestedBy)
+	assert.Equal(t, now, pending.RequestedAt)
+	assert.DeepEqual(t, request, pending.Request)
+
+	_, err = store.Get("missing")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestList(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	for _, id := range []string{"second", "first", "third"} {
+		issuer := "test issuer"
+		if id == "third" {
+			issuer = "other issuer"
+		}
+
+		_, err := store.Add(id, issuer, &service.NewCertificateRequest{CommonName: "certstore.com"}, "")
+		assert.NotError(t, err, "adding approval request failed")
+		now = now.Add(-time.Minute)
+	}
+
+	requests, err := store.List("")
+	assert.NotError(t, err, "listing approval requests failed")
+	assert.Equal(t, 3, len(requests))
+	assert.Equal(t, "third", requests[0].OperationID)
+	assert.Equal(t, "second", requests[2].OperationID)
+
+	requests, err = store.List("test issuer")
+	assert.NotError(t, err, "listing approval requests failed")
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "first", requests[0].OperationID)
+}
+
+func TestTake(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	_, err := store.Add("operation", "test issuer", &service.NewCertificateRequest{CommonName: "certstore.com"}, "")
+	assert.NotError(t, err, "adding approval request failed")
+
+	taken, err := store.Take("operation")
+	assert.NotError(t, err, "taking approval request failed")
+	assert.Equal(t, "operation", taken.OperationID)
+
+	// decided only once
+	_, err = store.Take("operation")
+	assert.True(t, IsNotFound(err))
+
+	requests, err := store.List("")
+	assert.NotError(t, err, "listing approval requests failed")
+	assert.Equal(t, 0, len(requests))
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	pending := &Request{OperationID: "operation", RequestedBy: "requester"}
+
+	nobody := &Policy{}
+	assert.False(t, nobody.Allowed("admin", pending))
+	assert.False(t, nobody.Allowed("requester", pending))
+
+	approvers := &Policy{Approvers: []string{"admin", "requester"}}
+	assert.True(t, approvers.Allowed("admin", pending))
+	assert.False(t, approvers.Allowed("requester", pending))
+	assert.False(t, approvers.Allowed("other", pending))
+	assert.False(t, approvers.Allowed("", pending))
+}
+
+func createStore(now *time.Time) *Store {
+	return NewStoreWithTimeProvider(storage.NewMemoryStorage(), func() time.Time {
+		return *now
+	})
+}
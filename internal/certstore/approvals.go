@@ -0,0 +1,157 @@
+package certstore
+
+import (
+	"context"
+	"fmt"
+
+	"bilalekrem.com/certstore/internal/audit"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/approval"
+	"bilalekrem.com/certstore/internal/certstore/identity"
+	"bilalekrem.com/certstore/internal/certstore/operation"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+// submitForApproval keeps the request until an approver decides, operation awaits approval meanwhile
+func (c *certStoreImpl) submitForApproval(ctx context.Context, issuer string,
+	request *service.NewCertificateRequest) (*operation.Operation, error) {
+
+	requestedBy := identity.Name(ctx)
+	op, err := c.operations.CreateWithState(issuer, requestedBy, operation.AwaitingApproval)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.approvals.Add(op.ID, issuer, request, requestedBy)
+	if err != nil {
+		op.State = operation.Failed
+		op.Error = fmt.Sprintf("saving approval request failed, %v", err)
+		c.updateOperation(op)
+		return nil, service.NewBackendUnavailableError("Saving approval request failed", err)
+	}
+
+	audit.Decide(ctx, audit.POLICY_APPROVAL, audit.DECISION_PENDING, "")
+	logging.GetLogger().Infof("Submitted certificate request of issuer [%s] for approval, operation: [%s], requested by: [%s]",
+		issuer, op.ID, requestedBy)
+
+	return op, nil
+}
+
+func (c *certStoreImpl) ListApprovals(issuer string) ([]*approval.Request, error) {
+	return c.approvals.List(issuer)
+}
+
+func (c *certStoreImpl) ApproveRequest(ctx context.Context, operationID string) (*operation.Operation, error) {
+	pending, certIssuer, err := c.decidableRequest(ctx, operationID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := c.takeRequest(operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ----
+
+	approver := identity.Name(ctx)
+	logging.GetLogger().Infof("Approved certificate request of issuer [%s], operation: [%s], approved by: [%s]",
+		pending.Issuer, operationID, approver)
+
+	audit.Decide(ctx, audit.POLICY_APPROVAL, audit.DECISION_ALLOWED, "")
+	trail := audit.FromContext(ctx)
+	trail.SetIssuer(pending.Issuer)
+	trail.SetNames(eventNames(pending.Request.CommonName, pending.Request.SubjectAlternativeNames))
+
+	op.State = operation.Pending
+	c.updateOperation(op)
+	c.issueInBackground(ctx, pending.Issuer, certIssuer, pending.Request, op,
+		fmt.Sprintf("requested by [%s], approved by [%s]", pending.RequestedBy, approver))
+
+	return op, nil
+}
+
+func (c *certStoreImpl) DenyRequest(ctx context.Context, operationID string, reason string) (*operation.Operation, error) {
+	pending, _, err := c.decidableRequest(ctx, operationID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := c.takeRequest(operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ----
+
+	approver := identity.Name(ctx)
+	logging.GetLogger().Infof("Denied certificate request of issuer [%s], operation: [%s], denied by: [%s], reason: [%s]",
+		pending.Issuer, operationID, approver, reason)
+
+	audit.Decide(ctx, audit.POLICY_APPROVAL, audit.DECISION_DENIED, reason)
+	trail := audit.FromContext(ctx)
+	trail.SetIssuer(pending.Issuer)
+	trail.SetNames(eventNames(pending.Request.CommonName, pending.Request.SubjectAlternativeNames))
+
+	denied := service.NewPolicyDeniedError(fmt.Sprintf("Request is denied by [%s], reason: [%s]", approver, reason), nil)
+	op.State = operation.Failed
+	op.Error = denied.Error()
+	op.ErrorKind = denied.Kind
+	c.updateOperation(op)
+	c.emitFailed(pending.Issuer, pending.Request, denied)
+
+	return op, nil
+}
+
+// decidableRequest returns the pending request, if the caller is allowed to decide it. Requests of an issuer not
+// requiring approval anymore, e.g. after a reload, are decided by anyone other than the requester. Requests of a
+// removed issuer can still be denied if the issuer is not required, returned issuer is nil then
+func (c *certStoreImpl) decidableRequest(ctx context.Context, operationID string,
+	issuerRequired bool) (*approval.Request, *certIssuer, error) {
+
+	pending, err := c.approvals.Get(operationID)
+	if approval.IsNotFound(err) {
+		logging.GetLogger().Debugf("Approval request not found: [%s]", operationID)
+		return nil, nil, service.NewNotFoundError(fmt.Sprintf("Approval request not found: [%s]", operationID), err)
+	} else if err != nil {
+		return nil, nil, service.NewBackendUnavailableError("Reading approval request failed", err)
+	}
+
+	certIssuer, err := c.getIssuer(pending.Issuer)
+	if err != nil && issuerRequired {
+		return nil, nil, err
+	}
+
+	approver := identity.Name(ctx)
+	allowed := approver == "" || approver != pending.RequestedBy
+	if certIssuer != nil && certIssuer.approval != nil {
+		allowed = certIssuer.approval.Allowed(approver, pending)
+	}
+
+	if !allowed {
+		logging.GetLogger().Warnf("[%s] is not allowed to decide request of issuer [%s], operation: [%s]",
+			approver, pending.Issuer, operationID)
+		return nil, nil, service.NewPolicyDeniedError(fmt.Sprintf("[%s] is not allowed to decide request [%s] of issuer [%s]",
+			approver, operationID, pending.Issuer), nil)
+	}
+
+	return pending, certIssuer, nil
+}
+
+// takeRequest removes the pending request so that it is decided once, and returns its operation
+func (c *certStoreImpl) takeRequest(operationID string) (*operation.Operation, error) {
+	op, err := c.operations.Get(operationID)
+	if err != nil {
+		logging.GetLogger().Errorf("Getting operation of approval request [%s] failed, %v", operationID, err)
+		return nil, service.NewBackendUnavailableError(fmt.Sprintf("Getting operation [%s] failed", operationID), err)
+	}
+
+	_, err = c.approvals.Take(operationID)
+	if approval.IsNotFound(err) {
+		return nil, service.NewNotFoundError(fmt.Sprintf("Approval request is decided already: [%s]", operationID), err)
+	} else if err != nil {
+		return nil, service.NewBackendUnavailableError("Removing approval request failed", err)
+	}
+
+	return op, nil
+}
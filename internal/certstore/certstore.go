@@ -1,7 +1,104 @@
 package certstore
 
-import "bilalekrem.com/certstore/internal/certificate/service"
+import (
+	"context"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/keypool"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/approval"
+	"bilalekrem.com/certstore/internal/certstore/config"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/certstore/operation"
+	"bilalekrem.com/certstore/internal/certstore/ratelimit"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
+)
+
+type IssuerInfo struct {
+	Name         string
+	Type         string
+	Capabilities service.Capabilities
+
+	// zero if issuer does not sign with a CA certificate of its own
+	CAExpiry time.Time
+}
+
+// ReloadResult lists the issuers changed by a reload, by name
+type ReloadResult struct {
+	Added   []string
+	Updated []string
+	Removed []string
+}
 
 type CertStore interface {
-	IssueCertificate(string, *service.NewCertificateRequest) (*service.NewCertificateResponse, error)
+	IssueCertificate(context.Context, string, *service.NewCertificateRequest) (*service.NewCertificateResponse, error)
+
+	// fails with a validation error if issuer does not sign SSH certificates
+	IssueSSHCertificate(context.Context, string, *service.NewSSHCertificateRequest) (*service.NewSSHCertificateResponse, error)
+
+	// returns nil budget if issuer does not track rate limits
+	GetRateLimitBudget(issuer string, domains []string) (*ratelimit.Budget, error)
+
+	// issues certificate in background, result is kept in the returned operation. Only values of the context are
+	// kept, background issuance is not canceled with it
+	SubmitCertificate(context.Context, string, *service.NewCertificateRequest) (*operation.Operation, error)
+
+	// fails with not found error if the caller in ctx is neither the requester nor an admin. Private key of an
+	// issued operation is returned only to the first caller claiming it, until operation.PRIVATE_KEY_TTL passes
+	GetOperation(ctx context.Context, id string) (*operation.Operation, error)
+
+	// returned channel receives updates of the operation without private key until stop function is called
+	WatchOperation(id string) (<-chan *operation.Operation, func())
+
+	// requests of issuers requiring approval, ordered by request time, only the ones of the issuer if it is not empty
+	ListApprovals(issuer string) ([]*approval.Request, error)
+
+	// issues the request in background, fails with policy denied error if the caller in ctx is not an approver
+	ApproveRequest(ctx context.Context, operationID string) (*operation.Operation, error)
+
+	// fails the operation of the request with policy denied error and the reason
+	DenyRequest(ctx context.Context, operationID string, reason string) (*operation.Operation, error)
+
+	// returns issuers sorted by name
+	ListIssuers() []IssuerInfo
+
+	// signed tree head of the transparency log, fails with not found error if the log is not enabled
+	SignedTreeHead() (*transparency.SignedTreeHead, error)
+
+	// audit path of the leaf in the tree of given size, the latest tree if size is zero
+	InclusionProof(leafHash []byte, treeSize uint64) (*transparency.InclusionProof, error)
+
+	// proves the tree of first size is a prefix of the tree of second size
+	ConsistencyProof(firstSize uint64, secondSize uint64) ([][]byte, error)
+
+	// checks the issuer is able to issue certificates, without issuing one
+	CheckIssuerHealth(ctx context.Context, issuer string) error
+
+	// checks state storage is readable and writable
+	CheckStorageHealth() error
+
+	// hit, miss and generation time of key pools, in the order of configuration
+	KeyPoolStats() []keypool.Stats
+
+	// certificates in inventory ordered by issue time, only the ones of the issuer if it is not empty. Callers other
+	// than admins see only the certificates they requested
+	ListCertificates(ctx context.Context, issuer string) ([]*inventory.Certificate, error)
+
+	// fails with not found error if certificate is not in inventory. Certificate is not found for callers other than
+	// its requester or an admin
+	GetCertificate(ctx context.Context, issuer string, serialNumber string) (*inventory.Certificate, error)
+
+	// revokes at the CA if issuer supports it, and records the revocation in inventory. Only the requester of the
+	// certificate or an admin revokes it, certificate is not found for others
+	RevokeCertificate(ctx context.Context, issuer string, serialNumber string,
+		reason service.RevocationReason) (*inventory.Certificate, error)
+
+	// certificate in inventory expiring first, fails with not found error if there is no valid certificate
+	EarliestExpiry() (*inventory.Certificate, error)
+
+	// replaces issuers changed in config, nothing is changed if config is not valid or an issuer can not be created
+	Reload(*config.Config) (*ReloadResult, error)
+
+	// waits for submitted requests until ctx is done, then stops issuer queues and key pools
+	Stop(ctx context.Context) error
 }
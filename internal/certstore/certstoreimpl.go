@@ -1,60 +1,1115 @@
 package certstore
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
 
+	"bilalekrem.com/certstore/internal/audit"
+	"bilalekrem.com/certstore/internal/certificate/caa"
+	"bilalekrem.com/certstore/internal/certificate/keypool"
 	"bilalekrem.com/certstore/internal/certificate/service"
 	"bilalekrem.com/certstore/internal/certificate/service/factory"
+	"bilalekrem.com/certstore/internal/certstore/approval"
 	"bilalekrem.com/certstore/internal/certstore/config"
+	"bilalekrem.com/certstore/internal/certstore/event"
+	"bilalekrem.com/certstore/internal/certstore/identity"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/certstore/operation"
+	"bilalekrem.com/certstore/internal/certstore/queue"
+	"bilalekrem.com/certstore/internal/certstore/ratelimit"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
+	"bilalekrem.com/certstore/internal/lifecycle"
 	"bilalekrem.com/certstore/internal/logging"
+	"bilalekrem.com/certstore/internal/metrics"
 )
 
 type certStoreImpl struct {
-	certIssuers map[string]service.CertificateService
+	// issuers are registered while requests are served, e.g. replaced by a reload
+	mutex       sync.RWMutex
+	certIssuers map[string]*certIssuer
+
+	// reloads are applied one at a time
+	reloadMutex sync.Mutex
+
+	caaChecker *caa.Checker
+	storage    storage.Storage
+	inventory  *inventory.Inventory
+	operations *operation.Store
+	approvals  *approval.Store
+
+	// nil if transparency log is not enabled
+	transparencyLog *transparency.Log
+
+	// nil if events are not enabled
+	events *event.Dispatcher
+
+	// background checks, e.g. expiring certificates, are stopped by closing the channel once
+	stopBackground     chan struct{}
+	stopBackgroundOnce sync.Once
+
+	keyPools      []*keypool.Pool
+	keyGenerators map[string]service.KeyGenerator
+
+	// config of the running server, to tell changed issuers on reload
+	conf *config.Config
+
+	// requests issued in background, waited on stop
+	submitted sync.WaitGroup
+}
+
+type certIssuer struct {
+	service     service.CertificateService
+	serviceType factory.ServiceType
+
+	caaIdentity string
+	caaMode     caa.Mode
+
+	// nil if issuer does not track rate limits
+	rateLimiter *ratelimit.Tracker
+
+	// nil if issuer does not reuse existing certificates
+	reuse *inventory.ReusePolicy
+
+	// zero if issuer does not have its own deadline
+	timeout time.Duration
+
+	// nil if requests are not queued
+	queue *queue.Queue
+
+	// nil if requests do not require approval
+	approval *approval.Policy
+
+	// nil if issuer is not created from config, e.g. registered by tests
+	conf *config.CertificateServiceConfig
+}
+
+const (
+	// storage health check writes and reads back this key
+	HEALTH_STORAGE_BUCKET = "health"
+	HEALTH_STORAGE_KEY    = "probe"
+
+	// private keys of operations not claimed in operation.PRIVATE_KEY_TTL are removed on this interval
+	PRIVATE_KEY_CHECK_INTERVAL = time.Minute
+
+	// minimum time given to deliver queued events on stop, after submitted requests are completed
+	EVENTS_STOP_TIMEOUT = 5 * time.Second
+)
+
+// outcomes of issuance metrics, besides the kinds of service errors
+const (
+	ISSUANCE_ISSUED            = "issued"
+	ISSUANCE_REUSED            = "reused"
+	ISSUANCE_CANCELED          = "canceled"
+	ISSUANCE_DEADLINE_EXCEEDED = "deadline-exceeded"
+	ISSUANCE_FAILED            = "error"
+)
+
+// services reading files only while they are created, recreated on every reload to pick up rotated files
+var recreatedOnReload = map[factory.ServiceType]bool{
+	factory.Simple:    true,
+	factory.SSHCA:     true,
+	factory.Certstore: true,
+}
+
+// internal issuers, certificates they issue are appended to the transparency log
+var loggedIssuers = map[factory.ServiceType]bool{
+	factory.Simple:               true,
+	factory.CertificateAuthority: true,
+	factory.SSHCA:                true,
 }
 
 // -------
 
 func NewFromConfig(conf *config.Config) (*certStoreImpl, error) {
+	certStorage, err := storage.New(conf.StoragePath)
+	if err != nil {
+		logging.GetLogger().Errorf("creating certstore storage failed, %v", err)
+		return nil, err
+	}
+
 	store := &certStoreImpl{
-		certIssuers: make(map[string]service.CertificateService),
+		certIssuers: make(map[string]*certIssuer),
+		caaChecker:  caa.NewChecker(conf.CAAResolver),
+		storage:     certStorage,
+		inventory:   inventory.New(certStorage),
+		operations:  operation.NewStore(certStorage),
+		approvals:   approval.NewStore(certStorage),
+		conf:        conf,
+
+		stopBackground: make(chan struct{}),
+	}
+
+	err = store.operations.FailInterrupted()
+	if err != nil {
+		logging.GetLogger().Errorf("failing interrupted operations failed, %v", err)
+		return nil, err
+	}
+
+	if conf.TransparencyLog.Enabled() {
+		store.transparencyLog, err = transparency.New(&conf.TransparencyLog, certStorage)
+		if err != nil {
+			logging.GetLogger().Errorf("creating transparency log failed, %v", err)
+			return nil, err
+		}
 	}
 
 	// ------
 
+	// key pools and issuer queues start working once they are created, they are stopped if anything after fails
+	store.keyGenerators = make(map[string]service.KeyGenerator)
+	for _, keyPoolConfig := range conf.KeyPools {
+		pool, err := keypool.New(keyPoolConfig)
+		if err != nil {
+			logging.GetLogger().Errorf("creating key pool [%s] failed, %v", keyPoolConfig.KeyType, err)
+			store.stopWorkers()
+			return nil, err
+		}
+
+		store.keyPools = append(store.keyPools, pool)
+		store.keyGenerators[keyPoolConfig.KeyType] = pool
+	}
+
 	for _, issuerConfig := range conf.IssuerConfigs {
-		issuer := factory.NewService(issuerConfig.Type, issuerConfig.Args)
+		certIssuer, err := store.newCertIssuer(issuerConfig)
+		if err != nil {
+			store.stopWorkers()
+			return nil, err
+		}
+
+		store.registerIssuer(issuerConfig.Name, certIssuer)
+	}
+
+	store.events, err = event.New(&conf.Events)
+	if err != nil {
+		logging.GetLogger().Errorf("creating event dispatcher failed, %v", err)
+		store.stopWorkers()
+		return nil, err
+	}
 
-		store.RegisterIssuer(issuerConfig.Name, issuer)
+	// started last, nothing fails after them
+	if store.events != nil {
+		go store.watchExpiring(conf.Events.CheckInterval(), conf.Events.ExpiringSoonWindow(), store.stopBackground)
 	}
+	go store.removeExpiredPrivateKeys(PRIVATE_KEY_CHECK_INTERVAL, store.stopBackground)
 
 	return store, nil
 }
 
-// ------
+// Reload replaces the issuers whose config is changed, adds new ones and removes the ones not in config anymore.
+// Nothing is changed if any of the issuers can not be created. Requests in progress are completed by the issuers
+// they started with.
+func (c *certStoreImpl) Reload(conf *config.Config) (*ReloadResult, error) {
+	err := config.Validate(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("Validating reloaded certstore config failed, %v", err)
+		return nil, err
+	}
 
-func (c *certStoreImpl) IssueCertificate(issuer string, request *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
-	certService, exist := c.certIssuers[issuer]
-	if !exist {
-		logging.GetLogger().Debug("Issuer not found: [%s]", issuer)
-		return nil, errors.New(fmt.Sprintf("Issuer not found: [%s]", issuer))
+	c.reloadMutex.Lock()
+	defer c.reloadMutex.Unlock()
+
+	c.warnRestartRequired(conf)
+
+	// ----
+
+	c.mutex.RLock()
+	current := make(map[string]*certIssuer, len(c.certIssuers))
+	for name, certIssuer := range c.certIssuers {
+		current[name] = certIssuer
+	}
+	c.mutex.RUnlock()
+
+	result := &ReloadResult{Added: []string{}, Updated: []string{}, Removed: []string{}}
+	changed := make(map[string]*certIssuer)
+	for _, issuerConfig := range conf.IssuerConfigs {
+		existing, exist := current[issuerConfig.Name]
+		if exist && existing.conf != nil && reflect.DeepEqual(*existing.conf, issuerConfig) &&
+			!recreatedOnReload[issuerConfig.Type] {
+			continue
+		}
+
+		certIssuer, err := c.newCertIssuer(issuerConfig)
+		if err != nil {
+			for _, created := range changed {
+				stopIssuer(created)
+			}
+			return nil, err
+		}
+
+		changed[issuerConfig.Name] = certIssuer
+		if exist {
+			result.Updated = append(result.Updated, issuerConfig.Name)
+		} else {
+			result.Added = append(result.Added, issuerConfig.Name)
+		}
+	}
+
+	configured := make(map[string]bool)
+	for _, issuerConfig := range conf.IssuerConfigs {
+		configured[issuerConfig.Name] = true
 	}
 
 	// ----
 
-	logging.GetLogger().Debugf("Issuer found, creating a new certificate %s", request)
-	response, err := certService.CreateCertificate(request)
+	replaced := []*certIssuer{}
+	c.mutex.Lock()
+	for name, certIssuer := range c.certIssuers {
+		if !configured[name] && certIssuer.conf != nil {
+			delete(c.certIssuers, name)
+			replaced = append(replaced, certIssuer)
+			result.Removed = append(result.Removed, name)
+		}
+	}
+	for name, certIssuer := range changed {
+		if existing, exist := c.certIssuers[name]; exist {
+			replaced = append(replaced, existing)
+		}
+		c.certIssuers[name] = certIssuer
+	}
+	c.conf = conf
+	c.mutex.Unlock()
+
+	for _, certIssuer := range replaced {
+		stopIssuer(certIssuer)
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Removed)
+	logging.GetLogger().Infof("Reloaded issuers, added: %v, updated: %v, removed: %v", result.Added, result.Updated, result.Removed)
+
+	return result, nil
+}
+
+// Stop is called once no new requests are accepted, requests submitted already are completed first since they
+// need issuer queues
+func (c *certStoreImpl) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.submitted.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logging.GetLogger().Warnf("Submitted certificate requests are not completed in time, %v", ctx.Err())
+		err = ctx.Err()
+	}
+
+	c.stopWorkers()
+
+	// stopped last, events of submitted requests are emitted once they are completed. Waiting for them may consume
+	// ctx, so that events are given their own time to be delivered
+	c.stopBackgroundOnce.Do(func() { close(c.stopBackground) })
+	eventsCtx, cancel := lifecycle.StopContext(ctx, EVENTS_STOP_TIMEOUT)
+	defer cancel()
+
+	eventsErr := c.events.Stop(eventsCtx)
+	if err == nil {
+		err = eventsErr
+	}
+
+	return err
+}
+
+// stopWorkers stops issuer queues and key pools, the ones started so far if certstore could not be created
+func (c *certStoreImpl) stopWorkers() {
+	c.mutex.RLock()
+	for _, certIssuer := range c.certIssuers {
+		stopIssuer(certIssuer)
+	}
+	c.mutex.RUnlock()
+
+	for _, pool := range c.keyPools {
+		pool.Stop()
+	}
+}
+
+// ------
+
+func (c *certStoreImpl) IssueCertificate(ctx context.Context, issuer string, request *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
+	certIssuer, err := c.getIssuer(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if certIssuer.approval != nil {
+		logging.GetLogger().Debugf("Issuer requires approval, refusing unary request: [%s]", issuer)
+		return nil, service.NewPolicyDeniedError(fmt.Sprintf("Issuer [%s] requires approval, request must be submitted", issuer), nil)
+	}
+
+	return c.issueCertificate(ctx, issuer, certIssuer, request, identity.Name(ctx), func() {})
+}
+
+func (c *certStoreImpl) IssueSSHCertificate(ctx context.Context, issuer string,
+	request *service.NewSSHCertificateRequest) (*service.NewSSHCertificateResponse, error) {
+
+	certIssuer, err := c.getIssuer(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	sshService, ok := certIssuer.service.(service.SSHCertificateService)
+	if !ok {
+		logging.GetLogger().Debugf("Issuer does not sign ssh certificates: [%s]", issuer)
+		return nil, service.NewValidationError("issuer", fmt.Sprintf("issuer does not sign SSH certificates: [%s]", issuer))
+	}
+
+	if certIssuer.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, certIssuer.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var response *service.NewSSHCertificateResponse
+	var createErr error
+	err = c.runQueued(ctx, issuer, certIssuer, service.PriorityNormal, func() {
+		response, createErr = sshService.CreateSSHCertificate(ctx, request)
+	})
+	if err == nil {
+		err = createErr
+	}
+	if err == nil {
+		err = c.appendSSHToTransparencyLog(issuer, certIssuer, response)
+	}
+	observeIssuance(issuer, issuanceOutcome(err), start)
 	if err != nil {
+		logging.GetLogger().Errorf("Issuer [%s] failed to create ssh certificate, %v", issuer, err)
 		return nil, err
 	}
 
 	return response, nil
 }
 
+func (c *certStoreImpl) SubmitCertificate(ctx context.Context, issuer string,
+	request *service.NewCertificateRequest) (*operation.Operation, error) {
+
+	certIssuer, err := c.getIssuer(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if certIssuer.approval != nil {
+		return c.submitForApproval(ctx, issuer, request)
+	}
+
+	// refused right away like unary requests, rather than an operation failing shortly after
+	if certIssuer.queue != nil && certIssuer.queue.Full() {
+		logging.GetLogger().Infof("Queue of issuer [%s] is full, submitted request is refused", issuer)
+		return nil, service.NewRateLimitedError(fmt.Sprintf("Queue of issuer [%s] is full", issuer), 0, queue.ErrFull)
+	}
+
+	op, err := c.operations.Create(issuer, identity.Name(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	logging.GetLogger().Infof("Submitted certificate request of issuer [%s], operation: [%s]", issuer, op.ID)
+	c.issueInBackground(ctx, issuer, certIssuer, request, op, "")
+	return op, nil
+}
+
+// issueInBackground issues the request of the operation, it outlives the request of ctx so only issuer's own
+// deadline applies. Detail is recorded in the audit entry of the issuance
+func (c *certStoreImpl) issueInBackground(ctx context.Context, issuer string, certIssuer *certIssuer,
+	request *service.NewCertificateRequest, op *operation.Operation, detail string) {
+
+	detached, trail := audit.Detach(ctx, audit.ACTION_ISSUE_SUBMITTED)
+	trail.SetOperationID(op.ID)
+	if detail != "" {
+		trail.SetDetail(detail)
+	}
+
+	c.submitted.Add(1)
+	go func(op operation.Operation) {
+		defer c.submitted.Done()
+
+		validating := func() {
+			op.State = operation.Validating
+			c.updateOperation(&op)
+		}
+
+		response, err := c.issueCertificate(detached, issuer, certIssuer, request, op.RequestedBy, validating)
+		trail.Finish(issuanceOutcome(err), err)
+		if err != nil {
+			op.State = operation.Failed
+			op.Error = err.Error()
+			if serviceError := service.AsError(err); serviceError != nil {
+				op.ErrorKind = serviceError.Kind
+				op.RetryAfter = serviceError.RetryAfter
+			}
+		} else {
+			op.State = operation.Issued
+			op.Certificate = response.Certificate
+			op.PrivateKey = response.PrivateKey
+			op.Chain = response.Chain
+		}
+
+		c.updateOperation(&op)
+	}(*op)
+}
+
+// GetOperation returns not found error if the operation is not requested by the caller in ctx, unless it is an
+// admin. Private key of an issued operation is returned only to the first caller claiming it
+func (c *certStoreImpl) GetOperation(ctx context.Context, id string) (*operation.Operation, error) {
+	op, err := c.operations.Get(id)
+	if operation.IsNotFound(err) {
+		logging.GetLogger().Debugf("Operation not found: [%s]", id)
+		return nil, service.NewNotFoundError(fmt.Sprintf("Operation not found: [%s]", id), err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !identity.Authorized(ctx, op.RequestedBy) {
+		logging.GetLogger().Infof("Operation [%s] of [%s] is refused to [%s]", id, op.RequestedBy, identity.Name(ctx))
+		return nil, service.NewNotFoundError(fmt.Sprintf("Operation not found: [%s]", id), nil)
+	}
+
+	op.PrivateKey = nil
+	if op.State == operation.Issued {
+		op.PrivateKey, err = c.operations.ClaimPrivateKey(id, identity.Name(ctx))
+		if err != nil {
+			logging.GetLogger().Errorf("Claiming private key of operation [%s] failed, %v", id, err)
+			return nil, service.NewBackendUnavailableError("Claiming private key of operation failed", err)
+		}
+	}
+
+	return op, nil
+}
+
+// removeExpiredPrivateKeys removes private keys of operations not claimed in time, right away, then on every
+// interval until stop is closed
+func (c *certStoreImpl) removeExpiredPrivateKeys(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.operations.RemoveExpiredPrivateKeys()
+		if err != nil {
+			logging.GetLogger().Errorf("Removing expired private keys of operations failed, %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *certStoreImpl) WatchOperation(id string) (<-chan *operation.Operation, func()) {
+	return c.operations.Watch(id)
+}
+
+func (c *certStoreImpl) GetRateLimitBudget(issuer string, domains []string) (*ratelimit.Budget, error) {
+	certIssuer, err := c.getIssuer(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if certIssuer.rateLimiter == nil {
+		return nil, nil
+	}
+
+	return certIssuer.rateLimiter.Budget(domains)
+}
+
+func (c *certStoreImpl) ListIssuers() []IssuerInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	issuers := []IssuerInfo{}
+	for name, certIssuer := range c.certIssuers {
+		info := IssuerInfo{
+			Name:         name,
+			Type:         string(certIssuer.serviceType),
+			Capabilities: certIssuer.service.Capabilities(),
+		}
+		if caHolder, ok := certIssuer.service.(service.CAHolder); ok && caHolder.CACertificate() != nil {
+			info.CAExpiry = caHolder.CACertificate().NotAfter
+		}
+
+		issuers = append(issuers, info)
+	}
+
+	sort.Slice(issuers, func(i, j int) bool {
+		return issuers[i].Name < issuers[j].Name
+	})
+
+	return issuers
+}
+
+func (c *certStoreImpl) KeyPoolStats() []keypool.Stats {
+	stats := []keypool.Stats{}
+	for _, pool := range c.keyPools {
+		stats = append(stats, pool.Stats())
+	}
+
+	return stats
+}
+
+// ListCertificates returns only the certificates requested by the caller in ctx, unless it is an admin
+func (c *certStoreImpl) ListCertificates(ctx context.Context, issuer string) ([]*inventory.Certificate, error) {
+	certificates, err := c.inventory.List()
+	if err != nil {
+		return nil, err
+	}
+
+	issued := []*inventory.Certificate{}
+	for _, certificate := range certificates {
+		if issuer != "" && certificate.Issuer != issuer {
+			continue
+		} else if !identity.Authorized(ctx, certificate.RequestedBy) {
+			continue
+		}
+
+		issued = append(issued, certificate)
+	}
+
+	return issued, nil
+}
+
+// GetCertificate does not tell callers other than the requester, or an admin, that certificate exists
+func (c *certStoreImpl) GetCertificate(ctx context.Context, issuer string, serialNumber string) (*inventory.Certificate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	certificate, err := c.inventory.Get(issuer, serialNumber)
+	if inventory.IsNotFound(err) {
+		logging.GetLogger().Debugf("Certificate not found: [%s/%s]", issuer, serialNumber)
+		return nil, service.NewNotFoundError(fmt.Sprintf("Certificate not found: [%s/%s]", issuer, serialNumber), err)
+	} else if err != nil {
+		return nil, err
+	} else if !identity.Authorized(ctx, certificate.RequestedBy) {
+		logging.GetLogger().Infof("Certificate [%s/%s] of [%s] is refused to [%s]", issuer, serialNumber,
+			certificate.RequestedBy, identity.Name(ctx))
+		return nil, service.NewNotFoundError(fmt.Sprintf("Certificate not found: [%s/%s]", issuer, serialNumber), nil)
+	}
+
+	return certificate, nil
+}
+
+// RevokeCertificate returns the certificate as is if it is revoked already. Certificates of issuers not able to
+// revoke, or not configured anymore, are only marked revoked in inventory. Certificates not requested by the caller
+// in ctx are not found, unless it is an admin
+func (c *certStoreImpl) RevokeCertificate(ctx context.Context, issuer string, serialNumber string,
+	reason service.RevocationReason) (*inventory.Certificate, error) {
+
+	certificate, err := c.GetCertificate(ctx, issuer, serialNumber)
+	if err != nil {
+		return nil, err
+	} else if certificate.Revoked() {
+		return certificate, nil
+	}
+
+	c.mutex.RLock()
+	certIssuer, exist := c.certIssuers[issuer]
+	c.mutex.RUnlock()
+
+	if exist {
+		if revoker, ok := certIssuer.service.(service.Revoker); ok {
+			err = revoker.RevokeCertificate(ctx, certificate.Certificate, reason)
+			if err != nil {
+				logging.GetLogger().Errorf("Issuer [%s] failed to revoke certificate [%s], %v", issuer, serialNumber, err)
+				return nil, err
+			}
+		} else {
+			logging.GetLogger().Infof("Issuer [%s] does not revoke certificates, revocation is kept in inventory only", issuer)
+		}
+	} else {
+		logging.GetLogger().Warnf("Issuer [%s] is not configured, revocation is kept in inventory only", issuer)
+	}
+
+	logging.GetLogger().Infof("Revoked certificate [%s] of issuer [%s], reason: [%d]", serialNumber, issuer, reason)
+	revoked, err := c.inventory.Revoke(issuer, serialNumber, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	c.events.Emit(certificateEvent(event.Revoked, revoked))
+	return revoked, nil
+}
+
+func (c *certStoreImpl) EarliestExpiry() (*inventory.Certificate, error) {
+	return c.inventory.EarliestExpiry()
+}
+
+func (c *certStoreImpl) SignedTreeHead() (*transparency.SignedTreeHead, error) {
+	if c.transparencyLog == nil {
+		return nil, transparencyLogNotEnabled()
+	}
+
+	return c.transparencyLog.SignedTreeHead()
+}
+
+func (c *certStoreImpl) InclusionProof(leafHash []byte, treeSize uint64) (*transparency.InclusionProof, error) {
+	if c.transparencyLog == nil {
+		return nil, transparencyLogNotEnabled()
+	}
+
+	proof, err := c.transparencyLog.InclusionProof(leafHash, treeSize)
+	if errors.Is(err, transparency.ErrNotFound) {
+		logging.GetLogger().Debugf("Leaf not found in transparency log: [%x]", leafHash)
+		return nil, service.NewNotFoundError(fmt.Sprintf("Certificate not found in transparency log tree of size [%d]", treeSize), err)
+	} else if errors.Is(err, transparency.ErrTreeSize) {
+		return nil, service.NewValidationError("treeSize", err.Error())
+	}
+
+	return proof, err
+}
+
+func (c *certStoreImpl) ConsistencyProof(firstSize uint64, secondSize uint64) ([][]byte, error) {
+	if c.transparencyLog == nil {
+		return nil, transparencyLogNotEnabled()
+	}
+
+	proof, err := c.transparencyLog.ConsistencyProof(firstSize, secondSize)
+	if errors.Is(err, transparency.ErrTreeSize) {
+		return nil, service.NewValidationError("secondTreeSize", err.Error())
+	}
+
+	return proof, err
+}
+
+func (c *certStoreImpl) CheckIssuerHealth(ctx context.Context, issuer string) error {
+	certIssuer, err := c.getIssuer(issuer)
+	if err != nil {
+		return err
+	}
+
+	return certIssuer.service.HealthCheck(ctx)
+}
+
+func (c *certStoreImpl) CheckStorageHealth() error {
+	value := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	err := c.storage.Put(HEALTH_STORAGE_BUCKET, HEALTH_STORAGE_KEY, value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("writing storage failed, %v", err))
+	}
+
+	stored, err := c.storage.Get(HEALTH_STORAGE_BUCKET, HEALTH_STORAGE_KEY)
+	if err != nil {
+		return errors.New(fmt.Sprintf("reading storage failed, %v", err))
+	} else if string(stored) != string(value) {
+		return errors.New("storage returned a different value than written")
+	}
+
+	return nil
+}
+
 // ------
 
 func (c *certStoreImpl) RegisterIssuer(issuer string, certService service.CertificateService) {
+	c.registerIssuer(issuer, &certIssuer{service: certService})
+}
+
+func (c *certStoreImpl) newCertIssuer(issuerConfig config.CertificateServiceConfig) (*certIssuer, error) {
+	issuer, err := factory.NewServiceWithKeyGenerators(issuerConfig.Type, issuerConfig.Args, c.keyGenerators)
+	if err != nil {
+		logging.GetLogger().Errorf("creating certificate service of issuer [%s] failed, %v", issuerConfig.Name, err)
+		return nil, errors.New(fmt.Sprintf("creating certificate service of issuer [%s] failed, %v", issuerConfig.Name, err))
+	}
+
+	caaIdentity := issuerConfig.CAAIdentity
+	if caaIdentity == "" {
+		caaIdentity = factory.DefaultCAAIdentity(issuerConfig.Type)
+	}
+
+	caaMode := issuerConfig.CAAMode
+	if caaMode == "" {
+		caaMode = caa.Enforce
+	}
+
+	rateLimit := issuerConfig.RateLimit
+	if rateLimit == nil && issuerConfig.Type == factory.LetsEncrypt {
+		rateLimit = ratelimit.LetsEncryptConfig()
+		if c.conf.StoragePath == "" {
+			logging.GetLogger().Warnf("Rate limit budgets of issuer [%s] are kept in memory and reset on restart, storage-path is not set",
+				issuerConfig.Name)
+		}
+	}
+
+	var rateLimiter *ratelimit.Tracker
+	if rateLimit != nil && rateLimit.Enabled() {
+		rateLimiter = ratelimit.NewTracker(issuerConfig.Name, *rateLimit, c.storage)
+	}
+
+	queueConfig := issuerConfig.Queue
+	if queueConfig == nil && issuerConfig.Type == factory.LetsEncrypt {
+		queueConfig = queue.LetsEncryptConfig()
+	}
+
+	var issuerQueue *queue.Queue
+	if queueConfig != nil && queueConfig.Enabled() {
+		issuerQueue = queue.New(issuerConfig.Name, *queueConfig)
+	}
+
+	var approvalPolicy *approval.Policy
+	if issuerConfig.ApprovalRequired {
+		approvalPolicy = &approval.Policy{Approvers: issuerConfig.Approvers}
+	}
+
+	return &certIssuer{
+		service:     issuer,
+		serviceType: issuerConfig.Type,
+		caaIdentity: caaIdentity,
+		caaMode:     caaMode,
+		rateLimiter: rateLimiter,
+		reuse:       issuerConfig.Reuse,
+		timeout:     issuerConfig.Timeout,
+		queue:       issuerQueue,
+		approval:    approvalPolicy,
+		conf:        &issuerConfig,
+	}, nil
+}
+
+// warnRestartRequired logs the changes taking effect only after a restart
+func (c *certStoreImpl) warnRestartRequired(conf *config.Config) {
+	if conf.StoragePath != c.conf.StoragePath {
+		logging.GetLogger().Warnf("storage-path change requires a restart, keeping [%s]", c.conf.StoragePath)
+	}
+	if conf.CAAResolver != c.conf.CAAResolver {
+		logging.GetLogger().Warnf("caa-resolver change requires a restart, keeping [%s]", c.conf.CAAResolver)
+	}
+	if !reflect.DeepEqual(conf.KeyPools, c.conf.KeyPools) {
+		logging.GetLogger().Warnf("key-pools change requires a restart, keeping the running key pools")
+	}
+	if !reflect.DeepEqual(conf.Events, c.conf.Events) {
+		logging.GetLogger().Warnf("events change requires a restart, keeping the running webhooks")
+	}
+	if conf.TransparencyLog != c.conf.TransparencyLog {
+		logging.GetLogger().Warnf("transparency-log change requires a restart, keeping [%s]", c.conf.TransparencyLog.SigningKey)
+	}
+}
+
+// replaces the issuer if it is registered already, requests in progress are completed by the replaced one
+func (c *certStoreImpl) registerIssuer(issuer string, certIssuer *certIssuer) {
 	logging.GetLogger().Debugf("Registering a new certificate service: [%s]", issuer)
-	c.certIssuers[issuer] = certService
+
+	c.mutex.Lock()
+	replaced, exist := c.certIssuers[issuer]
+	c.certIssuers[issuer] = certIssuer
+	c.mutex.Unlock()
+
+	if exist {
+		stopIssuer(replaced)
+	}
+}
+
+func (c *certStoreImpl) getIssuer(issuer string) (*certIssuer, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	certIssuer, exist := c.certIssuers[issuer]
+	if !exist {
+		logging.GetLogger().Debugf("Issuer not found: [%s]", issuer)
+		return nil, service.NewNotFoundError(fmt.Sprintf("Issuer not found: [%s]", issuer), nil)
+	}
+
+	return certIssuer, nil
+}
+
+// runQueued runs fn in the issuer's queue, or right away if the issuer does not queue requests
+func (c *certStoreImpl) runQueued(ctx context.Context, issuer string, certIssuer *certIssuer,
+	priority service.Priority, fn func()) error {
+
+	if certIssuer.queue == nil {
+		fn()
+		return nil
+	}
+
+	err := certIssuer.queue.Do(ctx, priority, fn)
+	if errors.Is(err, queue.ErrFull) {
+		return service.NewRateLimitedError(fmt.Sprintf("Queue of issuer [%s] is full", issuer), 0, err)
+	} else if errors.Is(err, queue.ErrStopped) {
+		return service.NewBackendUnavailableError(fmt.Sprintf("Issuer [%s] is replaced", issuer), err)
+	}
+
+	return err
+}
+
+// validating is called just before the issuer's service is requested, requestedBy is recorded in inventory
+func (c *certStoreImpl) issueCertificate(ctx context.Context, issuer string, certIssuer *certIssuer,
+	request *service.NewCertificateRequest, requestedBy string, validating func()) (*service.NewCertificateResponse, error) {
+
+	start := time.Now()
+	if existing := c.findReusable(issuer, certIssuer, request, requestedBy); existing != nil {
+		response := &service.NewCertificateResponse{
+			Certificate: existing.Certificate,
+			PrivateKey:  existing.PrivateKey,
+			Chain:       existing.Chain,
+		}
+
+		// certificates issued before the log is enabled are logged once they are reused, logged ones are not
+		// appended again
+		err := c.appendToTransparencyLog(issuer, certIssuer, response)
+		if err != nil {
+			observeIssuance(issuer, issuanceOutcome(err), start)
+			return nil, err
+		}
+
+		observeIssuance(issuer, ISSUANCE_REUSED, start)
+		audit.Decide(ctx, audit.POLICY_REUSE, audit.DECISION_REUSED, existing.SerialNumber)
+		audit.SetSerialNumber(ctx, existing.SerialNumber)
+		return response, nil
+	}
+
+	response, err := c.createCertificate(ctx, issuer, certIssuer, request, requestedBy, validating)
+	observeIssuance(issuer, issuanceOutcome(err), start)
+	if err != nil {
+		c.emitFailed(issuer, request, err)
+	}
+
+	return response, err
+}
+
+// findReusable returns nil if issuer does not reuse certificates or there is no certificate of the requester to reuse
+func (c *certStoreImpl) findReusable(issuer string, certIssuer *certIssuer,
+	request *service.NewCertificateRequest, requestedBy string) *inventory.Certificate {
+
+	if certIssuer.reuse == nil || request.ForceNew {
+		return nil
+	}
+
+	existing, err := c.inventory.FindReusable(issuer, request, requestedBy, certIssuer.reuse.MinRemainingDays)
+	if err != nil {
+		if !inventory.IsNotFound(err) {
+			logging.GetLogger().Warnf("Looking up reusable certificate failed, issuing a new one, %v", err)
+		}
+		return nil
+	}
+
+	logging.GetLogger().Infof("Reusing existing certificate [%s] of issuer [%s]", existing.SerialNumber, issuer)
+	return existing
+}
+
+func (c *certStoreImpl) createCertificate(ctx context.Context, issuer string, certIssuer *certIssuer,
+	request *service.NewCertificateRequest, requestedBy string, validating func()) (*service.NewCertificateResponse, error) {
+
+	err := c.checkCAA(ctx, issuer, certIssuer, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// counted before the request waits in the queue, so that requests waiting together can not exceed the limits
+	var reservation *ratelimit.Reservation
+	if certIssuer.rateLimiter != nil {
+		reservation, err = certIssuer.rateLimiter.Reserve(requestedDomains(request))
+		var exceeded *ratelimit.ExceededError
+		if errors.As(err, &exceeded) {
+			logging.GetLogger().Errorf("Issuer [%s] refused the request, %v", issuer, err)
+			audit.Decide(ctx, audit.POLICY_RATE_LIMIT, audit.DECISION_DENIED, err.Error())
+			return nil, service.NewRateLimitedError(fmt.Sprintf("Issuer [%s] refused the request", issuer), exceeded.RetryAfter, err)
+		} else if err != nil {
+			logging.GetLogger().Errorf("Checking rate limits failed, issuer: [%s], %v", issuer, err)
+			return nil, err
+		}
+
+		audit.Decide(ctx, audit.POLICY_RATE_LIMIT, audit.DECISION_ALLOWED, "")
+	}
+
+	// ----
+
+	if certIssuer.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, certIssuer.timeout)
+		defer cancel()
+	}
+
+	// deadline covers the time waiting in the queue
+	var response *service.NewCertificateResponse
+	var createErr error
+	err = c.runQueued(ctx, issuer, certIssuer, request.Priority, func() {
+		validating()
+
+		logging.GetLogger().Debugf("Issuer found, creating a new certificate %s", request)
+		response, createErr = certIssuer.service.CreateCertificate(ctx, request)
+	})
+	if err == nil {
+		err = createErr
+	}
+	if err != nil {
+		logging.GetLogger().Errorf("Issuer [%s] failed to create certificate, %v", issuer, err)
+		releaseRateLimit(issuer, reservation)
+		return nil, err
+	}
+
+	if response != nil {
+		err = c.appendToTransparencyLog(issuer, certIssuer, response)
+		if err != nil {
+			// certificate is not handed out, request does not count
+			releaseRateLimit(issuer, reservation)
+			return nil, err
+		}
+
+		previous := c.findPrevious(issuer, request)
+		certificate, err := c.inventory.Add(issuer, request, response, requestedBy, certIssuer.reuse != nil)
+		if err != nil {
+			logging.GetLogger().Errorf("Adding certificate to inventory failed, issuer: [%s], %v", issuer, err)
+
+			// certificate is issued anyway, audit entry still tells which one
+			if serialNumber, err := inventory.SerialNumber(response.Certificate); err == nil {
+				audit.SetSerialNumber(ctx, serialNumber)
+			}
+		} else {
+			audit.SetSerialNumber(ctx, certificate.SerialNumber)
+		}
+		c.emitIssued(issuer, request, certificate, previous)
+	}
+
+	return response, nil
+}
+
+// appendToTransparencyLog fails the issuance if the certificate can not be logged, so that no certificate of an
+// internal issuer is handed out without being in the log
+func (c *certStoreImpl) appendToTransparencyLog(issuer string, certIssuer *certIssuer,
+	response *service.NewCertificateResponse) error {
+
+	if c.transparencyLog == nil || !loggedIssuers[certIssuer.serviceType] {
+		return nil
+	}
+
+	leaf, err := transparency.CertificateLeaf(response.Certificate)
+	if err != nil {
+		logging.GetLogger().Errorf("Issuer [%s] returned a certificate not able to be logged, %v", issuer, err)
+		return err
+	}
+
+	return c.appendLeaf(issuer, leaf)
+}
+
+func (c *certStoreImpl) appendSSHToTransparencyLog(issuer string, certIssuer *certIssuer,
+	response *service.NewSSHCertificateResponse) error {
+
+	if c.transparencyLog == nil || !loggedIssuers[certIssuer.serviceType] {
+		return nil
+	}
+
+	leaf, err := transparency.SSHCertificateLeaf(response.Certificate)
+	if err != nil {
+		logging.GetLogger().Errorf("Issuer [%s] returned an ssh certificate not able to be logged, %v", issuer, err)
+		return err
+	}
+
+	return c.appendLeaf(issuer, leaf)
+}
+
+func (c *certStoreImpl) appendLeaf(issuer string, leaf []byte) error {
+	index, err := c.transparencyLog.Append(leaf)
+	if err != nil {
+		logging.GetLogger().Errorf("Appending certificate of issuer [%s] to transparency log failed, %v", issuer, err)
+		return service.NewBackendUnavailableError(
+			fmt.Sprintf("Appending certificate of issuer [%s] to transparency log failed", issuer), err)
+	}
+
+	logging.GetLogger().Debugf("Appended certificate of issuer [%s] to transparency log, index: [%d]", issuer, index)
+	return nil
+}
+
+func (c *certStoreImpl) updateOperation(op *operation.Operation) {
+	err := c.operations.Update(op)
+	if err != nil {
+		logging.GetLogger().Errorf("Updating operation [%s] failed, %v", op.ID, err)
+	}
+}
+
+func (c *certStoreImpl) checkCAA(ctx context.Context, issuer string, certIssuer *certIssuer,
+	request *service.NewCertificateRequest) error {
+
+	if certIssuer.caaIdentity == "" {
+		return nil
+	}
+
+	for _, domain := range requestedDomains(request) {
+		err := c.caaChecker.Check(domain, certIssuer.caaIdentity)
+		if err == nil {
+			audit.Decide(ctx, audit.POLICY_CAA, audit.DECISION_ALLOWED, domain)
+			continue
+		}
+
+		if certIssuer.caaMode == caa.Warn {
+			logging.GetLogger().Warnf("CAA check failed for issuer [%s], continuing in warn mode, %v", issuer, err)
+			audit.Decide(ctx, audit.POLICY_CAA, audit.DECISION_WARNED, err.Error())
+			continue
+		}
+
+		logging.GetLogger().Errorf("CAA check failed for issuer [%s], %v", issuer, err)
+		audit.Decide(ctx, audit.POLICY_CAA, audit.DECISION_DENIED, err.Error())
+		message := fmt.Sprintf("CAA check failed for issuer [%s]", issuer)
+		if errors.Is(err, caa.ErrLookupFailed) {
+			return service.NewBackendUnavailableError(message, err)
+		}
+
+		return service.NewPolicyDeniedError(message, err)
+	}
+
+	return nil
+}
+
+func transparencyLogNotEnabled() error {
+	return service.NewNotFoundError("Transparency log is not enabled", nil)
+}
+
+func observeIssuance(issuer string, outcome string, start time.Time) {
+	metrics.Issuances.WithLabelValues(issuer, outcome).Inc()
+	metrics.IssuanceDuration.WithLabelValues(issuer, outcome).Observe(time.Since(start).Seconds())
+}
+
+// issuanceOutcome is the kind of the error for service errors, e.g. rate-limited
+func issuanceOutcome(err error) string {
+	if err == nil {
+		return ISSUANCE_ISSUED
+	}
+
+	if serviceErr := service.AsError(err); serviceErr != nil {
+		return string(serviceErr.Kind)
+	} else if errors.Is(err, context.Canceled) {
+		return ISSUANCE_CANCELED
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		return ISSUANCE_DEADLINE_EXCEEDED
+	}
+
+	return ISSUANCE_FAILED
+}
+
+// releaseRateLimit gives back the budget reserved for a certificate not issued
+func releaseRateLimit(issuer string, reservation *ratelimit.Reservation) {
+	if reservation == nil {
+		return
+	}
+
+	err := reservation.Release()
+	if err != nil {
+		logging.GetLogger().Errorf("Releasing rate limit reservation failed, issuer: [%s], %v", issuer, err)
+	}
+}
+
+// stopIssuer is called once issuer is replaced or removed, or server is stopping. Services holding connections,
+// e.g. relay, close them once requests in progress are completed
+func stopIssuer(certIssuer *certIssuer) {
+	if certIssuer.queue != nil {
+		certIssuer.queue.Stop()
+	}
+
+	if closer, ok := certIssuer.service.(io.Closer); ok {
+		err := closer.Close()
+		if err != nil {
+			logging.GetLogger().Warnf("closing certificate service failed, %v", err)
+		}
+	}
+}
+
+func requestedDomains(request *service.NewCertificateRequest) []string {
+	return append([]string{request.CommonName}, request.SubjectAlternativeNames...)
 }
@@ -1,12 +1,48 @@
 package certstore
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/audit"
+	"bilalekrem.com/certstore/internal/certificate/caa"
 	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certificate/service/factory"
+	"bilalekrem.com/certstore/internal/certificate/service/sshca"
+	"bilalekrem.com/certstore/internal/certificate/x509utils"
+	"bilalekrem.com/certstore/internal/certstore/approval"
 	"bilalekrem.com/certstore/internal/certstore/config"
+	"bilalekrem.com/certstore/internal/certstore/event"
+	"bilalekrem.com/certstore/internal/certstore/identity"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/certstore/operation"
+	"bilalekrem.com/certstore/internal/certstore/queue"
+	"bilalekrem.com/certstore/internal/certstore/ratelimit"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
+	"bilalekrem.com/certstore/internal/metrics"
 	"github.com/golang/mock/gomock"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestCreateCertStoreWithConfig(t *testing.T) {
@@ -24,14 +60,14 @@ func TestIssueCertificate(t *testing.T) {
 	firstService := certificate_service.NewMockCertificateService(ctrl)
 	firstService.
 		EXPECT().
-		CreateCertificate(gomock.Eq(firstRequest)).
+		CreateCertificate(gomock.Any(), gomock.Eq(firstRequest)).
 		MinTimes(1)
 
 	secondRequest := &certificate_service.NewCertificateRequest{CommonName: "second cert"}
 	secondService := certificate_service.NewMockCertificateService(ctrl)
 	secondService.
 		EXPECT().
-		CreateCertificate(gomock.Eq(secondRequest)).
+		CreateCertificate(gomock.Any(), gomock.Eq(secondRequest)).
 		MinTimes(1)
 
 	// ----
@@ -42,19 +78,1718 @@ func TestIssueCertificate(t *testing.T) {
 
 	// ----
 
-	store.IssueCertificate("first issuer", firstRequest)
-	store.IssueCertificate("second issuer", secondRequest)
+	store.IssueCertificate(context.Background(), "first issuer", firstRequest)
+	store.IssueCertificate(context.Background(), "second issuer", secondRequest)
+}
+
+func TestIssueCertificateCAADenied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	store := createWithCAARecords(t, "certstore.com", "letsencrypt.org")
+	store.registerIssuer("issuer", &certIssuer{
+		service:     certService,
+		caaIdentity: "ca.certstore.internal",
+		caaMode:     caa.Enforce,
+	})
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{
+		CommonName:              "certstore.com",
+		SubjectAlternativeNames: []string{"www.certstore.com"},
+	}
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.ErrorContains(t, err, "CAA check failed")
+	assert.Equal(t, certificate_service.PolicyDeniedErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestIssueCertificateCAADeniedAudited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := createWithCAARecords(t, "certstore.com", "letsencrypt.org")
+	store.registerIssuer("issuer", &certIssuer{
+		service:     certificate_service.NewMockCertificateService(ctrl),
+		caaIdentity: "ca.certstore.internal",
+		caaMode:     caa.Enforce,
+	})
+
+	buffer := &bytes.Buffer{}
+	trail := audit.NewWriterLogger(buffer).Start(audit.Entry{Action: "IssueCertificate"})
+
+	// ----
+
+	_, err := store.IssueCertificate(audit.NewContext(context.Background(), trail), "issuer",
+		&certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.Error(t, err, "issuing certificate should be denied")
+	trail.Finish("policy-denied", err)
+
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	assert.Equal(t, 1, len(entries[0].Decisions))
+	assert.Equal(t, audit.POLICY_CAA, entries[0].Decisions[0].Policy)
+	assert.Equal(t, audit.DECISION_DENIED, entries[0].Decisions[0].Result)
+}
+
+func TestIssueCertificateCAAAuthorized(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	request := &certificate_service.NewCertificateRequest{
+		CommonName:              "certstore.com",
+		SubjectAlternativeNames: []string{"www.certstore.com"},
+	}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Eq(request)).
+		Times(1)
+
+	store := createWithCAARecords(t, "certstore.com", "letsencrypt.org")
+	store.registerIssuer("issuer", &certIssuer{
+		service:     certService,
+		caaIdentity: "letsencrypt.org",
+		caaMode:     caa.Enforce,
+	})
+
+	// ----
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "issuing certificate failed")
+}
+
+func TestIssueCertificateCAAWarnMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Eq(request)).
+		Times(1)
+
+	store := createWithCAARecords(t, "certstore.com", "letsencrypt.org")
+	store.registerIssuer("issuer", &certIssuer{
+		service:     certService,
+		caaIdentity: "ca.certstore.internal",
+		caaMode:     caa.Warn,
+	})
+
+	// ----
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "issuing certificate should not fail in warn mode")
+}
+
+func TestDefaultCAAIdentity(t *testing.T) {
+	configYaml := `services:
+  - name: test-cert-service
+    type: CertificateAuthority
+  - name: test-cert-service-with-caa
+    type: CertificateAuthority
+    caa-identity: ca.certstore.internal`
+	conf, err := config.ParseYaml(configYaml)
+	assert.NotError(t, err, "parsing certstore config failed")
+
+	store, err := NewFromConfig(conf)
+	assert.NotError(t, err, "creating certstore failed")
+
+	assert.Equal(t, "", store.certIssuers["test-cert-service"].caaIdentity)
+	assert.Equal(t, "ca.certstore.internal", store.certIssuers["test-cert-service-with-caa"].caaIdentity)
+	assert.Equal(t, caa.Enforce, store.certIssuers["test-cert-service-with-caa"].caaMode)
+}
+
+func TestIssueCertificateRateLimitExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Eq(request)).
+		Return(&certificate_service.NewCertificateResponse{}, nil).
+		Times(1)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{
+		service:     certService,
+		rateLimiter: ratelimit.NewTracker("issuer", ratelimit.Config{DuplicateCertificates: 1}, store.storage),
+	})
+
+	// ----
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "issuing certificate failed")
+
+	_, err = store.IssueCertificate(context.Background(), "issuer", request)
+	assert.ErrorContains(t, err, "rate limit exceeded")
+	rateLimited := certificate_service.AsError(err)
+	assert.Equal(t, certificate_service.RateLimitedErrorKind, rateLimited.Kind)
+	assert.True(t, rateLimited.RetryAfter > 0)
+
+	// ----
+
+	budget, err := store.GetRateLimitBudget("issuer", []string{"certstore.com"})
+	assert.NotError(t, err, "getting rate limit budget failed")
+	assert.Equal(t, 1, budget.Duplicate.Used)
+	assert.Equal(t, 0, budget.Duplicate.Remaining)
+}
+
+func TestIssueCertificateFailureReleasesRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Eq(request)).
+		Return(nil, errors.New("order failed")).
+		Times(1)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{
+		service:     certService,
+		rateLimiter: ratelimit.NewTracker("issuer", ratelimit.Config{DuplicateCertificates: 1}, store.storage),
+	})
+
+	// ----
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.ErrorContains(t, err, "order failed")
+
+	budget, err := store.GetRateLimitBudget("issuer", []string{"certstore.com"})
+	assert.NotError(t, err, "getting rate limit budget failed")
+	assert.Equal(t, 0, budget.Duplicate.Used)
+}
+
+func TestListIssuers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	capabilities := certificate_service.Capabilities{
+		KeyTypes:        []string{certificate_service.KEY_TYPE_RSA_2048},
+		SupportedFields: []string{certificate_service.FIELD_COMMON_NAME},
+	}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		Capabilities().
+		Return(capabilities).
+		Times(2)
+
+	store := createWithConfig(t)
+	store.registerIssuer("second issuer", &certIssuer{service: certService, serviceType: factory.LetsEncrypt})
+	store.registerIssuer("first issuer", &certIssuer{service: certService, serviceType: factory.Simple})
+
+	// ----
+
+	issuers := store.ListIssuers()
+	assert.Equal(t, 3, len(issuers))
+	assert.Equal(t, "first issuer", issuers[0].Name)
+	assert.Equal(t, "Simple", issuers[0].Type)
+	assert.Equal(t, "second issuer", issuers[1].Name)
+	assert.DeepEqual(t, capabilities, issuers[1].Capabilities)
+	assert.Equal(t, "test-cert-service", issuers[2].Name)
+	assert.Equal(t, "CertificateAuthority", issuers[2].Type)
+}
+
+func TestNewFromConfigFailsWithNotValidIssuer(t *testing.T) {
+	conf, err := config.ParseYaml(`services:
+  - name: test-cert-service
+    type: Simple
+    args:
+      private-key: simple-private-key-file-path
+      certificate: simple-certificate-file-path`)
+	assert.NotError(t, err, "parsing certstore config failed")
+
+	_, err = NewFromConfig(conf)
+	assert.ErrorContains(t, err, "creating certificate service of issuer [test-cert-service] failed")
+}
+
+func TestNewFromConfigStopsWorkersOnFailure(t *testing.T) {
+	conf, err := config.ParseYaml(`services:
+  - name: queued
+    type: CertificateAuthority
+    queue:
+      concurrency: 4
+  - name: not-valid
+    type: Simple
+    args:
+      private-key: simple-private-key-file-path
+      certificate: simple-certificate-file-path`)
+	assert.NotError(t, err, "parsing certstore config failed")
+
+	before := runtime.NumGoroutine()
+	_, err = NewFromConfig(conf)
+	assert.Error(t, err, "creating certstore should fail")
+
+	// workers of the queue created before the failing issuer are stopped
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.TrueM(t, runtime.NumGoroutine() <= before, "workers are left running")
+}
+
+func TestCheckIssuerHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		HealthCheck(gomock.Any()).
+		Return(errors.New("ca expires soon"))
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	// ----
+
+	err := store.CheckIssuerHealth(context.Background(), "test-cert-service")
+	assert.NotError(t, err, "ca certificate service should be healthy")
+
+	err = store.CheckIssuerHealth(context.Background(), "issuer")
+	assert.ErrorContains(t, err, "ca expires soon")
+
+	err = store.CheckIssuerHealth(context.Background(), "unknown issuer")
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestCheckStorageHealth(t *testing.T) {
+	store := createWithConfig(t)
+	assert.NotError(t, store.CheckStorageHealth(), "storage should be healthy")
+
+	store.storage = failingStorage{}
+	assert.ErrorContains(t, store.CheckStorageHealth(), "writing storage failed")
+}
+
+func TestIssueSSHCertificateNotSSHIssuer(t *testing.T) {
+	store := createWithConfig(t)
+	request := &certificate_service.NewSSHCertificateRequest{Principals: []string{"alice"}}
+
+	_, err := store.IssueSSHCertificate(context.Background(), "test-cert-service", request)
+	assert.ErrorContains(t, err, "issuer does not sign SSH certificates")
+	assert.Equal(t, certificate_service.ValidationErrorKind, certificate_service.AsError(err).Kind)
+
+	_, err = store.IssueSSHCertificate(context.Background(), "unknown issuer", request)
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestKeyPoolStats(t *testing.T) {
+	conf, err := config.ParseYaml(`key-pools:
+  - key-type: RSA-2048
+    size: 1`)
+	assert.NotError(t, err, "parsing certstore config failed")
+
+	store, err := NewFromConfig(conf)
+	assert.NotError(t, err, "creating certstore failed")
+	defer store.keyPools[0].Stop()
+
+	stats := store.KeyPoolStats()
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, certificate_service.KEY_TYPE_RSA_2048, stats[0].KeyType)
+}
+
+func TestRateLimitBudgetNotTracked(t *testing.T) {
+	store := createWithConfig(t)
+
+	budget, err := store.GetRateLimitBudget("test-cert-service", []string{"certstore.com"})
+	assert.NotError(t, err, "getting rate limit budget failed")
+	assert.Nil(t, budget)
+
+	_, err = store.GetRateLimitBudget("unknown issuer", []string{"certstore.com"})
+	assert.ErrorContains(t, err, "Issuer not found")
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestIssueCertificateReusesExisting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issued := &certificate_service.NewCertificateResponse{
+		Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90)),
+		PrivateKey:  []byte("test private key"),
+	}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(issued, nil).
+		Times(2)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{
+		service: certService,
+		reuse:   &inventory.ReusePolicy{MinRemainingDays: 30},
+	})
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "issuing certificate failed")
+
+	reused, err := store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+	assert.DeepEqual(t, issued, reused)
+
+	// ----
+
+	_, err = store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{
+		CommonName: "certstore.com",
+		ForceNew:   true,
+	})
+	assert.NotError(t, err, "issuing certificate failed")
+}
+
+func TestIssueCertificateReusesOnlyOwnCertificates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			return &certificate_service.NewCertificateResponse{
+				Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90)),
+				PrivateKey:  []byte("test private key"),
+			}, nil
+		}).
+		Times(2)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{
+		service: certService,
+		reuse:   &inventory.ReusePolicy{MinRemainingDays: 30},
+	})
+
+	first := identity.NewContext(context.Background(), identity.Caller{Name: "agent-1"})
+	second := identity.NewContext(context.Background(), identity.Caller{Name: "agent-2"})
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	issued, err := store.IssueCertificate(first, "issuer", request)
+	assert.NotError(t, err, "issuing certificate failed")
+
+	// private key of agent-1 is not handed to agent-2, a new certificate is issued
+	other, err := store.IssueCertificate(second, "issuer", request)
+	assert.NotError(t, err, "issuing certificate failed")
+	assert.False(t, bytes.Equal(issued.Certificate, other.Certificate))
+
+	reused, err := store.IssueCertificate(first, "issuer", request)
+	assert.NotError(t, err, "issuing certificate failed")
+	assert.DeepEqual(t, issued.Certificate, reused.Certificate)
+}
+
+func TestIssuanceMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issued := &certificate_service.NewCertificateResponse{
+		Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90)),
+		PrivateKey:  []byte("test private key"),
+	}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	gomock.InOrder(
+		certService.
+			EXPECT().
+			CreateCertificate(gomock.Any(), gomock.Any()).
+			Return(issued, nil),
+		certService.
+			EXPECT().
+			CreateCertificate(gomock.Any(), gomock.Any()).
+			Return(nil, certificate_service.NewBackendUnavailableError("backend is down", nil)),
+	)
+
+	store := createWithConfig(t)
+	store.registerIssuer("metrics issuer", &certIssuer{
+		service: certService,
+		reuse:   &inventory.ReusePolicy{MinRemainingDays: 30},
+	})
+
+	// ----
+
+	store.IssueCertificate(context.Background(), "metrics issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	store.IssueCertificate(context.Background(), "metrics issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	store.IssueCertificate(context.Background(), "metrics issuer", &certificate_service.NewCertificateRequest{CommonName: "other.certstore.com"})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Issuances.WithLabelValues("metrics issuer", ISSUANCE_ISSUED)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Issuances.WithLabelValues("metrics issuer", ISSUANCE_REUSED)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Issuances.WithLabelValues("metrics issuer", "backend-unavailable")))
+}
+
+func TestIssuanceOutcome(t *testing.T) {
+	assert.Equal(t, ISSUANCE_ISSUED, issuanceOutcome(nil))
+	assert.Equal(t, "rate-limited", issuanceOutcome(certificate_service.NewRateLimitedError("limited", 0, nil)))
+	assert.Equal(t, ISSUANCE_CANCELED, issuanceOutcome(context.Canceled))
+	assert.Equal(t, ISSUANCE_DEADLINE_EXCEEDED, issuanceOutcome(fmt.Errorf("waiting, %w", context.DeadlineExceeded)))
+	assert.Equal(t, ISSUANCE_FAILED, issuanceOutcome(errors.New("failed")))
+}
+
+func TestEarliestExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 30))}, nil)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	_, err := store.EarliestExpiry()
+	assert.True(t, inventory.IsNotFound(err))
+
+	_, err = store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+
+	certificate, err := store.EarliestExpiry()
+	assert.NotError(t, err, "finding earliest expiry failed")
+	assert.Equal(t, "issuer", certificate.Issuer)
+}
+
+func TestListAndGetCertificates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			return &certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}, nil
+		}).
+		Times(2)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("first", certService)
+	store.RegisterIssuer("second", certService)
+
+	_, err := store.IssueCertificate(context.Background(), "first", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+	_, err = store.IssueCertificate(context.Background(), "second", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+
+	// ----
+
+	all, err := store.ListCertificates(context.Background(), "")
+	assert.NotError(t, err, "listing certificates failed")
+	assert.Equal(t, 2, len(all))
+
+	issued, err := store.ListCertificates(context.Background(), "second")
+	assert.NotError(t, err, "listing certificates failed")
+	assert.Equal(t, 1, len(issued))
+	assert.Equal(t, "second", issued[0].Issuer)
+
+	certificate, err := store.GetCertificate(context.Background(), "second", issued[0].SerialNumber)
+	assert.NotError(t, err, "getting certificate failed")
+	assert.Equal(t, "certstore.com", certificate.CommonName)
+
+	_, err = store.GetCertificate(context.Background(), "first", "not-a-serial")
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestRevokeCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issued := &certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(issued, nil)
+
+	revoker := certificate_service.NewMockRevoker(ctrl)
+	revoker.
+		EXPECT().
+		RevokeCertificate(gomock.Any(), gomock.Eq(issued.Certificate), gomock.Eq(certificate_service.RevocationSuperseded)).
+		Return(nil).
+		Times(1)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", &revokingService{certService, revoker})
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+
+	certificates, err := store.ListCertificates(context.Background(), "issuer")
+	assert.NotError(t, err, "listing certificates failed")
+	serialNumber := certificates[0].SerialNumber
+
+	// ----
+
+	revoked, err := store.RevokeCertificate(context.Background(), "issuer", serialNumber, certificate_service.RevocationSuperseded)
+	assert.NotError(t, err, "revoking certificate failed")
+	assert.True(t, revoked.Revoked())
+
+	// revoked already, CA is not requested again
+	_, err = store.RevokeCertificate(context.Background(), "issuer", serialNumber, certificate_service.RevocationSuperseded)
+	assert.NotError(t, err, "revoking certificate again failed")
+}
+
+func TestRevokeCertificateFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issued := &certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(issued, nil)
+
+	revoker := certificate_service.NewMockRevoker(ctrl)
+	revoker.
+		EXPECT().
+		RevokeCertificate(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(certificate_service.NewBackendUnavailableError("acme is down", nil))
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", &revokingService{certService, revoker})
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+
+	certificates, err := store.ListCertificates(context.Background(), "issuer")
+	assert.NotError(t, err, "listing certificates failed")
+
+	// ----
+
+	_, err = store.RevokeCertificate(context.Background(), "issuer", certificates[0].SerialNumber, certificate_service.RevocationUnspecified)
+	assert.Equal(t, certificate_service.BackendUnavailableErrorKind, certificate_service.AsError(err).Kind)
+
+	certificate, err := store.GetCertificate(context.Background(), "issuer", certificates[0].SerialNumber)
+	assert.NotError(t, err, "getting certificate failed")
+	assert.False(t, certificate.Revoked())
+}
+
+func TestGetListAndRevokeCertificatesOfRequester(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			return &certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}, nil
+		}).
+		Times(2)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	requester := identity.NewContext(context.Background(), identity.Caller{Name: "agent-1"})
+	other := identity.NewContext(context.Background(), identity.Caller{Name: "agent-2"})
+	admin := identity.NewContext(context.Background(), identity.Caller{Name: "admin", Admin: true})
+
+	_, err := store.IssueCertificate(requester, "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+	_, err = store.IssueCertificate(other, "issuer", &certificate_service.NewCertificateRequest{CommonName: "other.certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+
+	// ----
+
+	certificates, err := store.ListCertificates(requester, "")
+	assert.NotError(t, err, "listing certificates failed")
+	assert.Equal(t, 1, len(certificates))
+	assert.Equal(t, "agent-1", certificates[0].RequestedBy)
+
+	all, err := store.ListCertificates(admin, "issuer")
+	assert.NotError(t, err, "listing certificates failed")
+	assert.Equal(t, 2, len(all))
+
+	_, err = store.GetCertificate(other, "issuer", certificates[0].SerialNumber)
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+
+	certificate, err := store.GetCertificate(requester, "issuer", certificates[0].SerialNumber)
+	assert.NotError(t, err, "getting certificate failed")
+	assert.Equal(t, "certstore.com", certificate.CommonName)
+
+	_, err = store.GetCertificate(admin, "issuer", certificates[0].SerialNumber)
+	assert.NotError(t, err, "getting certificate failed")
+
+	_, err = store.RevokeCertificate(other, "issuer", certificates[0].SerialNumber, certificate_service.RevocationUnspecified)
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+
+	revoked, err := store.RevokeCertificate(requester, "issuer", certificates[0].SerialNumber, certificate_service.RevocationUnspecified)
+	assert.NotError(t, err, "revoking certificate failed")
+	assert.True(t, revoked.Revoked())
+}
+
+func TestIssueCertificateReuseAudited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{
+			Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90)),
+			PrivateKey:  []byte("test private key"),
+		}, nil)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{
+		service: certService,
+		reuse:   &inventory.ReusePolicy{MinRemainingDays: 30},
+	})
+
+	buffer := &bytes.Buffer{}
+	logger := audit.NewWriterLogger(buffer)
+
+	// ----
+
+	for i := 0; i < 2; i++ {
+		trail := logger.Start(audit.Entry{Action: "IssueCertificate"})
+		_, err := store.IssueCertificate(audit.NewContext(context.Background(), trail), "issuer",
+			&certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+		assert.NotError(t, err, "issuing certificate failed")
+		trail.Finish("", nil)
+	}
+
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	assert.Equal(t, 0, len(entries[0].Decisions))
+	assert.Equal(t, entries[0].SerialNumber, entries[1].SerialNumber)
+	assert.DeepEqual(t, []audit.Decision{{Policy: audit.POLICY_REUSE, Result: audit.DECISION_REUSED, Detail: entries[0].SerialNumber}},
+		entries[1].Decisions)
+}
+
+func TestIssueCertificateInventoryFailedAudited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issued := &certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(issued, nil)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+	store.inventory = inventory.New(readOnlyStorage{storage.NewMemoryStorage()})
+
+	buffer := &bytes.Buffer{}
+	trail := audit.NewWriterLogger(buffer).Start(audit.Entry{Action: "IssueCertificate"})
+
+	// ----
+
+	_, err := store.IssueCertificate(audit.NewContext(context.Background(), trail), "issuer",
+		&certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+	trail.Finish("", nil)
+
+	serialNumber, err := inventory.SerialNumber(issued.Certificate)
+	assert.NotError(t, err, "reading serial number failed")
+
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	assert.Equal(t, serialNumber, entries[0].SerialNumber)
+}
+
+func TestIssueCertificateReuseDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issued := &certificate_service.NewCertificateResponse{
+		Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90)),
+		PrivateKey:  []byte("test private key"),
+	}
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(issued, nil).
+		Times(2)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	store.IssueCertificate(context.Background(), "issuer", request)
+	store.IssueCertificate(context.Background(), "issuer", request)
+
+	// certificates are kept in inventory without private keys
+	certificates, err := store.inventory.List()
+	assert.NotError(t, err, "listing inventory failed")
+	assert.Equal(t, 1, len(certificates))
+	assert.Equal(t, 0, len(certificates[0].PrivateKey))
+}
+
+func TestIssueCertificateIssuerTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{service: certService, timeout: 10 * time.Millisecond})
+
+	// ----
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestIssueCertificateQueueFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan bool)
+	release := make(chan bool)
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			started <- true
+			<-release
+			return &certificate_service.NewCertificateResponse{}, nil
+		}).
+		Times(2)
+
+	issuerQueue := queue.New("issuer", queue.Config{Concurrency: 1, Length: 1})
+	defer issuerQueue.Stop()
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{service: certService, queue: issuerQueue})
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	var wg sync.WaitGroup
+	issue := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.IssueCertificate(context.Background(), "issuer", request)
+		}()
+	}
+
+	// first request is running, second one is waiting
+	issue()
+	<-started
+	issue()
+	for issuerQueue.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.Equal(t, certificate_service.RateLimitedErrorKind, certificate_service.AsError(err).Kind)
+
+	// ----
+
+	release <- true
+	<-started
+	release <- true
+	wg.Wait()
+}
+
+func TestRegisterIssuerWhileIssuing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{}, nil).
+		AnyTimes()
+	certService.EXPECT().Capabilities().AnyTimes()
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	// ----
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			store.RegisterIssuer(fmt.Sprintf("issuer-%d", i), certService)
+			store.RegisterIssuer("issuer", certService)
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{})
+			assert.NotError(t, err, "issuing certificate failed")
+			store.ListIssuers()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 12, len(store.ListIssuers()))
+}
+
+func TestReload(t *testing.T) {
+	store := createWithYaml(t, `services:
+  - name: unchanged
+    type: CertificateAuthority
+  - name: updated
+    type: CertificateAuthority
+  - name: removed
+    type: CertificateAuthority`)
+	unchanged, _ := store.getIssuer("unchanged")
+
+	// ----
+
+	conf, err := config.ParseYaml(`services:
+  - name: unchanged
+    type: CertificateAuthority
+  - name: updated
+    type: CertificateAuthority
+    timeout: 1m
+  - name: added
+    type: CertificateAuthority`)
+	assert.NotError(t, err, "parsing certstore config failed")
+
+	result, err := store.Reload(conf)
+	assert.NotError(t, err, "reloading certstore failed")
+	assert.DeepEqual(t, &ReloadResult{Added: []string{"added"}, Updated: []string{"updated"}, Removed: []string{"removed"}}, result)
+
+	// ----
+
+	reloaded, _ := store.getIssuer("unchanged")
+	assert.True(t, unchanged == reloaded)
+
+	updated, _ := store.getIssuer("updated")
+	assert.Equal(t, time.Minute, updated.timeout)
+
+	_, err = store.getIssuer("removed")
+	assert.Error(t, err, "removed issuer should not be found")
+}
+
+func TestReloadClosesReplacedServices(t *testing.T) {
+	store := createWithConfig(t)
+	replaced := &closingService{}
+	store.registerIssuer("replaced", &certIssuer{service: replaced})
+	kept := &closingService{}
+	store.registerIssuer("kept", &certIssuer{service: kept})
+
+	conf, err := config.ParseYaml(`services:
+  - name: replaced
+    type: CertificateAuthority`)
+	assert.NotError(t, err, "parsing certstore config failed")
+
+	_, err = store.Reload(conf)
+	assert.NotError(t, err, "reloading certstore failed")
+	assert.True(t, replaced.closed)
+	assert.False(t, kept.closed)
+
+	// ----
+
+	err = store.Stop(context.Background())
+	assert.NotError(t, err, "stopping certstore failed")
+	assert.True(t, kept.closed)
+}
+
+func TestReloadFailsWithNotValidIssuer(t *testing.T) {
+	store := createWithConfig(t)
+
+	conf, err := config.ParseYaml(`services:
+  - name: added
+    type: CertificateAuthority
+  - name: simple
+    type: Simple
+    args:
+      private-key: "simple-private-key-file-path"
+      certificate: "simple-certificate-file-path"`)
+	assert.NotError(t, err, "parsing certstore config failed")
+
+	_, err = store.Reload(conf)
+	assert.ErrorContains(t, err, "creating certificate service of issuer [simple] failed")
+
+	// nothing is changed
+	issuers := store.ListIssuers()
+	assert.Equal(t, 1, len(issuers))
+	assert.Equal(t, "test-cert-service", issuers[0].Name)
+}
+
+func TestReloadFailsWithNotValidConfig(t *testing.T) {
+	store := createWithConfig(t)
+
+	_, err := store.Reload(&config.Config{IssuerConfigs: []config.CertificateServiceConfig{{Name: "", Type: factory.Simple}}})
+	assert.ErrorContains(t, err, "'name' is required")
+	assert.Equal(t, 1, len(store.ListIssuers()))
+}
+
+func TestSubmitCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	release := make(chan bool)
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			<-release
+			return &certificate_service.NewCertificateResponse{Certificate: []byte("test certificate")}, nil
+		})
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	// ----
+
+	submitted, err := store.SubmitCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "submitting certificate failed")
+	assert.Equal(t, operation.Pending, submitted.State)
+
+	updates, stop := store.WatchOperation(submitted.ID)
+	defer stop()
+
+	assert.Equal(t, operation.Validating, (<-updates).State)
+	release <- true
+	assert.Equal(t, operation.Issued, (<-updates).State)
+
+	// ----
+
+	issued, err := store.GetOperation(context.Background(), submitted.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, "test certificate", string(issued.Certificate))
+}
+
+func TestGetOperationOfRequester(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: []byte("test certificate"), PrivateKey: []byte("test private key")}, nil)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	requester := identity.NewContext(context.Background(), identity.Caller{Name: "agent-1"})
+	other := identity.NewContext(context.Background(), identity.Caller{Name: "agent-2"})
+	admin := identity.NewContext(context.Background(), identity.Caller{Name: "admin", Admin: true})
+
+	// ----
+
+	submitted, err := store.SubmitCertificate(requester, "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "submitting certificate failed")
+	store.submitted.Wait()
+
+	_, err = store.GetOperation(other, submitted.ID)
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+
+	issued, err := store.GetOperation(requester, submitted.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, "agent-1", issued.RequestedBy)
+	assert.Equal(t, "test private key", string(issued.PrivateKey))
+
+	// private key is returned only to its first claimer, which can claim it again, e.g. after a restart
+	issued, err = store.GetOperation(admin, submitted.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, "test certificate", string(issued.Certificate))
+	assert.Equal(t, 0, len(issued.PrivateKey))
+
+	resumed, err := store.GetOperation(requester, submitted.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, "test private key", string(resumed.PrivateKey))
+}
+
+func TestSubmitCertificateQueueFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan bool)
+	release := make(chan bool)
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			started <- true
+			<-release
+			return &certificate_service.NewCertificateResponse{}, nil
+		}).
+		Times(2)
+
+	issuerQueue := queue.New("issuer", queue.Config{Concurrency: 1, Length: 1})
+	defer issuerQueue.Stop()
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{service: certService, queue: issuerQueue})
+
+	// ----
+
+	// first request is running, second one is waiting
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := store.SubmitCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "submitting certificate failed")
+	<-started
+	_, err = store.SubmitCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "submitting certificate failed")
+	for issuerQueue.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err = store.SubmitCertificate(context.Background(), "issuer", request)
+	assert.Equal(t, certificate_service.RateLimitedErrorKind, certificate_service.AsError(err).Kind)
+
+	// ----
+
+	release <- true
+	<-started
+	release <- true
+}
+
+func TestSubmitCertificateAudited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}, nil)
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	buffer := &bytes.Buffer{}
+	trail := audit.NewWriterLogger(buffer).Start(audit.Entry{Action: "SubmitCertificateRequest", Identity: "agent"})
+	ctx, cancel := context.WithCancel(audit.NewContext(context.Background(), trail))
+
+	// ----
+
+	submitted, err := store.SubmitCertificate(ctx, "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "submitting certificate failed")
+	cancel()
+	trail.Finish("", nil)
+
+	issued := waitOperation(t, store, submitted.ID)
+	assert.Equal(t, operation.Issued, issued.State)
+	assert.NotError(t, store.Stop(context.Background()), "stopping certstore failed")
+
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, audit.ACTION_ISSUE_SUBMITTED, entries[1].Action)
+	assert.Equal(t, "agent", entries[1].Identity)
+	assert.Equal(t, submitted.ID, entries[1].OperationID)
+
+	certificates, err := store.ListCertificates(context.Background(), "issuer")
+	assert.NotError(t, err, "listing certificates failed")
+	assert.Equal(t, certificates[0].SerialNumber, entries[1].SerialNumber)
+}
+
+func TestStopWaitsSubmittedCertificates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	release := make(chan bool)
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			<-release
+			return &certificate_service.NewCertificateResponse{Certificate: []byte("test certificate")}, nil
+		})
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{
+		service: certService,
+		queue:   queue.New("issuer", queue.Config{Concurrency: 1}),
+	})
+
+	submitted, err := store.SubmitCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "submitting certificate failed")
+
+	// ----
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, store.Stop(ctx))
+
+	go func() {
+		release <- true
+	}()
+	assert.NotError(t, store.Stop(context.Background()), "stopping certstore failed")
+
+	issued, err := store.GetOperation(context.Background(), submitted.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, operation.Issued, issued.State)
+
+	_, err = store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.Equal(t, certificate_service.BackendUnavailableErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestSubmitCertificateFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("test error")).
+		AnyTimes()
+
+	store := createWithConfig(t)
+	store.RegisterIssuer("issuer", certService)
+
+	// ----
+
+	submitted, err := store.SubmitCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "submitting certificate failed")
+
+	failed := waitOperation(t, store, submitted.ID)
+	assert.Equal(t, operation.Failed, failed.State)
+	assert.Equal(t, "test error", failed.Error)
+}
+
+func TestSubmitCertificateUnknownIssuer(t *testing.T) {
+	store := createWithConfig(t)
+
+	_, err := store.SubmitCertificate(context.Background(), "unknown issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.ErrorContains(t, err, "Issuer not found")
+}
+
+func TestSubmitCertificateApprovalRequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: []byte("test certificate")}, nil)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{service: certService, approval: &approval.Policy{Approvers: []string{"admin", "agent"}}})
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	submitted, err := store.SubmitCertificate(identity.NewContext(context.Background(), identity.Caller{Name: "agent"}), "issuer", request)
+	assert.NotError(t, err, "submitting certificate failed")
+	assert.Equal(t, operation.AwaitingApproval, submitted.State)
+
+	// only submitted requests wait for approval
+	_, err = store.IssueCertificate(context.Background(), "issuer", request)
+	assert.Equal(t, certificate_service.PolicyDeniedErrorKind, certificate_service.AsError(err).Kind)
+
+	pending, err := store.ListApprovals("issuer")
+	assert.NotError(t, err, "listing approval requests failed")
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, submitted.ID, pending[0].OperationID)
+	assert.Equal(t, "agent", pending[0].RequestedBy)
+
+	// ----
+
+	// requester can not approve its own request
+	_, err = store.ApproveRequest(identity.NewContext(context.Background(), identity.Caller{Name: "agent"}), submitted.ID)
+	assert.Equal(t, certificate_service.PolicyDeniedErrorKind, certificate_service.AsError(err).Kind)
+
+	approved, err := store.ApproveRequest(identity.NewContext(context.Background(), identity.Caller{Name: "admin"}), submitted.ID)
+	assert.NotError(t, err, "approving request failed")
+	assert.Equal(t, operation.Pending, approved.State)
+
+	issued := waitOperation(t, store, submitted.ID)
+	assert.Equal(t, operation.Issued, issued.State)
+	assert.Equal(t, "test certificate", string(issued.Certificate))
+
+	// decided once
+	_, err = store.DenyRequest(identity.NewContext(context.Background(), identity.Caller{Name: "admin"}), submitted.ID, "too late")
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+
+	pending, err = store.ListApprovals("")
+	assert.NotError(t, err, "listing approval requests failed")
+	assert.Equal(t, 0, len(pending))
+}
+
+func TestDenyRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{service: certService, approval: &approval.Policy{Approvers: []string{"admin"}}})
+
+	submitted, err := store.SubmitCertificate(identity.NewContext(context.Background(), identity.Caller{Name: "agent"}), "issuer",
+		&certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "submitting certificate failed")
+
+	// ----
+
+	_, err = store.DenyRequest(identity.NewContext(context.Background(), identity.Caller{Name: "other-admin"}), submitted.ID, "not expected")
+	assert.Equal(t, certificate_service.PolicyDeniedErrorKind, certificate_service.AsError(err).Kind)
+
+	denied, err := store.DenyRequest(identity.NewContext(context.Background(), identity.Caller{Name: "admin"}), submitted.ID, "not expected")
+	assert.NotError(t, err, "denying request failed")
+	assert.Equal(t, operation.Failed, denied.State)
+	assert.Equal(t, certificate_service.PolicyDeniedErrorKind, denied.ErrorKind)
+	assert.Equal(t, "Request is denied by [admin], reason: [not expected]", denied.Error)
+
+	op, err := store.GetOperation(context.Background(), submitted.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, operation.Failed, op.State)
+
+	_, err = store.ApproveRequest(identity.NewContext(context.Background(), identity.Caller{Name: "admin"}), "missing")
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestDenyRequestOfRemovedIssuer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	store := createWithConfig(t)
+	store.registerIssuer("issuer", &certIssuer{service: certService, approval: &approval.Policy{Approvers: []string{"admin"}}})
+
+	submitted, err := store.SubmitCertificate(identity.NewContext(context.Background(), identity.Caller{Name: "agent"}), "issuer",
+		&certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "submitting certificate failed")
+
+	store.mutex.Lock()
+	delete(store.certIssuers, "issuer")
+	store.mutex.Unlock()
+
+	// ----
+
+	// approving needs the issuer, but the request can still be denied and its operation failed
+	admin := identity.NewContext(context.Background(), identity.Caller{Name: "admin", Admin: true})
+	_, err = store.ApproveRequest(admin, submitted.ID)
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+
+	_, err = store.DenyRequest(identity.NewContext(context.Background(), identity.Caller{Name: "agent"}), submitted.ID, "issuer is removed")
+	assert.Equal(t, certificate_service.PolicyDeniedErrorKind, certificate_service.AsError(err).Kind)
+
+	denied, err := store.DenyRequest(admin, submitted.ID, "issuer is removed")
+	assert.NotError(t, err, "denying request failed")
+	assert.Equal(t, operation.Failed, denied.State)
+
+	pending, err := store.ListApprovals("")
+	assert.NotError(t, err, "listing approval requests failed")
+	assert.Equal(t, 0, len(pending))
+}
+
+func TestGetOperationNotFound(t *testing.T) {
+	store := createWithConfig(t)
+
+	_, err := store.GetOperation(context.Background(), "missing")
+	assert.True(t, operation.IsNotFound(err))
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
 }
 
 // -----
 
+func TestIssueCertificateTransparencyLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	internalCertificate := createTestCertificate(t, time.Now().AddDate(0, 0, 90))
+	internalService := certificate_service.NewMockCertificateService(ctrl)
+	internalService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: internalCertificate}, nil)
+
+	externalCertificate := createTestCertificate(t, time.Now().AddDate(0, 0, 90))
+	externalService := certificate_service.NewMockCertificateService(ctrl)
+	externalService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: externalCertificate}, nil)
+
+	store, signer := createWithTransparencyLog(t, storage.NewMemoryStorage())
+	store.registerIssuer("internal", &certIssuer{service: internalService, serviceType: factory.Simple})
+	store.registerIssuer("external", &certIssuer{service: externalService, serviceType: factory.LetsEncrypt})
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := store.IssueCertificate(context.Background(), "internal", request)
+	assert.NotError(t, err, "issuing certificate failed")
+	_, err = store.IssueCertificate(context.Background(), "external", request)
+	assert.NotError(t, err, "issuing certificate failed")
+
+	head, err := store.SignedTreeHead()
+	assert.NotError(t, err, "getting signed tree head failed")
+	assert.Equal(t, uint64(1), head.TreeSize)
+	assert.NotError(t, head.Verify(signer.Public()), "verifying tree head failed")
+
+	// ----
+
+	leaf, _ := transparency.CertificateLeaf(internalCertificate)
+	proof, err := store.InclusionProof(transparency.LeafHash(leaf), head.TreeSize)
+	assert.NotError(t, err, "proving inclusion failed")
+
+	err = transparency.VerifyInclusion(transparency.LeafHash(leaf), proof.LeafIndex, proof.TreeSize, proof.AuditPath, head.RootHash)
+	assert.NotError(t, err, "verifying inclusion failed")
+
+	// certificates of external issuers are not logged
+	leaf, _ = transparency.CertificateLeaf(externalCertificate)
+	_, err = store.InclusionProof(transparency.LeafHash(leaf), 0)
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+
+	_, err = store.ConsistencyProof(1, 2)
+	assert.Equal(t, certificate_service.ValidationErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestIssueCertificateTransparencyLogFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}, nil).
+		Times(2)
+
+	store, _ := createWithTransparencyLog(t, readOnlyStorage{storage.NewMemoryStorage()})
+	store.registerIssuer("internal", &certIssuer{
+		service:     certService,
+		serviceType: factory.Simple,
+		rateLimiter: ratelimit.NewTracker("internal", ratelimit.Config{DuplicateCertificates: 1}, storage.NewMemoryStorage()),
+	})
+
+	// ----
+
+	// certificate is not handed out if it is not logged
+	_, err := store.IssueCertificate(context.Background(), "internal", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.Equal(t, certificate_service.BackendUnavailableErrorKind, certificate_service.AsError(err).Kind)
+
+	// and it does not count against rate limits
+	_, err = store.IssueCertificate(context.Background(), "internal", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.Equal(t, certificate_service.BackendUnavailableErrorKind, certificate_service.AsError(err).Kind)
+
+	budget, err := store.GetRateLimitBudget("internal", []string{"certstore.com"})
+	assert.NotError(t, err, "getting rate limit budget failed")
+	assert.Equal(t, 0, budget.Duplicate.Used)
+}
+
+func TestIssueSSHCertificateTransparencyLog(t *testing.T) {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NotError(t, err, "generating ca key failed")
+	caKeyBytes, err := x509.MarshalPKCS8PrivateKey(caKey)
+	assert.NotError(t, err, "marshalling ca key failed")
+	sshCA, err := sshca.New(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: caKeyBytes}), time.Hour, 0, []string{"alice"})
+	assert.NotError(t, err, "creating ssh ca failed")
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NotError(t, err, "generating key failed")
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	assert.NotError(t, err, "converting public key failed")
+
+	store, _ := createWithTransparencyLog(t, storage.NewMemoryStorage())
+	store.registerIssuer("ssh", &certIssuer{service: sshCA, serviceType: factory.SSHCA})
+
+	// ----
+
+	response, err := store.IssueSSHCertificate(context.Background(), "ssh", &certificate_service.NewSSHCertificateRequest{
+		PublicKey:  ssh.MarshalAuthorizedKey(sshPublicKey),
+		Type:       certificate_service.SSHUserCertificate,
+		Principals: []string{"alice"},
+		Validity:   time.Hour,
+	})
+	assert.NotError(t, err, "issuing ssh certificate failed")
+
+	leaf, err := transparency.SSHCertificateLeaf(response.Certificate)
+	assert.NotError(t, err, "getting ssh certificate leaf failed")
+
+	proof, err := store.InclusionProof(transparency.LeafHash(leaf), 0)
+	assert.NotError(t, err, "proving inclusion failed")
+	assert.Equal(t, uint64(1), proof.TreeSize)
+}
+
+// certificates issued before the log is enabled are logged once they are reused
+func TestIssueCertificateReuseTransparencyLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issued := createTestCertificate(t, time.Now().AddDate(0, 0, 90))
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: issued, PrivateKey: []byte("test private key")}, nil)
+
+	store, _ := createWithTransparencyLog(t, storage.NewMemoryStorage())
+	transparencyLog := store.transparencyLog
+	store.transparencyLog = nil
+	store.registerIssuer("internal", &certIssuer{
+		service:     certService,
+		serviceType: factory.Simple,
+		reuse:       &inventory.ReusePolicy{MinRemainingDays: 30},
+	})
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com"}
+	_, err := store.IssueCertificate(context.Background(), "internal", request)
+	assert.NotError(t, err, "issuing certificate failed")
+
+	// ----
+
+	store.transparencyLog = transparencyLog
+	for i := 0; i < 2; i++ {
+		reused, err := store.IssueCertificate(context.Background(), "internal", request)
+		assert.NotError(t, err, "reusing certificate failed")
+		assert.Equal(t, string(issued), string(reused.Certificate))
+	}
+
+	head, err := store.SignedTreeHead()
+	assert.NotError(t, err, "getting signed tree head failed")
+	assert.Equal(t, uint64(1), head.TreeSize)
+
+	leaf, _ := transparency.CertificateLeaf(issued)
+	_, err = store.InclusionProof(transparency.LeafHash(leaf), 0)
+	assert.NotError(t, err, "reused certificate is not logged")
+}
+
+func TestTransparencyLogNotEnabled(t *testing.T) {
+	store := createWithConfig(t)
+
+	_, err := store.SignedTreeHead()
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+
+	_, err = store.InclusionProof([]byte("leaf hash"), 0)
+	assert.Equal(t, certificate_service.NotFoundErrorKind, certificate_service.AsError(err).Kind)
+}
+
+func TestIssueCertificateEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	gomock.InOrder(
+		certService.
+			EXPECT().
+			CreateCertificate(gomock.Any(), gomock.Any()).
+			Return(&certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}, nil),
+		certService.
+			EXPECT().
+			CreateCertificate(gomock.Any(), gomock.Any()).
+			Return(&certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 90))}, nil),
+		certService.
+			EXPECT().
+			CreateCertificate(gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("issuer is down")),
+	)
+
+	revoker := certificate_service.NewMockRevoker(ctrl)
+	revoker.
+		EXPECT().
+		RevokeCertificate(gomock.Any(), gomock.Any(), gomock.Eq(certificate_service.RevocationSuperseded)).
+		Return(nil)
+
+	events := make(chan event.Event, 10)
+	store := createWithEvents(t, events)
+	store.RegisterIssuer("issuer", &revokingService{certService, revoker})
+
+	// ----
+
+	request := &certificate_service.NewCertificateRequest{CommonName: "certstore.com", SubjectAlternativeNames: []string{"www.certstore.com"}}
+	_, err := store.IssueCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "issuing certificate failed")
+	_, err = store.IssueCertificate(context.Background(), "issuer", request)
+	assert.NotError(t, err, "renewing certificate failed")
+	_, err = store.IssueCertificate(context.Background(), "issuer", request)
+	assert.Error(t, err, "issuing certificate should have failed")
+
+	issued := <-events
+	assert.Equal(t, event.Issued, issued.Type)
+	assert.Equal(t, "issuer", issued.Issuer)
+	assert.DeepEqual(t, []string{"certstore.com", "www.certstore.com"}, issued.Names)
+	assert.NotNil(t, issued.NotAfter)
+
+	renewed := <-events
+	assert.Equal(t, event.Renewed, renewed.Type)
+	assert.Equal(t, issued.SerialNumber, renewed.Replaces)
+
+	failed := <-events
+	assert.Equal(t, event.Failed, failed.Type)
+	assert.Equal(t, "issuer is down", failed.Error)
+
+	// ----
+
+	_, err = store.RevokeCertificate(context.Background(), "issuer", renewed.SerialNumber, certificate_service.RevocationSuperseded)
+	assert.NotError(t, err, "revoking certificate failed")
+
+	revoked := <-events
+	assert.Equal(t, event.Revoked, revoked.Type)
+	assert.Equal(t, renewed.SerialNumber, revoked.SerialNumber)
+	assert.Equal(t, int(certificate_service.RevocationSuperseded), revoked.RevocationReason)
+
+	assert.NotError(t, store.Stop(context.Background()), "stopping certstore failed")
+}
+
+func TestNotifyExpiring(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 10))}, nil)
+
+	events := make(chan event.Event, 10)
+	store := createWithEvents(t, events)
+	store.RegisterIssuer("issuer", certService)
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+	issued := <-events
+
+	// ----
+
+	// notified once, also after a restart as it is kept in storage
+	store.notifyExpiring(30 * 24 * time.Hour)
+	store.notifyExpiring(30 * 24 * time.Hour)
+	assert.NotError(t, store.Stop(context.Background()), "stopping certstore failed")
+
+	expiring := <-events
+	assert.Equal(t, event.ExpiringSoon, expiring.Type)
+	assert.Equal(t, issued.SerialNumber, expiring.SerialNumber)
+	assert.Equal(t, 0, len(events))
+}
+
+func TestNotifyExpiringRemovesRevoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certService := certificate_service.NewMockCertificateService(ctrl)
+	certService.
+		EXPECT().
+		CreateCertificate(gomock.Any(), gomock.Any()).
+		Return(&certificate_service.NewCertificateResponse{Certificate: createTestCertificate(t, time.Now().AddDate(0, 0, 10))}, nil)
+
+	revoker := certificate_service.NewMockRevoker(ctrl)
+	revoker.
+		EXPECT().
+		RevokeCertificate(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	events := make(chan event.Event, 10)
+	store := createWithEvents(t, events)
+	store.RegisterIssuer("issuer", &revokingService{certService, revoker})
+
+	_, err := store.IssueCertificate(context.Background(), "issuer", &certificate_service.NewCertificateRequest{CommonName: "certstore.com"})
+	assert.NotError(t, err, "issuing certificate failed")
+	issued := <-events
+
+	store.notifyExpiring(30 * 24 * time.Hour)
+	notified, err := store.storage.List(EXPIRING_SOON_STORAGE_BUCKET)
+	assert.NotError(t, err, "listing notified certificates failed")
+	assert.Equal(t, 1, len(notified))
+
+	// ----
+
+	_, err = store.RevokeCertificate(context.Background(), "issuer", issued.SerialNumber, certificate_service.RevocationUnspecified)
+	assert.NotError(t, err, "revoking certificate failed")
+
+	store.notifyExpiring(30 * 24 * time.Hour)
+	notified, err = store.storage.List(EXPIRING_SOON_STORAGE_BUCKET)
+	assert.NotError(t, err, "listing notified certificates failed")
+	assert.Equal(t, 0, len(notified))
+
+	assert.NotError(t, store.Stop(context.Background()), "stopping certstore failed")
+}
+
+func waitOperation(t *testing.T, store *certStoreImpl, id string) *operation.Operation {
+	for i := 0; i < 100; i++ {
+		op, err := store.GetOperation(context.Background(), id)
+		assert.NotError(t, err, "getting operation failed")
+		if op.Done() {
+			return op
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("operation [%s] is not done", id)
+	return nil
+}
+
+func createTestCertificate(t *testing.T, notAfter time.Time) []byte {
+	serialNumber, err := x509utils.GetRandomCertificateSerialNumber()
+	assert.NotError(t, err, "generating serial number failed")
+
+	certTemplate := &x509.Certificate{
+		SerialNumber: serialNumber,
+		NotBefore:    time.Now(),
+		NotAfter:     notAfter,
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NotError(t, err, "generating private key failed")
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, &privateKey.PublicKey, privateKey)
+	assert.NotError(t, err, "creating certificate failed")
+
+	return x509utils.EncodePEMCert(certBytes).Bytes()
+}
+
+func createWithCAARecords(t *testing.T, domain string, issuer string) *certStoreImpl {
+	dnsServer := caa.NewTestDNSServer(t, map[string][]*dns.CAA{
+		domain: {caa.NewTestCAARecord(domain, 0, caa.TAG_ISSUE, issuer)},
+	})
+
+	store := createWithConfig(t)
+	store.caaChecker = caa.NewChecker(dnsServer.Address)
+	return store
+}
+
 func createWithConfig(t *testing.T) *certStoreImpl {
-	configYaml := `services:
+	return createWithYaml(t, `services:
   - name: test-cert-service
-    type: Simple
-    args:
-      private-key: simple-private-key-file-path
-      certificate: simple-certificate-file-path`
+    type: CertificateAuthority`)
+}
+
+func createWithYaml(t *testing.T, configYaml string) *certStoreImpl {
 	conf, err := config.ParseYaml(configYaml)
 	assert.NotError(t, err, "parsing certstore config failed")
 
@@ -63,3 +1798,86 @@ func createWithConfig(t *testing.T) *certStoreImpl {
 
 	return store
 }
+
+func createWithTransparencyLog(t *testing.T, logStorage storage.Storage) (*certStoreImpl, crypto.Signer) {
+	signer := createTestSigner(t)
+	transparencyLog, err := transparency.NewWithSigner(signer, logStorage, time.Now)
+	assert.NotError(t, err, "creating transparency log failed")
+
+	store := createWithConfig(t)
+	store.transparencyLog = transparencyLog
+	return store, signer
+}
+
+// createWithEvents posts events to a webhook sending them to the channel, expiry check notifies certificates expiring
+// in a day only so that it does not race with tests notifying explicitly
+func createWithEvents(t *testing.T, events chan<- event.Event) *certStoreImpl {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received := event.Event{}
+		err := json.NewDecoder(r.Body).Decode(&received)
+		assert.NotError(t, err, "decoding event failed")
+		events <- received
+	}))
+	t.Cleanup(receiver.Close)
+
+	return createWithYaml(t, fmt.Sprintf(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+events:
+  expiring-soon-days: 1
+  webhooks:
+    - name: receiver
+      url: %s`, receiver.URL))
+}
+
+func createTestSigner(t *testing.T) crypto.Signer {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NotError(t, err, "generating signing key failed")
+
+	return signer
+}
+
+// failingStorage fails every operation, e.g. a full disk
+type failingStorage struct{}
+
+func (failingStorage) Get(bucket string, key string) ([]byte, error) {
+	return nil, errors.New("disk failed")
+}
+
+func (failingStorage) Put(bucket string, key string, value []byte) error {
+	return errors.New("disk failed")
+}
+
+func (failingStorage) Delete(bucket string, key string) error {
+	return errors.New("disk failed")
+}
+
+func (failingStorage) List(bucket string) (map[string][]byte, error) {
+	return nil, errors.New("disk failed")
+}
+
+// readOnlyStorage fails writes only, e.g. a full disk with state written before
+type readOnlyStorage struct {
+	storage.Storage
+}
+
+func (readOnlyStorage) Put(bucket string, key string, value []byte) error {
+	return errors.New("disk is full")
+}
+
+// closingService is a certificate service holding a connection, e.g. relay
+type closingService struct {
+	certificate_service.CertificateService
+	closed bool
+}
+
+func (s *closingService) Close() error {
+	s.closed = true
+	return nil
+}
+
+// revokingService is a certificate service able to revoke certificates, e.g. lets encrypt
+type revokingService struct {
+	*certificate_service.MockCertificateService
+	*certificate_service.MockRevoker
+}
@@ -4,21 +4,65 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"bilalekrem.com/certstore/internal/certificate/caa"
+	"bilalekrem.com/certstore/internal/certificate/keypool"
 	service_factory "bilalekrem.com/certstore/internal/certificate/service/factory"
+	"bilalekrem.com/certstore/internal/certstore/event"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/certstore/queue"
+	"bilalekrem.com/certstore/internal/certstore/ratelimit"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
 	"bilalekrem.com/certstore/internal/logging"
 )
 
 type Config struct {
 	IssuerConfigs []CertificateServiceConfig `yaml:"services"`
+
+	// dns server to query CAA records, system resolver is used if empty
+	CAAResolver string `yaml:"caa-resolver"`
+
+	// directory to keep server state, state is kept in memory if empty
+	StoragePath string `yaml:"storage-path"`
+
+	// pre-generated keys per key type, keys are generated when they are requested if there is no pool
+	KeyPools []keypool.Config `yaml:"key-pools"`
+
+	// certificates of internal issuers are appended to the log, disabled if signing key is not provided. Requires
+	// storage path
+	TransparencyLog transparency.Config `yaml:"transparency-log"`
+
+	// webhooks notified of issued, revoked and expiring certificates, disabled if there is no webhook
+	Events event.Config `yaml:"events"`
 }
 
 type CertificateServiceConfig struct {
 	Name string                      `yaml:"name"`
 	Type service_factory.ServiceType `yaml:"type"`
 	Args map[string]string           `yaml:"args"`
+
+	// issuer domain name to look for in CAA records, CAA check is skipped if empty
+	CAAIdentity string   `yaml:"caa-identity"`
+	CAAMode     caa.Mode `yaml:"caa-mode"`
+
+	RateLimit *ratelimit.Config `yaml:"rate-limit"`
+
+	// opt-in, existing valid certificates are returned for identical requests if provided
+	Reuse *inventory.ReusePolicy `yaml:"reuse"`
+
+	// deadline of a single issuance, no deadline other than the caller's if zero
+	Timeout time.Duration `yaml:"timeout"`
+
+	// bounds concurrent requests to the issuer's backend, requests are not queued if absent
+	Queue *queue.Config `yaml:"queue"`
+
+	// opt-in, submitted requests wait for one of approvers, other than the requester, to approve them. Approvers
+	// are required if approval is required
+	ApprovalRequired bool     `yaml:"approval-required"`
+	Approvers        []string `yaml:"approvers"`
 }
 
 // ------
@@ -47,7 +91,7 @@ func parse(yamlBytes []byte) (*Config, error) {
 
 	// ----
 
-	err = validate(config)
+	err = Validate(config)
 	if err != nil {
 		logging.GetLogger().Errorf("Validating config failed %v\n", err)
 		return nil, err
@@ -56,7 +100,31 @@ func parse(yamlBytes []byte) (*Config, error) {
 	return config, nil
 }
 
-func validate(config *Config) error {
+// Validate is called while parsing, configs not parsed from yaml, e.g. embedded in server config, should be
+// validated before they are used
+func Validate(config *Config) error {
+	err := config.Events.Validate()
+	if err != nil {
+		return err
+	}
+
+	if config.TransparencyLog.Enabled() && config.StoragePath == "" {
+		return errors.New("transparency-log requires storage-path, a log kept in memory is lost on restart")
+	}
+
+	keyTypes := make(map[string]bool)
+	for _, keyPoolConfig := range config.KeyPools {
+		err := keyPoolConfig.Validate()
+		if err != nil {
+			return err
+		}
+
+		if keyTypes[keyPoolConfig.KeyType] {
+			return errors.New(fmt.Sprintf("key pool is configured more than once, %s", keyPoolConfig.KeyType))
+		}
+		keyTypes[keyPoolConfig.KeyType] = true
+	}
+
 	for _, issuerConfig := range config.IssuerConfigs {
 		if issuerConfig.Name == "" {
 			return errors.New("issuer config name is empty, 'name' is required")
@@ -64,10 +132,50 @@ func validate(config *Config) error {
 
 		if issuerConfig.Type != service_factory.Simple &&
 			issuerConfig.Type != service_factory.CertificateAuthority &&
-			issuerConfig.Type != service_factory.LetsEncrypt {
+			issuerConfig.Type != service_factory.LetsEncrypt &&
+			issuerConfig.Type != service_factory.Plugin &&
+			issuerConfig.Type != service_factory.Certstore &&
+			issuerConfig.Type != service_factory.SSHCA {
 			return errors.New(fmt.Sprintf("issuer config service type is unknown, 'ServiceType' is required, %s",
 				string(issuerConfig.Type)))
 		}
+
+		if issuerConfig.CAAMode != "" && issuerConfig.CAAMode != caa.Enforce && issuerConfig.CAAMode != caa.Warn {
+			return errors.New(fmt.Sprintf("issuer config caa mode is unknown, possible values: [%s, %s], %s",
+				caa.Enforce, caa.Warn, string(issuerConfig.CAAMode)))
+		}
+
+		rateLimit := issuerConfig.RateLimit
+		if rateLimit != nil && (rateLimit.CertificatesPerDomain < 0 || rateLimit.DuplicateCertificates < 0 || rateLimit.Window < 0) {
+			return errors.New(fmt.Sprintf("issuer config rate limits can not be negative, %s", issuerConfig.Name))
+		} else if rateLimit != nil && rateLimit.Enabled() && config.StoragePath == "" {
+			return errors.New(fmt.Sprintf("issuer config rate limits require storage-path, budgets kept in memory are reset on restart, %s",
+				issuerConfig.Name))
+		}
+
+		if issuerConfig.Reuse != nil && issuerConfig.Reuse.MinRemainingDays < 0 {
+			return errors.New(fmt.Sprintf("issuer config reuse min remaining days can not be negative, %s", issuerConfig.Name))
+		}
+
+		if issuerConfig.Timeout < 0 {
+			return errors.New(fmt.Sprintf("issuer config timeout can not be negative, %s", issuerConfig.Name))
+		}
+
+		if issuerConfig.Queue != nil {
+			err := issuerConfig.Queue.Validate()
+			if err != nil {
+				return errors.New(fmt.Sprintf("issuer config queue is not valid, %s, %v", issuerConfig.Name, err))
+			}
+		}
+
+		if issuerConfig.ApprovalRequired && issuerConfig.Type == service_factory.SSHCA {
+			return errors.New(fmt.Sprintf("issuer config approval is not supported for ssh certificates, they are not submitted, %s",
+				issuerConfig.Name))
+		} else if issuerConfig.ApprovalRequired && len(issuerConfig.Approvers) == 0 {
+			return errors.New(fmt.Sprintf("issuer config approval is required but approvers are empty, %s", issuerConfig.Name))
+		} else if len(issuerConfig.Approvers) > 0 && !issuerConfig.ApprovalRequired {
+			return errors.New(fmt.Sprintf("issuer config approvers are set but approval is not required, %s", issuerConfig.Name))
+		}
 	}
 	return nil
 }
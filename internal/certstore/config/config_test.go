@@ -2,9 +2,12 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/caa"
 	service_factory "bilalekrem.com/certstore/internal/certificate/service/factory"
+	"bilalekrem.com/certstore/internal/certstore/event"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -67,3 +70,264 @@ func TestIssuerServiceTypeCertificateAuthority(t *testing.T) {
 	assert.NotError(t, err, "parsing yaml failed")
 	assert.DeepEqual(t, service_factory.CertificateAuthority, string(config.IssuerConfigs[0].Type))
 }
+
+func TestIssuerCAAConfig(t *testing.T) {
+	config, err := ParseYaml(`caa-resolver: "127.0.0.1:53"
+services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    caa-identity: ca.certstore.com
+    caa-mode: warn`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.Equal(t, "127.0.0.1:53", config.CAAResolver)
+	assert.Equal(t, "ca.certstore.com", config.IssuerConfigs[0].CAAIdentity)
+	assert.Equal(t, caa.Warn, config.IssuerConfigs[0].CAAMode)
+}
+
+func TestIssuerCAAModeUnknown(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    caa-identity: ca.certstore.com
+    caa-mode: ignore`)
+
+	assert.ErrorContains(t, err, "caa mode is unknown")
+}
+
+func TestIssuerRateLimitConfig(t *testing.T) {
+	config, err := ParseYaml(`storage-path: /var/lib/certstore
+services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    rate-limit:
+      certificates-per-domain: 20
+      duplicate-certificates: 2
+      window: 24h`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.Equal(t, "/var/lib/certstore", config.StoragePath)
+
+	rateLimit := config.IssuerConfigs[0].RateLimit
+	assert.Equal(t, 20, rateLimit.CertificatesPerDomain)
+	assert.Equal(t, 2, rateLimit.DuplicateCertificates)
+	assert.Equal(t, 24*time.Hour, rateLimit.Window)
+}
+
+func TestIssuerRateLimitNegative(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    rate-limit:
+      certificates-per-domain: -1`)
+
+	assert.ErrorContains(t, err, "can not be negative")
+}
+
+func TestIssuerRateLimitWithoutStoragePath(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    rate-limit:
+      certificates-per-domain: 20`)
+
+	assert.ErrorContains(t, err, "rate limits require storage-path")
+}
+
+func TestIssuerReuseConfig(t *testing.T) {
+	config, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    reuse:
+      min-remaining-days: 30
+  - name: test-cert-service-without-reuse
+    type: CertificateAuthority`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.Equal(t, 30, config.IssuerConfigs[0].Reuse.MinRemainingDays)
+	assert.Nil(t, config.IssuerConfigs[1].Reuse)
+}
+
+func TestIssuerReuseNegative(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    reuse:
+      min-remaining-days: -1`)
+
+	assert.ErrorContains(t, err, "reuse min remaining days can not be negative")
+}
+
+func TestIssuerTimeoutConfig(t *testing.T) {
+	config, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    timeout: 2m`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.Equal(t, 2*time.Minute, config.IssuerConfigs[0].Timeout)
+}
+
+func TestIssuerTimeoutNegative(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    timeout: -1s`)
+
+	assert.ErrorContains(t, err, "timeout can not be negative")
+}
+
+func TestIssuerQueueConfig(t *testing.T) {
+	config, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    queue:
+      concurrency: 2
+      length: 10`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.Equal(t, 2, config.IssuerConfigs[0].Queue.Concurrency)
+	assert.Equal(t, 10, config.IssuerConfigs[0].Queue.Length)
+}
+
+func TestIssuerQueueNegative(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    queue:
+      concurrency: -1`)
+
+	assert.ErrorContains(t, err, "queue is not valid")
+}
+
+func TestIssuerApprovalConfig(t *testing.T) {
+	config, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    approval-required: true
+    approvers: [admin-1, admin-2]`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.True(t, config.IssuerConfigs[0].ApprovalRequired)
+	assert.DeepEqual(t, []string{"admin-1", "admin-2"}, config.IssuerConfigs[0].Approvers)
+}
+
+func TestIssuerApproversWithoutApproval(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    approvers: [admin-1]`)
+
+	assert.ErrorContains(t, err, "approval is not required")
+}
+
+func TestIssuerApprovalWithoutApprovers(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: CertificateAuthority
+    approval-required: true`)
+
+	assert.ErrorContains(t, err, "approval is required but approvers are empty")
+}
+
+func TestIssuerApprovalSSH(t *testing.T) {
+	_, err := ParseYaml(`services:
+  - name: test-cert-service
+    type: SSHCA
+    approval-required: true
+    approvers: [admin-1]`)
+
+	assert.ErrorContains(t, err, "approval is not supported for ssh certificates")
+}
+
+func TestKeyPoolConfig(t *testing.T) {
+	config, err := ParseYaml(`key-pools:
+  - key-type: RSA-4096
+    size: 20
+    concurrency: 2
+services:
+  - name: test-cert-service
+    type: CertificateAuthority`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.Equal(t, 1, len(config.KeyPools))
+	assert.Equal(t, "RSA-4096", config.KeyPools[0].KeyType)
+	assert.Equal(t, 20, config.KeyPools[0].Size)
+	assert.Equal(t, 2, config.KeyPools[0].Concurrency)
+}
+
+func TestKeyPoolConfigDuplicate(t *testing.T) {
+	_, err := ParseYaml(`key-pools:
+  - key-type: RSA-4096
+    size: 20
+  - key-type: RSA-4096
+    size: 10`)
+
+	assert.ErrorContains(t, err, "key pool is configured more than once")
+}
+
+func TestKeyPoolConfigNotValid(t *testing.T) {
+	_, err := ParseYaml(`key-pools:
+  - key-type: RSA-4096`)
+
+	assert.ErrorContains(t, err, "key pool size must be positive")
+}
+
+func TestTransparencyLogConfig(t *testing.T) {
+	config, err := ParseYaml(`storage-path: /var/lib/certstore
+transparency-log:
+  signing-key: /etc/certstore/log.key
+services:
+  - name: test-cert-service
+    type: CertificateAuthority`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.True(t, config.TransparencyLog.Enabled())
+	assert.Equal(t, "/etc/certstore/log.key", config.TransparencyLog.SigningKey)
+}
+
+func TestTransparencyLogWithoutStoragePath(t *testing.T) {
+	_, err := ParseYaml(`transparency-log:
+  signing-key: /etc/certstore/log.key
+services:
+  - name: test-cert-service
+    type: CertificateAuthority`)
+
+	assert.ErrorContains(t, err, "transparency-log requires storage-path")
+}
+
+func TestEventsConfig(t *testing.T) {
+	config, err := ParseYaml(`events:
+  dead-letter-path: /var/lib/certstore/dead-letter.jsonl
+  expiring-soon-days: 14
+  expiry-check-interval: 30m
+  webhooks:
+    - name: alerts
+      url: https://alerts.certstore.com/hooks
+      secret-file: /etc/certstore/webhook.secret
+      events: [failed, expiring-soon]
+      max-retries: 3
+services:
+  - name: test-cert-service
+    type: CertificateAuthority`)
+
+	assert.NotError(t, err, "parsing yaml failed")
+	assert.True(t, config.Events.Enabled())
+	assert.Equal(t, 14*24*time.Hour, config.Events.ExpiringSoonWindow())
+	assert.Equal(t, 30*time.Minute, config.Events.CheckInterval())
+	assert.DeepEqual(t, []event.Type{event.Failed, event.ExpiringSoon}, config.Events.Webhooks[0].Events)
+	assert.Equal(t, 3, *config.Events.Webhooks[0].MaxRetries)
+}
+
+func TestEventsConfigNotValid(t *testing.T) {
+	_, err := ParseYaml(`events:
+  webhooks:
+    - name: alerts
+      url: https://alerts.certstore.com/hooks
+      events: [expired]
+services:
+  - name: test-cert-service
+    type: CertificateAuthority`)
+
+	assert.ErrorContains(t, err, "webhook event type is unknown")
+}
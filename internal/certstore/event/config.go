@@ -0,0 +1,110 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	DEFAULT_EXPIRING_SOON_DAYS    = 30
+	DEFAULT_EXPIRY_CHECK_INTERVAL = time.Hour
+
+	DEFAULT_MAX_RETRIES     = 5
+	DEFAULT_TIMEOUT         = 10 * time.Second
+	DEFAULT_INITIAL_BACKOFF = time.Second
+	DEFAULT_MAX_BACKOFF     = 5 * time.Minute
+	DEFAULT_QUEUE_SIZE      = 1000
+)
+
+// events are not emitted if there is no webhook
+type Config struct {
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+
+	// events not delivered after all retries are appended to it as json lines, only logged if empty
+	DeadLetterPath string `yaml:"dead-letter-path"`
+
+	// certificates expiring in this many days are notified once, unless they are renewed already
+	ExpiringSoonDays    int           `yaml:"expiring-soon-days"`
+	ExpiryCheckInterval time.Duration `yaml:"expiry-check-interval"`
+}
+
+type WebhookConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+
+	// file keeping the HMAC key signing requests, requests are not signed if empty
+	SecretFile string `yaml:"secret-file"`
+
+	// every event type is posted if empty
+	Events []Type `yaml:"events"`
+
+	// default is used if absent, zero delivers events once without retries
+	MaxRetries *int `yaml:"max-retries"`
+
+	// defaults are used for zero values
+	Timeout        time.Duration `yaml:"timeout"`
+	InitialBackoff time.Duration `yaml:"initial-backoff"`
+	MaxBackoff     time.Duration `yaml:"max-backoff"`
+
+	// events waiting for delivery, events are dead lettered if the queue is full
+	QueueSize int `yaml:"queue-size"`
+}
+
+func (c *Config) Enabled() bool {
+	return len(c.Webhooks) > 0
+}
+
+func (c *Config) Validate() error {
+	if c.ExpiringSoonDays < 0 || c.ExpiryCheckInterval < 0 {
+		return errors.New(fmt.Sprintf("events expiring soon days and expiry check interval can not be negative, %d, %v",
+			c.ExpiringSoonDays, c.ExpiryCheckInterval))
+	}
+
+	names := make(map[string]bool)
+	for _, webhook := range c.Webhooks {
+		if webhook.Name == "" {
+			return errors.New("webhook name is empty, 'name' is required")
+		} else if names[webhook.Name] {
+			return errors.New(fmt.Sprintf("webhook is configured more than once, %s", webhook.Name))
+		}
+		names[webhook.Name] = true
+
+		parsed, err := url.Parse(webhook.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New(fmt.Sprintf("webhook url is not a valid http url, %s: [%s]", webhook.Name, webhook.URL))
+		}
+
+		for _, eventType := range webhook.Events {
+			if !types[eventType] {
+				return errors.New(fmt.Sprintf("webhook event type is unknown, %s: [%s], possible values: [%s, %s, %s, %s, %s]",
+					webhook.Name, eventType, Issued, Renewed, Revoked, Failed, ExpiringSoon))
+			}
+		}
+
+		if (webhook.MaxRetries != nil && *webhook.MaxRetries < 0) || webhook.Timeout < 0 || webhook.InitialBackoff < 0 || webhook.MaxBackoff < 0 ||
+			webhook.QueueSize < 0 {
+			return errors.New(fmt.Sprintf("webhook retries, timeout, backoff and queue size can not be negative, %s", webhook.Name))
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) ExpiringSoonWindow() time.Duration {
+	days := c.ExpiringSoonDays
+	if days == 0 {
+		days = DEFAULT_EXPIRING_SOON_DAYS
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func (c *Config) CheckInterval() time.Duration {
+	if c.ExpiryCheckInterval == 0 {
+		return DEFAULT_EXPIRY_CHECK_INTERVAL
+	}
+
+	return c.ExpiryCheckInterval
+}
@@ -0,0 +1,74 @@
+package event
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+// DeadLetter is an event not delivered to a webhook, written as a single json line
+type DeadLetter struct {
+	Time     time.Time `json:"time"`
+	Webhook  string    `json:"webhook"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Event    Event     `json:"event"`
+}
+
+// deadLetterFile appends dead letters to a file, they are only logged if there is no file
+type deadLetterFile struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func newDeadLetterFile(path string) (*deadLetterFile, error) {
+	if path == "" {
+		return &deadLetterFile{}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		logging.GetLogger().Errorf("opening dead letter file failed, %v", err)
+		return nil, err
+	}
+
+	return &deadLetterFile{file: file}, nil
+}
+
+func (d *deadLetterFile) write(letter DeadLetter) {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		logging.GetLogger().Errorf("encoding dead letter failed, %v", err)
+		return
+	}
+
+	logging.GetLogger().Errorf("Event [%s] is not delivered to webhook [%s] after %d attempts, %s", letter.Event.ID,
+		letter.Webhook, letter.Attempts, letter.Error)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.file == nil {
+		logging.GetLogger().Errorf("Dropping event, there is no dead letter file: %s", line)
+		return
+	}
+
+	_, err = d.file.Write(append(line, '\n'))
+	if err != nil {
+		logging.GetLogger().Errorf("writing dead letter failed, dropping event: %s, %v", line, err)
+	}
+}
+
+func (d *deadLetterFile) close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+
+	return d.file.Close()
+}
@@ -0,0 +1,176 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+// Dispatcher posts events to webhooks in background, each webhook has its own queue so that a slow one does not
+// hold back the others. Nil dispatcher drops events, so that callers do not check whether events are enabled
+type Dispatcher struct {
+	webhooks   []*webhook
+	deadLetter *deadLetterFile
+
+	// canceled if the dispatcher is not stopped in time, events in progress are dead lettered
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex   sync.RWMutex
+	stopped bool
+	workers sync.WaitGroup
+
+	timeProvider func() time.Time
+}
+
+// New returns nil if events are not enabled
+func New(conf *Config) (*Dispatcher, error) {
+	if !conf.Enabled() {
+		return nil, nil
+	}
+
+	err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := []*webhook{}
+	for _, webhookConfig := range conf.Webhooks {
+		webhook, err := newWebhook(webhookConfig)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	deadLetter, err := newDeadLetterFile(conf.DeadLetterPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatcher := &Dispatcher{
+		webhooks:     webhooks,
+		deadLetter:   deadLetter,
+		ctx:          ctx,
+		cancel:       cancel,
+		timeProvider: time.Now,
+	}
+
+	for _, webhook := range webhooks {
+		dispatcher.workers.Add(1)
+		go dispatcher.run(webhook)
+	}
+
+	return dispatcher, nil
+}
+
+// Emit queues the event for the webhooks subscribed to its type, id and time are set if they are empty
+func (d *Dispatcher) Emit(event Event) {
+	if d == nil {
+		return
+	}
+
+	if event.ID == "" {
+		event.ID = newID()
+	}
+	if event.Time.IsZero() {
+		event.Time = d.timeProvider()
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.stopped {
+		logging.GetLogger().Warnf("Event dispatcher is stopped, dropping event [%s] of [%s]", event.Type, event.Issuer)
+		return
+	}
+
+	for _, webhook := range d.webhooks {
+		if !webhook.subscribed(event.Type) {
+			continue
+		}
+
+		select {
+		case webhook.queue <- event:
+		default:
+			d.deadLetter.write(DeadLetter{
+				Time:    d.timeProvider(),
+				Webhook: webhook.conf.Name,
+				Error:   "queue of webhook is full",
+				Event:   event,
+			})
+		}
+	}
+}
+
+// Stop waits for queued events to be delivered until ctx is done, then dead letters the remaining ones
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mutex.Lock()
+	if !d.stopped {
+		d.stopped = true
+		for _, webhook := range d.webhooks {
+			close(webhook.queue)
+		}
+	}
+	d.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logging.GetLogger().Warnf("Events are not delivered in time, dead lettering the remaining ones, %v", ctx.Err())
+		d.cancel()
+		<-done
+		err = ctx.Err()
+	}
+	d.cancel()
+
+	closeErr := d.deadLetter.close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// ----
+
+func (d *Dispatcher) run(webhook *webhook) {
+	defer d.workers.Done()
+
+	for event := range webhook.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			logging.GetLogger().Errorf("encoding event [%s] failed, %v", event.ID, err)
+			continue
+		}
+
+		attempts, err := webhook.deliver(d.ctx, event, body)
+		if err != nil {
+			d.deadLetter.write(DeadLetter{
+				Time:     d.timeProvider(),
+				Webhook:  webhook.conf.Name,
+				Attempts: attempts,
+				Error:    err.Error(),
+				Event:    event,
+			})
+			continue
+		}
+
+		logging.GetLogger().Debugf("Delivered event [%s] to webhook [%s]", event.ID, webhook.conf.Name)
+	}
+}
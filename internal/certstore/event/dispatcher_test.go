@@ -0,0 +1,247 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestEmitDeliversSignedEvent(t *testing.T) {
+	receiver := newReceiver(t, http.StatusOK)
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	ioutil.WriteFile(secretFile, []byte("webhook secret\n"), 0600)
+
+	dispatcher := createDispatcher(t, &Config{Webhooks: []WebhookConfig{
+		{Name: "chat-ops", URL: receiver.server.URL, SecretFile: secretFile},
+	}})
+	dispatcher.Emit(Event{Type: Issued, Issuer: "internal", CommonName: "certstore.com", SerialNumber: "2a"})
+	assert.NotError(t, dispatcher.Stop(context.Background()), "stopping dispatcher failed")
+
+	// ----
+
+	requests := receiver.received()
+	assert.Equal(t, 1, len(requests))
+
+	request := requests[0]
+	assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+	assert.Equal(t, "issued", request.header.Get(EVENT_HEADER))
+	assert.True(t, Verify([]byte("webhook secret"), request.header.Get(TIMESTAMP_HEADER), request.body,
+		request.header.Get(SIGNATURE_HEADER)))
+	assert.False(t, Verify([]byte("other secret"), request.header.Get(TIMESTAMP_HEADER), request.body,
+		request.header.Get(SIGNATURE_HEADER)))
+
+	event := Event{}
+	err := json.Unmarshal(request.body, &event)
+	assert.NotError(t, err, "decoding event failed")
+	assert.Equal(t, request.header.Get(DELIVERY_HEADER), event.ID)
+	assert.Equal(t, "certstore.com", event.CommonName)
+	assert.Equal(t, "2a", event.SerialNumber)
+	assert.False(t, event.Time.IsZero())
+}
+
+func TestEmitFiltersEventTypes(t *testing.T) {
+	everything := newReceiver(t, http.StatusOK)
+	revocations := newReceiver(t, http.StatusNoContent)
+
+	dispatcher := createDispatcher(t, &Config{Webhooks: []WebhookConfig{
+		{Name: "cmdb", URL: everything.server.URL},
+		{Name: "security", URL: revocations.server.URL, Events: []Type{Revoked}},
+	}})
+	dispatcher.Emit(Event{Type: Issued, Issuer: "internal"})
+	dispatcher.Emit(Event{Type: Revoked, Issuer: "internal"})
+	dispatcher.Stop(context.Background())
+
+	// ----
+
+	assert.Equal(t, 2, len(everything.received()))
+	assert.Equal(t, 1, len(revocations.received()))
+	assert.Equal(t, "revoked", revocations.received()[0].header.Get(EVENT_HEADER))
+}
+
+func TestEmitRetries(t *testing.T) {
+	receiver := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.log")
+
+	dispatcher := createDispatcher(t, &Config{DeadLetterPath: deadLetterPath, Webhooks: []WebhookConfig{
+		{Name: "chat-ops", URL: receiver.server.URL, InitialBackoff: time.Millisecond},
+	}})
+	dispatcher.Emit(Event{Type: Issued, Issuer: "internal"})
+	dispatcher.Stop(context.Background())
+
+	// ----
+
+	requests := receiver.received()
+	assert.Equal(t, 3, len(requests))
+	assert.Equal(t, requests[0].header.Get(DELIVERY_HEADER), requests[2].header.Get(DELIVERY_HEADER))
+	assert.Equal(t, 0, len(readDeadLetters(t, deadLetterPath)))
+}
+
+func TestEmitDeadLetter(t *testing.T) {
+	failing := newReceiver(t, http.StatusInternalServerError)
+	notRetried := newReceiver(t, http.StatusInternalServerError)
+	refusing := newReceiver(t, http.StatusBadRequest)
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.log")
+
+	dispatcher := createDispatcher(t, &Config{DeadLetterPath: deadLetterPath, Webhooks: []WebhookConfig{
+		{Name: "failing", URL: failing.server.URL, MaxRetries: retries(2), InitialBackoff: time.Millisecond},
+		{Name: "not-retried", URL: notRetried.server.URL, MaxRetries: retries(0), InitialBackoff: time.Millisecond},
+		{Name: "refusing", URL: refusing.server.URL, InitialBackoff: time.Millisecond},
+	}})
+	dispatcher.Emit(Event{Type: Failed, Issuer: "internal", ErrorKind: "policy-denied"})
+	dispatcher.Stop(context.Background())
+
+	// ----
+
+	letters := readDeadLetters(t, deadLetterPath)
+	assert.Equal(t, 3, len(letters))
+
+	attempts := map[string]int{}
+	for _, letter := range letters {
+		attempts[letter.Webhook] = letter.Attempts
+		assert.Equal(t, "policy-denied", letter.Event.ErrorKind)
+	}
+
+	// client errors are not retried, zero max retries is not replaced by the default
+	assert.Equal(t, 3, attempts["failing"])
+	assert.Equal(t, 1, attempts["not-retried"])
+	assert.Equal(t, 1, attempts["refusing"])
+	assert.Equal(t, 3, len(failing.received()))
+	assert.Equal(t, 1, len(notRetried.received()))
+	assert.Equal(t, 1, len(refusing.received()))
+}
+
+func TestStopDeadLettersUndelivered(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.log")
+	dispatcher := createDispatcher(t, &Config{DeadLetterPath: deadLetterPath, Webhooks: []WebhookConfig{
+		{Name: "slow", URL: server.URL},
+	}})
+	dispatcher.Emit(Event{Type: Issued, Issuer: "internal"})
+	dispatcher.Emit(Event{Type: Revoked, Issuer: "internal"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := dispatcher.Stop(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 2, len(readDeadLetters(t, deadLetterPath)))
+
+	// events emitted after stop are dropped
+	dispatcher.Emit(Event{Type: Issued, Issuer: "internal"})
+}
+
+func TestNilDispatcher(t *testing.T) {
+	dispatcher, err := New(&Config{})
+	assert.NotError(t, err, "creating dispatcher failed")
+	assert.Nil(t, dispatcher)
+
+	dispatcher.Emit(Event{Type: Issued})
+	assert.NotError(t, dispatcher.Stop(context.Background()), "stopping nil dispatcher failed")
+}
+
+func TestConfigValidate(t *testing.T) {
+	conf := &Config{Webhooks: []WebhookConfig{{Name: "chat-ops", URL: "https://hooks.certstore.com/events", Events: []Type{ExpiringSoon}}}}
+	assert.NotError(t, conf.Validate(), "config should be valid")
+
+	conf.Webhooks = append(conf.Webhooks, WebhookConfig{Name: "chat-ops", URL: "https://hooks.certstore.com"})
+	assert.ErrorContains(t, conf.Validate(), "configured more than once")
+
+	conf.Webhooks = []WebhookConfig{{Name: "chat-ops", URL: "ftp://hooks.certstore.com"}}
+	assert.ErrorContains(t, conf.Validate(), "not a valid http url")
+
+	conf.Webhooks = []WebhookConfig{{Name: "chat-ops", URL: "https://hooks.certstore.com", Events: []Type{"renewal"}}}
+	assert.ErrorContains(t, conf.Validate(), "event type is unknown")
+
+	conf.Webhooks = []WebhookConfig{{Name: "chat-ops", URL: "https://hooks.certstore.com", MaxRetries: retries(-1)}}
+	assert.ErrorContains(t, conf.Validate(), "can not be negative")
+}
+
+// ----
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver responds with the given statuses in order, the last one is repeated
+type receiver struct {
+	mutex    sync.Mutex
+	statuses []int
+	requests []receivedRequest
+	server   *httptest.Server
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		r.mutex.Lock()
+		status := r.statuses[len(r.statuses)-1]
+		if len(r.requests) < len(r.statuses) {
+			status = r.statuses[len(r.requests)]
+		}
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		r.mutex.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+
+	return r
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]receivedRequest{}, r.requests...)
+}
+
+func retries(count int) *int {
+	return &count
+}
+
+func createDispatcher(t *testing.T, conf *Config) *Dispatcher {
+	dispatcher, err := New(conf)
+	assert.NotError(t, err, "creating dispatcher failed")
+
+	return dispatcher
+}
+
+func readDeadLetters(t *testing.T, path string) []DeadLetter {
+	content, err := ioutil.ReadFile(path)
+	assert.NotError(t, err, "reading dead letter file failed")
+
+	letters := []DeadLetter{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if line == "" {
+			continue
+		}
+
+		letter := DeadLetter{}
+		err = json.Unmarshal([]byte(line), &letter)
+		assert.NotError(t, err, "decoding dead letter failed")
+		letters = append(letters, letter)
+	}
+
+	return letters
+}
@@ -0,0 +1,62 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+type Type string
+
+const (
+	Issued Type = "issued"
+
+	// issued, replacing a valid certificate of the issuer with the same names
+	Renewed      Type = "renewed"
+	Revoked      Type = "revoked"
+	Failed       Type = "failed"
+	ExpiringSoon Type = "expiring-soon"
+)
+
+var types = map[Type]bool{
+	Issued:       true,
+	Renewed:      true,
+	Revoked:      true,
+	Failed:       true,
+	ExpiringSoon: true,
+}
+
+// Event is posted as json to webhooks, id is the same for every delivery attempt of the event
+type Event struct {
+	ID   string    `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+
+	Issuer     string   `json:"issuer"`
+	CommonName string   `json:"common-name,omitempty"`
+	Names      []string `json:"names,omitempty"`
+
+	// hex encoded serial number, empty for failed issuances
+	SerialNumber string     `json:"serial-number,omitempty"`
+	NotAfter     *time.Time `json:"not-after,omitempty"`
+
+	// serial number of the certificate replaced by a renewed one
+	Replaces         string `json:"replaces,omitempty"`
+	RevocationReason int    `json:"revocation-reason,omitempty"`
+
+	// error kind is the kind of typed errors, e.g. rate-limited, or canceled, deadline-exceeded or error
+	ErrorKind string `json:"error-kind,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		// ids only tell events apart for receivers, time is unique enough
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(id)
+}
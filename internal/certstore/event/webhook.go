@@ -0,0 +1,204 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	EVENT_HEADER     = "X-Certstore-Event"
+	DELIVERY_HEADER  = "X-Certstore-Delivery"
+	TIMESTAMP_HEADER = "X-Certstore-Timestamp"
+	SIGNATURE_HEADER = "X-Certstore-Signature"
+
+	SIGNATURE_PREFIX = "sha256="
+)
+
+// webhook posts events in the order they are emitted, retrying each until it is delivered or retries are exhausted
+type webhook struct {
+	conf       WebhookConfig
+	maxRetries int
+	secret     []byte
+	events     map[Type]bool
+	queue      chan Event
+	client     *http.Client
+}
+
+// permanentError is a failure not worth retrying, e.g. 400 or 404 responses
+type permanentError struct {
+	cause error
+}
+
+func (e *permanentError) Error() string {
+	return e.cause.Error()
+}
+
+func newWebhook(conf WebhookConfig) (*webhook, error) {
+	var secret []byte
+	if conf.SecretFile != "" {
+		content, err := ioutil.ReadFile(conf.SecretFile)
+		if err != nil {
+			logging.GetLogger().Errorf("reading secret of webhook [%s] failed, %v", conf.Name, err)
+			return nil, err
+		}
+		secret = bytes.TrimSpace(content)
+	}
+
+	maxRetries := DEFAULT_MAX_RETRIES
+	if conf.MaxRetries != nil {
+		maxRetries = *conf.MaxRetries
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = DEFAULT_TIMEOUT
+	}
+	if conf.InitialBackoff == 0 {
+		conf.InitialBackoff = DEFAULT_INITIAL_BACKOFF
+	}
+	if conf.MaxBackoff == 0 {
+		conf.MaxBackoff = DEFAULT_MAX_BACKOFF
+	}
+	if conf.QueueSize == 0 {
+		conf.QueueSize = DEFAULT_QUEUE_SIZE
+	}
+
+	events := make(map[Type]bool)
+	for _, eventType := range conf.Events {
+		events[eventType] = true
+	}
+
+	return &webhook{
+		conf:       conf,
+		maxRetries: maxRetries,
+		secret:     secret,
+		events:     events,
+		queue:      make(chan Event, conf.QueueSize),
+		client:     &http.Client{Timeout: conf.Timeout},
+	}, nil
+}
+
+func (w *webhook) subscribed(eventType Type) bool {
+	return len(w.events) == 0 || w.events[eventType]
+}
+
+// deliver returns the number of attempts, and the last error if the event is not delivered
+func (w *webhook) deliver(ctx context.Context, event Event, body []byte) (int, error) {
+	backoff := w.conf.InitialBackoff
+	attempts := 0
+	for {
+		attempts++
+		retryAfter, err := w.post(ctx, event, body)
+		if err == nil {
+			return attempts, nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempts > w.maxRetries {
+			return attempts, err
+		}
+
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > w.conf.MaxBackoff {
+			wait = w.conf.MaxBackoff
+		}
+		logging.GetLogger().Warnf("Posting event [%s] to webhook [%s] failed, retrying in %v, %v", event.ID, w.conf.Name, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > w.conf.MaxBackoff {
+			backoff = w.conf.MaxBackoff
+		}
+	}
+}
+
+// post returns the delay asked by Retry-After header of the response if any
+func (w *webhook) post(ctx context.Context, event Event, body []byte) (time.Duration, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, &permanentError{cause: err}
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EVENT_HEADER, string(event.Type))
+	request.Header.Set(DELIVERY_HEADER, event.ID)
+	request.Header.Set(TIMESTAMP_HEADER, timestamp)
+	if len(w.secret) > 0 {
+		request.Header.Set(SIGNATURE_HEADER, Sign(w.secret, timestamp, body))
+	}
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return 0, nil
+	}
+
+	err = errors.New(fmt.Sprintf("webhook responded with status %d", response.StatusCode))
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return retryAfter(response), err
+	case response.StatusCode == http.StatusRequestTimeout:
+		return 0, err
+	}
+
+	return 0, &permanentError{cause: errors.New(fmt.Sprintf("webhook refused the event with status %d", response.StatusCode))}
+}
+
+// retryAfter supports delay in seconds only, not http dates
+func retryAfter(response *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// ----
+
+// Sign is the value of signature header, HMAC-SHA256 of timestamp header and body joined with a dot
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return SIGNATURE_PREFIX + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature header of a request, for receivers written in go. Receivers should also reject
+// requests with an old timestamp, so that a request can not be replayed
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, SIGNATURE_PREFIX) {
+		return false
+	}
+
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
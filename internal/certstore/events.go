@@ -0,0 +1,141 @@
+package certstore
+
+import (
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/event"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	// certificates notified as expiring soon, so that they are notified once. Removed once they are expired, renewed
+	// or revoked
+	EXPIRING_SOON_STORAGE_BUCKET = "events/expiring-soon"
+)
+
+// watchExpiring notifies certificates expiring soon right away, then on every interval until stop is closed
+func (c *certStoreImpl) watchExpiring(interval time.Duration, within time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.notifyExpiring(within)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *certStoreImpl) notifyExpiring(within time.Duration) {
+	certificates, err := c.inventory.ListExpiring(within)
+	if err != nil {
+		logging.GetLogger().Errorf("Listing expiring certificates failed, %v", err)
+		return
+	}
+
+	notified, err := c.storage.List(EXPIRING_SOON_STORAGE_BUCKET)
+	if err != nil {
+		logging.GetLogger().Errorf("Listing notified expiring certificates failed, %v", err)
+		return
+	}
+
+	for _, certificate := range certificates {
+		key := certificate.Issuer + "/" + certificate.SerialNumber
+		if _, exist := notified[key]; exist {
+			delete(notified, key)
+			continue
+		}
+
+		c.events.Emit(certificateEvent(event.ExpiringSoon, certificate))
+
+		err = c.storage.Put(EXPIRING_SOON_STORAGE_BUCKET, key, []byte(time.Now().UTC().Format(time.RFC3339)))
+		if err != nil {
+			logging.GetLogger().Errorf("Saving expiring certificate [%s] as notified failed, %v", key, err)
+		}
+	}
+
+	// the rest are not expiring anymore
+	for key := range notified {
+		err = c.storage.Delete(EXPIRING_SOON_STORAGE_BUCKET, key)
+		if err != nil {
+			logging.GetLogger().Warnf("Removing notified expiring certificate [%s] failed, %v", key, err)
+		}
+	}
+}
+
+// findPrevious returns nil if events are not enabled, or there is no valid certificate the request renews
+func (c *certStoreImpl) findPrevious(issuer string, request *service.NewCertificateRequest) *inventory.Certificate {
+	if c.events == nil {
+		return nil
+	}
+
+	previous, err := c.inventory.FindPrevious(issuer, request)
+	if err != nil {
+		if !inventory.IsNotFound(err) {
+			logging.GetLogger().Warnf("Looking up renewed certificate failed, %v", err)
+		}
+		return nil
+	}
+
+	return previous
+}
+
+// emitIssued emits renewed if there is a previous certificate, issued certificate is nil if it is not in inventory
+func (c *certStoreImpl) emitIssued(issuer string, request *service.NewCertificateRequest,
+	certificate *inventory.Certificate, previous *inventory.Certificate) {
+
+	issued := event.Event{
+		Type:       event.Issued,
+		Issuer:     issuer,
+		CommonName: request.CommonName,
+		Names:      eventNames(request.CommonName, request.SubjectAlternativeNames),
+	}
+	if certificate != nil {
+		issued = certificateEvent(event.Issued, certificate)
+	}
+
+	if previous != nil {
+		issued.Type = event.Renewed
+		issued.Replaces = previous.SerialNumber
+	}
+
+	c.events.Emit(issued)
+}
+
+func (c *certStoreImpl) emitFailed(issuer string, request *service.NewCertificateRequest, err error) {
+	c.events.Emit(event.Event{
+		Type:       event.Failed,
+		Issuer:     issuer,
+		CommonName: request.CommonName,
+		Names:      eventNames(request.CommonName, request.SubjectAlternativeNames),
+		ErrorKind:  issuanceOutcome(err),
+		Error:      err.Error(),
+	})
+}
+
+func certificateEvent(eventType event.Type, certificate *inventory.Certificate) event.Event {
+	notAfter := certificate.NotAfter
+	return event.Event{
+		Type:             eventType,
+		Issuer:           certificate.Issuer,
+		CommonName:       certificate.CommonName,
+		Names:            eventNames(certificate.CommonName, certificate.SubjectAlternativeNames),
+		SerialNumber:     certificate.SerialNumber,
+		NotAfter:         &notAfter,
+		RevocationReason: int(certificate.RevocationReason),
+	}
+}
+
+func eventNames(commonName string, sans []string) []string {
+	names := []string{}
+	if commonName != "" {
+		names = append(names, commonName)
+	}
+
+	return append(names, sans...)
+}
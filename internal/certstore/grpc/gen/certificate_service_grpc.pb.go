@@ -19,6 +19,20 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CertificateServiceClient interface {
 	IssueCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateResponse, error)
+	GetRateLimitBudget(ctx context.Context, in *RateLimitBudgetRequest, opts ...grpc.CallOption) (*RateLimitBudgetResponse, error)
+	SubmitCertificateRequest(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*Operation, error)
+	GetOperation(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	WatchOperation(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (CertificateService_WatchOperationClient, error)
+	IssueCertificateV2(ctx context.Context, in *CertificateRequestV2, opts ...grpc.CallOption) (*CertificateResponseV2, error)
+	SubmitCertificateRequestV2(ctx context.Context, in *CertificateRequestV2, opts ...grpc.CallOption) (*Operation, error)
+	ListIssuers(ctx context.Context, in *ListIssuersRequest, opts ...grpc.CallOption) (*ListIssuersResponse, error)
+	IssueSSHCertificate(ctx context.Context, in *SSHCertificateRequest, opts ...grpc.CallOption) (*SSHCertificateResponse, error)
+	ListCertificates(ctx context.Context, in *ListCertificatesRequest, opts ...grpc.CallOption) (*ListCertificatesResponse, error)
+	GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*InventoryCertificate, error)
+	RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*InventoryCertificate, error)
+	GetSignedTreeHead(ctx context.Context, in *SignedTreeHeadRequest, opts ...grpc.CallOption) (*SignedTreeHead, error)
+	GetInclusionProof(ctx context.Context, in *InclusionProofRequest, opts ...grpc.CallOption) (*InclusionProofResponse, error)
+	GetConsistencyProof(ctx context.Context, in *ConsistencyProofRequest, opts ...grpc.CallOption) (*ConsistencyProofResponse, error)
 }
 
 type certificateServiceClient struct {
@@ -38,11 +52,174 @@ func (c *certificateServiceClient) IssueCertificate(ctx context.Context, in *Cer
 	return out, nil
 }
 
+func (c *certificateServiceClient) GetRateLimitBudget(ctx context.Context, in *RateLimitBudgetRequest, opts ...grpc.CallOption) (*RateLimitBudgetResponse, error) {
+	out := new(RateLimitBudgetResponse)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/GetRateLimitBudget", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) SubmitCertificateRequest(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/SubmitCertificateRequest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) GetOperation(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/GetOperation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) WatchOperation(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (CertificateService_WatchOperationClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CertificateService_ServiceDesc.Streams[0], "/proto.CertificateService/WatchOperation", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &certificateServiceWatchOperationClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CertificateService_WatchOperationClient interface {
+	Recv() (*Operation, error)
+	grpc.ClientStream
+}
+
+type certificateServiceWatchOperationClient struct {
+	grpc.ClientStream
+}
+
+func (x *certificateServiceWatchOperationClient) Recv() (*Operation, error) {
+	m := new(Operation)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *certificateServiceClient) IssueCertificateV2(ctx context.Context, in *CertificateRequestV2, opts ...grpc.CallOption) (*CertificateResponseV2, error) {
+	out := new(CertificateResponseV2)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/IssueCertificateV2", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) SubmitCertificateRequestV2(ctx context.Context, in *CertificateRequestV2, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/SubmitCertificateRequestV2", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) ListIssuers(ctx context.Context, in *ListIssuersRequest, opts ...grpc.CallOption) (*ListIssuersResponse, error) {
+	out := new(ListIssuersResponse)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/ListIssuers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) IssueSSHCertificate(ctx context.Context, in *SSHCertificateRequest, opts ...grpc.CallOption) (*SSHCertificateResponse, error) {
+	out := new(SSHCertificateResponse)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/IssueSSHCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) ListCertificates(ctx context.Context, in *ListCertificatesRequest, opts ...grpc.CallOption) (*ListCertificatesResponse, error) {
+	out := new(ListCertificatesResponse)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/ListCertificates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*InventoryCertificate, error) {
+	out := new(InventoryCertificate)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/GetCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*InventoryCertificate, error) {
+	out := new(InventoryCertificate)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/RevokeCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) GetSignedTreeHead(ctx context.Context, in *SignedTreeHeadRequest, opts ...grpc.CallOption) (*SignedTreeHead, error) {
+	out := new(SignedTreeHead)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/GetSignedTreeHead", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) GetInclusionProof(ctx context.Context, in *InclusionProofRequest, opts ...grpc.CallOption) (*InclusionProofResponse, error) {
+	out := new(InclusionProofResponse)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/GetInclusionProof", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *certificateServiceClient) GetConsistencyProof(ctx context.Context, in *ConsistencyProofRequest, opts ...grpc.CallOption) (*ConsistencyProofResponse, error) {
+	out := new(ConsistencyProofResponse)
+	err := c.cc.Invoke(ctx, "/proto.CertificateService/GetConsistencyProof", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CertificateServiceServer is the server API for CertificateService service.
 // All implementations must embed UnimplementedCertificateServiceServer
 // for forward compatibility
 type CertificateServiceServer interface {
 	IssueCertificate(context.Context, *CertificateRequest) (*CertificateResponse, error)
+	GetRateLimitBudget(context.Context, *RateLimitBudgetRequest) (*RateLimitBudgetResponse, error)
+	SubmitCertificateRequest(context.Context, *CertificateRequest) (*Operation, error)
+	GetOperation(context.Context, *OperationRequest) (*Operation, error)
+	WatchOperation(*OperationRequest, CertificateService_WatchOperationServer) error
+	IssueCertificateV2(context.Context, *CertificateRequestV2) (*CertificateResponseV2, error)
+	SubmitCertificateRequestV2(context.Context, *CertificateRequestV2) (*Operation, error)
+	ListIssuers(context.Context, *ListIssuersRequest) (*ListIssuersResponse, error)
+	IssueSSHCertificate(context.Context, *SSHCertificateRequest) (*SSHCertificateResponse, error)
+	ListCertificates(context.Context, *ListCertificatesRequest) (*ListCertificatesResponse, error)
+	GetCertificate(context.Context, *GetCertificateRequest) (*InventoryCertificate, error)
+	RevokeCertificate(context.Context, *RevokeCertificateRequest) (*InventoryCertificate, error)
+	GetSignedTreeHead(context.Context, *SignedTreeHeadRequest) (*SignedTreeHead, error)
+	GetInclusionProof(context.Context, *InclusionProofRequest) (*InclusionProofResponse, error)
+	GetConsistencyProof(context.Context, *ConsistencyProofRequest) (*ConsistencyProofResponse, error)
 	mustEmbedUnimplementedCertificateServiceServer()
 }
 
@@ -53,6 +230,48 @@ type UnimplementedCertificateServiceServer struct {
 func (UnimplementedCertificateServiceServer) IssueCertificate(context.Context, *CertificateRequest) (*CertificateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method IssueCertificate not implemented")
 }
+func (UnimplementedCertificateServiceServer) GetRateLimitBudget(context.Context, *RateLimitBudgetRequest) (*RateLimitBudgetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRateLimitBudget not implemented")
+}
+func (UnimplementedCertificateServiceServer) SubmitCertificateRequest(context.Context, *CertificateRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitCertificateRequest not implemented")
+}
+func (UnimplementedCertificateServiceServer) GetOperation(context.Context, *OperationRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOperation not implemented")
+}
+func (UnimplementedCertificateServiceServer) WatchOperation(*OperationRequest, CertificateService_WatchOperationServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchOperation not implemented")
+}
+func (UnimplementedCertificateServiceServer) IssueCertificateV2(context.Context, *CertificateRequestV2) (*CertificateResponseV2, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueCertificateV2 not implemented")
+}
+func (UnimplementedCertificateServiceServer) SubmitCertificateRequestV2(context.Context, *CertificateRequestV2) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitCertificateRequestV2 not implemented")
+}
+func (UnimplementedCertificateServiceServer) ListIssuers(context.Context, *ListIssuersRequest) (*ListIssuersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIssuers not implemented")
+}
+func (UnimplementedCertificateServiceServer) IssueSSHCertificate(context.Context, *SSHCertificateRequest) (*SSHCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueSSHCertificate not implemented")
+}
+func (UnimplementedCertificateServiceServer) ListCertificates(context.Context, *ListCertificatesRequest) (*ListCertificatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCertificates not implemented")
+}
+func (UnimplementedCertificateServiceServer) GetCertificate(context.Context, *GetCertificateRequest) (*InventoryCertificate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCertificate not implemented")
+}
+func (UnimplementedCertificateServiceServer) RevokeCertificate(context.Context, *RevokeCertificateRequest) (*InventoryCertificate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeCertificate not implemented")
+}
+func (UnimplementedCertificateServiceServer) GetSignedTreeHead(context.Context, *SignedTreeHeadRequest) (*SignedTreeHead, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSignedTreeHead not implemented")
+}
+func (UnimplementedCertificateServiceServer) GetInclusionProof(context.Context, *InclusionProofRequest) (*InclusionProofResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInclusionProof not implemented")
+}
+func (UnimplementedCertificateServiceServer) GetConsistencyProof(context.Context, *ConsistencyProofRequest) (*ConsistencyProofResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConsistencyProof not implemented")
+}
 func (UnimplementedCertificateServiceServer) mustEmbedUnimplementedCertificateServiceServer() {}
 
 // UnsafeCertificateServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -84,6 +303,261 @@ func _CertificateService_IssueCertificate_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CertificateService_GetRateLimitBudget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateLimitBudgetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).GetRateLimitBudget(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/GetRateLimitBudget",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).GetRateLimitBudget(ctx, req.(*RateLimitBudgetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_SubmitCertificateRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).SubmitCertificateRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/SubmitCertificateRequest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).SubmitCertificateRequest(ctx, req.(*CertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_GetOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).GetOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/GetOperation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).GetOperation(ctx, req.(*OperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_WatchOperation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(OperationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CertificateServiceServer).WatchOperation(m, &certificateServiceWatchOperationServer{stream})
+}
+
+type CertificateService_WatchOperationServer interface {
+	Send(*Operation) error
+	grpc.ServerStream
+}
+
+type certificateServiceWatchOperationServer struct {
+	grpc.ServerStream
+}
+
+func (x *certificateServiceWatchOperationServer) Send(m *Operation) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CertificateService_IssueCertificateV2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRequestV2)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).IssueCertificateV2(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/IssueCertificateV2",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).IssueCertificateV2(ctx, req.(*CertificateRequestV2))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_SubmitCertificateRequestV2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRequestV2)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).SubmitCertificateRequestV2(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/SubmitCertificateRequestV2",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).SubmitCertificateRequestV2(ctx, req.(*CertificateRequestV2))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_ListIssuers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListIssuersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).ListIssuers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/ListIssuers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).ListIssuers(ctx, req.(*ListIssuersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_IssueSSHCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SSHCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).IssueSSHCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/IssueSSHCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).IssueSSHCertificate(ctx, req.(*SSHCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_ListCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCertificatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).ListCertificates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/ListCertificates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).ListCertificates(ctx, req.(*ListCertificatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_GetCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).GetCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/GetCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).GetCertificate(ctx, req.(*GetCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_RevokeCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).RevokeCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/RevokeCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).RevokeCertificate(ctx, req.(*RevokeCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_GetSignedTreeHead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignedTreeHeadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).GetSignedTreeHead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/GetSignedTreeHead",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).GetSignedTreeHead(ctx, req.(*SignedTreeHeadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_GetInclusionProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InclusionProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).GetInclusionProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/GetInclusionProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).GetInclusionProof(ctx, req.(*InclusionProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CertificateService_GetConsistencyProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConsistencyProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateServiceServer).GetConsistencyProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.CertificateService/GetConsistencyProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateServiceServer).GetConsistencyProof(ctx, req.(*ConsistencyProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CertificateService_ServiceDesc is the grpc.ServiceDesc for CertificateService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -95,7 +569,65 @@ var CertificateService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IssueCertificate",
 			Handler:    _CertificateService_IssueCertificate_Handler,
 		},
+		{
+			MethodName: "GetRateLimitBudget",
+			Handler:    _CertificateService_GetRateLimitBudget_Handler,
+		},
+		{
+			MethodName: "SubmitCertificateRequest",
+			Handler:    _CertificateService_SubmitCertificateRequest_Handler,
+		},
+		{
+			MethodName: "GetOperation",
+			Handler:    _CertificateService_GetOperation_Handler,
+		},
+		{
+			MethodName: "IssueCertificateV2",
+			Handler:    _CertificateService_IssueCertificateV2_Handler,
+		},
+		{
+			MethodName: "SubmitCertificateRequestV2",
+			Handler:    _CertificateService_SubmitCertificateRequestV2_Handler,
+		},
+		{
+			MethodName: "ListIssuers",
+			Handler:    _CertificateService_ListIssuers_Handler,
+		},
+		{
+			MethodName: "IssueSSHCertificate",
+			Handler:    _CertificateService_IssueSSHCertificate_Handler,
+		},
+		{
+			MethodName: "ListCertificates",
+			Handler:    _CertificateService_ListCertificates_Handler,
+		},
+		{
+			MethodName: "GetCertificate",
+			Handler:    _CertificateService_GetCertificate_Handler,
+		},
+		{
+			MethodName: "RevokeCertificate",
+			Handler:    _CertificateService_RevokeCertificate_Handler,
+		},
+		{
+			MethodName: "GetSignedTreeHead",
+			Handler:    _CertificateService_GetSignedTreeHead_Handler,
+		},
+		{
+			MethodName: "GetInclusionProof",
+			Handler:    _CertificateService_GetInclusionProof_Handler,
+		},
+		{
+			MethodName: "GetConsistencyProof",
+			Handler:    _CertificateService_GetConsistencyProof_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchOperation",
+			Handler:       _CertificateService_WatchOperation_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "certificate_service.proto",
 }
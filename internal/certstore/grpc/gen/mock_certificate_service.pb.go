@@ -10,6 +10,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	grpc "google.golang.org/grpc"
+	metadata "google.golang.org/grpc/metadata"
 )
 
 // MockCertificateServiceClient is a mock of CertificateServiceClient interface.
@@ -35,6 +36,126 @@ func (m *MockCertificateServiceClient) EXPECT() *MockCertificateServiceClientMoc
 	return m.recorder
 }
 
+// GetCertificate mocks base method.
+func (m *MockCertificateServiceClient) GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*InventoryCertificate, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCertificate", varargs...)
+	ret0, _ := ret[0].(*InventoryCertificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCertificate indicates an expected call of GetCertificate.
+func (mr *MockCertificateServiceClientMockRecorder) GetCertificate(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCertificate", reflect.TypeOf((*MockCertificateServiceClient)(nil).GetCertificate), varargs...)
+}
+
+// GetConsistencyProof mocks base method.
+func (m *MockCertificateServiceClient) GetConsistencyProof(ctx context.Context, in *ConsistencyProofRequest, opts ...grpc.CallOption) (*ConsistencyProofResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetConsistencyProof", varargs...)
+	ret0, _ := ret[0].(*ConsistencyProofResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsistencyProof indicates an expected call of GetConsistencyProof.
+func (mr *MockCertificateServiceClientMockRecorder) GetConsistencyProof(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsistencyProof", reflect.TypeOf((*MockCertificateServiceClient)(nil).GetConsistencyProof), varargs...)
+}
+
+// GetInclusionProof mocks base method.
+func (m *MockCertificateServiceClient) GetInclusionProof(ctx context.Context, in *InclusionProofRequest, opts ...grpc.CallOption) (*InclusionProofResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetInclusionProof", varargs...)
+	ret0, _ := ret[0].(*InclusionProofResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInclusionProof indicates an expected call of GetInclusionProof.
+func (mr *MockCertificateServiceClientMockRecorder) GetInclusionProof(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInclusionProof", reflect.TypeOf((*MockCertificateServiceClient)(nil).GetInclusionProof), varargs...)
+}
+
+// GetOperation mocks base method.
+func (m *MockCertificateServiceClient) GetOperation(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetOperation", varargs...)
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOperation indicates an expected call of GetOperation.
+func (mr *MockCertificateServiceClientMockRecorder) GetOperation(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOperation", reflect.TypeOf((*MockCertificateServiceClient)(nil).GetOperation), varargs...)
+}
+
+// GetRateLimitBudget mocks base method.
+func (m *MockCertificateServiceClient) GetRateLimitBudget(ctx context.Context, in *RateLimitBudgetRequest, opts ...grpc.CallOption) (*RateLimitBudgetResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetRateLimitBudget", varargs...)
+	ret0, _ := ret[0].(*RateLimitBudgetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRateLimitBudget indicates an expected call of GetRateLimitBudget.
+func (mr *MockCertificateServiceClientMockRecorder) GetRateLimitBudget(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRateLimitBudget", reflect.TypeOf((*MockCertificateServiceClient)(nil).GetRateLimitBudget), varargs...)
+}
+
+// GetSignedTreeHead mocks base method.
+func (m *MockCertificateServiceClient) GetSignedTreeHead(ctx context.Context, in *SignedTreeHeadRequest, opts ...grpc.CallOption) (*SignedTreeHead, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetSignedTreeHead", varargs...)
+	ret0, _ := ret[0].(*SignedTreeHead)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSignedTreeHead indicates an expected call of GetSignedTreeHead.
+func (mr *MockCertificateServiceClientMockRecorder) GetSignedTreeHead(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSignedTreeHead", reflect.TypeOf((*MockCertificateServiceClient)(nil).GetSignedTreeHead), varargs...)
+}
+
 // IssueCertificate mocks base method.
 func (m *MockCertificateServiceClient) IssueCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateResponse, error) {
 	m.ctrl.T.Helper()
@@ -55,6 +176,289 @@ func (mr *MockCertificateServiceClientMockRecorder) IssueCertificate(ctx, in int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueCertificate", reflect.TypeOf((*MockCertificateServiceClient)(nil).IssueCertificate), varargs...)
 }
 
+// IssueCertificateV2 mocks base method.
+func (m *MockCertificateServiceClient) IssueCertificateV2(ctx context.Context, in *CertificateRequestV2, opts ...grpc.CallOption) (*CertificateResponseV2, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "IssueCertificateV2", varargs...)
+	ret0, _ := ret[0].(*CertificateResponseV2)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueCertificateV2 indicates an expected call of IssueCertificateV2.
+func (mr *MockCertificateServiceClientMockRecorder) IssueCertificateV2(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueCertificateV2", reflect.TypeOf((*MockCertificateServiceClient)(nil).IssueCertificateV2), varargs...)
+}
+
+// IssueSSHCertificate mocks base method.
+func (m *MockCertificateServiceClient) IssueSSHCertificate(ctx context.Context, in *SSHCertificateRequest, opts ...grpc.CallOption) (*SSHCertificateResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "IssueSSHCertificate", varargs...)
+	ret0, _ := ret[0].(*SSHCertificateResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueSSHCertificate indicates an expected call of IssueSSHCertificate.
+func (mr *MockCertificateServiceClientMockRecorder) IssueSSHCertificate(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueSSHCertificate", reflect.TypeOf((*MockCertificateServiceClient)(nil).IssueSSHCertificate), varargs...)
+}
+
+// ListCertificates mocks base method.
+func (m *MockCertificateServiceClient) ListCertificates(ctx context.Context, in *ListCertificatesRequest, opts ...grpc.CallOption) (*ListCertificatesResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListCertificates", varargs...)
+	ret0, _ := ret[0].(*ListCertificatesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCertificates indicates an expected call of ListCertificates.
+func (mr *MockCertificateServiceClientMockRecorder) ListCertificates(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCertificates", reflect.TypeOf((*MockCertificateServiceClient)(nil).ListCertificates), varargs...)
+}
+
+// ListIssuers mocks base method.
+func (m *MockCertificateServiceClient) ListIssuers(ctx context.Context, in *ListIssuersRequest, opts ...grpc.CallOption) (*ListIssuersResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListIssuers", varargs...)
+	ret0, _ := ret[0].(*ListIssuersResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIssuers indicates an expected call of ListIssuers.
+func (mr *MockCertificateServiceClientMockRecorder) ListIssuers(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIssuers", reflect.TypeOf((*MockCertificateServiceClient)(nil).ListIssuers), varargs...)
+}
+
+// RevokeCertificate mocks base method.
+func (m *MockCertificateServiceClient) RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*InventoryCertificate, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeCertificate", varargs...)
+	ret0, _ := ret[0].(*InventoryCertificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeCertificate indicates an expected call of RevokeCertificate.
+func (mr *MockCertificateServiceClientMockRecorder) RevokeCertificate(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeCertificate", reflect.TypeOf((*MockCertificateServiceClient)(nil).RevokeCertificate), varargs...)
+}
+
+// SubmitCertificateRequest mocks base method.
+func (m *MockCertificateServiceClient) SubmitCertificateRequest(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*Operation, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SubmitCertificateRequest", varargs...)
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitCertificateRequest indicates an expected call of SubmitCertificateRequest.
+func (mr *MockCertificateServiceClientMockRecorder) SubmitCertificateRequest(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitCertificateRequest", reflect.TypeOf((*MockCertificateServiceClient)(nil).SubmitCertificateRequest), varargs...)
+}
+
+// SubmitCertificateRequestV2 mocks base method.
+func (m *MockCertificateServiceClient) SubmitCertificateRequestV2(ctx context.Context, in *CertificateRequestV2, opts ...grpc.CallOption) (*Operation, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SubmitCertificateRequestV2", varargs...)
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitCertificateRequestV2 indicates an expected call of SubmitCertificateRequestV2.
+func (mr *MockCertificateServiceClientMockRecorder) SubmitCertificateRequestV2(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitCertificateRequestV2", reflect.TypeOf((*MockCertificateServiceClient)(nil).SubmitCertificateRequestV2), varargs...)
+}
+
+// WatchOperation mocks base method.
+func (m *MockCertificateServiceClient) WatchOperation(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (CertificateService_WatchOperationClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WatchOperation", varargs...)
+	ret0, _ := ret[0].(CertificateService_WatchOperationClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchOperation indicates an expected call of WatchOperation.
+func (mr *MockCertificateServiceClientMockRecorder) WatchOperation(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchOperation", reflect.TypeOf((*MockCertificateServiceClient)(nil).WatchOperation), varargs...)
+}
+
+// MockCertificateService_WatchOperationClient is a mock of CertificateService_WatchOperationClient interface.
+type MockCertificateService_WatchOperationClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockCertificateService_WatchOperationClientMockRecorder
+}
+
+// MockCertificateService_WatchOperationClientMockRecorder is the mock recorder for MockCertificateService_WatchOperationClient.
+type MockCertificateService_WatchOperationClientMockRecorder struct {
+	mock *MockCertificateService_WatchOperationClient
+}
+
+// NewMockCertificateService_WatchOperationClient creates a new mock instance.
+func NewMockCertificateService_WatchOperationClient(ctrl *gomock.Controller) *MockCertificateService_WatchOperationClient {
+	mock := &MockCertificateService_WatchOperationClient{ctrl: ctrl}
+	mock.recorder = &MockCertificateService_WatchOperationClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCertificateService_WatchOperationClient) EXPECT() *MockCertificateService_WatchOperationClientMockRecorder {
+	return m.recorder
+}
+
+// CloseSend mocks base method.
+func (m *MockCertificateService_WatchOperationClient) CloseSend() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSend")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSend indicates an expected call of CloseSend.
+func (mr *MockCertificateService_WatchOperationClientMockRecorder) CloseSend() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSend", reflect.TypeOf((*MockCertificateService_WatchOperationClient)(nil).CloseSend))
+}
+
+// Context mocks base method.
+func (m *MockCertificateService_WatchOperationClient) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockCertificateService_WatchOperationClientMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockCertificateService_WatchOperationClient)(nil).Context))
+}
+
+// Header mocks base method.
+func (m *MockCertificateService_WatchOperationClient) Header() (metadata.MD, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Header")
+	ret0, _ := ret[0].(metadata.MD)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Header indicates an expected call of Header.
+func (mr *MockCertificateService_WatchOperationClientMockRecorder) Header() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Header", reflect.TypeOf((*MockCertificateService_WatchOperationClient)(nil).Header))
+}
+
+// Recv mocks base method.
+func (m *MockCertificateService_WatchOperationClient) Recv() (*Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recv")
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recv indicates an expected call of Recv.
+func (mr *MockCertificateService_WatchOperationClientMockRecorder) Recv() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recv", reflect.TypeOf((*MockCertificateService_WatchOperationClient)(nil).Recv))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockCertificateService_WatchOperationClient) RecvMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockCertificateService_WatchOperationClientMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockCertificateService_WatchOperationClient)(nil).RecvMsg), m)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockCertificateService_WatchOperationClient) SendMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockCertificateService_WatchOperationClientMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockCertificateService_WatchOperationClient)(nil).SendMsg), m)
+}
+
+// Trailer mocks base method.
+func (m *MockCertificateService_WatchOperationClient) Trailer() metadata.MD {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trailer")
+	ret0, _ := ret[0].(metadata.MD)
+	return ret0
+}
+
+// Trailer indicates an expected call of Trailer.
+func (mr *MockCertificateService_WatchOperationClientMockRecorder) Trailer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trailer", reflect.TypeOf((*MockCertificateService_WatchOperationClient)(nil).Trailer))
+}
+
 // MockCertificateServiceServer is a mock of CertificateServiceServer interface.
 type MockCertificateServiceServer struct {
 	ctrl     *gomock.Controller
@@ -78,6 +482,96 @@ func (m *MockCertificateServiceServer) EXPECT() *MockCertificateServiceServerMoc
 	return m.recorder
 }
 
+// GetCertificate mocks base method.
+func (m *MockCertificateServiceServer) GetCertificate(arg0 context.Context, arg1 *GetCertificateRequest) (*InventoryCertificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCertificate", arg0, arg1)
+	ret0, _ := ret[0].(*InventoryCertificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCertificate indicates an expected call of GetCertificate.
+func (mr *MockCertificateServiceServerMockRecorder) GetCertificate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCertificate", reflect.TypeOf((*MockCertificateServiceServer)(nil).GetCertificate), arg0, arg1)
+}
+
+// GetConsistencyProof mocks base method.
+func (m *MockCertificateServiceServer) GetConsistencyProof(arg0 context.Context, arg1 *ConsistencyProofRequest) (*ConsistencyProofResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsistencyProof", arg0, arg1)
+	ret0, _ := ret[0].(*ConsistencyProofResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsistencyProof indicates an expected call of GetConsistencyProof.
+func (mr *MockCertificateServiceServerMockRecorder) GetConsistencyProof(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsistencyProof", reflect.TypeOf((*MockCertificateServiceServer)(nil).GetConsistencyProof), arg0, arg1)
+}
+
+// GetInclusionProof mocks base method.
+func (m *MockCertificateServiceServer) GetInclusionProof(arg0 context.Context, arg1 *InclusionProofRequest) (*InclusionProofResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInclusionProof", arg0, arg1)
+	ret0, _ := ret[0].(*InclusionProofResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInclusionProof indicates an expected call of GetInclusionProof.
+func (mr *MockCertificateServiceServerMockRecorder) GetInclusionProof(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInclusionProof", reflect.TypeOf((*MockCertificateServiceServer)(nil).GetInclusionProof), arg0, arg1)
+}
+
+// GetOperation mocks base method.
+func (m *MockCertificateServiceServer) GetOperation(arg0 context.Context, arg1 *OperationRequest) (*Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOperation", arg0, arg1)
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOperation indicates an expected call of GetOperation.
+func (mr *MockCertificateServiceServerMockRecorder) GetOperation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOperation", reflect.TypeOf((*MockCertificateServiceServer)(nil).GetOperation), arg0, arg1)
+}
+
+// GetRateLimitBudget mocks base method.
+func (m *MockCertificateServiceServer) GetRateLimitBudget(arg0 context.Context, arg1 *RateLimitBudgetRequest) (*RateLimitBudgetResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRateLimitBudget", arg0, arg1)
+	ret0, _ := ret[0].(*RateLimitBudgetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRateLimitBudget indicates an expected call of GetRateLimitBudget.
+func (mr *MockCertificateServiceServerMockRecorder) GetRateLimitBudget(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRateLimitBudget", reflect.TypeOf((*MockCertificateServiceServer)(nil).GetRateLimitBudget), arg0, arg1)
+}
+
+// GetSignedTreeHead mocks base method.
+func (m *MockCertificateServiceServer) GetSignedTreeHead(arg0 context.Context, arg1 *SignedTreeHeadRequest) (*SignedTreeHead, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSignedTreeHead", arg0, arg1)
+	ret0, _ := ret[0].(*SignedTreeHead)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSignedTreeHead indicates an expected call of GetSignedTreeHead.
+func (mr *MockCertificateServiceServerMockRecorder) GetSignedTreeHead(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSignedTreeHead", reflect.TypeOf((*MockCertificateServiceServer)(nil).GetSignedTreeHead), arg0, arg1)
+}
+
 // IssueCertificate mocks base method.
 func (m *MockCertificateServiceServer) IssueCertificate(arg0 context.Context, arg1 *CertificateRequest) (*CertificateResponse, error) {
 	m.ctrl.T.Helper()
@@ -93,6 +587,125 @@ func (mr *MockCertificateServiceServerMockRecorder) IssueCertificate(arg0, arg1
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueCertificate", reflect.TypeOf((*MockCertificateServiceServer)(nil).IssueCertificate), arg0, arg1)
 }
 
+// IssueCertificateV2 mocks base method.
+func (m *MockCertificateServiceServer) IssueCertificateV2(arg0 context.Context, arg1 *CertificateRequestV2) (*CertificateResponseV2, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueCertificateV2", arg0, arg1)
+	ret0, _ := ret[0].(*CertificateResponseV2)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueCertificateV2 indicates an expected call of IssueCertificateV2.
+func (mr *MockCertificateServiceServerMockRecorder) IssueCertificateV2(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueCertificateV2", reflect.TypeOf((*MockCertificateServiceServer)(nil).IssueCertificateV2), arg0, arg1)
+}
+
+// IssueSSHCertificate mocks base method.
+func (m *MockCertificateServiceServer) IssueSSHCertificate(arg0 context.Context, arg1 *SSHCertificateRequest) (*SSHCertificateResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueSSHCertificate", arg0, arg1)
+	ret0, _ := ret[0].(*SSHCertificateResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueSSHCertificate indicates an expected call of IssueSSHCertificate.
+func (mr *MockCertificateServiceServerMockRecorder) IssueSSHCertificate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueSSHCertificate", reflect.TypeOf((*MockCertificateServiceServer)(nil).IssueSSHCertificate), arg0, arg1)
+}
+
+// ListCertificates mocks base method.
+func (m *MockCertificateServiceServer) ListCertificates(arg0 context.Context, arg1 *ListCertificatesRequest) (*ListCertificatesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCertificates", arg0, arg1)
+	ret0, _ := ret[0].(*ListCertificatesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCertificates indicates an expected call of ListCertificates.
+func (mr *MockCertificateServiceServerMockRecorder) ListCertificates(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCertificates", reflect.TypeOf((*MockCertificateServiceServer)(nil).ListCertificates), arg0, arg1)
+}
+
+// ListIssuers mocks base method.
+func (m *MockCertificateServiceServer) ListIssuers(arg0 context.Context, arg1 *ListIssuersRequest) (*ListIssuersResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIssuers", arg0, arg1)
+	ret0, _ := ret[0].(*ListIssuersResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIssuers indicates an expected call of ListIssuers.
+func (mr *MockCertificateServiceServerMockRecorder) ListIssuers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIssuers", reflect.TypeOf((*MockCertificateServiceServer)(nil).ListIssuers), arg0, arg1)
+}
+
+// RevokeCertificate mocks base method.
+func (m *MockCertificateServiceServer) RevokeCertificate(arg0 context.Context, arg1 *RevokeCertificateRequest) (*InventoryCertificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeCertificate", arg0, arg1)
+	ret0, _ := ret[0].(*InventoryCertificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeCertificate indicates an expected call of RevokeCertificate.
+func (mr *MockCertificateServiceServerMockRecorder) RevokeCertificate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeCertificate", reflect.TypeOf((*MockCertificateServiceServer)(nil).RevokeCertificate), arg0, arg1)
+}
+
+// SubmitCertificateRequest mocks base method.
+func (m *MockCertificateServiceServer) SubmitCertificateRequest(arg0 context.Context, arg1 *CertificateRequest) (*Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitCertificateRequest", arg0, arg1)
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitCertificateRequest indicates an expected call of SubmitCertificateRequest.
+func (mr *MockCertificateServiceServerMockRecorder) SubmitCertificateRequest(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitCertificateRequest", reflect.TypeOf((*MockCertificateServiceServer)(nil).SubmitCertificateRequest), arg0, arg1)
+}
+
+// SubmitCertificateRequestV2 mocks base method.
+func (m *MockCertificateServiceServer) SubmitCertificateRequestV2(arg0 context.Context, arg1 *CertificateRequestV2) (*Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitCertificateRequestV2", arg0, arg1)
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitCertificateRequestV2 indicates an expected call of SubmitCertificateRequestV2.
+func (mr *MockCertificateServiceServerMockRecorder) SubmitCertificateRequestV2(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitCertificateRequestV2", reflect.TypeOf((*MockCertificateServiceServer)(nil).SubmitCertificateRequestV2), arg0, arg1)
+}
+
+// WatchOperation mocks base method.
+func (m *MockCertificateServiceServer) WatchOperation(arg0 *OperationRequest, arg1 CertificateService_WatchOperationServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchOperation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchOperation indicates an expected call of WatchOperation.
+func (mr *MockCertificateServiceServerMockRecorder) WatchOperation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchOperation", reflect.TypeOf((*MockCertificateServiceServer)(nil).WatchOperation), arg0, arg1)
+}
+
 // mustEmbedUnimplementedCertificateServiceServer mocks base method.
 func (m *MockCertificateServiceServer) mustEmbedUnimplementedCertificateServiceServer() {
 	m.ctrl.T.Helper()
@@ -139,3 +752,122 @@ func (mr *MockUnsafeCertificateServiceServerMockRecorder) mustEmbedUnimplemented
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedCertificateServiceServer", reflect.TypeOf((*MockUnsafeCertificateServiceServer)(nil).mustEmbedUnimplementedCertificateServiceServer))
 }
+
+// MockCertificateService_WatchOperationServer is a mock of CertificateService_WatchOperationServer interface.
+type MockCertificateService_WatchOperationServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCertificateService_WatchOperationServerMockRecorder
+}
+
+// MockCertificateService_WatchOperationServerMockRecorder is the mock recorder for MockCertificateService_WatchOperationServer.
+type MockCertificateService_WatchOperationServerMockRecorder struct {
+	mock *MockCertificateService_WatchOperationServer
+}
+
+// NewMockCertificateService_WatchOperationServer creates a new mock instance.
+func NewMockCertificateService_WatchOperationServer(ctrl *gomock.Controller) *MockCertificateService_WatchOperationServer {
+	mock := &MockCertificateService_WatchOperationServer{ctrl: ctrl}
+	mock.recorder = &MockCertificateService_WatchOperationServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCertificateService_WatchOperationServer) EXPECT() *MockCertificateService_WatchOperationServerMockRecorder {
+	return m.recorder
+}
+
+// Context mocks base method.
+func (m *MockCertificateService_WatchOperationServer) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockCertificateService_WatchOperationServerMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockCertificateService_WatchOperationServer)(nil).Context))
+}
+
+// RecvMsg mocks base method.
+func (m_2 *MockCertificateService_WatchOperationServer) RecvMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "RecvMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockCertificateService_WatchOperationServerMockRecorder) RecvMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockCertificateService_WatchOperationServer)(nil).RecvMsg), m)
+}
+
+// Send mocks base method.
+func (m *MockCertificateService_WatchOperationServer) Send(arg0 *Operation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockCertificateService_WatchOperationServerMockRecorder) Send(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockCertificateService_WatchOperationServer)(nil).Send), arg0)
+}
+
+// SendHeader mocks base method.
+func (m *MockCertificateService_WatchOperationServer) SendHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendHeader indicates an expected call of SendHeader.
+func (mr *MockCertificateService_WatchOperationServerMockRecorder) SendHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendHeader", reflect.TypeOf((*MockCertificateService_WatchOperationServer)(nil).SendHeader), arg0)
+}
+
+// SendMsg mocks base method.
+func (m_2 *MockCertificateService_WatchOperationServer) SendMsg(m interface{}) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SendMsg", m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockCertificateService_WatchOperationServerMockRecorder) SendMsg(m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockCertificateService_WatchOperationServer)(nil).SendMsg), m)
+}
+
+// SetHeader mocks base method.
+func (m *MockCertificateService_WatchOperationServer) SetHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHeader indicates an expected call of SetHeader.
+func (mr *MockCertificateService_WatchOperationServerMockRecorder) SetHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHeader", reflect.TypeOf((*MockCertificateService_WatchOperationServer)(nil).SetHeader), arg0)
+}
+
+// SetTrailer mocks base method.
+func (m *MockCertificateService_WatchOperationServer) SetTrailer(arg0 metadata.MD) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrailer", arg0)
+}
+
+// SetTrailer indicates an expected call of SetTrailer.
+func (mr *MockCertificateService_WatchOperationServerMockRecorder) SetTrailer(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrailer", reflect.TypeOf((*MockCertificateService_WatchOperationServer)(nil).SetTrailer), arg0)
+}
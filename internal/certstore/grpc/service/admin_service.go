@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+
+	certstore_pac "bilalekrem.com/certstore/internal/certstore"
+	"bilalekrem.com/certstore/internal/certstore/approval"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/identity"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ReloadFunc re-reads server config and applies it, implemented by the server serving admin service
+type ReloadFunc func() (*certstore_pac.ReloadResult, error)
+
+type adminService struct {
+	grpc.UnimplementedAdminServiceServer
+
+	certstore certstore_pac.CertStore
+	reload    ReloadFunc
+}
+
+func NewAdminService(certstore certstore_pac.CertStore, reload ReloadFunc) *adminService {
+	return &adminService{
+		certstore: certstore,
+		reload:    reload,
+	}
+}
+
+func (s *adminService) Reload(ctx context.Context, _ *grpc.ReloadRequest) (*grpc.ReloadResponse, error) {
+	err := authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.reload()
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while reloading in grpc service, %v", err)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return &grpc.ReloadResponse{
+		AddedIssuers:   result.Added,
+		UpdatedIssuers: result.Updated,
+		RemovedIssuers: result.Removed,
+	}, nil
+}
+
+func (s *adminService) ListApprovals(ctx context.Context, req *grpc.ListApprovalsRequest) (*grpc.ListApprovalsResponse, error) {
+	err := authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.certstore.ListApprovals(req.Issuer)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while listing approval requests in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	resp := &grpc.ListApprovalsResponse{Requests: []*grpc.ApprovalRequest{}}
+	for _, pending := range requests {
+		resp.Requests = append(resp.Requests, convertApprovalRequest(pending))
+	}
+
+	return resp, nil
+}
+
+func (s *adminService) ApproveRequest(ctx context.Context, req *grpc.ApprovalDecisionRequest) (*grpc.Operation, error) {
+	err := authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := s.certstore.ApproveRequest(ctx, req.OperationId)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while approving request in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return convertOperation(op), nil
+}
+
+func (s *adminService) DenyRequest(ctx context.Context, req *grpc.ApprovalDecisionRequest) (*grpc.Operation, error) {
+	err := authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := s.certstore.DenyRequest(ctx, req.OperationId, req.Reason)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while denying request in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return convertOperation(op), nil
+}
+
+// authorizeAdmin refuses callers which are not admins, server refuses them before calling admin service as well.
+// Requests without a caller are made by server itself
+func authorizeAdmin(ctx context.Context) error {
+	caller, ok := identity.FromContext(ctx)
+	if ok && !caller.Admin {
+		logging.GetLogger().Warnf("Caller is not an admin: [%s]", caller.Name)
+		return status.Errorf(codes.PermissionDenied, "caller is not an admin: [%s]", caller.Name)
+	}
+
+	return nil
+}
+
+func convertApprovalRequest(pending *approval.Request) *grpc.ApprovalRequest {
+	return &grpc.ApprovalRequest{
+		OperationId:     pending.OperationID,
+		Issuer:          pending.Issuer,
+		RequestedBy:     pending.RequestedBy,
+		RequestedAt:     timestamppb.New(pending.RequestedAt),
+		CommonName:      pending.Request.CommonName,
+		SANs:            pending.Request.SubjectAlternativeNames,
+		Email:           pending.Request.Email,
+		Organization:    pending.Request.Organization,
+		ValiditySeconds: int64(pending.Request.ValidityDuration().Seconds()),
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	certstore_pac "bilalekrem.com/certstore/internal/certstore"
+	"bilalekrem.com/certstore/internal/certstore/approval"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/identity"
+	"bilalekrem.com/certstore/internal/certstore/operation"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReload(t *testing.T) {
+	svc := NewAdminService(nil, func() (*certstore_pac.ReloadResult, error) {
+		return &certstore_pac.ReloadResult{Added: []string{"added"}, Updated: []string{}, Removed: []string{"removed"}}, nil
+	})
+
+	resp, err := svc.Reload(context.Background(), &grpc.ReloadRequest{})
+	assert.NotError(t, err, "reloading failed")
+	assert.DeepEqual(t, []string{"added"}, resp.AddedIssuers)
+	assert.DeepEqual(t, []string{"removed"}, resp.RemovedIssuers)
+}
+
+func TestReloadRefused(t *testing.T) {
+	svc := NewAdminService(nil, func() (*certstore_pac.ReloadResult, error) {
+		return nil, errors.New("tls-ca-cert is required argument")
+	})
+
+	_, err := svc.Reload(context.Background(), &grpc.ReloadRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.ErrorContains(t, err, "tls-ca-cert is required argument")
+}
+
+func TestListApprovals(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	requestedAt := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListApprovals(gomock.Eq("issuer")).
+		Return([]*approval.Request{{
+			OperationID: "operation",
+			Issuer:      "issuer",
+			RequestedBy: "agent",
+			RequestedAt: requestedAt,
+			Request: &certificate_service.NewCertificateRequest{
+				CommonName:              "certstore.com",
+				SubjectAlternativeNames: []string{"www.certstore.com"},
+				ExpirationDays:          30,
+			},
+		}}, nil)
+
+	resp, err := NewAdminService(certstore, nil).ListApprovals(context.Background(), &grpc.ListApprovalsRequest{Issuer: "issuer"})
+	assert.NotError(t, err, "listing approval requests failed")
+	assert.Equal(t, 1, len(resp.Requests))
+	assert.Equal(t, "operation", resp.Requests[0].OperationId)
+	assert.Equal(t, "agent", resp.Requests[0].RequestedBy)
+	assert.Equal(t, requestedAt, resp.Requests[0].RequestedAt.AsTime())
+	assert.DeepEqual(t, []string{"www.certstore.com"}, resp.Requests[0].SANs)
+	assert.Equal(t, int64(30*24*60*60), resp.Requests[0].ValiditySeconds)
+}
+
+func TestApproveRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ApproveRequest(gomock.Any(), gomock.Eq("operation")).
+		Return(&operation.Operation{ID: "operation", Issuer: "issuer", State: operation.Pending}, nil)
+
+	resp, err := NewAdminService(certstore, nil).ApproveRequest(context.Background(), &grpc.ApprovalDecisionRequest{OperationId: "operation"})
+	assert.NotError(t, err, "approving request failed")
+	assert.Equal(t, grpc.OperationState_PENDING, resp.State)
+}
+
+func TestDenyRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		DenyRequest(gomock.Any(), gomock.Eq("operation"), gomock.Eq("not expected")).
+		Return(&operation.Operation{
+			ID:        "operation",
+			State:     operation.Failed,
+			Error:     "Request is denied by [admin], reason: [not expected]",
+			ErrorKind: certificate_service.PolicyDeniedErrorKind,
+		}, nil)
+
+	resp, err := NewAdminService(certstore, nil).DenyRequest(context.Background(),
+		&grpc.ApprovalDecisionRequest{OperationId: "operation", Reason: "not expected"})
+	assert.NotError(t, err, "denying request failed")
+	assert.Equal(t, grpc.OperationState_FAILED, resp.State)
+	assert.Equal(t, int32(codes.FailedPrecondition), resp.ErrorCode)
+}
+
+func TestApproveRequestNotAllowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ApproveRequest(gomock.Any(), gomock.Eq("operation")).
+		Return(nil, certificate_service.NewPolicyDeniedError("[agent] is not allowed to decide request [operation] of issuer [issuer]", nil))
+
+	_, err := NewAdminService(certstore, nil).ApproveRequest(context.Background(), &grpc.ApprovalDecisionRequest{OperationId: "operation"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestAdminRequiredForApprovals(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// refused before reaching certstore
+	svc := NewAdminService(certstore_pac.NewMockCertStore(ctrl), nil)
+	ctx := identity.NewContext(context.Background(), identity.Caller{Name: "agent"})
+
+	_, err := svc.ListApprovals(ctx, &grpc.ListApprovalsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = svc.ApproveRequest(ctx, &grpc.ApprovalDecisionRequest{OperationId: "operation"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = svc.DenyRequest(ctx, &grpc.ApprovalDecisionRequest{OperationId: "operation", Reason: "not expected"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	b64 "encoding/base64"
+	"time"
 
 	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
 	certstore_pac "bilalekrem.com/certstore/internal/certstore"
 	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/operation"
+	"bilalekrem.com/certstore/internal/certstore/ratelimit"
 	"bilalekrem.com/certstore/internal/logging"
 )
 
@@ -22,13 +25,13 @@ func NewCertificateService(certstore certstore_pac.CertStore) *certificateServic
 	}
 }
 
-func (s *certificateService) IssueCertificate(_ context.Context, req *grpc.CertificateRequest) (*grpc.CertificateResponse, error) {
+func (s *certificateService) IssueCertificate(ctx context.Context, req *grpc.CertificateRequest) (*grpc.CertificateResponse, error) {
 	certificateRequest := convertServiceRequestInternalRequest(req)
 
-	certificateResponse, err := s.certstore.IssueCertificate(req.Issuer, certificateRequest)
+	certificateResponse, err := s.certstore.IssueCertificate(ctx, req.Issuer, certificateRequest)
 	if err != nil {
 		logging.GetLogger().Debugf("Error occurred while issuing certificate in grpc service, %v", err)
-		return nil, err
+		return nil, toStatusError(err)
 	}
 
 	// ---
@@ -37,15 +40,111 @@ func (s *certificateService) IssueCertificate(_ context.Context, req *grpc.Certi
 	return resp, nil
 }
 
+func (s *certificateService) GetRateLimitBudget(_ context.Context, req *grpc.RateLimitBudgetRequest) (*grpc.RateLimitBudgetResponse, error) {
+	budget, err := s.certstore.GetRateLimitBudget(req.Issuer, req.Domains)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while getting rate limit budget in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	if budget == nil {
+		return &grpc.RateLimitBudgetResponse{Enabled: false}, nil
+	}
+
+	// ---
+
+	resp := &grpc.RateLimitBudgetResponse{
+		Enabled:       true,
+		WindowSeconds: int64(budget.Window.Seconds()),
+		Duplicate:     convertDomainBudget(budget.Duplicate),
+	}
+	for _, domainBudget := range budget.RegisteredDomains {
+		resp.RegisteredDomains = append(resp.RegisteredDomains, convertDomainBudget(domainBudget))
+	}
+
+	return resp, nil
+}
+
+func (s *certificateService) SubmitCertificateRequest(ctx context.Context, req *grpc.CertificateRequest) (*grpc.Operation, error) {
+	certificateRequest := convertServiceRequestInternalRequest(req)
+
+	op, err := s.certstore.SubmitCertificate(ctx, req.Issuer, certificateRequest)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while submitting certificate request in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return convertOperation(op), nil
+}
+
+func (s *certificateService) GetOperation(ctx context.Context, req *grpc.OperationRequest) (*grpc.Operation, error) {
+	op, err := s.getOperation(ctx, req.OperationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertOperation(op), nil
+}
+
+func (s *certificateService) WatchOperation(req *grpc.OperationRequest, stream grpc.CertificateService_WatchOperationServer) error {
+	// watching before getting the operation, not to miss an update in between
+	updates, stop := s.certstore.WatchOperation(req.OperationId)
+	defer stop()
+
+	op, err := s.getOperation(stream.Context(), req.OperationId)
+	if err != nil {
+		return err
+	}
+
+	for {
+		err = stream.Send(convertOperation(op))
+		if err != nil {
+			logging.GetLogger().Debugf("Sending operation update failed in grpc service, %v", err)
+			return err
+		}
+
+		if op.Done() {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return toStatusError(stream.Context().Err())
+		case op = <-updates:
+		}
+
+		// updates do not carry private key, it is claimed once operation is issued
+		if op.State == operation.Issued {
+			op, err = s.getOperation(stream.Context(), req.OperationId)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *certificateService) getOperation(ctx context.Context, id string) (*operation.Operation, error) {
+	op, err := s.certstore.GetOperation(ctx, id)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while getting operation in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return op, nil
+}
+
 // ----
 
 func convertServiceRequestInternalRequest(req *grpc.CertificateRequest) *certificate_service.NewCertificateRequest {
 	return &certificate_service.NewCertificateRequest{
 		CommonName:              req.CommonName,
-		Email:                   []string{req.Email},
-		Organization:            []string{req.Organization},
+		Email:                   optionalValue(req.Email),
+		Organization:            optionalValue(req.Organization),
 		ExpirationDays:          int(req.ExpirationDays),
+		Validity:                time.Duration(req.ValiditySeconds) * time.Second,
 		SubjectAlternativeNames: req.SANs,
+		ForceNew:                req.ForceNew,
+		Priority:                convertPriority(req.Priority),
 	}
 }
 
@@ -58,3 +157,68 @@ func convertInternalResponseToServiceResponse(res *certificate_service.NewCertif
 		PrivateKey:  privateKey,
 	}
 }
+
+var operationStates = map[operation.State]grpc.OperationState{
+	operation.Pending:    grpc.OperationState_PENDING,
+	operation.Validating: grpc.OperationState_VALIDATING,
+	operation.Issued:     grpc.OperationState_ISSUED,
+	operation.Failed:     grpc.OperationState_FAILED,
+
+	operation.AwaitingApproval: grpc.OperationState_AWAITING_APPROVAL,
+}
+
+func convertOperation(op *operation.Operation) *grpc.Operation {
+	resp := &grpc.Operation{
+		Id:     op.ID,
+		Issuer: op.Issuer,
+		State:  operationStates[op.State],
+		Error:  op.Error,
+	}
+
+	if op.State == operation.Failed {
+		resp.ErrorCode = int32(errorCode(op.ErrorKind))
+		resp.RetryAfterSeconds = int64(op.RetryAfter.Seconds())
+	}
+
+	if op.State == operation.Issued {
+		result := &certificate_service.NewCertificateResponse{
+			Certificate: op.Certificate,
+			PrivateKey:  op.PrivateKey,
+			Chain:       op.Chain,
+		}
+
+		resp.Result = convertInternalResponseToServiceResponse(result)
+		resp.ResultV2 = convertInternalResponseToServiceResponseV2(result)
+	}
+
+	return resp
+}
+
+func convertPriority(priority grpc.Priority) certificate_service.Priority {
+	switch priority {
+	case grpc.Priority_HIGH:
+		return certificate_service.PriorityHigh
+	case grpc.Priority_LOW:
+		return certificate_service.PriorityLow
+	}
+
+	return certificate_service.PriorityNormal
+}
+
+// v1 request has single value fields, empty value means not provided
+func optionalValue(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return []string{value}
+}
+
+func convertDomainBudget(budget ratelimit.DomainBudget) *grpc.DomainBudget {
+	return &grpc.DomainBudget{
+		Domain:    budget.Domain,
+		Limit:     int32(budget.Limit),
+		Used:      int32(budget.Used),
+		Remaining: int32(budget.Remaining),
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certificate/x509utils"
+	certstore_pac "bilalekrem.com/certstore/internal/certstore"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"github.com/golang/mock/gomock"
+)
+
+func TestIssueCertificateWithoutOptionalFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		IssueCertificate(gomock.Any(), gomock.Eq("issuer"), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, req *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			assert.Nil(t, req.Email)
+			assert.Nil(t, req.Organization)
+			assert.Equal(t, certificate_service.PriorityNormal, req.Priority)
+
+			return &certificate_service.NewCertificateResponse{}, nil
+		})
+
+	_, err := NewCertificateService(certstore).IssueCertificate(context.Background(), &grpc.CertificateRequest{
+		Issuer:     "issuer",
+		CommonName: "certstore.com",
+	})
+	assert.NotError(t, err, "issuing certificate failed")
+}
+
+func TestIssueCertificateV2(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	leaf, leafPem := createTestCertificate(t, 10)
+	_, issuerPem := createTestCertificate(t, 20)
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		IssueCertificate(gomock.Any(), gomock.Eq("issuer"), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, req *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			assert.Equal(t, "certstore.com", req.CommonName)
+			assert.DeepEqual(t, []string{"unit-a", "unit-b"}, req.OrganizationalUnit)
+			assert.DeepEqual(t, []string{"TR"}, req.Country)
+			assert.DeepEqual(t, []string{"first@certstore.com", "second@certstore.com"}, req.Email)
+			assert.Equal(t, certificate_service.PriorityHigh, req.Priority)
+
+			// issuer bundles its chain into the certificate
+			return &certificate_service.NewCertificateResponse{
+				Certificate: append(append([]byte{}, leafPem...), issuerPem...),
+				PrivateKey:  []byte("test private key"),
+				Chain:       issuerPem,
+			}, nil
+		})
+
+	response, err := NewCertificateService(certstore).IssueCertificateV2(context.Background(), &grpc.CertificateRequestV2{
+		Issuer: "issuer",
+		Subject: &grpc.Subject{
+			CommonName:         "certstore.com",
+			OrganizationalUnit: []string{"unit-a", "unit-b"},
+			Country:            []string{"TR"},
+		},
+		Emails:   []string{"first@certstore.com", "second@certstore.com"},
+		Priority: grpc.Priority_HIGH,
+	})
+	assert.NotError(t, err, "issuing certificate failed")
+
+	assert.DeepEqual(t, leafPem, response.Certificate)
+	assert.DeepEqual(t, issuerPem, response.Chain)
+
+	fingerprint := sha256.Sum256(leaf.Raw)
+	assert.Equal(t, "a", response.Metadata.SerialNumber)
+	assert.DeepEqual(t, fingerprint[:], response.Metadata.Fingerprint)
+	assert.Equal(t, leaf.NotAfter, response.Metadata.NotAfter.AsTime())
+	assert.Equal(t, "CN=test issuer", response.Metadata.Issuer)
+}
+
+// ----
+
+func createTestCertificate(t *testing.T, serialNumber int64) (*x509.Certificate, []byte) {
+	notBefore := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	certTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: "test issuer"},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.AddDate(0, 0, 90),
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NotError(t, err, "generating private key failed")
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, &privateKey.PublicKey, privateKey)
+	assert.NotError(t, err, "creating certificate failed")
+
+	certificate, err := x509.ParseCertificate(certBytes)
+	assert.NotError(t, err, "parsing certificate failed")
+
+	return certificate, x509utils.EncodePEMCert(certBytes).Bytes()
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certificate/x509utils"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *certificateService) IssueCertificateV2(ctx context.Context, req *grpc.CertificateRequestV2) (*grpc.CertificateResponseV2, error) {
+	certificateRequest := convertServiceRequestV2InternalRequest(req)
+
+	certificateResponse, err := s.certstore.IssueCertificate(ctx, req.Issuer, certificateRequest)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while issuing certificate in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return convertInternalResponseToServiceResponseV2(certificateResponse), nil
+}
+
+func (s *certificateService) SubmitCertificateRequestV2(ctx context.Context, req *grpc.CertificateRequestV2) (*grpc.Operation, error) {
+	certificateRequest := convertServiceRequestV2InternalRequest(req)
+
+	op, err := s.certstore.SubmitCertificate(ctx, req.Issuer, certificateRequest)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while submitting certificate request in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return convertOperation(op), nil
+}
+
+// ----
+
+func convertServiceRequestV2InternalRequest(req *grpc.CertificateRequestV2) *certificate_service.NewCertificateRequest {
+	subject := req.Subject
+	if subject == nil {
+		subject = &grpc.Subject{}
+	}
+
+	return &certificate_service.NewCertificateRequest{
+		CommonName:              subject.CommonName,
+		Email:                   req.Emails,
+		Organization:            subject.Organization,
+		OrganizationalUnit:      subject.OrganizationalUnit,
+		Locality:                subject.Locality,
+		Province:                subject.Province,
+		Country:                 subject.Country,
+		StreetAddress:           subject.StreetAddress,
+		PostalCode:              subject.PostalCode,
+		SerialNumber:            subject.SerialNumber,
+		ExpirationDays:          int(req.ExpirationDays),
+		Validity:                time.Duration(req.ValiditySeconds) * time.Second,
+		SubjectAlternativeNames: req.SANs,
+		ForceNew:                req.ForceNew,
+		Priority:                convertPriority(req.Priority),
+	}
+}
+
+// certificate of the response contains only the leaf certificate, even if the issuer bundles its chain
+func convertInternalResponseToServiceResponseV2(res *certificate_service.NewCertificateResponse) *grpc.CertificateResponseV2 {
+	resp := &grpc.CertificateResponseV2{
+		Certificate: res.Certificate,
+		PrivateKey:  res.PrivateKey,
+		Chain:       res.Chain,
+	}
+
+	leaf, _ := pem.Decode(res.Certificate)
+	if leaf == nil {
+		logging.GetLogger().Warnf("issued certificate is not PEM encoded, metadata is not available")
+		return resp
+	}
+	resp.Certificate = pem.EncodeToMemory(leaf)
+
+	certificate, err := x509utils.ParsePemCertificate(resp.Certificate)
+	if err != nil {
+		logging.GetLogger().Warnf("parsing issued certificate failed, metadata is not available, %v", err)
+		return resp
+	}
+
+	fingerprint := sha256.Sum256(certificate.Raw)
+	resp.Metadata = &grpc.CertificateMetadata{
+		SerialNumber: fmt.Sprintf("%x", certificate.SerialNumber),
+		NotBefore:    timestamppb.New(certificate.NotBefore),
+		NotAfter:     timestamppb.New(certificate.NotAfter),
+		Fingerprint:  fingerprint[:],
+		Issuer:       certificate.Issuer.String(),
+	}
+
+	return resp
+}
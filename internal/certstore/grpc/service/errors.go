@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	ERROR_INFO_DOMAIN = "certstore"
+)
+
+var errorKindCodes = map[certificate_service.ErrorKind]codes.Code{
+	certificate_service.ValidationErrorKind:         codes.InvalidArgument,
+	certificate_service.NotFoundErrorKind:           codes.NotFound,
+	certificate_service.PolicyDeniedErrorKind:       codes.FailedPrecondition,
+	certificate_service.BackendUnavailableErrorKind: codes.Unavailable,
+	certificate_service.RateLimitedErrorKind:        codes.ResourceExhausted,
+}
+
+// toStatusError converts errors of certificate services to grpc status errors, with error details
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	} else if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	serviceError := certificate_service.AsError(err)
+	if serviceError == nil {
+		return status.Error(codes.Unknown, err.Error())
+	}
+
+	st := status.New(errorCode(serviceError.Kind), err.Error())
+	details := errorDetails(serviceError.Kind, serviceError.FieldViolations, serviceError.RetryAfter)
+
+	detailed, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		logging.GetLogger().Warnf("adding details to grpc status failed, %v", detailsErr)
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+func errorCode(kind certificate_service.ErrorKind) codes.Code {
+	code, exist := errorKindCodes[kind]
+	if !exist {
+		return codes.Unknown
+	}
+
+	return code
+}
+
+// details are in the message form grpc status accepts, generated messages implement it
+func errorDetails(kind certificate_service.ErrorKind, violations []certificate_service.FieldViolation,
+	retryAfter time.Duration) []protoiface.MessageV1 {
+
+	details := []protoiface.MessageV1{&errdetails.ErrorInfo{Reason: string(kind), Domain: ERROR_INFO_DOMAIN}}
+
+	if len(violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+
+		details = append(details, badRequest)
+	}
+
+	if retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+
+	return details
+}
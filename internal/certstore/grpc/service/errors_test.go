@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/operation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatusErrorValidation(t *testing.T) {
+	err := toStatusError(certificate_service.NewValidationError("commonName", "common name is required"))
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "Validation error: common name is required", st.Message())
+
+	var badRequest *errdetails.BadRequest
+	var errorInfo *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			badRequest = d
+		case *errdetails.ErrorInfo:
+			errorInfo = d
+		}
+	}
+
+	assert.NotNil(t, errorInfo)
+	assert.Equal(t, "validation", errorInfo.Reason)
+	assert.Equal(t, ERROR_INFO_DOMAIN, errorInfo.Domain)
+
+	assert.NotNil(t, badRequest)
+	assert.Equal(t, 1, len(badRequest.FieldViolations))
+	assert.Equal(t, "commonName", badRequest.FieldViolations[0].Field)
+}
+
+func TestToStatusErrorRateLimited(t *testing.T) {
+	cause := certificate_service.NewRateLimitedError("Rate limit exceeded", time.Hour, errors.New("test"))
+	err := toStatusError(fmt.Errorf("issuing failed, %w", cause))
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = d
+		}
+	}
+
+	assert.NotNil(t, retryInfo)
+	assert.Equal(t, time.Hour, retryInfo.RetryDelay.AsDuration())
+}
+
+func TestToStatusErrorCodes(t *testing.T) {
+	assert.Nil(t, toStatusError(nil))
+	assert.Equal(t, codes.Unknown, status.Code(toStatusError(errors.New("untyped"))))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(toStatusError(context.DeadlineExceeded)))
+	assert.Equal(t, codes.Canceled, status.Code(toStatusError(context.Canceled)))
+
+	assert.Equal(t, codes.NotFound, status.Code(toStatusError(certificate_service.NewNotFoundError("test", nil))))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(toStatusError(certificate_service.NewPolicyDeniedError("test", nil))))
+	assert.Equal(t, codes.Unavailable, status.Code(toStatusError(certificate_service.NewBackendUnavailableError("test", nil))))
+}
+
+func TestConvertFailedOperation(t *testing.T) {
+	op := &operation.Operation{
+		ID:         "operation-id",
+		State:      operation.Failed,
+		Error:      "Rate limit exceeded",
+		ErrorKind:  certificate_service.RateLimitedErrorKind,
+		RetryAfter: time.Minute,
+	}
+
+	converted := convertOperation(op)
+	assert.Equal(t, int32(codes.ResourceExhausted), converted.ErrorCode)
+	assert.Equal(t, int64(60), converted.RetryAfterSeconds)
+
+	// untyped failure
+	op.ErrorKind = ""
+	op.RetryAfter = 0
+	converted = convertOperation(op)
+	assert.Equal(t, int32(codes.Unknown), converted.ErrorCode)
+	assert.Equal(t, int64(0), converted.RetryAfterSeconds)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *certificateService) ListCertificates(ctx context.Context, req *grpc.ListCertificatesRequest) (*grpc.ListCertificatesResponse, error) {
+	certificates, err := s.certstore.ListCertificates(ctx, req.Issuer)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while listing certificates in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	resp := &grpc.ListCertificatesResponse{}
+	for _, certificate := range certificates {
+		resp.Certificates = append(resp.Certificates, convertInventoryCertificate(certificate))
+	}
+
+	return resp, nil
+}
+
+func (s *certificateService) GetCertificate(ctx context.Context, req *grpc.GetCertificateRequest) (*grpc.InventoryCertificate, error) {
+	certificate, err := s.certstore.GetCertificate(ctx, req.Issuer, req.SerialNumber)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while getting certificate in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return convertInventoryCertificate(certificate), nil
+}
+
+func (s *certificateService) RevokeCertificate(ctx context.Context, req *grpc.RevokeCertificateRequest) (*grpc.InventoryCertificate, error) {
+	if _, exist := grpc.RevocationReason_name[int32(req.Reason)]; !exist {
+		return nil, toStatusError(certificate_service.NewValidationError("reason",
+			fmt.Sprintf("unsupported revocation reason: [%d]", req.Reason)))
+	}
+
+	certificate, err := s.certstore.RevokeCertificate(ctx, req.Issuer, req.SerialNumber,
+		certificate_service.RevocationReason(req.Reason))
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while revoking certificate in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return convertInventoryCertificate(certificate), nil
+}
+
+// ----
+
+// private key is left out, it is never served
+func convertInventoryCertificate(certificate *inventory.Certificate) *grpc.InventoryCertificate {
+	resp := &grpc.InventoryCertificate{
+		Issuer:           certificate.Issuer,
+		SerialNumber:     certificate.SerialNumber,
+		IssuedAt:         timestamppb.New(certificate.IssuedAt),
+		NotBefore:        timestamppb.New(certificate.NotBefore),
+		NotAfter:         timestamppb.New(certificate.NotAfter),
+		CommonName:       certificate.CommonName,
+		SANs:             certificate.SubjectAlternativeNames,
+		Certificate:      certificate.Certificate,
+		Chain:            certificate.Chain,
+		Revoked:          certificate.Revoked(),
+		RevocationReason: grpc.RevocationReason(certificate.RevocationReason),
+	}
+
+	if certificate.Revoked() {
+		resp.RevokedAt = timestamppb.New(certificate.RevokedAt)
+	}
+
+	return resp
+}
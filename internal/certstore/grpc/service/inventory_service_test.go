@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	certstore_pac "bilalekrem.com/certstore/internal/certstore"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListCertificates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListCertificates(gomock.Any(), gomock.Eq("issuer")).
+		Return([]*inventory.Certificate{
+			{Issuer: "issuer", SerialNumber: "01", CommonName: "certstore.com", PrivateKey: []byte("private-key")},
+		}, nil)
+
+	resp, err := NewCertificateService(certstore).ListCertificates(context.Background(), &grpc.ListCertificatesRequest{Issuer: "issuer"})
+	assert.NotError(t, err, "listing certificates failed")
+	assert.Equal(t, 1, len(resp.Certificates))
+	assert.Equal(t, "01", resp.Certificates[0].SerialNumber)
+	assert.Equal(t, "certstore.com", resp.Certificates[0].CommonName)
+	assert.False(t, resp.Certificates[0].Revoked)
+}
+
+func TestGetCertificateNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		GetCertificate(gomock.Any(), gomock.Eq("issuer"), gomock.Eq("01")).
+		Return(nil, certificate_service.NewNotFoundError("Certificate not found: [issuer/01]", nil))
+
+	_, err := NewCertificateService(certstore).GetCertificate(context.Background(),
+		&grpc.GetCertificateRequest{Issuer: "issuer", SerialNumber: "01"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestRevokeCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	revokedAt := time.Now()
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		RevokeCertificate(gomock.Any(), gomock.Eq("issuer"), gomock.Eq("01"), gomock.Eq(certificate_service.RevocationKeyCompromise)).
+		Return(&inventory.Certificate{
+			Issuer:           "issuer",
+			SerialNumber:     "01",
+			RevokedAt:        revokedAt,
+			RevocationReason: certificate_service.RevocationKeyCompromise,
+		}, nil)
+
+	resp, err := NewCertificateService(certstore).RevokeCertificate(context.Background(),
+		&grpc.RevokeCertificateRequest{Issuer: "issuer", SerialNumber: "01", Reason: grpc.RevocationReason_KEY_COMPROMISE})
+	assert.NotError(t, err, "revoking certificate failed")
+	assert.True(t, resp.Revoked)
+	assert.Equal(t, grpc.RevocationReason_KEY_COMPROMISE, resp.RevocationReason)
+	assert.True(t, resp.RevokedAt.AsTime().Equal(revokedAt))
+}
+
+func TestRevokeCertificateUnknownReason(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+
+	_, err := NewCertificateService(certstore).RevokeCertificate(context.Background(),
+		&grpc.RevokeCertificateRequest{Issuer: "issuer", SerialNumber: "01", Reason: grpc.RevocationReason(2)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+)
+
+func (s *certificateService) ListIssuers(_ context.Context, _ *grpc.ListIssuersRequest) (*grpc.ListIssuersResponse, error) {
+	resp := &grpc.ListIssuersResponse{}
+	for _, issuer := range s.certstore.ListIssuers() {
+		capabilities := issuer.Capabilities
+		resp.Issuers = append(resp.Issuers, &grpc.Issuer{
+			Name:            issuer.Name,
+			Type:            issuer.Type,
+			KeyTypes:        capabilities.KeyTypes,
+			MinValidityDays: int32(capabilities.MinValidityDays),
+			MaxValidityDays: int32(capabilities.MaxValidityDays),
+			SupportedFields: capabilities.SupportedFields,
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	certstore_pac "bilalekrem.com/certstore/internal/certstore"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"github.com/golang/mock/gomock"
+)
+
+func TestListIssuers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pac.IssuerInfo{
+			{
+				Name: "lets-encrypt",
+				Type: "LetsEncrypt",
+				Capabilities: certificate_service.Capabilities{
+					KeyTypes:        []string{certificate_service.KEY_TYPE_RSA_2048},
+					MinValidityDays: 90,
+					MaxValidityDays: 90,
+					SupportedFields: []string{certificate_service.FIELD_COMMON_NAME, certificate_service.FIELD_SANS},
+				},
+			},
+		})
+
+	resp, err := NewCertificateService(certstore).ListIssuers(context.Background(), &grpc.ListIssuersRequest{})
+	assert.NotError(t, err, "listing issuers failed")
+	assert.Equal(t, 1, len(resp.Issuers))
+
+	issuer := resp.Issuers[0]
+	assert.Equal(t, "lets-encrypt", issuer.Name)
+	assert.Equal(t, "LetsEncrypt", issuer.Type)
+	assert.Equal(t, int32(90), issuer.MaxValidityDays)
+	assert.DeepEqual(t, []string{"commonName", "sans"}, issuer.SupportedFields)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *certificateService) IssueSSHCertificate(ctx context.Context, req *grpc.SSHCertificateRequest) (*grpc.SSHCertificateResponse, error) {
+	certificateType := certificate_service.SSHUserCertificate
+	if req.Type == grpc.SSHCertificateType_HOST {
+		certificateType = certificate_service.SSHHostCertificate
+	}
+
+	certificateResponse, err := s.certstore.IssueSSHCertificate(ctx, req.Issuer, &certificate_service.NewSSHCertificateRequest{
+		PublicKey:       req.PublicKey,
+		Type:            certificateType,
+		KeyID:           req.KeyId,
+		Principals:      req.Principals,
+		Validity:        time.Duration(req.ValiditySeconds) * time.Second,
+		CriticalOptions: req.CriticalOptions,
+		Extensions:      req.Extensions,
+	})
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while issuing ssh certificate in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return &grpc.SSHCertificateResponse{
+		Certificate: certificateResponse.Certificate,
+		Serial:      certificateResponse.Serial,
+		ValidAfter:  timestamppb.New(certificateResponse.ValidAfter),
+		ValidBefore: timestamppb.New(certificateResponse.ValidBefore),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	certstore_pac "bilalekrem.com/certstore/internal/certstore"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIssueSSHCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	validAfter := time.Unix(1700000000, 0)
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		IssueSSHCertificate(gomock.Any(), "ssh-ca", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, request *certificate_service.NewSSHCertificateRequest) (*certificate_service.NewSSHCertificateResponse, error) {
+			assert.Equal(t, certificate_service.SSHHostCertificate, request.Type)
+			assert.Equal(t, 6*time.Hour, request.Validity)
+			assert.DeepEqual(t, []string{"web.certstore.com"}, request.Principals)
+
+			return &certificate_service.NewSSHCertificateResponse{
+				Certificate: []byte("ssh-ed25519-cert-v01@openssh.com AAAA"),
+				Serial:      42,
+				ValidAfter:  validAfter,
+				ValidBefore: validAfter.Add(request.Validity),
+			}, nil
+		})
+
+	resp, err := NewCertificateService(certstore).IssueSSHCertificate(context.Background(), &grpc.SSHCertificateRequest{
+		Issuer:          "ssh-ca",
+		PublicKey:       []byte("ssh-ed25519 AAAA"),
+		Type:            grpc.SSHCertificateType_HOST,
+		Principals:      []string{"web.certstore.com"},
+		ValiditySeconds: int64((6 * time.Hour).Seconds()),
+	})
+	assert.NotError(t, err, "issuing ssh certificate failed")
+	assert.Equal(t, uint64(42), resp.Serial)
+	assert.Equal(t, validAfter.Unix(), resp.ValidAfter.AsTime().Unix())
+	assert.Equal(t, "ssh-ed25519-cert-v01@openssh.com AAAA", string(resp.Certificate))
+}
+
+func TestIssueSSHCertificateNotSSHIssuer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		IssueSSHCertificate(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, certificate_service.NewValidationError("issuer", "issuer does not sign SSH certificates"))
+
+	_, err := NewCertificateService(certstore).IssueSSHCertificate(context.Background(), &grpc.SSHCertificateRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+func (s *certificateService) GetSignedTreeHead(_ context.Context, _ *grpc.SignedTreeHeadRequest) (*grpc.SignedTreeHead, error) {
+	head, err := s.certstore.SignedTreeHead()
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while getting signed tree head in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return &grpc.SignedTreeHead{
+		TreeSize:  head.TreeSize,
+		Timestamp: uint64(head.Timestamp.UnixMilli()),
+		RootHash:  head.RootHash,
+		Signature: head.Signature,
+	}, nil
+}
+
+func (s *certificateService) GetInclusionProof(_ context.Context, req *grpc.InclusionProofRequest) (*grpc.InclusionProofResponse, error) {
+	proof, err := s.certstore.InclusionProof(req.LeafHash, req.TreeSize)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while getting inclusion proof in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return &grpc.InclusionProofResponse{
+		LeafIndex: proof.LeafIndex,
+		TreeSize:  proof.TreeSize,
+		AuditPath: proof.AuditPath,
+	}, nil
+}
+
+func (s *certificateService) GetConsistencyProof(_ context.Context, req *grpc.ConsistencyProofRequest) (*grpc.ConsistencyProofResponse, error) {
+	proof, err := s.certstore.ConsistencyProof(req.FirstTreeSize, req.SecondTreeSize)
+	if err != nil {
+		logging.GetLogger().Debugf("Error occurred while getting consistency proof in grpc service, %v", err)
+		return nil, toStatusError(err)
+	}
+
+	return &grpc.ConsistencyProofResponse{Proof: proof}, nil
+}
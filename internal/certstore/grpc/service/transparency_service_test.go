@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	certstore_pac "bilalekrem.com/certstore/internal/certstore"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetSignedTreeHead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timestamp := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		SignedTreeHead().
+		Return(&transparency.SignedTreeHead{
+			TreeSize:  3,
+			Timestamp: timestamp,
+			RootHash:  []byte("root hash"),
+			Signature: []byte("signature"),
+		}, nil)
+
+	resp, err := NewCertificateService(certstore).GetSignedTreeHead(context.Background(), &grpc.SignedTreeHeadRequest{})
+	assert.NotError(t, err, "getting signed tree head failed")
+	assert.Equal(t, uint64(3), resp.TreeSize)
+	assert.Equal(t, uint64(timestamp.UnixMilli()), resp.Timestamp)
+	assert.DeepEqual(t, []byte("root hash"), resp.RootHash)
+	assert.DeepEqual(t, []byte("signature"), resp.Signature)
+}
+
+func TestGetInclusionProofNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		InclusionProof(gomock.Eq([]byte("leaf hash")), gomock.Eq(uint64(3))).
+		Return(nil, certificate_service.NewNotFoundError("Certificate not found in transparency log", nil))
+
+	_, err := NewCertificateService(certstore).GetInclusionProof(context.Background(),
+		&grpc.InclusionProofRequest{LeafHash: []byte("leaf hash"), TreeSize: 3})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGetConsistencyProof(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pac.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ConsistencyProof(gomock.Eq(uint64(2)), gomock.Eq(uint64(5))).
+		Return([][]byte{[]byte("first"), []byte("second")}, nil)
+
+	resp, err := NewCertificateService(certstore).GetConsistencyProof(context.Background(),
+		&grpc.ConsistencyProofRequest{FirstTreeSize: 2, SecondTreeSize: 5})
+	assert.NotError(t, err, "getting consistency proof failed")
+	assert.Equal(t, 2, len(resp.Proof))
+}
@@ -0,0 +1,43 @@
+package identity
+
+import (
+	"context"
+)
+
+// Caller of a request received by server, identified by common name of its client certificate
+type Caller struct {
+	// empty if client certificate has no common name
+	Name string
+
+	// listed in admin-identities of server config, admins can call admin rpcs and act on requests of others
+	Admin bool
+}
+
+type callerKey struct{}
+
+func NewContext(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// FromContext returns false if ctx has no caller, e.g. reload on SIGHUP, requests received by server always have one
+func FromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// Name returns empty string if the caller is not known
+func Name(ctx context.Context) string {
+	caller, _ := FromContext(ctx)
+	return caller.Name
+}
+
+// Authorized tells if caller may act on a resource of owner, e.g. an operation or a certificate it requested.
+// Admins act on any resource, requests without a caller are made by server itself
+func Authorized(ctx context.Context, owner string) bool {
+	caller, ok := FromContext(ctx)
+	if !ok || caller.Admin {
+		return true
+	}
+
+	return caller.Name != "" && caller.Name == owner
+}
@@ -0,0 +1,35 @@
+package identity
+
+import (
+	"context"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, "", Name(context.Background()))
+
+	ctx := NewContext(context.Background(), Caller{Name: "admin", Admin: true})
+	caller, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "admin", caller.Name)
+	assert.True(t, caller.Admin)
+	assert.Equal(t, "admin", Name(ctx))
+}
+
+func TestAuthorized(t *testing.T) {
+	assert.True(t, Authorized(context.Background(), "agent-1"))
+
+	agent := NewContext(context.Background(), Caller{Name: "agent-1"})
+	assert.True(t, Authorized(agent, "agent-1"))
+	assert.False(t, Authorized(agent, "agent-2"))
+
+	anonymous := NewContext(context.Background(), Caller{})
+	assert.False(t, Authorized(anonymous, ""))
+
+	admin := NewContext(context.Background(), Caller{Name: "admin", Admin: true})
+	assert.True(t, Authorized(admin, "agent-2"))
+}
@@ -0,0 +1,497 @@
+package inventory
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certificate/x509utils"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	STORAGE_BUCKET = "inventory"
+)
+
+// ReusePolicy allows returning an existing certificate for identical requests, instead of issuing a new one
+type ReusePolicy struct {
+	// existing certificate is reused only if it is valid for at least this many days
+	MinRemainingDays int `yaml:"min-remaining-days"`
+}
+
+type Certificate struct {
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial-number"`
+	IssuedAt     time.Time `json:"issued-at"`
+	NotBefore    time.Time `json:"not-before"`
+	NotAfter     time.Time `json:"not-after"`
+
+	// identity of the caller requested the certificate, empty if requested by server itself or before it is recorded
+	RequestedBy string `json:"requested-by,omitempty"`
+
+	CommonName              string   `json:"common-name"`
+	SubjectAlternativeNames []string `json:"sans"`
+	Email                   []string `json:"email"`
+	Organization            []string `json:"organization"`
+
+	OrganizationalUnit  []string `json:"organizational-unit,omitempty"`
+	Locality            []string `json:"locality,omitempty"`
+	Province            []string `json:"province,omitempty"`
+	Country             []string `json:"country,omitempty"`
+	StreetAddress       []string `json:"street-address,omitempty"`
+	PostalCode          []string `json:"postal-code,omitempty"`
+	SubjectSerialNumber string   `json:"subject-serial-number,omitempty"`
+
+	// all encoded in PEM format, private key is kept only for issuers with reuse policy
+	Certificate []byte `json:"certificate"`
+	Chain       []byte `json:"chain,omitempty"`
+	PrivateKey  []byte `json:"private-key,omitempty"`
+
+	// zero if certificate is not revoked
+	RevokedAt        time.Time                `json:"revoked-at,omitempty"`
+	RevocationReason service.RevocationReason `json:"revocation-reason,omitempty"`
+}
+
+func (c *Certificate) Revoked() bool {
+	return !c.RevokedAt.IsZero()
+}
+
+type TimeProvider func() time.Time
+
+// Inventory keeps certificates issued by the server
+type Inventory struct {
+	storage      storage.Storage
+	timeProvider TimeProvider
+
+	// certificates neither revoked nor expired, by key and by subject. Loaded from storage on first use and kept up
+	// to date on add and revoke, so that lookups on every issuance do not decode the whole inventory
+	mutex    sync.Mutex
+	index    map[string]*indexed
+	subjects map[string][]*indexed
+}
+
+// indexed is what lookups need to know of a certificate before reading it from storage
+type indexed struct {
+	key      string
+	subject  string
+	issuedAt time.Time
+	notAfter time.Time
+}
+
+func New(storage storage.Storage) *Inventory {
+	return NewWithTimeProvider(storage, time.Now)
+}
+
+func NewWithTimeProvider(storage storage.Storage, timeProvider TimeProvider) *Inventory {
+	return &Inventory{storage: storage, timeProvider: timeProvider}
+}
+
+// SerialNumber returns the hex encoded serial number of a PEM encoded certificate, the way certificates are keyed
+// in inventory
+func SerialNumber(certificate []byte) (string, error) {
+	x509Certificate, err := x509utils.ParsePemCertificate(certificate)
+	if err != nil {
+		return "", err
+	}
+
+	return hexSerialNumber(x509Certificate), nil
+}
+
+func hexSerialNumber(certificate *x509.Certificate) string {
+	return fmt.Sprintf("%x", certificate.SerialNumber)
+}
+
+func (i *Inventory) Add(issuer string, request *service.NewCertificateRequest, response *service.NewCertificateResponse,
+	requestedBy string, keepPrivateKey bool) (*Certificate, error) {
+
+	x509Certificate, err := x509utils.ParsePemCertificate(response.Certificate)
+	if err != nil {
+		logging.GetLogger().Errorf("parsing issued certificate for inventory failed, %v", err)
+		return nil, err
+	}
+
+	certificate := &Certificate{
+		Issuer:                  issuer,
+		SerialNumber:            hexSerialNumber(x509Certificate),
+		IssuedAt:                i.timeProvider(),
+		NotBefore:               x509Certificate.NotBefore,
+		NotAfter:                x509Certificate.NotAfter,
+		RequestedBy:             requestedBy,
+		CommonName:              request.CommonName,
+		SubjectAlternativeNames: request.SubjectAlternativeNames,
+		Email:                   request.Email,
+		Organization:            request.Organization,
+		OrganizationalUnit:      request.OrganizationalUnit,
+		Locality:                request.Locality,
+		Province:                request.Province,
+		Country:                 request.Country,
+		StreetAddress:           request.StreetAddress,
+		PostalCode:              request.PostalCode,
+		SubjectSerialNumber:     request.SerialNumber,
+		Certificate:             response.Certificate,
+		Chain:                   response.Chain,
+	}
+	if keepPrivateKey {
+		certificate.PrivateKey = response.PrivateKey
+	}
+
+	err = i.put(certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	i.mutex.Lock()
+	if i.index != nil {
+		i.add(certificate)
+	}
+	i.mutex.Unlock()
+
+	return certificate, nil
+}
+
+// Revoke records the revocation of the certificate, revoked certificates are not reused. Returns
+// storage.ErrNotFound if there is no such certificate
+func (i *Inventory) Revoke(issuer string, serialNumber string, reason service.RevocationReason) (*Certificate, error) {
+	certificate, err := i.Get(issuer, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate.RevokedAt = i.timeProvider()
+	certificate.RevocationReason = reason
+	err = i.put(certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	i.mutex.Lock()
+	if entry, exist := i.index[key(issuer, serialNumber)]; exist {
+		i.remove(entry)
+	}
+	i.mutex.Unlock()
+
+	return certificate, nil
+}
+
+func (i *Inventory) Get(issuer string, serialNumber string) (*Certificate, error) {
+	return i.get(key(issuer, serialNumber))
+}
+
+// List returns all certificates in inventory ordered by issue time
+func (i *Inventory) List() ([]*Certificate, error) {
+	values, err := i.storage.List(STORAGE_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := []*Certificate{}
+	for key, value := range values {
+		certificate := &Certificate{}
+		err = json.Unmarshal(value, certificate)
+		if err != nil {
+			logging.GetLogger().Warnf("decoding inventory certificate failed, key: [%s], %v", key, err)
+			continue
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	sort.Slice(certificates, func(a, b int) bool {
+		return certificates[a].IssuedAt.Before(certificates[b].IssuedAt)
+	})
+	return certificates, nil
+}
+
+// FindReusable returns the latest certificate of the issuer with same subject and names as the request, which is
+// requested by the same requester and valid for at least given days but not longer than the requested validity.
+// Private key of a certificate is not handed to others. Returns storage.ErrNotFound if there is no such certificate
+func (i *Inventory) FindReusable(issuer string, request *service.NewCertificateRequest, requestedBy string,
+	minRemainingDays int) (*Certificate, error) {
+
+	candidates, err := i.sameSubject(issuer, request)
+	if err != nil {
+		return nil, err
+	}
+
+	now := i.timeProvider()
+	validUntil := now.AddDate(0, 0, minRemainingDays)
+	requestedValidity := request.ValidityDuration()
+	for _, candidate := range candidates {
+		// a certificate valid longer than requested is not returned, e.g. a short lived one is requested
+		if !candidate.notAfter.After(validUntil) ||
+			(requestedValidity > 0 && candidate.notAfter.Sub(now) > requestedValidity) {
+			continue
+		}
+
+		certificate, err := i.get(candidate.key)
+		if err != nil {
+			return nil, err
+		} else if len(certificate.PrivateKey) == 0 || certificate.RequestedBy != requestedBy {
+			continue
+		}
+
+		return certificate, nil
+	}
+
+	return nil, storage.ErrNotFound
+}
+
+// FindPrevious returns the latest certificate of the issuer with same subject and names as the request, which is
+// neither expired nor revoked, e.g. the certificate a new one renews. Returns storage.ErrNotFound if there is no
+// such certificate
+func (i *Inventory) FindPrevious(issuer string, request *service.NewCertificateRequest) (*Certificate, error) {
+	candidates, err := i.sameSubject(issuer, request)
+	if err != nil {
+		return nil, err
+	} else if len(candidates) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return i.get(candidates[0].key)
+}
+
+// ListExpiring returns certificates expiring within the duration, ordered by issue time. Revoked certificates, and
+// the ones renewed by a certificate of the issuer with the same subject and names expiring later, are left out
+func (i *Inventory) ListExpiring(within time.Duration) ([]*Certificate, error) {
+	i.mutex.Lock()
+	err := i.loadIndex()
+	if err != nil {
+		i.mutex.Unlock()
+		return nil, err
+	}
+
+	now := i.timeProvider()
+	i.removeExpired(now)
+
+	keys := []string{}
+	for _, entry := range i.index {
+		if !entry.notAfter.After(now.Add(within)) && !i.renewed(entry) {
+			keys = append(keys, entry.key)
+		}
+	}
+	i.mutex.Unlock()
+
+	expiring := []*Certificate{}
+	for _, certificateKey := range keys {
+		certificate, err := i.get(certificateKey)
+		if err != nil {
+			return nil, err
+		}
+		expiring = append(expiring, certificate)
+	}
+
+	sort.Slice(expiring, func(a, b int) bool {
+		return expiring[a].IssuedAt.Before(expiring[b].IssuedAt)
+	})
+	return expiring, nil
+}
+
+// EarliestExpiry returns the certificate expiring first among the ones not expired or revoked yet. Returns
+// storage.ErrNotFound if there is no such certificate
+func (i *Inventory) EarliestExpiry() (*Certificate, error) {
+	i.mutex.Lock()
+	err := i.loadIndex()
+	if err != nil {
+		i.mutex.Unlock()
+		return nil, err
+	}
+
+	i.removeExpired(i.timeProvider())
+
+	var earliest *indexed
+	for _, entry := range i.index {
+		if earliest == nil || entry.notAfter.Before(earliest.notAfter) {
+			earliest = entry
+		}
+	}
+	i.mutex.Unlock()
+
+	if earliest == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	return i.get(earliest.key)
+}
+
+// ----
+
+func (i *Inventory) put(certificate *Certificate) error {
+	value, err := json.Marshal(certificate)
+	if err != nil {
+		return err
+	}
+
+	err = i.storage.Put(STORAGE_BUCKET, key(certificate.Issuer, certificate.SerialNumber), value)
+	if err != nil {
+		logging.GetLogger().Errorf("saving certificate to inventory failed, %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (i *Inventory) get(certificateKey string) (*Certificate, error) {
+	value, err := i.storage.Get(STORAGE_BUCKET, certificateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate := &Certificate{}
+	err = json.Unmarshal(value, certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return certificate, nil
+}
+
+// sameSubject returns the certificates of the issuer neither revoked nor expired, with same subject and names as
+// the request, latest issued first
+func (i *Inventory) sameSubject(issuer string, request *service.NewCertificateRequest) ([]*indexed, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	err := i.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	i.removeExpired(i.timeProvider())
+
+	candidates := append([]*indexed{}, i.subjects[requestSubject(issuer, request)]...)
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].issuedAt.After(candidates[b].issuedAt)
+	})
+	return candidates, nil
+}
+
+// loadIndex is called while holding the mutex
+func (i *Inventory) loadIndex() error {
+	if i.index != nil {
+		return nil
+	}
+
+	certificates, err := i.List()
+	if err != nil {
+		return err
+	}
+
+	i.index = make(map[string]*indexed)
+	i.subjects = make(map[string][]*indexed)
+
+	now := i.timeProvider()
+	for _, certificate := range certificates {
+		if certificate.NotAfter.After(now) && !certificate.Revoked() {
+			i.add(certificate)
+		}
+	}
+
+	return nil
+}
+
+// add, remove, removeExpired and renewed are called while holding the mutex, with the index loaded
+func (i *Inventory) add(certificate *Certificate) {
+	entry := &indexed{
+		key:      key(certificate.Issuer, certificate.SerialNumber),
+		subject:  certificateSubject(certificate),
+		issuedAt: certificate.IssuedAt,
+		notAfter: certificate.NotAfter,
+	}
+
+	i.index[entry.key] = entry
+	i.subjects[entry.subject] = append(i.subjects[entry.subject], entry)
+}
+
+func (i *Inventory) remove(entry *indexed) {
+	delete(i.index, entry.key)
+
+	remaining := []*indexed{}
+	for _, other := range i.subjects[entry.subject] {
+		if other.key != entry.key {
+			remaining = append(remaining, other)
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(i.subjects, entry.subject)
+	} else {
+		i.subjects[entry.subject] = remaining
+	}
+}
+
+func (i *Inventory) removeExpired(now time.Time) {
+	for _, entry := range i.index {
+		if !entry.notAfter.After(now) {
+			i.remove(entry)
+		}
+	}
+}
+
+// renewed tells whether a certificate of the same subject expires after the certificate
+func (i *Inventory) renewed(entry *indexed) bool {
+	for _, other := range i.subjects[entry.subject] {
+		if other.notAfter.After(entry.notAfter) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func key(issuer string, serialNumber string) string {
+	return issuer + "/" + serialNumber
+}
+
+// subject identifies certificates of an issuer requested with the same subject and names, e.g. a certificate and
+// the ones renewing it. Common name is compared as is, the others regardless of their order and case
+func subject(issuer string, commonName string, serialNumber string, sets ...[]string) string {
+	fields := []string{issuer, commonName, serialNumber}
+	for _, set := range sets {
+		fields = append(fields, strings.Join(normalize(set), ","))
+	}
+
+	encoded, _ := json.Marshal(fields)
+	return string(encoded)
+}
+
+func certificateSubject(certificate *Certificate) string {
+	return subject(certificate.Issuer, certificate.CommonName, certificate.SubjectSerialNumber,
+		certificate.SubjectAlternativeNames, certificate.Email, certificate.Organization,
+		certificate.OrganizationalUnit, certificate.Locality, certificate.Province, certificate.Country,
+		certificate.StreetAddress, certificate.PostalCode)
+}
+
+func requestSubject(issuer string, request *service.NewCertificateRequest) string {
+	return subject(issuer, request.CommonName, request.SerialNumber,
+		request.SubjectAlternativeNames, request.Email, request.Organization,
+		request.OrganizationalUnit, request.Locality, request.Province, request.Country,
+		request.StreetAddress, request.PostalCode)
+}
+
+func normalize(values []string) []string {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
+		}
+
+		seen[value] = true
+		normalized = append(normalized, value)
+	}
+
+	sort.Strings(normalized)
+	return normalized
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, storage.ErrNotFound)
+}
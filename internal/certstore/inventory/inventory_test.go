@@ -0,0 +1,336 @@
+package inventory
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certificate/x509utils"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+)
+
+func TestAddAndGet(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	request := newRequest("certstore.com", "www.certstore.com")
+	added, err := inventory.Add("test issuer", request, newResponse(t, 1, now.AddDate(0, 0, 90)), "test agent", false)
+	assert.NotError(t, err, "adding certificate failed")
+
+	assert.Equal(t, "1", added.SerialNumber)
+	assert.Equal(t, 0, len(added.PrivateKey))
+
+	certificate, err := inventory.Get("test issuer", "1")
+	assert.NotError(t, err, "getting certificate failed")
+	assert.Equal(t, "certstore.com", certificate.CommonName)
+	assert.Equal(t, now, certificate.IssuedAt)
+	assert.Equal(t, now.AddDate(0, 0, 90), certificate.NotAfter)
+	assert.Equal(t, "test agent", certificate.RequestedBy)
+
+	_, err = inventory.Get("other issuer", "1")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestSerialNumber(t *testing.T) {
+	serialNumber, err := SerialNumber(newResponse(t, 255, time.Now()).Certificate)
+	assert.NotError(t, err, "reading serial number failed")
+	assert.Equal(t, "ff", serialNumber)
+
+	_, err = SerialNumber([]byte("not a certificate"))
+	assert.Error(t, err, "reading serial number of invalid certificate should fail")
+}
+
+func TestAddInvalidCertificate(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	response := &service.NewCertificateResponse{Certificate: []byte("not a PEM certificate")}
+	_, err := inventory.Add("test issuer", newRequest("certstore.com"), response, "", false)
+	assert.ErrorContains(t, err, "decoding pem failed")
+}
+
+func TestFindReusable(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, 90), "certstore.com", "www.certstore.com")
+	now = now.Add(time.Hour)
+	addCertificate(t, inventory, "test issuer", 2, now.AddDate(0, 0, 90), "certstore.com", "www.certstore.com")
+
+	// same names in different order and case
+	certificate, err := inventory.FindReusable("test issuer", newRequest("certstore.com", "WWW.certstore.com"), "", 30)
+	assert.NotError(t, err, "finding reusable certificate failed")
+	assert.Equal(t, "2", certificate.SerialNumber)
+	assert.Equal(t, "test private key", string(certificate.PrivateKey))
+}
+
+func TestFindReusableNotMatching(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, 90), "certstore.com", "www.certstore.com")
+
+	_, err := inventory.FindReusable("test issuer", newRequest("certstore.com"), "", 30)
+	assert.True(t, IsNotFound(err))
+
+	_, err = inventory.FindReusable("other issuer", newRequest("certstore.com", "www.certstore.com"), "", 30)
+	assert.True(t, IsNotFound(err))
+
+	request := newRequest("certstore.com", "www.certstore.com")
+	request.Organization = []string{"certstore"}
+	_, err = inventory.FindReusable("test issuer", request, "", 30)
+	assert.True(t, IsNotFound(err))
+}
+
+func TestFindReusableOfRequester(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	_, err := inventory.Add("test issuer", newRequest("certstore.com"), newResponse(t, 1, now.AddDate(0, 0, 90)), "agent-1", true)
+	assert.NotError(t, err, "adding certificate failed")
+
+	certificate, err := inventory.FindReusable("test issuer", newRequest("certstore.com"), "agent-1", 30)
+	assert.NotError(t, err, "finding reusable certificate failed")
+	assert.Equal(t, "1", certificate.SerialNumber)
+
+	_, err = inventory.FindReusable("test issuer", newRequest("certstore.com"), "agent-2", 30)
+	assert.True(t, IsNotFound(err))
+}
+
+func TestFindReusableExpiringSoon(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, 20), "certstore.com")
+
+	_, err := inventory.FindReusable("test issuer", newRequest("certstore.com"), "", 30)
+	assert.True(t, IsNotFound(err))
+
+	_, err = inventory.FindReusable("test issuer", newRequest("certstore.com"), "", 10)
+	assert.NotError(t, err, "certificate has enough remaining days")
+}
+
+func TestFindReusableLongerThanRequested(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, 90), "certstore.com")
+
+	request := newRequest("certstore.com")
+	request.ExpirationDays = 7
+	_, err := inventory.FindReusable("test issuer", request, "", 1)
+	assert.True(t, IsNotFound(err))
+
+	request.ExpirationDays = 90
+	_, err = inventory.FindReusable("test issuer", request, "", 1)
+	assert.NotError(t, err, "certificate is not valid longer than requested")
+}
+
+func TestFindReusableWithoutPrivateKey(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	request := newRequest("certstore.com")
+	_, err := inventory.Add("test issuer", request, newResponse(t, 1, now.AddDate(0, 0, 90)), "", false)
+	assert.NotError(t, err, "adding certificate failed")
+
+	_, err = inventory.FindReusable("test issuer", request, "", 30)
+	assert.True(t, IsNotFound(err))
+}
+
+func TestEarliestExpiry(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	_, err := inventory.EarliestExpiry()
+	assert.True(t, IsNotFound(err))
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, -1), "expired.certstore.com")
+	addCertificate(t, inventory, "test issuer", 2, now.AddDate(0, 0, 60), "certstore.com")
+	addCertificate(t, inventory, "other issuer", 3, now.AddDate(0, 0, 30), "certstore.com")
+	addCertificate(t, inventory, "test issuer", 4, now.AddDate(0, 0, 90), "certstore.com")
+
+	certificate, err := inventory.EarliestExpiry()
+	assert.NotError(t, err, "finding earliest expiry failed")
+	assert.Equal(t, "3", certificate.SerialNumber)
+
+	// kept up to date after it is loaded
+	_, err = inventory.Revoke("other issuer", "3", service.RevocationUnspecified)
+	assert.NotError(t, err, "revoking certificate failed")
+	addCertificate(t, inventory, "other issuer", 5, now.AddDate(0, 0, 45), "certstore.com")
+
+	certificate, err = inventory.EarliestExpiry()
+	assert.NotError(t, err, "finding earliest expiry failed")
+	assert.Equal(t, "5", certificate.SerialNumber)
+
+	now = now.AddDate(0, 0, 50)
+	certificate, err = inventory.EarliestExpiry()
+	assert.NotError(t, err, "finding earliest expiry failed")
+	assert.Equal(t, "2", certificate.SerialNumber)
+}
+
+func TestFindPrevious(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, -1), "certstore.com")
+	addCertificate(t, inventory, "other issuer", 2, now.AddDate(0, 0, 30), "certstore.com")
+
+	_, err := inventory.FindPrevious("test issuer", newRequest("certstore.com"))
+	assert.True(t, IsNotFound(err))
+
+	addCertificate(t, inventory, "test issuer", 3, now.AddDate(0, 0, 30), "certstore.com")
+	previous, err := inventory.FindPrevious("test issuer", newRequest("certstore.com"))
+	assert.NotError(t, err, "finding previous certificate failed")
+	assert.Equal(t, "3", previous.SerialNumber)
+
+	_, err = inventory.FindPrevious("test issuer", newRequest("certstore.com", "www.certstore.com"))
+	assert.True(t, IsNotFound(err))
+}
+
+func TestListExpiring(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, -1), "expired.certstore.com")
+	addCertificate(t, inventory, "test issuer", 2, now.AddDate(0, 0, 10), "expiring.certstore.com")
+	addCertificate(t, inventory, "test issuer", 3, now.AddDate(0, 0, 60), "valid.certstore.com")
+
+	// renewed by a certificate of same issuer and names
+	addCertificate(t, inventory, "test issuer", 4, now.AddDate(0, 0, 5), "renewed.certstore.com", "www.certstore.com")
+	addCertificate(t, inventory, "test issuer", 5, now.AddDate(0, 0, 90), "renewed.certstore.com", "WWW.certstore.com")
+	addCertificate(t, inventory, "other issuer", 6, now.AddDate(0, 0, 90), "expiring.certstore.com")
+
+	addCertificate(t, inventory, "test issuer", 7, now.AddDate(0, 0, 20), "revoked.certstore.com")
+	_, err := inventory.Revoke("test issuer", "7", service.RevocationSuperseded)
+	assert.NotError(t, err, "revoking certificate failed")
+
+	expiring, err := inventory.ListExpiring(30 * 24 * time.Hour)
+	assert.NotError(t, err, "listing expiring certificates failed")
+	assert.Equal(t, 1, len(expiring))
+	assert.Equal(t, "2", expiring[0].SerialNumber)
+}
+
+func TestListExpiringRenewedWithSameSubject(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, 10), "certstore.com")
+
+	// same names but other organization does not renew the certificate, as it would not be found as previous
+	request := newRequest("certstore.com")
+	request.Organization = []string{"certstore"}
+	_, err := inventory.Add("test issuer", request, newResponse(t, 2, now.AddDate(0, 0, 90)), "", true)
+	assert.NotError(t, err, "adding certificate failed")
+
+	expiring, err := inventory.ListExpiring(30 * 24 * time.Hour)
+	assert.NotError(t, err, "listing expiring certificates failed")
+	assert.Equal(t, 1, len(expiring))
+	assert.Equal(t, "1", expiring[0].SerialNumber)
+
+	previous, err := inventory.FindPrevious("test issuer", newRequest("certstore.com"))
+	assert.NotError(t, err, "finding previous certificate failed")
+	assert.Equal(t, "1", previous.SerialNumber)
+}
+
+func TestIndexLoadedFromStorage(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, 10), "certstore.com")
+	addCertificate(t, inventory, "test issuer", 2, now.AddDate(0, 0, 20), "www.certstore.com")
+	now = now.Add(time.Hour)
+	addCertificate(t, inventory, "test issuer", 3, now.AddDate(0, 0, 90), "www.certstore.com")
+	_, err := inventory.Revoke("test issuer", "1", service.RevocationUnspecified)
+	assert.NotError(t, err, "revoking certificate failed")
+
+	reloaded := NewWithTimeProvider(inventory.storage, func() time.Time {
+		return now
+	})
+
+	_, err = reloaded.FindPrevious("test issuer", newRequest("certstore.com"))
+	assert.True(t, IsNotFound(err))
+
+	previous, err := reloaded.FindPrevious("test issuer", newRequest("www.certstore.com"))
+	assert.NotError(t, err, "finding previous certificate failed")
+	assert.Equal(t, "3", previous.SerialNumber)
+
+	expiring, err := reloaded.ListExpiring(30 * 24 * time.Hour)
+	assert.NotError(t, err, "listing expiring certificates failed")
+	assert.Equal(t, 0, len(expiring))
+
+	// certificates expired since loading are left out
+	now = now.AddDate(0, 0, 91)
+	_, err = reloaded.FindPrevious("test issuer", newRequest("www.certstore.com"))
+	assert.True(t, IsNotFound(err))
+}
+
+func TestRevoke(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	inventory := createInventory(&now)
+
+	addCertificate(t, inventory, "test issuer", 1, now.AddDate(0, 0, 90), "certstore.com")
+	addCertificate(t, inventory, "test issuer", 2, now.AddDate(0, 0, 30), "www.certstore.com")
+
+	revoked, err := inventory.Revoke("test issuer", "1", service.RevocationKeyCompromise)
+	assert.NotError(t, err, "revoking certificate failed")
+	assert.True(t, revoked.Revoked())
+	assert.Equal(t, now, revoked.RevokedAt)
+
+	stored, err := inventory.Get("test issuer", "1")
+	assert.NotError(t, err, "getting certificate failed")
+	assert.Equal(t, service.RevocationKeyCompromise, stored.RevocationReason)
+
+	// revoked certificates are not reused
+	_, err = inventory.FindReusable("test issuer", newRequest("certstore.com"), "", 30)
+	assert.True(t, IsNotFound(err))
+
+	_, err = inventory.Revoke("test issuer", "3", service.RevocationUnspecified)
+	assert.True(t, IsNotFound(err))
+}
+
+// ----
+
+func createInventory(now *time.Time) *Inventory {
+	return NewWithTimeProvider(storage.NewMemoryStorage(), func() time.Time {
+		return *now
+	})
+}
+
+func addCertificate(t *testing.T, inventory *Inventory, issuer string, serialNumber int64, notAfter time.Time,
+	commonName string, sans ...string) {
+
+	_, err := inventory.Add(issuer, newRequest(commonName, sans...), newResponse(t, serialNumber, notAfter), "", true)
+	assert.NotError(t, err, "adding certificate failed")
+}
+
+func newRequest(commonName string, sans ...string) *service.NewCertificateRequest {
+	return &service.NewCertificateRequest{
+		CommonName:              commonName,
+		SubjectAlternativeNames: sans,
+	}
+}
+
+func newResponse(t *testing.T, serialNumber int64, notAfter time.Time) *service.NewCertificateResponse {
+	certTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		NotBefore:    notAfter.AddDate(0, 0, -90),
+		NotAfter:     notAfter,
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NotError(t, err, "generating private key failed")
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, &privateKey.PublicKey, privateKey)
+	assert.NotError(t, err, "creating certificate failed")
+
+	return &service.NewCertificateResponse{
+		Certificate: x509utils.EncodePEMCert(certBytes).Bytes(),
+		PrivateKey:  []byte("test private key"),
+	}
+}
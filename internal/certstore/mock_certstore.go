@@ -5,9 +5,17 @@
 package certstore
 
 import (
+	context "context"
 	reflect "reflect"
 
+	keypool "bilalekrem.com/certstore/internal/certificate/keypool"
 	service "bilalekrem.com/certstore/internal/certificate/service"
+	approval "bilalekrem.com/certstore/internal/certstore/approval"
+	config "bilalekrem.com/certstore/internal/certstore/config"
+	inventory "bilalekrem.com/certstore/internal/certstore/inventory"
+	operation "bilalekrem.com/certstore/internal/certstore/operation"
+	ratelimit "bilalekrem.com/certstore/internal/certstore/ratelimit"
+	transparency "bilalekrem.com/certstore/internal/certstore/transparency"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,62 +42,327 @@ func (m *MockCertStore) EXPECT() *MockCertStoreMockRecorder {
 	return m.recorder
 }
 
-// CreateClusterCACertificate mocks base method.
-func (m *MockCertStore) CreateClusterCACertificate(clusterName string) (*service.NewCertificateResponse, error) {
+// ApproveRequest mocks base method.
+func (m *MockCertStore) ApproveRequest(ctx context.Context, operationID string) (*operation.Operation, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateClusterCACertificate", clusterName)
-	ret0, _ := ret[0].(*service.NewCertificateResponse)
+	ret := m.ctrl.Call(m, "ApproveRequest", ctx, operationID)
+	ret0, _ := ret[0].(*operation.Operation)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateClusterCACertificate indicates an expected call of CreateClusterCACertificate.
-func (mr *MockCertStoreMockRecorder) CreateClusterCACertificate(clusterName interface{}) *gomock.Call {
+// ApproveRequest indicates an expected call of ApproveRequest.
+func (mr *MockCertStoreMockRecorder) ApproveRequest(ctx, operationID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClusterCACertificate", reflect.TypeOf((*MockCertStore)(nil).CreateClusterCACertificate), clusterName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveRequest", reflect.TypeOf((*MockCertStore)(nil).ApproveRequest), ctx, operationID)
 }
 
-// CreateServerCertificate mocks base method.
-func (m *MockCertStore) CreateServerCertificate(advertisedServerName string) (*service.NewCertificateResponse, error) {
+// CheckIssuerHealth mocks base method.
+func (m *MockCertStore) CheckIssuerHealth(ctx context.Context, issuer string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateServerCertificate", advertisedServerName)
-	ret0, _ := ret[0].(*service.NewCertificateResponse)
+	ret := m.ctrl.Call(m, "CheckIssuerHealth", ctx, issuer)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckIssuerHealth indicates an expected call of CheckIssuerHealth.
+func (mr *MockCertStoreMockRecorder) CheckIssuerHealth(ctx, issuer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckIssuerHealth", reflect.TypeOf((*MockCertStore)(nil).CheckIssuerHealth), ctx, issuer)
+}
+
+// CheckStorageHealth mocks base method.
+func (m *MockCertStore) CheckStorageHealth() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckStorageHealth")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckStorageHealth indicates an expected call of CheckStorageHealth.
+func (mr *MockCertStoreMockRecorder) CheckStorageHealth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckStorageHealth", reflect.TypeOf((*MockCertStore)(nil).CheckStorageHealth))
+}
+
+// ConsistencyProof mocks base method.
+func (m *MockCertStore) ConsistencyProof(firstSize, secondSize uint64) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsistencyProof", firstSize, secondSize)
+	ret0, _ := ret[0].([][]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateServerCertificate indicates an expected call of CreateServerCertificate.
-func (mr *MockCertStoreMockRecorder) CreateServerCertificate(advertisedServerName interface{}) *gomock.Call {
+// ConsistencyProof indicates an expected call of ConsistencyProof.
+func (mr *MockCertStoreMockRecorder) ConsistencyProof(firstSize, secondSize interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServerCertificate", reflect.TypeOf((*MockCertStore)(nil).CreateServerCertificate), advertisedServerName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsistencyProof", reflect.TypeOf((*MockCertStore)(nil).ConsistencyProof), firstSize, secondSize)
 }
 
-// CreateAgentCertificate mocks base method.
-func (m *MockCertStore) CreateAgentCertificate(address string) (*service.NewCertificateResponse, error) {
+// DenyRequest mocks base method.
+func (m *MockCertStore) DenyRequest(ctx context.Context, operationID, reason string) (*operation.Operation, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAgentCertificate", address)
-	ret0, _ := ret[0].(*service.NewCertificateResponse)
+	ret := m.ctrl.Call(m, "DenyRequest", ctx, operationID, reason)
+	ret0, _ := ret[0].(*operation.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DenyRequest indicates an expected call of DenyRequest.
+func (mr *MockCertStoreMockRecorder) DenyRequest(ctx, operationID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DenyRequest", reflect.TypeOf((*MockCertStore)(nil).DenyRequest), ctx, operationID, reason)
+}
+
+// EarliestExpiry mocks base method.
+func (m *MockCertStore) EarliestExpiry() (*inventory.Certificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarliestExpiry")
+	ret0, _ := ret[0].(*inventory.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EarliestExpiry indicates an expected call of EarliestExpiry.
+func (mr *MockCertStoreMockRecorder) EarliestExpiry() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarliestExpiry", reflect.TypeOf((*MockCertStore)(nil).EarliestExpiry))
+}
+
+// GetCertificate mocks base method.
+func (m *MockCertStore) GetCertificate(ctx context.Context, issuer, serialNumber string) (*inventory.Certificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCertificate", ctx, issuer, serialNumber)
+	ret0, _ := ret[0].(*inventory.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCertificate indicates an expected call of GetCertificate.
+func (mr *MockCertStoreMockRecorder) GetCertificate(ctx, issuer, serialNumber interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCertificate", reflect.TypeOf((*MockCertStore)(nil).GetCertificate), ctx, issuer, serialNumber)
+}
+
+// GetOperation mocks base method.
+func (m *MockCertStore) GetOperation(ctx context.Context, id string) (*operation.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOperation", ctx, id)
+	ret0, _ := ret[0].(*operation.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOperation indicates an expected call of GetOperation.
+func (mr *MockCertStoreMockRecorder) GetOperation(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOperation", reflect.TypeOf((*MockCertStore)(nil).GetOperation), ctx, id)
+}
+
+// GetRateLimitBudget mocks base method.
+func (m *MockCertStore) GetRateLimitBudget(issuer string, domains []string) (*ratelimit.Budget, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRateLimitBudget", issuer, domains)
+	ret0, _ := ret[0].(*ratelimit.Budget)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRateLimitBudget indicates an expected call of GetRateLimitBudget.
+func (mr *MockCertStoreMockRecorder) GetRateLimitBudget(issuer, domains interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRateLimitBudget", reflect.TypeOf((*MockCertStore)(nil).GetRateLimitBudget), issuer, domains)
+}
+
+// InclusionProof mocks base method.
+func (m *MockCertStore) InclusionProof(leafHash []byte, treeSize uint64) (*transparency.InclusionProof, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InclusionProof", leafHash, treeSize)
+	ret0, _ := ret[0].(*transparency.InclusionProof)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAgentCertificate indicates an expected call of CreateAgentCertificate.
-func (mr *MockCertStoreMockRecorder) CreateAgentCertificate(address interface{}) *gomock.Call {
+// InclusionProof indicates an expected call of InclusionProof.
+func (mr *MockCertStoreMockRecorder) InclusionProof(leafHash, treeSize interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAgentCertificate", reflect.TypeOf((*MockCertStore)(nil).CreateAgentCertificate), address)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InclusionProof", reflect.TypeOf((*MockCertStore)(nil).InclusionProof), leafHash, treeSize)
 }
 
 // IssueCertificate mocks base method.
-func (m *MockCertStore) IssueCertificate(arg0 string, arg1 *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
+func (m *MockCertStore) IssueCertificate(arg0 context.Context, arg1 string, arg2 *service.NewCertificateRequest) (*service.NewCertificateResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IssueCertificate", arg0, arg1)
+	ret := m.ctrl.Call(m, "IssueCertificate", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*service.NewCertificateResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // IssueCertificate indicates an expected call of IssueCertificate.
-func (mr *MockCertStoreMockRecorder) IssueCertificate(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockCertStoreMockRecorder) IssueCertificate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueCertificate", reflect.TypeOf((*MockCertStore)(nil).IssueCertificate), arg0, arg1, arg2)
+}
+
+// IssueSSHCertificate mocks base method.
+func (m *MockCertStore) IssueSSHCertificate(arg0 context.Context, arg1 string, arg2 *service.NewSSHCertificateRequest) (*service.NewSSHCertificateResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueSSHCertificate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*service.NewSSHCertificateResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueSSHCertificate indicates an expected call of IssueSSHCertificate.
+func (mr *MockCertStoreMockRecorder) IssueSSHCertificate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueSSHCertificate", reflect.TypeOf((*MockCertStore)(nil).IssueSSHCertificate), arg0, arg1, arg2)
+}
+
+// KeyPoolStats mocks base method.
+func (m *MockCertStore) KeyPoolStats() []keypool.Stats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeyPoolStats")
+	ret0, _ := ret[0].([]keypool.Stats)
+	return ret0
+}
+
+// KeyPoolStats indicates an expected call of KeyPoolStats.
+func (mr *MockCertStoreMockRecorder) KeyPoolStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeyPoolStats", reflect.TypeOf((*MockCertStore)(nil).KeyPoolStats))
+}
+
+// ListApprovals mocks base method.
+func (m *MockCertStore) ListApprovals(issuer string) ([]*approval.Request, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApprovals", issuer)
+	ret0, _ := ret[0].([]*approval.Request)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApprovals indicates an expected call of ListApprovals.
+func (mr *MockCertStoreMockRecorder) ListApprovals(issuer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApprovals", reflect.TypeOf((*MockCertStore)(nil).ListApprovals), issuer)
+}
+
+// ListCertificates mocks base method.
+func (m *MockCertStore) ListCertificates(ctx context.Context, issuer string) ([]*inventory.Certificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCertificates", ctx, issuer)
+	ret0, _ := ret[0].([]*inventory.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCertificates indicates an expected call of ListCertificates.
+func (mr *MockCertStoreMockRecorder) ListCertificates(ctx, issuer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCertificates", reflect.TypeOf((*MockCertStore)(nil).ListCertificates), ctx, issuer)
+}
+
+// ListIssuers mocks base method.
+func (m *MockCertStore) ListIssuers() []IssuerInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIssuers")
+	ret0, _ := ret[0].([]IssuerInfo)
+	return ret0
+}
+
+// ListIssuers indicates an expected call of ListIssuers.
+func (mr *MockCertStoreMockRecorder) ListIssuers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIssuers", reflect.TypeOf((*MockCertStore)(nil).ListIssuers))
+}
+
+// Reload mocks base method.
+func (m *MockCertStore) Reload(arg0 *config.Config) (*ReloadResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reload", arg0)
+	ret0, _ := ret[0].(*ReloadResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reload indicates an expected call of Reload.
+func (mr *MockCertStoreMockRecorder) Reload(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reload", reflect.TypeOf((*MockCertStore)(nil).Reload), arg0)
+}
+
+// RevokeCertificate mocks base method.
+func (m *MockCertStore) RevokeCertificate(ctx context.Context, issuer, serialNumber string, reason service.RevocationReason) (*inventory.Certificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeCertificate", ctx, issuer, serialNumber, reason)
+	ret0, _ := ret[0].(*inventory.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeCertificate indicates an expected call of RevokeCertificate.
+func (mr *MockCertStoreMockRecorder) RevokeCertificate(ctx, issuer, serialNumber, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeCertificate", reflect.TypeOf((*MockCertStore)(nil).RevokeCertificate), ctx, issuer, serialNumber, reason)
+}
+
+// SignedTreeHead mocks base method.
+func (m *MockCertStore) SignedTreeHead() (*transparency.SignedTreeHead, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignedTreeHead")
+	ret0, _ := ret[0].(*transparency.SignedTreeHead)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignedTreeHead indicates an expected call of SignedTreeHead.
+func (mr *MockCertStoreMockRecorder) SignedTreeHead() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignedTreeHead", reflect.TypeOf((*MockCertStore)(nil).SignedTreeHead))
+}
+
+// Stop mocks base method.
+func (m *MockCertStore) Stop(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stop", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stop indicates an expected call of Stop.
+func (mr *MockCertStoreMockRecorder) Stop(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockCertStore)(nil).Stop), ctx)
+}
+
+// SubmitCertificate mocks base method.
+func (m *MockCertStore) SubmitCertificate(arg0 context.Context, arg1 string, arg2 *service.NewCertificateRequest) (*operation.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitCertificate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*operation.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitCertificate indicates an expected call of SubmitCertificate.
+func (mr *MockCertStoreMockRecorder) SubmitCertificate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitCertificate", reflect.TypeOf((*MockCertStore)(nil).SubmitCertificate), arg0, arg1, arg2)
+}
+
+// WatchOperation mocks base method.
+func (m *MockCertStore) WatchOperation(id string) (<-chan *operation.Operation, func()) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchOperation", id)
+	ret0, _ := ret[0].(<-chan *operation.Operation)
+	ret1, _ := ret[1].(func())
+	return ret0, ret1
+}
+
+// WatchOperation indicates an expected call of WatchOperation.
+func (mr *MockCertStoreMockRecorder) WatchOperation(id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueCertificate", reflect.TypeOf((*MockCertStore)(nil).IssueCertificate), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchOperation", reflect.TypeOf((*MockCertStore)(nil).WatchOperation), id)
 }
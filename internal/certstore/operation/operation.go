@@ -0,0 +1,346 @@
+package operation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+type State string
+
+const (
+	Pending    State = "PENDING"
+	Validating State = "VALIDATING"
+	Issued     State = "ISSUED"
+	Failed     State = "FAILED"
+
+	// request is kept until an approver decides, it is pending once approved and failed if denied
+	AwaitingApproval State = "AWAITING_APPROVAL"
+)
+
+const (
+	STORAGE_BUCKET = "operations"
+
+	// expiry time of private keys not retrieved yet, by operation id
+	PRIVATE_KEYS_STORAGE_BUCKET = "operation-private-keys"
+
+	// private key of an issued operation is removed after this long, or once it is retrieved by an unknown caller
+	PRIVATE_KEY_TTL = time.Hour
+
+	// operation passes through each state at most once, so a watcher never misses an update
+	WATCH_BUFFER_SIZE = 4
+)
+
+// Operation is an asynchronous certificate issuance, its result is kept in storage
+type Operation struct {
+	ID        string    `json:"id"`
+	Issuer    string    `json:"issuer"`
+	State     State     `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created-at"`
+	UpdatedAt time.Time `json:"updated-at"`
+
+	// empty if requester is not known
+	RequestedBy string `json:"requested-by,omitempty"`
+
+	// set if operation failed with a typed service error
+	ErrorKind  service.ErrorKind `json:"error-kind,omitempty"`
+	RetryAfter time.Duration     `json:"retry-after,omitempty"`
+
+	// all encoded in PEM format, set only when operation is issued. Private key is kept until PRIVATE_KEY_TTL
+	// passes, see ClaimPrivateKey
+	Certificate []byte `json:"certificate,omitempty"`
+	PrivateKey  []byte `json:"private-key,omitempty"`
+	Chain       []byte `json:"chain,omitempty"`
+
+	// first caller which claimed the private key, only it can claim the key again
+	PrivateKeyClaimedBy string `json:"private-key-claimed-by,omitempty"`
+}
+
+func (o *Operation) Done() bool {
+	return o.State == Issued || o.State == Failed
+}
+
+// ----
+
+type TimeProvider func() time.Time
+
+type Store struct {
+	mutex        sync.Mutex
+	storage      storage.Storage
+	watchers     map[string][]chan *Operation
+	timeProvider TimeProvider
+}
+
+func NewStore(storage storage.Storage) *Store {
+	return NewStoreWithTimeProvider(storage, time.Now)
+}
+
+func NewStoreWithTimeProvider(storage storage.Storage, timeProvider TimeProvider) *Store {
+	return &Store{
+		storage:      storage,
+		watchers:     make(map[string][]chan *Operation),
+		timeProvider: timeProvider,
+	}
+}
+
+// Create saves a new pending operation for the issuer
+func (s *Store) Create(issuer string, requestedBy string) (*Operation, error) {
+	return s.CreateWithState(issuer, requestedBy, Pending)
+}
+
+// CreateWithState saves a new operation for the issuer, e.g. awaiting approval
+func (s *Store) CreateWithState(issuer string, requestedBy string, state State) (*Operation, error) {
+	id, err := newID()
+	if err != nil {
+		logging.GetLogger().Errorf("generating operation id failed, %v", err)
+		return nil, err
+	}
+
+	now := s.timeProvider()
+	operation := &Operation{
+		ID:          id,
+		Issuer:      issuer,
+		State:       state,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err = s.save(operation)
+	if err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// Update saves the operation and notifies its watchers, updates are sent without private key, see ClaimPrivateKey
+func (s *Store) Update(operation *Operation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	operation.UpdatedAt = s.timeProvider()
+	err := s.save(operation)
+	if err != nil {
+		return err
+	}
+
+	if len(operation.PrivateKey) > 0 {
+		expiresAt := operation.UpdatedAt.Add(PRIVATE_KEY_TTL).UTC().Format(time.RFC3339)
+		err = s.storage.Put(PRIVATE_KEYS_STORAGE_BUCKET, operation.ID, []byte(expiresAt))
+		if err != nil {
+			logging.GetLogger().Errorf("saving private key expiry of operation failed, id: [%s], %v", operation.ID, err)
+			return err
+		}
+	}
+
+	for _, watcher := range s.watchers[operation.ID] {
+		updated := *operation
+		updated.PrivateKey = nil
+		select {
+		case watcher <- &updated:
+		default:
+			logging.GetLogger().Warnf("operation watcher is full, dropping update of [%s]", operation.ID)
+		}
+	}
+
+	return nil
+}
+
+// Get returns storage.ErrNotFound if there is no operation with the id
+func (s *Store) Get(id string) (*Operation, error) {
+	value, err := s.storage.Get(STORAGE_BUCKET, id)
+	if err != nil {
+		return nil, err
+	}
+
+	operation := &Operation{}
+	err = json.Unmarshal(value, operation)
+	if err != nil {
+		logging.GetLogger().Errorf("decoding operation failed, id: [%s], %v", id, err)
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// ClaimPrivateKey returns the private key of the operation to the claimer. The first claimer can claim it again
+// until PRIVATE_KEY_TTL passes, e.g. the key is not delivered or the agent restarts before saving it, others get
+// nil. An unknown claimer, with empty name, can not claim it again, so the key is removed once it is returned.
+// Returns nil if operation has no private key, or it is expired already
+func (s *Store) ClaimPrivateKey(id string, claimer string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	operation, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey := operation.PrivateKey
+	if len(privateKey) == 0 {
+		return nil, nil
+	} else if operation.PrivateKeyClaimedBy != "" && operation.PrivateKeyClaimedBy != claimer {
+		logging.GetLogger().Infof("private key of operation [%s] is claimed by [%s], refused to [%s]",
+			id, operation.PrivateKeyClaimedBy, claimer)
+		return nil, nil
+	}
+
+	if claimer == "" {
+		err = s.removePrivateKey(operation)
+	} else if operation.PrivateKeyClaimedBy == "" {
+		operation.PrivateKeyClaimedBy = claimer
+		err = s.save(operation)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
+}
+
+// RemoveExpiredPrivateKeys removes private keys not claimed in PRIVATE_KEY_TTL
+func (s *Store) RemoveExpiredPrivateKeys() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expiries, err := s.storage.List(PRIVATE_KEYS_STORAGE_BUCKET)
+	if err != nil {
+		return err
+	}
+
+	now := s.timeProvider()
+	for id, expiry := range expiries {
+		expiresAt, err := time.Parse(time.RFC3339, string(expiry))
+		if err == nil && now.Before(expiresAt) {
+			continue
+		}
+
+		operation, err := s.Get(id)
+		if IsNotFound(err) {
+			s.storage.Delete(PRIVATE_KEYS_STORAGE_BUCKET, id)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		logging.GetLogger().Infof("private key of operation [%s] is not claimed in time, removing it", id)
+		err = s.removePrivateKey(operation)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Watch returns a channel receiving updates of the operation, and a function to stop watching
+func (s *Store) Watch(id string) (<-chan *Operation, func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	watcher := make(chan *Operation, WATCH_BUFFER_SIZE)
+	s.watchers[id] = append(s.watchers[id], watcher)
+
+	return watcher, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		watchers := s.watchers[id]
+		for index, existing := range watchers {
+			if existing == watcher {
+				watchers = append(watchers[:index], watchers[index+1:]...)
+				break
+			}
+		}
+
+		if len(watchers) == 0 {
+			delete(s.watchers, id)
+		} else {
+			s.watchers[id] = watchers
+		}
+	}
+}
+
+// FailInterrupted marks operations that were in progress when the server stopped as failed, operations awaiting
+// approval are not in progress yet and kept
+func (s *Store) FailInterrupted() error {
+	values, err := s.storage.List(STORAGE_BUCKET)
+	if err != nil {
+		return err
+	}
+
+	for id := range values {
+		operation, err := s.Get(id)
+		if err != nil {
+			continue
+		}
+
+		if operation.Done() || operation.State == AwaitingApproval {
+			continue
+		}
+
+		logging.GetLogger().Warnf("operation [%s] was interrupted, marking as failed", id)
+		operation.State = Failed
+		operation.Error = "operation interrupted by server restart"
+		err = s.Update(operation)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, storage.ErrNotFound)
+}
+
+// ----
+
+func (s *Store) save(operation *Operation) error {
+	value, err := json.Marshal(operation)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.Put(STORAGE_BUCKET, operation.ID, value)
+	if err != nil {
+		logging.GetLogger().Errorf("saving operation failed, id: [%s], %v", operation.ID, err)
+		return err
+	}
+
+	return nil
+}
+
+// removePrivateKey is called while holding the mutex
+func (s *Store) removePrivateKey(operation *Operation) error {
+	operation.PrivateKey = nil
+	err := s.save(operation)
+	if err != nil {
+		return err
+	}
+
+	return s.storage.Delete(PRIVATE_KEYS_STORAGE_BUCKET, operation.ID)
+}
+
+func newID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
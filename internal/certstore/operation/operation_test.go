@@ -0,0 +1,211 @@
+package operation
+
+import (
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+)
+
+func TestCreateAndGet(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	created, err := store.Create("test issuer", "agent-1")
+	assert.NotError(t, err, "creating operation failed")
+	assert.Equal(t, Pending, created.State)
+	assert.Equal(t, 32, len(created.ID))
+
+	operation, err := store.Get(created.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, "test issuer", operation.Issuer)
+	assert.Equal(t, "agent-1", operation.RequestedBy)
+	assert.Equal(t, now, operation.CreatedAt)
+	assert.False(t, operation.Done())
+
+	_, err = store.Get("missing")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestUpdate(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	operation, err := store.Create("test issuer", "agent-1")
+	assert.NotError(t, err, "creating operation failed")
+
+	now = now.Add(time.Minute)
+	operation.State = Issued
+	operation.Certificate = []byte("test certificate")
+	err = store.Update(operation)
+	assert.NotError(t, err, "updating operation failed")
+
+	updated, err := store.Get(operation.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.True(t, updated.Done())
+	assert.Equal(t, now, updated.UpdatedAt)
+	assert.Equal(t, "test certificate", string(updated.Certificate))
+}
+
+func TestWatch(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	operation, err := store.Create("test issuer", "agent-1")
+	assert.NotError(t, err, "creating operation failed")
+
+	updates, stop := store.Watch(operation.ID)
+
+	operation.State = Validating
+	store.Update(operation)
+	operation.State = Failed
+	operation.Error = "test error"
+	store.Update(operation)
+
+	assert.Equal(t, Validating, (<-updates).State)
+	failed := <-updates
+	assert.Equal(t, Failed, failed.State)
+	assert.Equal(t, "test error", failed.Error)
+
+	// ----
+
+	stop()
+	assert.Equal(t, 0, len(store.watchers))
+}
+
+func TestClaimPrivateKey(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	operation, err := store.Create("test issuer", "agent-1")
+	assert.NotError(t, err, "creating operation failed")
+
+	updates, stop := store.Watch(operation.ID)
+	defer stop()
+
+	operation.State = Issued
+	operation.Certificate = []byte("test certificate")
+	operation.PrivateKey = []byte("test private key")
+	err = store.Update(operation)
+	assert.NotError(t, err, "updating operation failed")
+
+	// watchers do not receive private keys
+	assert.Equal(t, 0, len((<-updates).PrivateKey))
+
+	// ----
+
+	privateKey, err := store.ClaimPrivateKey(operation.ID, "agent-1")
+	assert.NotError(t, err, "claiming private key failed")
+	assert.Equal(t, "test private key", string(privateKey))
+
+	// claimed again by the same claimer, e.g. it is not delivered, but not by others
+	privateKey, err = store.ClaimPrivateKey(operation.ID, "agent-1")
+	assert.NotError(t, err, "claiming private key again failed")
+	assert.Equal(t, "test private key", string(privateKey))
+
+	privateKey, err = store.ClaimPrivateKey(operation.ID, "agent-2")
+	assert.NotError(t, err, "claiming private key failed")
+	assert.Nil(t, privateKey)
+
+	privateKey, err = store.ClaimPrivateKey(operation.ID, "")
+	assert.NotError(t, err, "claiming private key failed")
+	assert.Nil(t, privateKey)
+
+	claimed, err := store.Get(operation.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, "agent-1", claimed.PrivateKeyClaimedBy)
+	assert.Equal(t, "test certificate", string(claimed.Certificate))
+}
+
+func TestClaimPrivateKeyUnknownClaimer(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	operation, err := store.Create("test issuer", "")
+	assert.NotError(t, err, "creating operation failed")
+
+	operation.State = Issued
+	operation.PrivateKey = []byte("test private key")
+	err = store.Update(operation)
+	assert.NotError(t, err, "updating operation failed")
+
+	// ----
+
+	privateKey, err := store.ClaimPrivateKey(operation.ID, "")
+	assert.NotError(t, err, "claiming private key failed")
+	assert.Equal(t, "test private key", string(privateKey))
+
+	privateKey, err = store.ClaimPrivateKey(operation.ID, "")
+	assert.NotError(t, err, "claiming private key again failed")
+	assert.Nil(t, privateKey)
+
+	claimed, err := store.Get(operation.ID)
+	assert.NotError(t, err, "getting operation failed")
+	assert.Equal(t, 0, len(claimed.PrivateKey))
+}
+
+func TestRemoveExpiredPrivateKeys(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	operation, err := store.Create("test issuer", "agent-1")
+	assert.NotError(t, err, "creating operation failed")
+
+	operation.State = Issued
+	operation.PrivateKey = []byte("test private key")
+	err = store.Update(operation)
+	assert.NotError(t, err, "updating operation failed")
+
+	err = store.RemoveExpiredPrivateKeys()
+	assert.NotError(t, err, "removing expired private keys failed")
+	kept, _ := store.Get(operation.ID)
+	assert.Equal(t, "test private key", string(kept.PrivateKey))
+
+	// ----
+
+	now = now.Add(PRIVATE_KEY_TTL)
+	err = store.RemoveExpiredPrivateKeys()
+	assert.NotError(t, err, "removing expired private keys failed")
+
+	removed, _ := store.Get(operation.ID)
+	assert.Equal(t, 0, len(removed.PrivateKey))
+
+	privateKey, err := store.ClaimPrivateKey(operation.ID, "agent-1")
+	assert.NotError(t, err, "claiming private key failed")
+	assert.Nil(t, privateKey)
+}
+
+func TestFailInterrupted(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	store := createStore(&now)
+
+	pending, _ := store.Create("test issuer", "agent-1")
+	issued, _ := store.Create("test issuer", "agent-1")
+	issued.State = Issued
+	store.Update(issued)
+	awaiting, _ := store.CreateWithState("test issuer", "agent-1", AwaitingApproval)
+
+	err := store.FailInterrupted()
+	assert.NotError(t, err, "failing interrupted operations failed")
+
+	operation, _ := store.Get(pending.ID)
+	assert.Equal(t, Failed, operation.State)
+	assert.Equal(t, "operation interrupted by server restart", operation.Error)
+
+	operation, _ = store.Get(issued.ID)
+	assert.Equal(t, Issued, operation.State)
+
+	// still waiting for a decision after restart
+	operation, _ = store.Get(awaiting.ID)
+	assert.Equal(t, AwaitingApproval, operation.State)
+	assert.False(t, operation.Done())
+}
+
+// ----
+
+func createStore(now *time.Time) *Store {
+	return NewStoreWithTimeProvider(storage.NewMemoryStorage(), func() time.Time {
+		return *now
+	})
+}
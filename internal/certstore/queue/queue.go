@@ -0,0 +1,221 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	DEFAULT_LENGTH = 100
+
+	// lets encrypt allows 300 pending authorizations per account, orders are kept well below it
+	LETS_ENCRYPT_CONCURRENCY = 4
+)
+
+var (
+	ErrFull    = errors.New("queue is full")
+	ErrStopped = errors.New("queue is stopped")
+)
+
+// zero concurrency means requests are not queued
+type Config struct {
+	Concurrency int `yaml:"concurrency"`
+
+	// requests waiting more than length are refused, default length is used if zero
+	Length int `yaml:"length"`
+}
+
+func LetsEncryptConfig() *Config {
+	return &Config{
+		Concurrency: LETS_ENCRYPT_CONCURRENCY,
+		Length:      DEFAULT_LENGTH,
+	}
+}
+
+func (c *Config) Enabled() bool {
+	return c.Concurrency > 0
+}
+
+func (c *Config) Validate() error {
+	if c.Concurrency < 0 || c.Length < 0 {
+		return errors.New(fmt.Sprintf("queue concurrency and length can not be negative, %d, %d", c.Concurrency, c.Length))
+	}
+
+	return nil
+}
+
+type Stats struct {
+	Waiting int
+	Running int
+}
+
+// ----
+
+type job struct {
+	run  func()
+	done chan struct{}
+
+	// set while holding queue mutex
+	started  bool
+	canceled bool
+}
+
+// Queue runs jobs of an issuer with bounded concurrency, high priority jobs first
+type Queue struct {
+	name   string
+	length int
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	waiting map[service.Priority][]*job
+	count   int
+	running int
+	stopped bool
+}
+
+func New(name string, conf Config) *Queue {
+	length := conf.Length
+	if length == 0 {
+		length = DEFAULT_LENGTH
+	}
+
+	q := &Queue{
+		name:    name,
+		length:  length,
+		waiting: make(map[service.Priority][]*job),
+	}
+	q.cond = sync.NewCond(&q.mutex)
+
+	for i := 0; i < conf.Concurrency; i++ {
+		go q.work()
+	}
+
+	return q
+}
+
+// Do runs fn in a worker and waits until it returns. Fails without running fn if the queue is full, or
+// if the context is done while waiting in the queue.
+func (q *Queue) Do(ctx context.Context, priority service.Priority, fn func()) error {
+	j := &job{run: fn, done: make(chan struct{})}
+
+	q.mutex.Lock()
+	if q.stopped {
+		q.mutex.Unlock()
+		return ErrStopped
+	} else if q.count >= q.length {
+		q.mutex.Unlock()
+		logging.GetLogger().Infof("queue of issuer [%s] is full, %d requests are waiting", q.name, q.length)
+		return ErrFull
+	}
+
+	priority = normalize(priority)
+	q.waiting[priority] = append(q.waiting[priority], j)
+	q.count++
+	q.cond.Signal()
+	q.mutex.Unlock()
+
+	// ----
+
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mutex.Lock()
+	if !j.started {
+		j.canceled = true
+		q.count--
+		q.mutex.Unlock()
+		return ctx.Err()
+	}
+	q.mutex.Unlock()
+
+	// fn is running already, it sees the same context
+	<-j.done
+	return nil
+}
+
+func (q *Queue) Stats() Stats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return Stats{Waiting: q.count, Running: q.running}
+}
+
+// Full returns true if a new job would be refused with ErrFull
+func (q *Queue) Full() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.count >= q.length
+}
+
+// Stop refuses new jobs, waiting jobs are still run
+func (q *Queue) Stop() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.stopped = true
+	q.cond.Broadcast()
+}
+
+// ----
+
+func (q *Queue) work() {
+	for {
+		j := q.next()
+		if j == nil {
+			return
+		}
+
+		j.run()
+
+		q.mutex.Lock()
+		q.running--
+		q.mutex.Unlock()
+		close(j.done)
+	}
+}
+
+// next blocks until there is a job to run, returns nil if queue is stopped and there is no job left
+func (q *Queue) next() *job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for {
+		for _, priority := range []service.Priority{service.PriorityHigh, service.PriorityNormal, service.PriorityLow} {
+			for len(q.waiting[priority]) > 0 {
+				j := q.waiting[priority][0]
+				q.waiting[priority] = q.waiting[priority][1:]
+				if j.canceled {
+					continue
+				}
+
+				j.started = true
+				q.count--
+				q.running++
+				return j
+			}
+		}
+
+		if q.stopped {
+			return nil
+		}
+
+		q.cond.Wait()
+	}
+}
+
+func normalize(priority service.Priority) service.Priority {
+	if priority == service.PriorityHigh || priority == service.PriorityLow {
+		return priority
+	}
+
+	return service.PriorityNormal
+}
@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+)
+
+func TestQueueRunsJobs(t *testing.T) {
+	q := New("issuer", Config{Concurrency: 2})
+	defer q.Stop()
+
+	ran := false
+	err := q.Do(context.Background(), service.PriorityNormal, func() { ran = true })
+
+	assert.NotError(t, err, "running job failed")
+	assert.True(t, ran)
+	assert.Equal(t, Stats{}, q.Stats())
+}
+
+func TestQueueFull(t *testing.T) {
+	q := New("issuer", Config{Concurrency: 1, Length: 1})
+	defer q.Stop()
+
+	release := blockWorker(t, q)
+	defer close(release)
+
+	go q.Do(context.Background(), service.PriorityNormal, func() {})
+	waitStats(t, q, Stats{Waiting: 1, Running: 1})
+
+	assert.True(t, q.Full())
+	err := q.Do(context.Background(), service.PriorityNormal, func() {})
+	assert.Equal(t, ErrFull, err)
+}
+
+func TestQueuePriority(t *testing.T) {
+	q := New("issuer", Config{Concurrency: 1})
+	defer q.Stop()
+
+	release := blockWorker(t, q)
+
+	var mutex sync.Mutex
+	order := []service.Priority{}
+	var wg sync.WaitGroup
+	enqueue := func(priority service.Priority, waiting int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Do(context.Background(), priority, func() {
+				mutex.Lock()
+				order = append(order, priority)
+				mutex.Unlock()
+			})
+		}()
+		waitStats(t, q, Stats{Waiting: waiting, Running: 1})
+	}
+
+	enqueue(service.PriorityLow, 1)
+	enqueue("", 2)
+	enqueue(service.PriorityHigh, 3)
+
+	close(release)
+	wg.Wait()
+
+	assert.DeepEqual(t, []service.Priority{service.PriorityHigh, "", service.PriorityLow}, order)
+}
+
+func TestQueueContextDoneWhileWaiting(t *testing.T) {
+	q := New("issuer", Config{Concurrency: 1})
+	defer q.Stop()
+
+	release := blockWorker(t, q)
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran := false
+	err := q.Do(ctx, service.PriorityNormal, func() { ran = true })
+
+	assert.Equal(t, context.Canceled, err)
+	assert.False(t, ran)
+	assert.Equal(t, Stats{Running: 1}, q.Stats())
+}
+
+func TestQueueStopped(t *testing.T) {
+	q := New("issuer", Config{Concurrency: 1})
+	q.Stop()
+
+	err := q.Do(context.Background(), service.PriorityNormal, func() {})
+	assert.Equal(t, ErrStopped, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NotError(t, (&Config{Concurrency: 1}).Validate(), "config should be valid")
+	assert.Error(t, (&Config{Concurrency: -1}).Validate(), "negative concurrency should not be valid")
+	assert.Error(t, (&Config{Length: -1}).Validate(), "negative length should not be valid")
+}
+
+// ----
+
+// blockWorker keeps a worker busy until returned channel is closed
+func blockWorker(t *testing.T, q *Queue) chan struct{} {
+	release := make(chan struct{})
+	go q.Do(context.Background(), service.PriorityNormal, func() { <-release })
+	waitStats(t, q, Stats{Running: 1})
+
+	return release
+}
+
+func waitStats(t *testing.T, q *Queue, expected Stats) {
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Stats() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue stats are %+v, expected %+v", q.Stats(), expected)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
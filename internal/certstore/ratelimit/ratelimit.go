@@ -0,0 +1,333 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/logging"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	DEFAULT_WINDOW = 7 * 24 * time.Hour
+
+	// https://letsencrypt.org/docs/rate-limits/
+	LETS_ENCRYPT_CERTIFICATES_PER_DOMAIN = 50
+	LETS_ENCRYPT_DUPLICATE_CERTIFICATES  = 5
+
+	STORAGE_BUCKET_PREFIX = "ratelimit/"
+	DOMAIN_KEY_PREFIX     = "domain/"
+	NAME_SET_KEY_PREFIX   = "names/"
+)
+
+// zero limit means the limit is not enforced
+type Config struct {
+	CertificatesPerDomain int           `yaml:"certificates-per-domain"`
+	DuplicateCertificates int           `yaml:"duplicate-certificates"`
+	Window                time.Duration `yaml:"window"`
+}
+
+func LetsEncryptConfig() *Config {
+	return &Config{
+		CertificatesPerDomain: LETS_ENCRYPT_CERTIFICATES_PER_DOMAIN,
+		DuplicateCertificates: LETS_ENCRYPT_DUPLICATE_CERTIFICATES,
+		Window:                DEFAULT_WINDOW,
+	}
+}
+
+func (c *Config) Enabled() bool {
+	return c.CertificatesPerDomain > 0 || c.DuplicateCertificates > 0
+}
+
+type Budget struct {
+	Window            time.Duration
+	RegisteredDomains []DomainBudget
+	Duplicate         DomainBudget
+}
+
+type DomainBudget struct {
+	Domain    string
+	Limit     int
+	Used      int
+	Remaining int
+}
+
+type ExceededError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: %s, retry after %s", e.Reason, e.RetryAfter)
+}
+
+// ----
+
+type TimeProvider func() time.Time
+
+// Tracker counts issued certificates per registered domain and per exact set of names in a sliding window
+type Tracker struct {
+	mutex        sync.Mutex
+	config       Config
+	bucket       string
+	storage      storage.Storage
+	timeProvider TimeProvider
+}
+
+func NewTracker(issuer string, config Config, storage storage.Storage) *Tracker {
+	return NewTrackerWithTimeProvider(issuer, config, storage, time.Now)
+}
+
+func NewTrackerWithTimeProvider(issuer string, config Config, storage storage.Storage, timeProvider TimeProvider) *Tracker {
+	if config.Window <= 0 {
+		config.Window = DEFAULT_WINDOW
+	}
+
+	return &Tracker{
+		config:       config,
+		bucket:       STORAGE_BUCKET_PREFIX + issuer,
+		storage:      storage,
+		timeProvider: timeProvider,
+	}
+}
+
+// Reservation is an issuance counted in the budgets before the certificate is issued
+type Reservation struct {
+	tracker *Tracker
+	keys    []string
+	at      time.Time
+}
+
+// Reserve counts an issuance of a certificate for the domains if it does not exceed any budget, returns
+// ExceededError otherwise. Checking and counting at once keeps concurrent requests from exceeding the budgets
+// together, the reservation is released if the issuance fails
+func (t *Tracker) Reserve(domains []string) (*Reservation, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.timeProvider()
+	err := t.check(domains, now)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &Reservation{tracker: t, at: now}
+	for _, key := range issuanceKeys(domains) {
+		issuances, err := t.load(key, now)
+		if err == nil {
+			err = t.save(key, append(issuances, now))
+		}
+		if err != nil {
+			reservation.release()
+			return nil, err
+		}
+
+		reservation.keys = append(reservation.keys, key)
+	}
+
+	return reservation, nil
+}
+
+// Release removes the reserved issuance from the budgets, e.g. issuer failed to issue the certificate
+func (r *Reservation) Release() error {
+	r.tracker.mutex.Lock()
+	defer r.tracker.mutex.Unlock()
+
+	return r.release()
+}
+
+func (r *Reservation) release() error {
+	now := r.tracker.timeProvider()
+	for _, key := range r.keys {
+		issuances, err := r.tracker.load(key, now)
+		if err != nil {
+			return err
+		}
+
+		for index, issuance := range issuances {
+			if issuance.Equal(r.at) {
+				issuances = append(issuances[:index], issuances[index+1:]...)
+				break
+			}
+		}
+
+		err = r.tracker.save(key, issuances)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.keys = nil
+	return nil
+}
+
+func (t *Tracker) Budget(domains []string) (*Budget, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.timeProvider()
+	budget := &Budget{Window: t.config.Window}
+
+	for _, registeredDomain := range registeredDomains(domains) {
+		issuances, err := t.load(DOMAIN_KEY_PREFIX+registeredDomain, now)
+		if err != nil {
+			return nil, err
+		}
+
+		budget.RegisteredDomains = append(budget.RegisteredDomains,
+			newDomainBudget(registeredDomain, t.config.CertificatesPerDomain, len(issuances)))
+	}
+
+	issuances, err := t.load(nameSetKey(domains), now)
+	if err != nil {
+		return nil, err
+	}
+	budget.Duplicate = newDomainBudget(strings.Join(normalizeNames(domains), ","), t.config.DuplicateCertificates, len(issuances))
+
+	return budget, nil
+}
+
+// ----
+
+func (t *Tracker) check(domains []string, now time.Time) error {
+	if t.config.CertificatesPerDomain > 0 {
+		for _, registeredDomain := range registeredDomains(domains) {
+			issuances, err := t.load(DOMAIN_KEY_PREFIX+registeredDomain, now)
+			if err != nil {
+				return err
+			}
+
+			if len(issuances) >= t.config.CertificatesPerDomain {
+				return &ExceededError{
+					Reason:     fmt.Sprintf("%d certificates issued for registered domain [%s]", len(issuances), registeredDomain),
+					RetryAfter: issuances[0].Add(t.config.Window).Sub(now),
+				}
+			}
+		}
+	}
+
+	if t.config.DuplicateCertificates > 0 {
+		issuances, err := t.load(nameSetKey(domains), now)
+		if err != nil {
+			return err
+		}
+
+		if len(issuances) >= t.config.DuplicateCertificates {
+			return &ExceededError{
+				Reason:     fmt.Sprintf("%d duplicate certificates issued for %v", len(issuances), normalizeNames(domains)),
+				RetryAfter: issuances[0].Add(t.config.Window).Sub(now),
+			}
+		}
+	}
+
+	return nil
+}
+
+// load returns issuance times in the window, ordered from oldest to newest
+func (t *Tracker) load(key string, now time.Time) ([]time.Time, error) {
+	value, err := t.storage.Get(t.bucket, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return []time.Time{}, nil
+	} else if err != nil {
+		logging.GetLogger().Errorf("loading rate limit record failed, %v", err)
+		return nil, err
+	}
+
+	issuances := []time.Time{}
+	err = json.Unmarshal(value, &issuances)
+	if err != nil {
+		logging.GetLogger().Errorf("decoding rate limit record failed, %v", err)
+		return nil, err
+	}
+
+	windowStart := now.Add(-t.config.Window)
+	inWindow := []time.Time{}
+	for _, issuance := range issuances {
+		if issuance.After(windowStart) {
+			inWindow = append(inWindow, issuance)
+		}
+	}
+
+	sort.Slice(inWindow, func(i, j int) bool { return inWindow[i].Before(inWindow[j]) })
+	return inWindow, nil
+}
+
+func (t *Tracker) save(key string, issuances []time.Time) error {
+	value, err := json.Marshal(issuances)
+	if err != nil {
+		return err
+	}
+
+	return t.storage.Put(t.bucket, key, value)
+}
+
+func newDomainBudget(domain string, limit int, used int) DomainBudget {
+	remaining := limit - used
+	if remaining < 0 || limit == 0 {
+		remaining = 0
+	}
+
+	return DomainBudget{Domain: domain, Limit: limit, Used: used, Remaining: remaining}
+}
+
+func normalizeNames(domains []string) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, domain := range domains {
+		name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// issuanceKeys are the records an issuance is counted in, the set of names and each registered domain
+func issuanceKeys(domains []string) []string {
+	keys := []string{nameSetKey(domains)}
+	for _, registeredDomain := range registeredDomains(domains) {
+		keys = append(keys, DOMAIN_KEY_PREFIX+registeredDomain)
+	}
+
+	return keys
+}
+
+func nameSetKey(domains []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(normalizeNames(domains), ",")))
+	return NAME_SET_KEY_PREFIX + hex.EncodeToString(hash[:])
+}
+
+// registeredDomains returns distinct registered domains (eTLD+1) of the names, uses the name itself when
+// registered domain can not be found, e.g. for a public suffix
+func registeredDomains(domains []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, name := range normalizeNames(domains) {
+		name = strings.TrimPrefix(name, "*.")
+
+		registeredDomain, err := publicsuffix.EffectiveTLDPlusOne(name)
+		if err != nil {
+			registeredDomain = name
+		}
+
+		if !seen[registeredDomain] {
+			seen[registeredDomain] = true
+			result = append(result, registeredDomain)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+)
+
+func TestCertificatesPerDomainExceeded(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	tracker := createTracker(Config{CertificatesPerDomain: 2}, &now)
+
+	recordIssuance(t, tracker, "a.certstore.com")
+	recordIssuance(t, tracker, "b.certstore.com")
+
+	_, err := tracker.Reserve([]string{"c.certstore.com"})
+	assert.ErrorContains(t, err, "registered domain [certstore.com]")
+
+	// other registered domains are not affected
+	_, err = tracker.Reserve([]string{"certstore.org"})
+	assert.NotError(t, err, "other registered domain should have budget")
+}
+
+func TestCertificatesPerDomainWindow(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	tracker := createTracker(Config{CertificatesPerDomain: 1, Window: 24 * time.Hour}, &now)
+
+	recordIssuance(t, tracker, "a.certstore.com")
+
+	now = now.Add(10 * time.Hour)
+	_, err := tracker.Reserve([]string{"b.certstore.com"})
+
+	var exceeded *ExceededError
+	assert.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, 14*time.Hour, exceeded.RetryAfter)
+
+	// ----
+
+	now = now.Add(15 * time.Hour)
+	_, err = tracker.Reserve([]string{"b.certstore.com"})
+	assert.NotError(t, err, "issuance should be out of window")
+}
+
+func TestDuplicateCertificatesExceeded(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	tracker := createTracker(Config{DuplicateCertificates: 1}, &now)
+
+	recordIssuance(t, tracker, "certstore.com", "www.certstore.com")
+
+	// same set of names in different order and case
+	_, err := tracker.Reserve([]string{"WWW.certstore.com", "certstore.com"})
+	assert.ErrorContains(t, err, "duplicate certificates")
+
+	_, err = tracker.Reserve([]string{"certstore.com"})
+	assert.NotError(t, err, "different set of names should have budget")
+}
+
+func TestPublicSuffixAwareRegisteredDomain(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	tracker := createTracker(Config{CertificatesPerDomain: 1}, &now)
+
+	recordIssuance(t, tracker, "a.certstore.co.uk")
+
+	_, err := tracker.Reserve([]string{"*.b.certstore.co.uk"})
+	assert.ErrorContains(t, err, "registered domain [certstore.co.uk]")
+
+	_, err = tracker.Reserve([]string{"other.co.uk"})
+	assert.NotError(t, err, "other registered domain should have budget")
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	tracker := createTracker(Config{CertificatesPerDomain: 50, DuplicateCertificates: 5}, &now)
+
+	recordIssuance(t, tracker, "certstore.com", "www.certstore.com")
+	recordIssuance(t, tracker, "api.certstore.com")
+
+	budget, err := tracker.Budget([]string{"certstore.com", "www.certstore.com", "certstore.org"})
+	assert.NotError(t, err, "getting budget failed")
+
+	assert.Equal(t, DEFAULT_WINDOW, budget.Window)
+	assert.Equal(t, 2, len(budget.RegisteredDomains))
+
+	assert.Equal(t, "certstore.com", budget.RegisteredDomains[0].Domain)
+	assert.Equal(t, 2, budget.RegisteredDomains[0].Used)
+	assert.Equal(t, 48, budget.RegisteredDomains[0].Remaining)
+
+	assert.Equal(t, "certstore.org", budget.RegisteredDomains[1].Domain)
+	assert.Equal(t, 0, budget.RegisteredDomains[1].Used)
+
+	assert.Equal(t, 0, budget.Duplicate.Used)
+	assert.Equal(t, 5, budget.Duplicate.Remaining)
+
+	// ----
+
+	budget, err = tracker.Budget([]string{"www.certstore.com", "certstore.com"})
+	assert.NotError(t, err, "getting budget failed")
+
+	assert.Equal(t, 1, budget.Duplicate.Used)
+	assert.Equal(t, 4, budget.Duplicate.Remaining)
+}
+
+func TestDisabledLimits(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	config := Config{}
+	tracker := createTracker(config, &now)
+
+	recordIssuance(t, tracker, "certstore.com")
+	recordIssuance(t, tracker, "certstore.com")
+
+	assert.False(t, config.Enabled())
+	_, err := tracker.Reserve([]string{"certstore.com"})
+	assert.NotError(t, err, "limits are disabled")
+}
+
+func TestReserveConcurrently(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	tracker := createTracker(Config{DuplicateCertificates: 3}, &now)
+
+	var reserved int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tracker.Reserve([]string{"certstore.com"})
+			if err == nil {
+				atomic.AddInt32(&reserved, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), reserved)
+}
+
+func TestReleaseReservation(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	tracker := createTracker(Config{CertificatesPerDomain: 2, DuplicateCertificates: 1}, &now)
+
+	recordIssuance(t, tracker, "a.certstore.com")
+	reservation, err := tracker.Reserve([]string{"b.certstore.com"})
+	assert.NotError(t, err, "reserving issuance failed")
+
+	_, err = tracker.Reserve([]string{"c.certstore.com"})
+	assert.ErrorContains(t, err, "registered domain [certstore.com]")
+
+	// ----
+
+	err = reservation.Release()
+	assert.NotError(t, err, "releasing reservation failed")
+
+	budget, err := tracker.Budget([]string{"b.certstore.com"})
+	assert.NotError(t, err, "getting budget failed")
+	assert.Equal(t, 1, budget.RegisteredDomains[0].Used)
+	assert.Equal(t, 0, budget.Duplicate.Used)
+
+	_, err = tracker.Reserve([]string{"c.certstore.com"})
+	assert.NotError(t, err, "released reservation should leave budget")
+}
+
+// ----
+
+func createTracker(config Config, now *time.Time) *Tracker {
+	return NewTrackerWithTimeProvider("test issuer", config, storage.NewMemoryStorage(), func() time.Time {
+		return *now
+	})
+}
+
+func recordIssuance(t *testing.T, tracker *Tracker, domains ...string) {
+	_, err := tracker.Reserve(domains)
+	assert.NotError(t, err, "reserving issuance failed")
+}
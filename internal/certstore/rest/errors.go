@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// same mapping with grpc-gateway, codes missing here are served as internal server error
+var codeStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+}
+
+func httpStatus(code codes.Code) int {
+	statusCode, exist := codeStatuses[code]
+	if !exist {
+		return http.StatusInternalServerError
+	}
+
+	return statusCode
+}
+
+// writeError writes the grpc status of err as json, details of the status are kept, e.g. field violations.
+// Retry-After header is set if the status has retry info
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	for _, detail := range st.Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok && retryInfo.RetryDelay != nil {
+			seconds := math.Ceil(retryInfo.RetryDelay.AsDuration().Seconds())
+			w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+		}
+	}
+
+	writeJSON(w, httpStatus(st.Code()), st.Proto())
+}
+
+func invalidBodyError(err error) error {
+	return status.Error(codes.InvalidArgument, fmt.Sprintf("invalid request body, %v", err))
+}
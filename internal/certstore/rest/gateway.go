@@ -0,0 +1,241 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+	grpc_pkg "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	OPENAPI_PATH      = "/openapi.yaml"
+	ISSUERS_PATH      = "/v1/issuers"
+	CERTIFICATES_PATH = "/v1/certificates"
+
+	// interceptors see requests under the full name of the grpc method they mirror
+	GRPC_SERVICE_NAME = "/proto.CertificateService/"
+
+	MAX_REQUEST_BODY_BYTES = 1 << 20
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var (
+	marshalOptions   = protojson.MarshalOptions{}
+	unmarshalOptions = protojson.UnmarshalOptions{}
+)
+
+// gateway serves certificate rpcs as json over http, requests are handed to the grpc service as is so that
+// validation and errors are the same for both
+type gateway struct {
+	service      grpc.CertificateServiceServer
+	interceptors []grpc_pkg.UnaryServerInterceptor
+}
+
+// NewHandler returns the http handler of certificate rpcs, authentication is left to the tls config of the listener.
+// Requests are run through interceptors, e.g. for metrics and audit, first one is the outermost as in
+// grpc.ChainUnaryInterceptor
+func NewHandler(service grpc.CertificateServiceServer, interceptors ...grpc_pkg.UnaryServerInterceptor) http.Handler {
+	g := &gateway{service: service, interceptors: interceptors}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OPENAPI_PATH, g.serveOpenAPI)
+	mux.HandleFunc(ISSUERS_PATH, g.serveIssuers)
+	mux.HandleFunc(CERTIFICATES_PATH, g.serveCertificates)
+	mux.HandleFunc(CERTIFICATES_PATH+"/", g.serveCertificate)
+	return mux
+}
+
+func (g *gateway) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+// GET /v1/issuers
+func (g *gateway) serveIssuers(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	resp, err := g.invoke(r, "ListIssuers", &grpc.ListIssuersRequest{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return g.service.ListIssuers(ctx, req.(*grpc.ListIssuersRequest))
+		})
+	writeResponse(w, resp, err)
+}
+
+// GET /v1/certificates?issuer=<issuer>, POST /v1/certificates
+func (g *gateway) serveCertificates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		req := &grpc.ListCertificatesRequest{Issuer: r.URL.Query().Get("issuer")}
+		resp, err := g.invoke(r, "ListCertificates", req,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return g.service.ListCertificates(ctx, req.(*grpc.ListCertificatesRequest))
+			})
+		writeResponse(w, resp, err)
+	case http.MethodPost:
+		req := &grpc.CertificateRequestV2{}
+		if !readRequest(w, r, req) {
+			return
+		}
+
+		resp, err := g.invoke(r, "IssueCertificateV2", req,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return g.service.IssueCertificateV2(ctx, req.(*grpc.CertificateRequestV2))
+			})
+		writeResponse(w, resp, err)
+	default:
+		allowMethod(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// GET /v1/certificates/<issuer>/<serial-number>, POST /v1/certificates/<issuer>/<serial-number>/revoke
+func (g *gateway) serveCertificate(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, CERTIFICATES_PATH+"/"), "/")
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		req := &grpc.GetCertificateRequest{Issuer: parts[0], SerialNumber: parts[1]}
+		resp, err := g.invoke(r, "GetCertificate", req,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return g.service.GetCertificate(ctx, req.(*grpc.GetCertificateRequest))
+			})
+		writeResponse(w, resp, err)
+	} else if len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] == "revoke" {
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+
+		req := &grpc.RevokeCertificateRequest{}
+		if !readRequest(w, r, req) {
+			return
+		}
+
+		// path takes precedence over the body
+		req.Issuer = parts[0]
+		req.SerialNumber = parts[1]
+		resp, err := g.invoke(r, "RevokeCertificate", req,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return g.service.RevokeCertificate(ctx, req.(*grpc.RevokeCertificateRequest))
+			})
+		writeResponse(w, resp, err)
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+// invoke runs handler through the interceptors of the grpc server, with the peer of the http request so that
+// interceptors see the same identity
+func (g *gateway) invoke(r *http.Request, method string, req interface{}, handler grpc_pkg.UnaryHandler) (proto.Message, error) {
+	ctx := r.Context()
+	if r.TLS != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{
+			Addr:     remoteAddr(r.RemoteAddr),
+			AuthInfo: credentials.TLSInfo{State: *r.TLS},
+		})
+	}
+
+	info := &grpc_pkg.UnaryServerInfo{Server: g.service, FullMethod: GRPC_SERVICE_NAME + method}
+	for index := len(g.interceptors) - 1; index >= 0; index-- {
+		interceptor, next := g.interceptors[index], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(proto.Message), nil
+}
+
+// ----
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// readRequest decodes json body into req, writes the error response and returns false if it fails. Empty body is
+// an empty request
+func readRequest(w http.ResponseWriter, r *http.Request, req proto.Message) bool {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MAX_REQUEST_BODY_BYTES))
+	if err != nil {
+		writeError(w, invalidBodyError(err))
+		return false
+	}
+
+	if len(body) == 0 {
+		return true
+	}
+
+	err = unmarshalOptions.Unmarshal(body, req)
+	if err != nil {
+		logging.GetLogger().Debugf("Decoding request body failed in rest gateway, %v", err)
+		writeError(w, invalidBodyError(err))
+		return false
+	}
+
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, resp proto.Message, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, message proto.Message) {
+	body, err := marshalOptions.Marshal(message)
+	if err != nil {
+		logging.GetLogger().Errorf("Encoding response failed in rest gateway, %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// ----
+
+// remoteAddr is the address of an http peer, in host:port form
+type remoteAddr string
+
+func (a remoteAddr) Network() string {
+	return "tcp"
+}
+
+func (a remoteAddr) String() string {
+	return string(a)
+}
@@ -0,0 +1,238 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	certstore_pkg "bilalekrem.com/certstore/internal/certstore"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	grpc_service "bilalekrem.com/certstore/internal/certstore/grpc/service"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"github.com/golang/mock/gomock"
+	grpc_pkg "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestListIssuers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{{Name: "lets-encrypt", Type: "LetsEncrypt"}})
+
+	resp := serve(t, certstore, http.MethodGet, "/v1/issuers", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	issuers := &grpc.ListIssuersResponse{}
+	decode(t, resp, issuers)
+	assert.Equal(t, 1, len(issuers.Issuers))
+	assert.Equal(t, "lets-encrypt", issuers.Issuers[0].Name)
+}
+
+func TestIssueCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		IssueCertificate(gomock.Any(), gomock.Eq("issuer"), gomock.Any()).
+		DoAndReturn(func(_ interface{}, _ string, req *certificate_service.NewCertificateRequest) (*certificate_service.NewCertificateResponse, error) {
+			assert.Equal(t, "certstore.com", req.CommonName)
+			assert.DeepEqual(t, []string{"www.certstore.com"}, req.SubjectAlternativeNames)
+			assert.Equal(t, certificate_service.PriorityHigh, req.Priority)
+			return &certificate_service.NewCertificateResponse{PrivateKey: []byte("private-key")}, nil
+		})
+
+	body := `{"issuer": "issuer", "subject": {"commonName": "certstore.com"}, "SANs": ["www.certstore.com"], "priority": "HIGH"}`
+	resp := serve(t, certstore, http.MethodPost, "/v1/certificates", body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	issued := &grpc.CertificateResponseV2{}
+	decode(t, resp, issued)
+	assert.Equal(t, "private-key", string(issued.PrivateKey))
+}
+
+func TestIssueCertificateInvalidBody(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+
+	resp := serve(t, certstore, http.MethodPost, "/v1/certificates", `{"issuer": "issuer", "unknown": true}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestIssueCertificateRateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		IssueCertificate(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, certificate_service.NewRateLimitedError("rate limit exceeded", 90*time.Second, nil))
+
+	resp := serve(t, certstore, http.MethodPost, "/v1/certificates", `{"issuer": "issuer"}`)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "90", resp.Header.Get("Retry-After"))
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.True(t, strings.Contains(string(body), "rate limit exceeded"))
+	assert.True(t, strings.Contains(string(body), "google.rpc.RetryInfo"))
+}
+
+func TestListCertificates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListCertificates(gomock.Any(), gomock.Eq("issuer")).
+		Return([]*inventory.Certificate{{Issuer: "issuer", SerialNumber: "01", PrivateKey: []byte("private-key")}}, nil)
+
+	resp := serve(t, certstore, http.MethodGet, "/v1/certificates?issuer=issuer", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.False(t, strings.Contains(string(body), "rivate"))
+
+	certificates := &grpc.ListCertificatesResponse{}
+	assert.NotError(t, protojson.Unmarshal(body, certificates), "decoding response failed")
+	assert.Equal(t, 1, len(certificates.Certificates))
+	assert.Equal(t, "01", certificates.Certificates[0].SerialNumber)
+}
+
+func TestGetCertificateNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		GetCertificate(gomock.Any(), gomock.Eq("issuer"), gomock.Eq("01")).
+		Return(nil, certificate_service.NewNotFoundError("Certificate not found: [issuer/01]", nil))
+
+	resp := serve(t, certstore, http.MethodGet, "/v1/certificates/issuer/01", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestRevokeCertificate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		RevokeCertificate(gomock.Any(), gomock.Eq("issuer"), gomock.Eq("01"), gomock.Eq(certificate_service.RevocationSuperseded)).
+		Return(&inventory.Certificate{
+			Issuer:           "issuer",
+			SerialNumber:     "01",
+			RevokedAt:        time.Now(),
+			RevocationReason: certificate_service.RevocationSuperseded,
+		}, nil)
+
+	resp := serve(t, certstore, http.MethodPost, "/v1/certificates/issuer/01/revoke", `{"reason": "SUPERSEDED"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	revoked := &grpc.InventoryCertificate{}
+	decode(t, resp, revoked)
+	assert.True(t, revoked.Revoked)
+	assert.Equal(t, grpc.RevocationReason_SUPERSEDED, revoked.RevocationReason)
+}
+
+func TestRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+
+	resp := serve(t, certstore, http.MethodGet, "/openapi.yaml", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+
+	resp = serve(t, certstore, http.MethodDelete, "/v1/certificates/issuer/01", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "GET", resp.Header.Get("Allow"))
+
+	resp = serve(t, certstore, http.MethodGet, "/v1/certificates/issuer/01/revoke", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp = serve(t, certstore, http.MethodGet, "/v1/certificates/issuer", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{})
+
+	var method string
+	var agent string
+	intercepted := []string{}
+	interceptor := func(ctx context.Context, req interface{}, info *grpc_pkg.UnaryServerInfo,
+		handler grpc_pkg.UnaryHandler) (interface{}, error) {
+
+		method = info.FullMethod
+		p, _ := peer.FromContext(ctx)
+		agent = p.AuthInfo.(credentials.TLSInfo).State.PeerCertificates[0].Subject.CommonName
+		intercepted = append(intercepted, "first")
+		return handler(ctx, req)
+	}
+	innerInterceptor := func(ctx context.Context, req interface{}, info *grpc_pkg.UnaryServerInfo,
+		handler grpc_pkg.UnaryHandler) (interface{}, error) {
+
+		intercepted = append(intercepted, "second")
+		return handler(ctx, req)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/issuers", nil)
+	request.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "agent"}}},
+	}
+
+	recorder := httptest.NewRecorder()
+	NewHandler(grpc_service.NewCertificateService(certstore), interceptor, innerInterceptor).ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "/proto.CertificateService/ListIssuers", method)
+	assert.Equal(t, "agent", agent)
+	assert.DeepEqual(t, []string{"first", "second"}, intercepted)
+}
+
+// ----
+
+func serve(t *testing.T, certstore certstore_pkg.CertStore, method string, target string, body string) *http.Response {
+	recorder := httptest.NewRecorder()
+	handler := NewHandler(grpc_service.NewCertificateService(certstore))
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	return recorder.Result()
+}
+
+func decode(t *testing.T, resp *http.Response, message proto.Message) {
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NotError(t, err, "reading response failed")
+	assert.NotError(t, protojson.Unmarshal(body, message), "decoding response failed")
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	b64 "encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+// fileStorage keeps each bucket in a directory and each value in a file, file names are base64 encoded keys
+type fileStorage struct {
+	mutex sync.RWMutex
+	path  string
+}
+
+func NewFileStorage(path string) (*fileStorage, error) {
+	err := os.MkdirAll(path, 0700)
+	if err != nil {
+		logging.GetLogger().Errorf("creating storage directory failed, %v", err)
+		return nil, err
+	}
+
+	return &fileStorage{path: path}, nil
+}
+
+func (s *fileStorage) Get(bucket string, key string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, err := ioutil.ReadFile(s.filePath(bucket, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (s *fileStorage) Put(bucket string, key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.MkdirAll(s.bucketPath(bucket), 0700)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that a crash does not leave a half written value behind
+	path := s.filePath(bucket, key)
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, value, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (s *fileStorage) Delete(bucket string, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.Remove(s.filePath(bucket, key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *fileStorage) List(bucket string) (map[string][]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	values := make(map[string][]byte)
+
+	files, err := ioutil.ReadDir(s.bucketPath(bucket))
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) == ".tmp" {
+			continue
+		}
+
+		key, err := b64.RawURLEncoding.DecodeString(file.Name())
+		if err != nil {
+			logging.GetLogger().Warnf("unknown file in storage bucket [%s]: [%s]", bucket, file.Name())
+			continue
+		}
+
+		value, err := ioutil.ReadFile(filepath.Join(s.bucketPath(bucket), file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		values[string(key)] = value
+	}
+
+	return values, nil
+}
+
+// ----
+
+func (s *fileStorage) bucketPath(bucket string) string {
+	return filepath.Join(s.path, b64.RawURLEncoding.EncodeToString([]byte(bucket)))
+}
+
+func (s *fileStorage) filePath(bucket string, key string) string {
+	return filepath.Join(s.bucketPath(bucket), b64.RawURLEncoding.EncodeToString([]byte(key)))
+}
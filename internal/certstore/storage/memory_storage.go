@@ -0,0 +1,64 @@
+package storage
+
+import "sync"
+
+type memoryStorage struct {
+	mutex   sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+func NewMemoryStorage() *memoryStorage {
+	return &memoryStorage{buckets: make(map[string]map[string][]byte)}
+}
+
+func (s *memoryStorage) Get(bucket string, key string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, exist := s.buckets[bucket][key]
+	if !exist {
+		return nil, ErrNotFound
+	}
+
+	return copyBytes(value), nil
+}
+
+func (s *memoryStorage) Put(bucket string, key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values, exist := s.buckets[bucket]
+	if !exist {
+		values = make(map[string][]byte)
+		s.buckets[bucket] = values
+	}
+
+	values[key] = copyBytes(value)
+	return nil
+}
+
+func (s *memoryStorage) Delete(bucket string, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.buckets[bucket], key)
+	return nil
+}
+
+func (s *memoryStorage) List(bucket string) (map[string][]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	values := make(map[string][]byte)
+	for key, value := range s.buckets[bucket] {
+		values[key] = copyBytes(value)
+	}
+
+	return values, nil
+}
+
+func copyBytes(value []byte) []byte {
+	copied := make([]byte, len(value))
+	copy(copied, value)
+	return copied
+}
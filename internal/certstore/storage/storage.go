@@ -0,0 +1,21 @@
+package storage
+
+import "errors"
+
+var ErrNotFound = errors.New("storage: key not found")
+
+// Storage keeps server state as raw values grouped into buckets
+type Storage interface {
+	Get(bucket string, key string) ([]byte, error)
+	Put(bucket string, key string, value []byte) error
+	Delete(bucket string, key string) error
+	List(bucket string) (map[string][]byte, error)
+}
+
+func New(path string) (Storage, error) {
+	if path == "" {
+		return NewMemoryStorage(), nil
+	}
+
+	return NewFileStorage(path)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemoryStorage())
+}
+
+func TestFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_file_storage")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	storage, err := NewFileStorage(dir)
+	assert.NotError(t, err, "creating file storage failed")
+
+	testStorage(t, storage)
+}
+
+func TestFileStoragePersistsValues(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_file_storage")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	storage, err := NewFileStorage(dir)
+	assert.NotError(t, err, "creating file storage failed")
+
+	err = storage.Put("bucket", "key/with:special chars", []byte("value"))
+	assert.NotError(t, err, "putting value failed")
+
+	// ----
+
+	reopened, err := NewFileStorage(dir)
+	assert.NotError(t, err, "reopening file storage failed")
+
+	value, err := reopened.Get("bucket", "key/with:special chars")
+	assert.NotError(t, err, "getting value failed")
+	assert.Equal(t, "value", string(value))
+}
+
+func TestNewWithoutPathIsMemoryStorage(t *testing.T) {
+	storage, err := New("")
+	assert.NotError(t, err, "creating storage failed")
+
+	_, ok := storage.(*memoryStorage)
+	assert.True(t, ok)
+}
+
+// ----
+
+func testStorage(t *testing.T, storage Storage) {
+	_, err := storage.Get("bucket", "missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	err = storage.Put("bucket", "first", []byte("first value"))
+	assert.NotError(t, err, "putting value failed")
+
+	err = storage.Put("bucket", "second", []byte("second value"))
+	assert.NotError(t, err, "putting value failed")
+
+	err = storage.Put("other bucket", "first", []byte("other value"))
+	assert.NotError(t, err, "putting value failed")
+
+	value, err := storage.Get("bucket", "first")
+	assert.NotError(t, err, "getting value failed")
+	assert.Equal(t, "first value", string(value))
+
+	// ----
+
+	values, err := storage.List("bucket")
+	assert.NotError(t, err, "listing bucket failed")
+	assert.Equal(t, 2, len(values))
+	assert.Equal(t, "second value", string(values["second"]))
+
+	// ----
+
+	err = storage.Put("bucket", "first", []byte("updated value"))
+	assert.NotError(t, err, "updating value failed")
+
+	value, err = storage.Get("bucket", "first")
+	assert.NotError(t, err, "getting value failed")
+	assert.Equal(t, "updated value", string(value))
+
+	// ----
+
+	err = storage.Delete("bucket", "first")
+	assert.NotError(t, err, "deleting value failed")
+
+	_, err = storage.Get("bucket", "first")
+	assert.Equal(t, ErrNotFound, err)
+
+	err = storage.Delete("bucket", "first")
+	assert.NotError(t, err, "deleting missing value should not fail")
+
+	values, err = storage.List("empty bucket")
+	assert.NotError(t, err, "listing empty bucket failed")
+	assert.Equal(t, 0, len(values))
+}
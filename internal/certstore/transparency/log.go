@@ -0,0 +1,208 @@
+package transparency
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	// leaves are kept by index, size is written after the leaf so that a partially appended leaf is overwritten
+	// by the next one
+	LEAVES_STORAGE_BUCKET = "transparency/leaves"
+	STORAGE_BUCKET        = "transparency"
+	SIZE_KEY              = "size"
+)
+
+var (
+	ErrNotFound = errors.New("transparency: leaf not found in log")
+	ErrTreeSize = errors.New("transparency: tree size is not valid")
+)
+
+// log is disabled if signing key is empty
+type Config struct {
+	// PEM encoded private key signing tree heads, ECDSA, Ed25519 or RSA
+	SigningKey string `yaml:"signing-key"`
+}
+
+func (c *Config) Enabled() bool {
+	return c.SigningKey != ""
+}
+
+// InclusionProof is the audit path of a leaf in the tree of given size
+type InclusionProof struct {
+	LeafIndex uint64
+	TreeSize  uint64
+	AuditPath [][]byte
+}
+
+type TimeProvider func() time.Time
+
+// Log is an append-only Merkle tree of certificates, leaf and subtree hashes are kept in memory to build proofs
+type Log struct {
+	mutex        sync.RWMutex
+	storage      storage.Storage
+	signer       crypto.Signer
+	tree         *tree
+	indices      map[string]uint64 // by leaf hash
+	timeProvider TimeProvider
+}
+
+func New(conf *Config, storage storage.Storage) (*Log, error) {
+	keyPem, err := ioutil.ReadFile(conf.SigningKey)
+	if err != nil {
+		logging.GetLogger().Errorf("reading transparency log signing key failed, %v", err)
+		return nil, err
+	}
+
+	signer, err := ParseSigningKey(keyPem)
+	if err != nil {
+		logging.GetLogger().Errorf("parsing transparency log signing key failed, %v", err)
+		return nil, err
+	}
+
+	return NewWithSigner(signer, storage, time.Now)
+}
+
+// NewWithSigner loads the leaves appended before from storage
+func NewWithSigner(signer crypto.Signer, storage storage.Storage, timeProvider TimeProvider) (*Log, error) {
+	log := &Log{
+		storage:      storage,
+		signer:       signer,
+		tree:         &tree{},
+		indices:      make(map[string]uint64),
+		timeProvider: timeProvider,
+	}
+
+	size, err := log.storedSize()
+	if err != nil {
+		logging.GetLogger().Errorf("reading transparency log size failed, %v", err)
+		return nil, err
+	}
+
+	for index := uint64(0); index < size; index++ {
+		leaf, err := storage.Get(LEAVES_STORAGE_BUCKET, leafKey(index))
+		if err != nil {
+			logging.GetLogger().Errorf("reading transparency log leaf [%d] failed, %v", index, err)
+			return nil, errors.New(fmt.Sprintf("reading transparency log leaf [%d] failed, %v", index, err))
+		}
+
+		log.add(LeafHash(leaf))
+	}
+
+	logging.GetLogger().Infof("Loaded transparency log, tree size: [%d]", size)
+	return log, nil
+}
+
+func (l *Log) PublicKey() crypto.PublicKey {
+	return l.signer.Public()
+}
+
+// Append adds the leaf and returns its index, a leaf appended before is not added again
+func (l *Log) Append(leaf []byte) (uint64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	leafHash := LeafHash(leaf)
+	if index, exist := l.indices[string(leafHash)]; exist {
+		return index, nil
+	}
+
+	index := uint64(l.tree.size())
+	err := l.storage.Put(LEAVES_STORAGE_BUCKET, leafKey(index), leaf)
+	if err != nil {
+		return 0, err
+	}
+	err = l.storage.Put(STORAGE_BUCKET, SIZE_KEY, []byte(strconv.FormatUint(index+1, 10)))
+	if err != nil {
+		return 0, err
+	}
+
+	l.add(leafHash)
+	return index, nil
+}
+
+// SignedTreeHead signs the current tree with the current time
+func (l *Log) SignedTreeHead() (*SignedTreeHead, error) {
+	l.mutex.RLock()
+	head := &SignedTreeHead{
+		TreeSize:  uint64(l.tree.size()),
+		Timestamp: l.timeProvider().Truncate(time.Millisecond),
+		RootHash:  l.tree.rootHash(l.tree.size()),
+	}
+	l.mutex.RUnlock()
+
+	err := sign(l.signer, head)
+	if err != nil {
+		logging.GetLogger().Errorf("signing transparency log tree head failed, %v", err)
+		return nil, err
+	}
+
+	return head, nil
+}
+
+// InclusionProof proves the leaf is in the tree of given size, the current tree if size is zero. Returns ErrNotFound
+// if the leaf is not in that tree
+func (l *Log) InclusionProof(leafHash []byte, treeSize uint64) (*InclusionProof, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if treeSize == 0 {
+		treeSize = uint64(l.tree.size())
+	} else if treeSize > uint64(l.tree.size()) {
+		return nil, fmt.Errorf("%w, %d is larger than the log size %d", ErrTreeSize, treeSize, l.tree.size())
+	}
+
+	index, exist := l.indices[string(leafHash)]
+	if !exist || index >= treeSize {
+		return nil, ErrNotFound
+	}
+
+	return &InclusionProof{
+		LeafIndex: index,
+		TreeSize:  treeSize,
+		AuditPath: l.tree.inclusionProof(int(treeSize), int(index)),
+	}, nil
+}
+
+// ConsistencyProof proves the tree of first size is a prefix of the tree of second size
+func (l *Log) ConsistencyProof(firstSize uint64, secondSize uint64) ([][]byte, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if firstSize > secondSize || secondSize > uint64(l.tree.size()) {
+		return nil, fmt.Errorf("%w, sizes %d and %d are not ordered within the log size %d",
+			ErrTreeSize, firstSize, secondSize, l.tree.size())
+	}
+
+	return l.tree.consistencyProof(int(firstSize), int(secondSize)), nil
+}
+
+// ----
+
+func (l *Log) add(leafHash []byte) {
+	l.indices[string(leafHash)] = uint64(l.tree.size())
+	l.tree.append(leafHash)
+}
+
+func (l *Log) storedSize() (uint64, error) {
+	value, err := l.storage.Get(STORAGE_BUCKET, SIZE_KEY)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(string(value), 10, 64)
+}
+
+func leafKey(index uint64) string {
+	return fmt.Sprintf("%016d", index)
+}
@@ -0,0 +1,153 @@
+package transparency
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+)
+
+func TestAppendAndProve(t *testing.T) {
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+	log, signer := createLog(t, storage.NewMemoryStorage(), &now)
+
+	for _, leaf := range []string{"first", "second", "third"} {
+		_, err := log.Append([]byte(leaf))
+		assert.NotError(t, err, "appending leaf failed")
+	}
+
+	head, err := log.SignedTreeHead()
+	assert.NotError(t, err, "signing tree head failed")
+	assert.Equal(t, uint64(3), head.TreeSize)
+	assert.Equal(t, now, head.Timestamp)
+	assert.NotError(t, head.Verify(signer.Public()), "verifying tree head failed")
+
+	// ----
+
+	proof, err := log.InclusionProof(LeafHash([]byte("second")), 0)
+	assert.NotError(t, err, "proving inclusion failed")
+	assert.Equal(t, uint64(1), proof.LeafIndex)
+	assert.Equal(t, uint64(3), proof.TreeSize)
+
+	err = VerifyInclusion(LeafHash([]byte("second")), proof.LeafIndex, proof.TreeSize, proof.AuditPath, head.RootHash)
+	assert.NotError(t, err, "verifying inclusion failed")
+
+	_, err = log.InclusionProof(LeafHash([]byte("third")), 2)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = log.InclusionProof(LeafHash([]byte("unknown")), 0)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = log.InclusionProof(LeafHash([]byte("first")), 4)
+	assert.True(t, errors.Is(err, ErrTreeSize))
+}
+
+func TestAppendExisting(t *testing.T) {
+	log, _ := createLog(t, storage.NewMemoryStorage(), nil)
+
+	_, err := log.Append([]byte("first"))
+	assert.NotError(t, err, "appending leaf failed")
+	_, err = log.Append([]byte("second"))
+	assert.NotError(t, err, "appending leaf failed")
+
+	index, err := log.Append([]byte("first"))
+	assert.NotError(t, err, "appending leaf failed")
+	assert.Equal(t, uint64(0), index)
+
+	head, _ := log.SignedTreeHead()
+	assert.Equal(t, uint64(2), head.TreeSize)
+}
+
+func TestConsistencyAfterRestart(t *testing.T) {
+	fileStorage, err := storage.NewFileStorage(t.TempDir())
+	assert.NotError(t, err, "creating storage failed")
+
+	log, signer := createLog(t, fileStorage, nil)
+	log.Append([]byte("first"))
+	log.Append([]byte("second"))
+	log.Append([]byte("third"))
+
+	first, _ := log.SignedTreeHead()
+
+	// ----
+
+	log, err = NewWithSigner(signer, fileStorage, time.Now)
+	assert.NotError(t, err, "loading log failed")
+	log.Append([]byte("fourth"))
+	log.Append([]byte("fifth"))
+
+	second, _ := log.SignedTreeHead()
+	assert.Equal(t, uint64(5), second.TreeSize)
+
+	proof, err := log.ConsistencyProof(first.TreeSize, second.TreeSize)
+	assert.NotError(t, err, "proving consistency failed")
+
+	err = VerifyConsistency(first.TreeSize, second.TreeSize, first.RootHash, second.RootHash, proof)
+	assert.NotError(t, err, "verifying consistency failed")
+
+	_, err = log.ConsistencyProof(3, 6)
+	assert.True(t, errors.Is(err, ErrTreeSize))
+}
+
+func TestTreeHeadSignatureNotValid(t *testing.T) {
+	log, signer := createLog(t, storage.NewMemoryStorage(), nil)
+	log.Append([]byte("first"))
+
+	head, _ := log.SignedTreeHead()
+	head.TreeSize = 2
+	assert.ErrorContains(t, head.Verify(signer.Public()), "signature is not valid")
+
+	// ----
+
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	head, _ = log.SignedTreeHead()
+	assert.Error(t, head.Verify(publicKey), "tree head should not be verified with another key")
+}
+
+func TestNewFromConfig(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	encoded, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+
+	keyPath := filepath.Join(t.TempDir(), "log.key")
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encoded}), 0600)
+
+	log, err := New(&Config{SigningKey: keyPath}, storage.NewMemoryStorage())
+	assert.NotError(t, err, "creating log failed")
+
+	head, _ := log.SignedTreeHead()
+	assert.NotError(t, head.Verify(publicKey), "verifying ed25519 tree head failed")
+
+	// ----
+
+	encodedPublicKey, _ := x509.MarshalPKIXPublicKey(publicKey)
+	parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encodedPublicKey}))
+	assert.NotError(t, err, "parsing public key failed")
+	assert.DeepEqual(t, crypto.PublicKey(publicKey), parsed)
+}
+
+// ----
+
+func createLog(t *testing.T, storage storage.Storage, now *time.Time) (*Log, crypto.Signer) {
+	signer, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	timeProvider := time.Now
+	if now != nil {
+		timeProvider = func() time.Time { return *now }
+	}
+
+	log, err := NewWithSigner(signer, storage, timeProvider)
+	assert.NotError(t, err, "creating log failed")
+
+	return log, signer
+}
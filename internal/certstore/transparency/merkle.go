@@ -0,0 +1,235 @@
+package transparency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// hashes and proofs of the Merkle tree in RFC 6962, leaf and node hashes are prefixed differently so that a leaf
+// can not be presented as a node
+
+const (
+	LEAF_HASH_PREFIX = 0x00
+	NODE_HASH_PREFIX = 0x01
+)
+
+var ErrProofMismatch = errors.New("transparency: proof does not match the tree head")
+
+func LeafHash(leaf []byte) []byte {
+	hash := sha256.Sum256(append([]byte{LEAF_HASH_PREFIX}, leaf...))
+	return hash[:]
+}
+
+func nodeHash(left []byte, right []byte) []byte {
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(data, NODE_HASH_PREFIX)
+	data = append(data, left...)
+	data = append(data, right...)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// tree keeps the hashes of complete subtrees by level, level zero being the leaf hashes. A complete subtree never
+// changes as the tree only grows, so roots and proofs are built from O(log n) of them instead of rehashing leaves
+type tree struct {
+	levels [][][]byte
+}
+
+func (t *tree) append(leafHash []byte) {
+	hash := leafHash
+	for level := 0; ; level++ {
+		if level == len(t.levels) {
+			t.levels = append(t.levels, [][]byte{})
+		}
+
+		t.levels[level] = append(t.levels[level], hash)
+		count := len(t.levels[level])
+		if count%2 == 1 {
+			return
+		}
+
+		hash = nodeHash(t.levels[level][count-2], t.levels[level][count-1])
+	}
+}
+
+func (t *tree) size() int {
+	if len(t.levels) == 0 {
+		return 0
+	}
+
+	return len(t.levels[0])
+}
+
+// rootHash is MTH of the first size leaves, hash of the empty string for an empty tree
+func (t *tree) rootHash(size int) []byte {
+	if size == 0 {
+		hash := sha256.Sum256(nil)
+		return hash[:]
+	}
+
+	return t.hash(0, size)
+}
+
+// hash is MTH of the leaves in [start, end). Ranges split as in RFC 6962 start at a multiple of the largest power
+// of two not larger than their size, so a range of a power of two size is a complete subtree
+func (t *tree) hash(start int, end int) []byte {
+	size := end - start
+	if size&(size-1) == 0 {
+		level := bits.TrailingZeros(uint(size))
+		return t.levels[level][start>>level]
+	}
+
+	split := splitPoint(size)
+	return nodeHash(t.hash(start, start+split), t.hash(start+split, end))
+}
+
+// inclusionProof is PATH(index, leaves) of the tree of first size leaves, the audit path from the leaf to the root
+func (t *tree) inclusionProof(size int, index int) [][]byte {
+	return t.path(0, size, index)
+}
+
+func (t *tree) path(start int, end int, index int) [][]byte {
+	if end-start <= 1 {
+		return [][]byte{}
+	}
+
+	split := start + splitPoint(end-start)
+	if index < split {
+		return append(t.path(start, split, index), t.hash(split, end))
+	}
+
+	return append(t.path(split, end, index), t.hash(start, split))
+}
+
+// consistencyProof is PROOF(first, leaves) of the tree of second size leaves, proving the tree of the first size
+// leaves is a prefix of it
+func (t *tree) consistencyProof(first int, second int) [][]byte {
+	if first == 0 || first == second {
+		return [][]byte{}
+	}
+
+	return t.subProof(0, second, first, true)
+}
+
+func (t *tree) subProof(start int, end int, size int, complete bool) [][]byte {
+	if size == end-start {
+		if complete {
+			return [][]byte{}
+		}
+		return [][]byte{t.hash(start, end)}
+	}
+
+	split := splitPoint(end - start)
+	if size <= split {
+		return append(t.subProof(start, start+split, size, complete), t.hash(start+split, end))
+	}
+
+	return append(t.subProof(start+split, end, size-split, false), t.hash(start, start+split))
+}
+
+// splitPoint is the largest power of two smaller than size
+func splitPoint(size int) int {
+	split := 1
+	for split<<1 < size {
+		split <<= 1
+	}
+
+	return split
+}
+
+// ----
+
+// VerifyInclusion checks the audit path leads from the leaf at index to the root of a tree of given size,
+// following RFC 9162 section 2.1.3.2
+func VerifyInclusion(leafHash []byte, index uint64, size uint64, proof [][]byte, root []byte) error {
+	if index >= size {
+		return errors.New(fmt.Sprintf("leaf index %d is out of tree size %d", index, size))
+	}
+
+	fn, sn := index, size-1
+	hash := leafHash
+	for _, node := range proof {
+		if sn == 0 {
+			return ErrProofMismatch
+		}
+
+		if fn&1 == 1 || fn == sn {
+			hash = nodeHash(node, hash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			hash = nodeHash(hash, node)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 || !bytes.Equal(hash, root) {
+		return ErrProofMismatch
+	}
+
+	return nil
+}
+
+// VerifyConsistency checks the tree of first size is a prefix of the tree of second size, following RFC 9162
+// section 2.1.4.2. Every tree is consistent with the empty tree
+func VerifyConsistency(firstSize uint64, secondSize uint64, firstRoot []byte, secondRoot []byte, proof [][]byte) error {
+	if firstSize > secondSize {
+		return errors.New(fmt.Sprintf("tree size %d is smaller than the previous tree size %d", secondSize, firstSize))
+	} else if firstSize == 0 {
+		return nil
+	} else if firstSize == secondSize {
+		if len(proof) != 0 || !bytes.Equal(firstRoot, secondRoot) {
+			return ErrProofMismatch
+		}
+		return nil
+	}
+
+	// first tree is a complete subtree, its root is the start of the path
+	if firstSize&(firstSize-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return ErrProofMismatch
+	}
+
+	fn, sn := firstSize-1, secondSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+
+	firstHash, secondHash := proof[0], proof[0]
+	for _, node := range proof[1:] {
+		if sn == 0 {
+			return ErrProofMismatch
+		}
+
+		if fn&1 == 1 || fn == sn {
+			firstHash = nodeHash(node, firstHash)
+			secondHash = nodeHash(node, secondHash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			secondHash = nodeHash(secondHash, node)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 || !bytes.Equal(firstHash, firstRoot) || !bytes.Equal(secondHash, secondRoot) {
+		return ErrProofMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package transparency
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+// leaves of the test vectors in RFC 6962 reference implementation
+var testLeaves = []string{
+	"",
+	"00",
+	"10",
+	"2021",
+	"3031",
+	"40414243",
+	"5051525354555657",
+	"606162636465666768696a6b6c6d6e6f",
+}
+
+func TestRootHash(t *testing.T) {
+	tree := newTestTree(testLeafHashes())
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hex.EncodeToString(tree.rootHash(0)))
+	assert.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		hex.EncodeToString(tree.rootHash(1)))
+	assert.Equal(t, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+		hex.EncodeToString(tree.rootHash(8)))
+}
+
+func TestInclusionProof(t *testing.T) {
+	leaves := testLeafHashes()
+	tree := newTestTree(leaves)
+	for size := 1; size <= len(leaves); size++ {
+		root := tree.rootHash(size)
+		for index := 0; index < size; index++ {
+			proof := tree.inclusionProof(size, index)
+			err := VerifyInclusion(leaves[index], uint64(index), uint64(size), proof, root)
+			assert.NotError(t, err, fmt.Sprintf("verifying leaf %d in tree of %d failed", index, size))
+
+			if index+1 < size {
+				err = VerifyInclusion(leaves[index+1], uint64(index), uint64(size), proof, root)
+				assert.Error(t, err, "proof of another leaf should not be verified")
+			}
+		}
+	}
+}
+
+func TestInclusionProofNotValid(t *testing.T) {
+	leaves := testLeafHashes()
+	tree := newTestTree(leaves)
+	root := tree.rootHash(8)
+	proof := tree.inclusionProof(8, 2)
+
+	err := VerifyInclusion(leaves[2], 3, 8, proof, root)
+	assert.Error(t, err, "proof should not be verified with another index")
+
+	err = VerifyInclusion(leaves[2], 2, 4, proof, root)
+	assert.Error(t, err, "proof should not be verified with another tree size")
+
+	err = VerifyInclusion(leaves[2], 2, 8, proof[:len(proof)-1], root)
+	assert.Error(t, err, "truncated proof should not be verified")
+
+	err = VerifyInclusion(leaves[2], 8, 8, proof, root)
+	assert.ErrorContains(t, err, "out of tree size")
+}
+
+func TestConsistencyProof(t *testing.T) {
+	leaves := testLeafHashes()
+	tree := newTestTree(leaves)
+	for second := 1; second <= len(leaves); second++ {
+		secondRoot := tree.rootHash(second)
+		for first := 0; first <= second; first++ {
+			firstRoot := tree.rootHash(first)
+			proof := tree.consistencyProof(first, second)
+
+			err := VerifyConsistency(uint64(first), uint64(second), firstRoot, secondRoot, proof)
+			assert.NotError(t, err, fmt.Sprintf("verifying consistency of %d and %d failed", first, second))
+
+			if first > 0 && first < second {
+				err = VerifyConsistency(uint64(first), uint64(second), LeafHash([]byte("tampered")), secondRoot, proof)
+				assert.Error(t, err, "proof should not be verified with another first root")
+			}
+		}
+	}
+}
+
+func TestConsistencyProofNotValid(t *testing.T) {
+	leaves := testLeafHashes()
+
+	// a tree with a different leaf is not a prefix
+	tampered := append([][]byte{}, leaves...)
+	tampered[1] = LeafHash([]byte("tampered"))
+
+	tree, tamperedTree := newTestTree(leaves), newTestTree(tampered)
+
+	proof := tree.consistencyProof(3, 8)
+	err := VerifyConsistency(3, 8, tamperedTree.rootHash(3), tree.rootHash(8), proof)
+	assert.Error(t, err, "tampered tree should not be consistent")
+
+	err = VerifyConsistency(8, 3, tree.rootHash(8), tree.rootHash(3), [][]byte{})
+	assert.ErrorContains(t, err, "smaller than the previous tree size")
+
+	err = VerifyConsistency(3, 3, tree.rootHash(3), tamperedTree.rootHash(3), [][]byte{})
+	assert.Error(t, err, "trees of same size with different roots should not be consistent")
+}
+
+// subtree hashes kept while the tree grows give the root hashed from the leaves, of the current and previous sizes
+func TestTreeGrows(t *testing.T) {
+	leaves := testLeafHashes()
+	tree := &tree{}
+	for size := 1; size <= len(leaves); size++ {
+		tree.append(leaves[size-1])
+		for previous := 1; previous <= size; previous++ {
+			assert.Equal(t, hex.EncodeToString(naiveRootHash(leaves[:previous])), hex.EncodeToString(tree.rootHash(previous)))
+		}
+	}
+}
+
+// ----
+
+// naiveRootHash is MTH computed from the leaves without subtree hashes
+func naiveRootHash(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+
+	split := splitPoint(len(leaves))
+	return nodeHash(naiveRootHash(leaves[:split]), naiveRootHash(leaves[split:]))
+}
+
+func newTestTree(leafHashes [][]byte) *tree {
+	tree := &tree{}
+	for _, leafHash := range leafHashes {
+		tree.append(leafHash)
+	}
+
+	return tree
+}
+
+func testLeafHashes() [][]byte {
+	hashes := [][]byte{}
+	for _, leaf := range testLeaves {
+		decoded, _ := hex.DecodeString(leaf)
+		hashes = append(hashes, LeafHash(decoded))
+	}
+
+	return hashes
+}
@@ -0,0 +1,146 @@
+package transparency
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// version and signature type of TreeHeadSignature in RFC 6962 section 3.5
+	TREE_HEAD_VERSION        = 0
+	TREE_HEAD_SIGNATURE_TYPE = 1
+)
+
+// SignedTreeHead commits the log to its tree of given size, timestamp is in milliseconds as it is signed
+type SignedTreeHead struct {
+	TreeSize  uint64    `json:"tree-size"`
+	Timestamp time.Time `json:"timestamp"`
+	RootHash  []byte    `json:"root-hash"`
+	Signature []byte    `json:"signature"`
+}
+
+// Verify checks the tree head is signed by the log with the public key
+func (h *SignedTreeHead) Verify(publicKey crypto.PublicKey) error {
+	data := signedData(h)
+	digest := sha256.Sum256(data)
+
+	verified := false
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		verified = ecdsa.VerifyASN1(key, digest[:], h.Signature)
+	case *rsa.PublicKey:
+		verified = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], h.Signature) == nil
+	case ed25519.PublicKey:
+		verified = ed25519.Verify(key, data, h.Signature)
+	default:
+		return errors.New(fmt.Sprintf("unsupported public key type: [%T]", publicKey))
+	}
+
+	if !verified {
+		return errors.New("tree head signature is not valid")
+	}
+
+	return nil
+}
+
+func sign(signer crypto.Signer, head *SignedTreeHead) error {
+	data := signedData(head)
+
+	var err error
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		head.Signature, err = signer.Sign(rand.Reader, data, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(data)
+		head.Signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+
+	return err
+}
+
+// signedData is the TreeHeadSignature structure, version, signature type, timestamp, tree size and root hash
+func signedData(head *SignedTreeHead) []byte {
+	data := make([]byte, 0, 2+8+8+len(head.RootHash))
+	data = append(data, TREE_HEAD_VERSION, TREE_HEAD_SIGNATURE_TYPE)
+	data = appendUint64(data, uint64(head.Timestamp.UnixMilli()))
+	data = appendUint64(data, head.TreeSize)
+
+	return append(data, head.RootHash...)
+}
+
+func appendUint64(data []byte, value uint64) []byte {
+	encoded := make([]byte, 8)
+	binary.BigEndian.PutUint64(encoded, value)
+	return append(data, encoded...)
+}
+
+// ----
+
+// ParseSigningKey accepts PKCS #8, SEC 1 EC and PKCS #1 RSA private keys in PEM format
+func ParseSigningKey(keyPem []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPem)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("unsupported signing key type: [%T]", key))
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("parsing signing key failed, unsupported PEM block: [%s]", block.Type))
+}
+
+// ParsePublicKey accepts PKIX public keys in PEM format, e.g. generated by openssl with -pubout
+func ParsePublicKey(keyPem []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(keyPem)
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// CertificateLeaf is the leaf of a PEM encoded certificate in the log, its DER encoding
+func CertificateLeaf(certificatePem []byte) ([]byte, error) {
+	block, _ := pem.Decode(certificatePem)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("certificate is not PEM encoded")
+	}
+
+	return block.Bytes, nil
+}
+
+// SSHCertificateLeaf is the leaf of an OpenSSH certificate in authorized_keys format in the log, its wire encoding
+func SSHCertificateLeaf(certificate []byte) ([]byte, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(certificate)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("parsing ssh certificate failed, %v", err))
+	}
+
+	if _, ok := publicKey.(*ssh.Certificate); !ok {
+		return nil, errors.New("ssh public key is not a certificate")
+	}
+
+	return publicKey.Marshal(), nil
+}
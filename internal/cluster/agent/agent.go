@@ -1,32 +1,52 @@
 package agent
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"time"
 
 	certificate_service "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/storage"
 	"bilalekrem.com/certstore/internal/cluster/agent/config"
 	"bilalekrem.com/certstore/internal/job"
 	"bilalekrem.com/certstore/internal/logging"
 	"bilalekrem.com/certstore/internal/pipeline"
 	"bilalekrem.com/certstore/internal/pipeline/action"
 	"bilalekrem.com/certstore/internal/pipeline/action/issuecertificate"
+	"bilalekrem.com/certstore/internal/pipeline/action/issuesshcertificate"
 	pipeline_action "bilalekrem.com/certstore/internal/pipeline/action/pipeline"
 	"bilalekrem.com/certstore/internal/pipeline/action/savecertificate"
+	"bilalekrem.com/certstore/internal/pipeline/action/savesshcertificate"
 	"bilalekrem.com/certstore/internal/pipeline/action/shell"
 	"bilalekrem.com/certstore/internal/pipeline/action/shouldrenewcertificate"
 	"bilalekrem.com/certstore/internal/pipeline/store"
 	"bilalekrem.com/certstore/internal/scheduler"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	ISSUE_CERTIFICATE_ACTION     = "issue-certificate"
+	ISSUE_SSH_CERTIFICATE_ACTION = "issue-ssh-certificate"
+
+	LIST_ISSUERS_TIMEOUT = 10 * time.Second
 )
 
 type Agent struct {
 	pipelineStore *store.PipelineStore
 	jobs          []job.Job
+	client        certificate_service.CertificateServiceClient
+
+	// health of the server is probed before running pipelines issuing certificates, not probed if nil
+	health healthpb.HealthClient
+
+	// zero if not configured, default timeout of lifecycle is used
+	shutdownTimeout time.Duration
 }
 
 func NewFromFile(path string) (*Agent, error) {
@@ -58,18 +78,36 @@ func NewFromConfig(conf *config.Config, skipJobInitialization bool) (*Agent, err
 	agent := &Agent{
 		pipelineStore: store.New(),
 		jobs:          []job.Job{},
+
+		shutdownTimeout: conf.ShutdownTimeout,
 	}
 
 	// ----
 
 	logging.GetLogger().Info("creating certificate service client for action store")
-	certificateServiceClient, err := getCertificateServiceClient(conf)
+	conn, err := dialServer(conf)
 	if err != nil {
 		logging.GetLogger().Errorf("creating cert service client faild, %v", err)
 		return nil, err
 	}
+	certificateServiceClient := certificate_service.NewCertificateServiceClient(conn)
+
+	state, err := storage.New(conf.StateDir)
+	if err != nil {
+		logging.GetLogger().Errorf("creating agent state storage failed, %v", err)
+		return nil, err
+	}
+
+	agent.client = certificateServiceClient
+	agent.health = healthpb.NewHealthClient(conn)
 
-	actionStore := getActionStore(certificateServiceClient, agent.pipelineStore)
+	err = agent.validatePipelines(conf.Pipelines)
+	if err != nil {
+		logging.GetLogger().Errorf("validating pipelines against issuers failed, %v", err)
+		return nil, err
+	}
+
+	actionStore := getActionStore(&certificateServiceClient, state, agent.pipelineStore)
 	agent.init(conf, actionStore, skipJobInitialization)
 
 	// ----
@@ -85,11 +123,149 @@ func (w *Agent) RunPipeline(pipelineName string) error {
 		return errors.New(fmt.Sprintf("pipeline not found, %s", pipelineName))
 	}
 
-	return pip.Run()
+	return pip.Run(context.Background())
+}
+
+// Stop stops scheduling jobs and waits for the running pipelines until ctx is done, pipelines not completed
+// until then are canceled
+func (w *Agent) Stop(ctx context.Context) error {
+	logging.GetLogger().Infof("Stopping agent, waiting for running pipelines")
+
+	errs := make(chan error, len(w.jobs))
+	for _, agentJob := range w.jobs {
+		go func(agentJob job.Job) {
+			errs <- agentJob.Stop(ctx)
+		}(agentJob)
+	}
+
+	var stopErr error
+	for range w.jobs {
+		err := <-errs
+		if err != nil && stopErr == nil {
+			stopErr = err
+		}
+	}
+
+	return stopErr
+}
+
+// ShutdownTimeout is the time given to running pipelines when the agent is stopped by a signal
+func (w *Agent) ShutdownTimeout() time.Duration {
+	return w.shutdownTimeout
+}
+
+// ListIssuersFromFile lists issuers of the server in agent config, without initializing pipelines
+func ListIssuersFromFile(ctx context.Context, path string) ([]*certificate_service.Issuer, error) {
+	conf, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := getCertificateServiceClient(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("creating cert service client faild, %v", err)
+		return nil, err
+	}
+
+	agent := &Agent{client: *client}
+	return agent.ListIssuers(ctx)
+}
+
+// ReloadServerFromFile asks the server in agent config to reload its own config
+func ReloadServerFromFile(ctx context.Context, path string) (*certificate_service.ReloadResponse, error) {
+	conf, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialServer(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("connecting server failed, %v", err)
+		return nil, err
+	}
+	defer conn.Close()
+
+	return certificate_service.NewAdminServiceClient(conn).Reload(ctx, &certificate_service.ReloadRequest{})
+}
+
+// ListIssuers returns issuers of the server with their capabilities
+func (w *Agent) ListIssuers(ctx context.Context) ([]*certificate_service.Issuer, error) {
+	resp, err := w.client.ListIssuers(ctx, &certificate_service.ListIssuersRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Issuers, nil
+}
+
+// validatePipelines checks issue-certificate actions against issuers of the server. Validation is skipped if
+// server is not reachable or does not support listing issuers, not to block agent startup
+func (w *Agent) validatePipelines(pipelineConfigs []pipeline.PipelineConfig) error {
+	if !hasIssueCertificateAction(pipelineConfigs) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), LIST_ISSUERS_TIMEOUT)
+	defer cancel()
+
+	issuers, err := w.ListIssuers(ctx)
+	if err != nil {
+		logging.GetLogger().Warnf("listing issuers failed, skipping pipeline validation, %v", err)
+		return nil
+	}
+
+	// ----
+
+	for _, pipelineConfig := range pipelineConfigs {
+		for _, actionConfig := range pipelineConfig.Actions {
+			if actionConfig.Name != ISSUE_CERTIFICATE_ACTION {
+				continue
+			}
+
+			err = issuecertificate.ValidateArgsAgainstIssuers(actionConfig.Args, issuers)
+			if err != nil {
+				return errors.New(fmt.Sprintf("pipeline [%s] is not valid, %v", pipelineConfig.Name, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasIssueCertificateAction(pipelineConfigs []pipeline.PipelineConfig) bool {
+	for _, pipelineConfig := range pipelineConfigs {
+		for _, actionConfig := range pipelineConfig.Actions {
+			if actionConfig.Name == ISSUE_CERTIFICATE_ACTION {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 // ----
 
+// readConfig reads and validates agent config, to connect the server without initializing pipelines
+func readConfig(path string) (*config.Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := config.Parse(string(bytes))
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
 func validateConfig(conf *config.Config) error {
 	if conf.ServerAddr == "" {
 		return fmt.Errorf("server-address is required argument")
@@ -105,22 +281,26 @@ func validateConfig(conf *config.Config) error {
 }
 
 func getCertificateServiceClient(conf *config.Config) (*certificate_service.CertificateServiceClient, error) {
-	serverAddress := conf.ServerAddr
-
-	tlsConf, err := createTlsConfig(conf)
+	conn, err := dialServer(conf)
 	if err != nil {
 		return nil, err
 	}
+	// todo bilal defer conn.Close()
 
-	opts := grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))
-	conn, err := grpc.Dial(serverAddress, opts)
+	client := certificate_service.NewCertificateServiceClient(conn)
+	return &client, nil
+}
+
+func dialServer(conf *config.Config) (*grpc.ClientConn, error) {
+	serverAddress := conf.ServerAddr
+
+	tlsConf, err := createTlsConfig(conf)
 	if err != nil {
 		return nil, err
 	}
-	// todo bilal defer conn.Close()
 
-	client := certificate_service.NewCertificateServiceClient(conn)
-	return &client, nil
+	opts := grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))
+	return grpc.Dial(serverAddress, opts)
 }
 
 func createTlsConfig(conf *config.Config) (*tls.Config, error) {
@@ -168,7 +348,7 @@ func (w *Agent) initPipelines(pipelineConfigs []pipeline.PipelineConfig, actionS
 		}
 
 		logging.GetLogger().Infof("pipeline is created: [%s]", pip.Name())
-		w.pipelineStore.StorePipeline(pip)
+		w.pipelineStore.StorePipeline(w.withHealthProbe(pip, pipelineIssuers(pipelineConfig)))
 	}
 
 	return nil
@@ -177,14 +357,17 @@ func (w *Agent) initPipelines(pipelineConfigs []pipeline.PipelineConfig, actionS
 func (w *Agent) initJobs(jobConfigs []config.JobConfig) error {
 	logging.GetLogger().Info("Initializing agent with job configs..")
 	for _, jobConfig := range jobConfigs {
-		dailyScheduler := scheduler.NewDailyScheduler()
+		var jobScheduler scheduler.Scheduler = scheduler.NewDailyScheduler()
+		if jobConfig.Interval > 0 {
+			jobScheduler = scheduler.NewIntervalScheduler(jobConfig.Interval)
+		}
 
 		pip := w.pipelineStore.GetPipeline(jobConfig.Pipeline)
 		if pip == nil {
 			logging.GetLogger().Errorf("pipeline not found, %s", jobConfig.Pipeline)
 			return errors.New(fmt.Sprintf("pipeline not found, %s", jobConfig.Pipeline))
 		}
-		pipelineJob := job.NewPipelineJob(jobConfig.Name, dailyScheduler, pip)
+		pipelineJob := job.NewPipelineJob(jobConfig.Name, jobScheduler, pip)
 		err := pipelineJob.Execute()
 		if err != nil {
 			logging.GetLogger().Errorf("Running job failed, %s", jobConfig.Pipeline)
@@ -196,12 +379,15 @@ func (w *Agent) initJobs(jobConfigs []config.JobConfig) error {
 	return nil
 }
 
-func getActionStore(client *certificate_service.CertificateServiceClient, pipelineStore *store.PipelineStore) *action.ActionStore {
+func getActionStore(client *certificate_service.CertificateServiceClient, state storage.Storage,
+	pipelineStore *store.PipelineStore) *action.ActionStore {
 	store := action.NewActionStore()
 
 	store.Put("sh", shell.NewShellAction())
-	store.Put("issue-certificate", issuecertificate.NewIssueCertificateAction(*client))
+	store.Put(ISSUE_CERTIFICATE_ACTION, issuecertificate.NewIssueCertificateActionWithState(*client, state))
 	store.Put("save-certificate", savecertificate.NewSaveCertificateAction())
+	store.Put(ISSUE_SSH_CERTIFICATE_ACTION, issuesshcertificate.NewIssueSSHCertificateAction(*client))
+	store.Put("save-ssh-certificate", savesshcertificate.NewSaveSSHCertificateAction())
 	store.Put("run-pipeline", pipeline_action.NewPipelineAction(pipelineStore))
 	store.Put("should-renew-certificate", shouldrenewcertificate.NewShouldRenewCertificateAction())
 
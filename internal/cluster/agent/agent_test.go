@@ -1,17 +1,23 @@
 package agent
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/grpc/gen"
 	"bilalekrem.com/certstore/internal/cluster/manager"
 	"bilalekrem.com/certstore/internal/cluster/agent/config"
 	"bilalekrem.com/certstore/internal/pipeline"
 	"bilalekrem.com/certstore/internal/pipeline/action"
 	"bilalekrem.com/certstore/internal/pipeline/store"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewFromFile(t *testing.T) {
@@ -133,6 +139,101 @@ func TestSkipInitializationJobs(t *testing.T) {
 	assert.Equal(t, 0, len(agent.jobs))
 }
 
+func TestStop(t *testing.T) {
+	agent := &Agent{
+		pipelineStore: store.New(),
+	}
+
+	pipelineConfigs := []pipeline.PipelineConfig{
+		{Name: "test-pipeline",
+			Actions: []pipeline.PipelineActionConfig{
+				{Name: "action-one"},
+			}},
+	}
+
+	actionStore := action.NewActionStore()
+	actionStore.Put("action-one", &action.MockAction{})
+
+	jobConfigs := []config.JobConfig{
+		{Name: "first job", Pipeline: "test-pipeline"},
+		{Name: "second job", Pipeline: "test-pipeline", Interval: time.Hour},
+	}
+
+	err := agent.initPipelines(pipelineConfigs, actionStore)
+	assert.NotError(t, err, "pipeline initialization failed")
+
+	err = agent.initJobs(jobConfigs)
+	assert.NotError(t, err, "job initialization failed")
+
+	// ----
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = agent.Stop(ctx)
+	assert.NotError(t, err, "stopping agent failed")
+}
+
+func TestValidatePipelines(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	client.
+		EXPECT().
+		ListIssuers(gomock.Any(), gomock.Any()).
+		Return(&gen.ListIssuersResponse{Issuers: []*gen.Issuer{{Name: "issuer"}}}, nil).
+		Times(2)
+
+	agent := &Agent{client: client}
+
+	err := agent.validatePipelines(getIssuePipelineConfigs("issuer"))
+	assert.NotError(t, err, "pipeline validation failed")
+
+	err = agent.validatePipelines(getIssuePipelineConfigs("unknown issuer"))
+	assert.ErrorContains(t, err, "pipeline [test-pipeline] is not valid")
+}
+
+func TestValidatePipelinesListIssuersFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := gen.NewMockCertificateServiceClient(ctrl)
+	client.
+		EXPECT().
+		ListIssuers(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.Unimplemented, "method ListIssuers not implemented"))
+
+	agent := &Agent{client: client}
+
+	// validation is skipped, not to block agent startup
+	err := agent.validatePipelines(getIssuePipelineConfigs("unknown issuer"))
+	assert.NotError(t, err, "pipeline validation should be skipped")
+}
+
+func TestValidatePipelinesWithoutIssueAction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// server is not requested
+	agent := &Agent{client: gen.NewMockCertificateServiceClient(ctrl)}
+
+	pipelineConfigs := []pipeline.PipelineConfig{
+		{Name: "test-pipeline", Actions: []pipeline.PipelineActionConfig{{Name: "sh"}}},
+	}
+	err := agent.validatePipelines(pipelineConfigs)
+	assert.NotError(t, err, "pipeline validation failed")
+}
+
+func getIssuePipelineConfigs(issuer string) []pipeline.PipelineConfig {
+	return []pipeline.PipelineConfig{
+		{Name: "test-pipeline",
+			Actions: []pipeline.PipelineActionConfig{
+				{Name: ISSUE_CERTIFICATE_ACTION, Args: map[string]string{"issuer": issuer, "common-name": "certstore.com"}},
+			}},
+	}
+}
+
 func testNewFromFile(t *testing.T, skipJobInitialization bool) {
 	dir, err := ioutil.TempDir("/tmp", "test_agent_new_")
 	assert.NotError(t, err, "creating temp dir failed")
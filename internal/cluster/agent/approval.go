@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+
+	certificate_service "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/grpc"
+)
+
+// approvers are identified by the client certificate in agent config, it must be allowed to decide requests of
+// the issuer and it can not decide its own requests
+
+// ListApprovalsFromFile lists requests waiting for approval in the server in agent config, of every issuer if
+// issuer is empty
+func ListApprovalsFromFile(ctx context.Context, path string, issuer string) ([]*certificate_service.ApprovalRequest, error) {
+	client, conn, err := dialAdminService(path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := client.ListApprovals(ctx, &certificate_service.ListApprovalsRequest{Issuer: issuer})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Requests, nil
+}
+
+// ApproveRequestFromFile approves the request, server issues the certificate in background and the requesting
+// agent receives it from the operation
+func ApproveRequestFromFile(ctx context.Context, path string, operationID string) (*certificate_service.Operation, error) {
+	client, conn, err := dialAdminService(path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return client.ApproveRequest(ctx, &certificate_service.ApprovalDecisionRequest{OperationId: operationID})
+}
+
+// DenyRequestFromFile denies the request, its operation fails with the reason
+func DenyRequestFromFile(ctx context.Context, path string, operationID string, reason string) (*certificate_service.Operation, error) {
+	client, conn, err := dialAdminService(path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return client.DenyRequest(ctx, &certificate_service.ApprovalDecisionRequest{OperationId: operationID, Reason: reason})
+}
+
+func dialAdminService(path string) (certificate_service.AdminServiceClient, *grpc.ClientConn, error) {
+	conf, err := readConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := dialServer(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("connecting server failed, %v", err)
+		return nil, nil, err
+	}
+
+	return certificate_service.NewAdminServiceClient(conn), conn, nil
+}
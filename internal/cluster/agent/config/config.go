@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"bilalekrem.com/certstore/internal/pipeline"
 	"gopkg.in/yaml.v3"
 )
@@ -11,13 +13,22 @@ type Config struct {
 	TlsAgentCert    string `yaml:"tls-agent-cert"`
 	TlsAgentCertKey string `yaml:"tls-agent-cert-key"`
 
+	// directory to keep agent state, e.g. pending operations, state is kept in memory if empty
+	StateDir string `yaml:"state-dir"`
+
 	Pipelines []pipeline.PipelineConfig `yaml:"pipelines"`
 	Jobs      []JobConfig               `yaml:"jobs"`
+
+	// time given to running pipelines on SIGINT or SIGTERM, defaults to 30 seconds
+	ShutdownTimeout time.Duration `yaml:"shutdown-timeout"`
 }
 
 type JobConfig struct {
 	Name     string `yaml:"name"`
 	Pipeline string `yaml:"pipeline"`
+
+	// job runs daily if it is zero, e.g. 1h for short lived certificates
+	Interval time.Duration `yaml:"interval"`
 }
 
 func Parse(configYaml string) (*Config, error) {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 )
@@ -25,7 +26,10 @@ pipelines:
       - name: second-action
 jobs:
   - name: "first-pipeline job"
-    pipeline: "first-pipeline"`
+    pipeline: "first-pipeline"
+  - name: "second-pipeline job"
+    pipeline: "second-pipeline"
+    interval: 1h`
 
 	config, err := Parse(configYaml)
 	assert.NotError(t, err, "parsing failed")
@@ -47,7 +51,9 @@ jobs:
 	// -----
 
 	jobs := config.Jobs
-	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, 2, len(jobs))
 	assert.Equal(t, "first-pipeline job", jobs[0].Name)
 	assert.Equal(t, "first-pipeline", jobs[0].Pipeline)
+	assert.Equal(t, time.Duration(0), jobs[0].Interval)
+	assert.Equal(t, time.Hour, jobs[1].Interval)
 }
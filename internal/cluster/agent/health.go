@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	certificate_service "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/cluster/healthservice"
+	"bilalekrem.com/certstore/internal/logging"
+	"bilalekrem.com/certstore/internal/pipeline"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	HEALTH_PROBE_TIMEOUT = 10 * time.Second
+
+	ISSUER_ARG = "issuer"
+)
+
+type ComponentHealth struct {
+	Service string
+	Status  healthpb.HealthCheckResponse_ServingStatus
+}
+
+// ServerHealthFromFile checks health of the server in agent config, its storage, ACME connectivity and issuers.
+// Components the server does not report, e.g. acme without ACME issuers, are SERVICE_UNKNOWN
+func ServerHealthFromFile(ctx context.Context, path string) ([]ComponentHealth, error) {
+	conf, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialServer(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("connecting server failed, %v", err)
+		return nil, err
+	}
+	defer conn.Close()
+
+	services := []string{healthservice.SERVER, healthservice.STORAGE, healthservice.ACME}
+	resp, err := certificate_service.NewCertificateServiceClient(conn).ListIssuers(ctx, &certificate_service.ListIssuersRequest{})
+	if err != nil {
+		logging.GetLogger().Warnf("listing issuers failed, checking health of the server only, %v", err)
+	} else {
+		for _, issuer := range resp.Issuers {
+			services = append(services, healthservice.Issuer(issuer.Name))
+		}
+	}
+
+	// ----
+
+	health := healthpb.NewHealthClient(conn)
+	components := []ComponentHealth{}
+	for _, service := range services {
+		serviceStatus, err := checkHealth(ctx, health, service)
+		if err != nil {
+			return nil, err
+		}
+
+		components = append(components, ComponentHealth{Service: service, Status: serviceStatus})
+	}
+
+	return components, nil
+}
+
+func checkHealth(ctx context.Context, health healthpb.HealthClient,
+	service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if status.Code(err) == codes.NotFound {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, nil
+	} else if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+
+	return resp.Status, nil
+}
+
+// ----
+
+// probedPipeline checks health of the server and the issuers used by the pipeline before running it
+type probedPipeline struct {
+	pipeline.Pipeline
+
+	health  healthpb.HealthClient
+	issuers []string
+}
+
+func (p *probedPipeline) Run(ctx context.Context) error {
+	err := probeServer(ctx, p.health, p.issuers)
+	if err != nil {
+		logging.GetLogger().Errorf("Pipeline [%s] is not run, %v", p.Name(), err)
+		return err
+	}
+
+	return p.Pipeline.Run(ctx)
+}
+
+// probeServer fails only if the server reports a component not serving. Servers not reachable, or not serving
+// health, are left to the pipeline to report
+func probeServer(ctx context.Context, health healthpb.HealthClient, issuers []string) error {
+	ctx, cancel := context.WithTimeout(ctx, HEALTH_PROBE_TIMEOUT)
+	defer cancel()
+
+	services := []string{healthservice.SERVER}
+	for _, issuer := range issuers {
+		services = append(services, healthservice.Issuer(issuer))
+	}
+
+	for _, service := range services {
+		serviceStatus, err := checkHealth(ctx, health, service)
+		if err != nil {
+			logging.GetLogger().Warnf("Probing server health failed, service: [%s], %v", service, err)
+			return nil
+		}
+
+		if serviceStatus == healthpb.HealthCheckResponse_NOT_SERVING {
+			return errors.New(fmt.Sprintf("server health of [%s] is %s", DisplayService(service), serviceStatus))
+		}
+	}
+
+	return nil
+}
+
+// withHealthProbe returns the pipeline as is if it does not issue certificates, or health is not probed
+func (w *Agent) withHealthProbe(pip pipeline.Pipeline, issuers []string) pipeline.Pipeline {
+	if w.health == nil || len(issuers) == 0 {
+		return pip
+	}
+
+	return &probedPipeline{Pipeline: pip, health: w.health, issuers: issuers}
+}
+
+// pipelineIssuers returns issuers of the issue actions of a pipeline, sorted
+func pipelineIssuers(pipelineConfig pipeline.PipelineConfig) []string {
+	unique := make(map[string]bool)
+	for _, actionConfig := range pipelineConfig.Actions {
+		if actionConfig.Name != ISSUE_CERTIFICATE_ACTION && actionConfig.Name != ISSUE_SSH_CERTIFICATE_ACTION {
+			continue
+		}
+
+		if issuer := actionConfig.Args[ISSUER_ARG]; issuer != "" {
+			unique[issuer] = true
+		}
+	}
+
+	issuers := []string{}
+	for issuer := range unique {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+
+	return issuers
+}
+
+// DisplayService names the overall server health service, which is empty
+func DisplayService(service string) string {
+	if service == healthservice.SERVER {
+		return "server"
+	}
+
+	return service
+}
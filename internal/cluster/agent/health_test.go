@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/pipeline"
+	"bilalekrem.com/certstore/internal/pipeline/action"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestProbeServer(t *testing.T) {
+	health := &fakeHealthClient{statuses: map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":               healthpb.HealthCheckResponse_SERVING,
+		"issuer/healthy": healthpb.HealthCheckResponse_SERVING,
+		"issuer/down":    healthpb.HealthCheckResponse_NOT_SERVING,
+	}}
+
+	assert.NotError(t, probeServer(context.Background(), health, []string{"healthy"}), "server should be healthy")
+	assert.NotError(t, probeServer(context.Background(), health, []string{"unknown"}), "unknown issuer is left to pipeline")
+
+	err := probeServer(context.Background(), health, []string{"healthy", "down"})
+	assert.ErrorContains(t, err, "server health of [issuer/down] is NOT_SERVING")
+
+	health.statuses[""] = healthpb.HealthCheckResponse_NOT_SERVING
+	err = probeServer(context.Background(), health, []string{})
+	assert.ErrorContains(t, err, "server health of [server] is NOT_SERVING")
+}
+
+func TestProbeServerNotReachable(t *testing.T) {
+	health := &fakeHealthClient{err: status.Error(codes.Unavailable, "connection refused")}
+
+	assert.NotError(t, probeServer(context.Background(), health, []string{"issuer"}), "probe should not fail the pipeline")
+}
+
+func TestProbedPipelineNotRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAction := action.NewMockAction(ctrl)
+	mockAction.
+		EXPECT().
+		Run(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	pip := pipeline.New("test-pipeline")
+	pip.RegisterAction(mockAction, map[string]string{})
+
+	health := &fakeHealthClient{statuses: map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":            healthpb.HealthCheckResponse_SERVING,
+		"issuer/down": healthpb.HealthCheckResponse_NOT_SERVING,
+	}}
+	agent := &Agent{health: health}
+
+	err := agent.withHealthProbe(pip, []string{"down"}).Run(context.Background())
+	assert.ErrorContains(t, err, "NOT_SERVING")
+}
+
+func TestWithHealthProbeWithoutIssuers(t *testing.T) {
+	pip := pipeline.New("test-pipeline")
+
+	agent := &Agent{health: &fakeHealthClient{}}
+	assert.Equal(t, pipeline.Pipeline(pip), agent.withHealthProbe(pip, []string{}))
+
+	agent = &Agent{}
+	assert.Equal(t, pipeline.Pipeline(pip), agent.withHealthProbe(pip, []string{"issuer"}))
+}
+
+func TestPipelineIssuers(t *testing.T) {
+	pipelineConfig := pipeline.PipelineConfig{
+		Name: "test-pipeline",
+		Actions: []pipeline.PipelineActionConfig{
+			{Name: ISSUE_CERTIFICATE_ACTION, Args: map[string]string{"issuer": "second"}},
+			{Name: "sh", Args: map[string]string{"issuer": "not-an-issuer"}},
+			{Name: ISSUE_SSH_CERTIFICATE_ACTION, Args: map[string]string{"issuer": "first"}},
+			{Name: ISSUE_CERTIFICATE_ACTION, Args: map[string]string{"issuer": "second"}},
+		},
+	}
+
+	assert.DeepEqual(t, []string{"first", "second"}, pipelineIssuers(pipelineConfig))
+}
+
+// ----
+
+// fakeHealthClient returns not found for services without status, or err for every check if set
+type fakeHealthClient struct {
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+	err      error
+}
+
+func (f *fakeHealthClient) Check(ctx context.Context, in *healthpb.HealthCheckRequest,
+	opts ...grpc.CallOption) (*healthpb.HealthCheckResponse, error) {
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	serviceStatus, exist := f.statuses[in.Service]
+	if !exist {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	return &healthpb.HealthCheckResponse{Status: serviceStatus}, nil
+}
+
+func (f *fakeHealthClient) Watch(ctx context.Context, in *healthpb.HealthCheckRequest,
+	opts ...grpc.CallOption) (healthpb.Health_WatchClient, error) {
+
+	return nil, errors.New("watching health is not supported")
+}
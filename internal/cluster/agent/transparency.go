@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	certificate_service "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	VERIFY_INCLUSION_TIMEOUT = 30 * time.Second
+)
+
+// Inclusion is a verified inclusion of a certificate in the transparency log of the server
+type Inclusion struct {
+	LeafIndex uint64
+	TreeHead  *transparency.SignedTreeHead
+
+	// nil if no tree head is trusted before
+	TrustedTreeHead *transparency.SignedTreeHead
+}
+
+// VerifyInclusionFromFile verifies the certificate is in the transparency log of the server in agent config, under
+// a tree head signed with the log's public key. If tree head path is given, the tree head saved there by a previous
+// run is trusted, the new tree head must be consistent with it and it is saved in its place
+func VerifyInclusionFromFile(ctx context.Context, path string, certificatePem []byte, publicKey crypto.PublicKey,
+	treeHeadPath string) (*Inclusion, error) {
+
+	conf, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := transparency.CertificateLeaf(certificatePem)
+	if err != nil {
+		return nil, err
+	}
+
+	var trusted *transparency.SignedTreeHead
+	if treeHeadPath != "" {
+		trusted, err = readTreeHead(treeHeadPath)
+		if err != nil {
+			logging.GetLogger().Errorf("reading trusted tree head failed, %v", err)
+			return nil, err
+		}
+	}
+
+	conn, err := dialServer(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("connecting server failed, %v", err)
+		return nil, err
+	}
+	defer conn.Close()
+
+	// ----
+
+	inclusion, err := verifyInclusion(ctx, certificate_service.NewCertificateServiceClient(conn), leaf, publicKey, trusted)
+	if err != nil {
+		return nil, err
+	}
+
+	if treeHeadPath != "" {
+		err = writeTreeHead(treeHeadPath, inclusion.TreeHead)
+		if err != nil {
+			logging.GetLogger().Errorf("saving trusted tree head failed, %v", err)
+			return nil, err
+		}
+	}
+
+	return inclusion, nil
+}
+
+func verifyInclusion(ctx context.Context, client certificate_service.CertificateServiceClient, leaf []byte,
+	publicKey crypto.PublicKey, trusted *transparency.SignedTreeHead) (*Inclusion, error) {
+
+	resp, err := client.GetSignedTreeHead(ctx, &certificate_service.SignedTreeHeadRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	treeHead := &transparency.SignedTreeHead{
+		TreeSize:  resp.TreeSize,
+		Timestamp: time.UnixMilli(int64(resp.Timestamp)).UTC(),
+		RootHash:  resp.RootHash,
+		Signature: resp.Signature,
+	}
+	err = treeHead.Verify(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if trusted != nil {
+		err = verifyConsistency(ctx, client, trusted, treeHead)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// ----
+
+	leafHash := transparency.LeafHash(leaf)
+	proof, err := client.GetInclusionProof(ctx, &certificate_service.InclusionProofRequest{
+		LeafHash: leafHash,
+		TreeSize: treeHead.TreeSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = transparency.VerifyInclusion(leafHash, proof.LeafIndex, treeHead.TreeSize, proof.AuditPath, treeHead.RootHash)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("certificate is not proved to be in the tree of size %d, %v", treeHead.TreeSize, err))
+	}
+
+	return &Inclusion{LeafIndex: proof.LeafIndex, TreeHead: treeHead, TrustedTreeHead: trusted}, nil
+}
+
+// verifyConsistency fails if the log is rewritten since the trusted tree head, e.g. a certificate is removed
+func verifyConsistency(ctx context.Context, client certificate_service.CertificateServiceClient,
+	trusted *transparency.SignedTreeHead, treeHead *transparency.SignedTreeHead) error {
+
+	if trusted.TreeSize > treeHead.TreeSize {
+		return errors.New(fmt.Sprintf("tree size %d is smaller than the trusted tree size %d", treeHead.TreeSize, trusted.TreeSize))
+	}
+
+	var proof [][]byte
+	if trusted.TreeSize > 0 && trusted.TreeSize < treeHead.TreeSize {
+		resp, err := client.GetConsistencyProof(ctx, &certificate_service.ConsistencyProofRequest{
+			FirstTreeSize:  trusted.TreeSize,
+			SecondTreeSize: treeHead.TreeSize,
+		})
+		if err != nil {
+			return err
+		}
+		proof = resp.Proof
+	}
+
+	err := transparency.VerifyConsistency(trusted.TreeSize, treeHead.TreeSize, trusted.RootHash, treeHead.RootHash, proof)
+	if err != nil {
+		return errors.New(fmt.Sprintf("tree of size %d is not consistent with the trusted tree of size %d, %v",
+			treeHead.TreeSize, trusted.TreeSize, err))
+	}
+
+	return nil
+}
+
+// readTreeHead returns nil if there is no tree head saved yet
+func readTreeHead(path string) (*transparency.SignedTreeHead, error) {
+	content, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	treeHead := &transparency.SignedTreeHead{}
+	err = json.Unmarshal(content, treeHead)
+	if err != nil {
+		return nil, err
+	}
+
+	return treeHead, nil
+}
+
+func writeTreeHead(path string, treeHead *transparency.SignedTreeHead) error {
+	content, err := json.MarshalIndent(treeHead, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0644)
+}
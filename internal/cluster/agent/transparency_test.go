@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certificate_service "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"bilalekrem.com/certstore/internal/certstore/transparency"
+	"google.golang.org/grpc"
+)
+
+func TestVerifyInclusion(t *testing.T) {
+	client, signer := createLogClient(t, "first", "certificate", "third")
+
+	inclusion, err := verifyInclusion(context.Background(), client, []byte("certificate"), signer.Public(), nil)
+	assert.NotError(t, err, "verifying inclusion failed")
+	assert.Equal(t, uint64(1), inclusion.LeafIndex)
+	assert.Equal(t, uint64(3), inclusion.TreeHead.TreeSize)
+
+	// ----
+
+	_, err = verifyInclusion(context.Background(), client, []byte("unknown"), signer.Public(), nil)
+	assert.ErrorContains(t, err, "leaf not found")
+
+	otherSigner, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = verifyInclusion(context.Background(), client, []byte("certificate"), otherSigner.Public(), nil)
+	assert.ErrorContains(t, err, "signature is not valid")
+}
+
+func TestVerifyInclusionWithTrustedTreeHead(t *testing.T) {
+	client, signer := createLogClient(t, "first", "certificate")
+	trusted, _ := client.log.SignedTreeHead()
+
+	client.log.Append([]byte("third"))
+	client.log.Append([]byte("fourth"))
+
+	inclusion, err := verifyInclusion(context.Background(), client, []byte("certificate"), signer.Public(), trusted)
+	assert.NotError(t, err, "verifying inclusion failed")
+	assert.Equal(t, uint64(4), inclusion.TreeHead.TreeSize)
+
+	// ----
+
+	// a log rewritten since the trusted tree head, with the same signing key
+	rewritten, _ := transparency.NewWithSigner(signer, storage.NewMemoryStorage(), time.Now)
+	for _, leaf := range []string{"first", "other", "certificate", "fourth"} {
+		rewritten.Append([]byte(leaf))
+	}
+
+	client.log = rewritten
+	_, err = verifyInclusion(context.Background(), client, []byte("certificate"), signer.Public(), trusted)
+	assert.ErrorContains(t, err, "is not consistent with the trusted tree of size 2")
+}
+
+func TestTrustedTreeHeadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree-head.json")
+
+	treeHead, err := readTreeHead(path)
+	assert.NotError(t, err, "reading missing tree head failed")
+	assert.Nil(t, treeHead)
+
+	client, _ := createLogClient(t, "first")
+	saved, _ := client.log.SignedTreeHead()
+	assert.NotError(t, writeTreeHead(path, saved), "saving tree head failed")
+
+	treeHead, err = readTreeHead(path)
+	assert.NotError(t, err, "reading tree head failed")
+	assert.Equal(t, saved.TreeSize, treeHead.TreeSize)
+	assert.True(t, saved.Timestamp.Equal(treeHead.Timestamp))
+	assert.DeepEqual(t, saved.RootHash, treeHead.RootHash)
+	assert.DeepEqual(t, saved.Signature, treeHead.Signature)
+}
+
+// ----
+
+// logClient serves the transparency log rpcs from a log
+type logClient struct {
+	certificate_service.CertificateServiceClient
+	log *transparency.Log
+}
+
+func (c *logClient) GetSignedTreeHead(ctx context.Context, in *certificate_service.SignedTreeHeadRequest,
+	opts ...grpc.CallOption) (*certificate_service.SignedTreeHead, error) {
+
+	head, err := c.log.SignedTreeHead()
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate_service.SignedTreeHead{
+		TreeSize:  head.TreeSize,
+		Timestamp: uint64(head.Timestamp.UnixMilli()),
+		RootHash:  head.RootHash,
+		Signature: head.Signature,
+	}, nil
+}
+
+func (c *logClient) GetInclusionProof(ctx context.Context, in *certificate_service.InclusionProofRequest,
+	opts ...grpc.CallOption) (*certificate_service.InclusionProofResponse, error) {
+
+	proof, err := c.log.InclusionProof(in.LeafHash, in.TreeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate_service.InclusionProofResponse{
+		LeafIndex: proof.LeafIndex,
+		TreeSize:  proof.TreeSize,
+		AuditPath: proof.AuditPath,
+	}, nil
+}
+
+func (c *logClient) GetConsistencyProof(ctx context.Context, in *certificate_service.ConsistencyProofRequest,
+	opts ...grpc.CallOption) (*certificate_service.ConsistencyProofResponse, error) {
+
+	proof, err := c.log.ConsistencyProof(in.FirstTreeSize, in.SecondTreeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate_service.ConsistencyProofResponse{Proof: proof}, nil
+}
+
+func createLogClient(t *testing.T, leaves ...string) (*logClient, *ecdsa.PrivateKey) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NotError(t, err, "generating signing key failed")
+
+	log, err := transparency.NewWithSigner(signer, storage.NewMemoryStorage(), time.Now)
+	assert.NotError(t, err, "creating transparency log failed")
+
+	for _, leaf := range leaves {
+		log.Append([]byte(leaf))
+	}
+
+	return &logClient{log: log}, signer
+}
@@ -0,0 +1,19 @@
+package healthservice
+
+// names of the services server reports through grpc health service, checked by agents
+const (
+	// overall health of the server, not serving if storage is not healthy
+	SERVER = ""
+
+	STORAGE = "storage"
+
+	// serving if every ACME issuer reaches its directory, unknown service if there is no ACME issuer
+	ACME = "acme"
+
+	// health of each issuer is served as a separate service, e.g. "issuer/lets-encrypt"
+	ISSUER_PREFIX = "issuer/"
+)
+
+func Issuer(name string) string {
+	return ISSUER_PREFIX + name
+}
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"errors"
 
 	"bilalekrem.com/certstore/internal/certificate/service"
@@ -23,7 +24,10 @@ func NewFromFile(caCertPath string, caKeyPath string) (*clusterManagerImpl, erro
 	args := make(map[string]string)
 	args["certificate"] = caCertPath
 	args["private-key"] = caKeyPath
-	certService := factory.NewService(factory.Simple, args)
+	certService, err := factory.NewService(factory.Simple, args)
+	if err != nil {
+		return nil, err
+	}
 
 	return &clusterManagerImpl{clusterCertService: certService}, nil
 }
@@ -39,12 +43,16 @@ func NewFromCA(cert []byte, key []byte) (*clusterManagerImpl, error) {
 
 func (*clusterManagerImpl) CreateClusterCACertificate(clusterName string) (*service.NewCertificateResponse, error) {
 	logging.GetLogger().Debug("creating cluster ca certificate")
-	caCertificateService := factory.NewService(factory.CertificateAuthority, nil)
+	caCertificateService, err := factory.NewService(factory.CertificateAuthority, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	request := &service.NewCertificateRequest{
 		CommonName:     clusterName,
 		ExpirationDays: DEFAULT_CLUSTER_CERT_EXPIRATION_DAYS,
 	}
-	response, err := caCertificateService.CreateCertificate(request)
+	response, err := caCertificateService.CreateCertificate(context.Background(), request)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +71,7 @@ func (c *clusterManagerImpl) CreateServerCertificate(name string) (*service.NewC
 	}
 
 	logging.GetLogger().Debugf("creating server certificate for %s", name)
-	response, err := c.clusterCertService.CreateCertificate(request)
+	response, err := c.clusterCertService.CreateCertificate(context.Background(), request)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +91,7 @@ func (c *clusterManagerImpl) CreateAgentCertificate(name string) (*service.NewCe
 	}
 
 	logging.GetLogger().Debugf("creating agent certificate for %s", name)
-	response, err := c.clusterCertService.CreateCertificate(request)
+	response, err := c.clusterCertService.CreateCertificate(context.Background(), request)
 	if err != nil {
 		return nil, err
 	}
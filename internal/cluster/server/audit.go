@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"bilalekrem.com/certstore/internal/audit"
+	grpc_gen "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ADMIN_SERVICE_PREFIX = "/proto.AdminService/"
+)
+
+// rpcs changing state, read only ones are not audited
+var auditedMethods = map[string]bool{
+	"/proto.CertificateService/IssueCertificate":           true,
+	"/proto.CertificateService/IssueCertificateV2":         true,
+	"/proto.CertificateService/SubmitCertificateRequest":   true,
+	"/proto.CertificateService/SubmitCertificateRequestV2": true,
+	"/proto.CertificateService/IssueSSHCertificate":        true,
+	"/proto.CertificateService/RevokeCertificate":          true,
+}
+
+func audited(fullMethod string) bool {
+	return auditedMethods[fullMethod] || strings.HasPrefix(fullMethod, ADMIN_SERVICE_PREFIX)
+}
+
+func (s *Server) unaryAuditInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+
+	if s.auditLogger == nil || !audited(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	trail := s.auditLogger.Start(requestEntry(ctx, info.FullMethod, req))
+	resp, err := handler(audit.NewContext(ctx, trail), req)
+	if err == nil {
+		recordResponse(trail, resp)
+	}
+	trail.Finish(errorKind(err), err)
+
+	return resp, err
+}
+
+// auditedReload reloads on SIGHUP, admin rpc is audited by the interceptor
+func (s *Server) auditedReload() {
+	trail := s.auditLogger.Start(audit.Entry{
+		Action:   audit.ACTION_RELOAD,
+		Identity: audit.LOCAL_IDENTITY,
+		Detail:   "SIGHUP",
+	})
+
+	result, err := s.Reload()
+	if err == nil {
+		trail.SetDetail(fmt.Sprintf("SIGHUP, added: %v, updated: %v, removed: %v", result.Added, result.Updated, result.Removed))
+	}
+	trail.Finish(errorKind(err), err)
+}
+
+func requestEntry(ctx context.Context, fullMethod string, req interface{}) audit.Entry {
+	entry := audit.Entry{
+		Action:   fullMethod[strings.LastIndex(fullMethod, "/")+1:],
+		Identity: agentName(ctx),
+	}
+
+	if certificate := peerCertificate(ctx); certificate != nil {
+		entry.IdentitySerial = fmt.Sprintf("%x", certificate.SerialNumber)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		entry.RemoteAddress = p.Addr.String()
+	}
+
+	switch r := req.(type) {
+	case *grpc_gen.CertificateRequest:
+		entry.Issuer = r.Issuer
+		entry.Names = requestedNames(r.CommonName, r.SANs)
+	case *grpc_gen.CertificateRequestV2:
+		entry.Issuer = r.Issuer
+		entry.Names = requestedNames(r.Subject.GetCommonName(), r.SANs)
+	case *grpc_gen.SSHCertificateRequest:
+		entry.Issuer = r.Issuer
+		entry.Names = r.Principals
+		entry.Detail = fmt.Sprintf("%s certificate, key id: [%s]", r.Type, r.KeyId)
+	case *grpc_gen.RevokeCertificateRequest:
+		entry.Issuer = r.Issuer
+		entry.SerialNumber = r.SerialNumber
+		entry.Detail = fmt.Sprintf("reason: %s", r.Reason)
+	case *grpc_gen.ApprovalDecisionRequest:
+		entry.OperationID = r.OperationId
+		if r.Reason != "" {
+			entry.Detail = fmt.Sprintf("reason: %s", r.Reason)
+		}
+	}
+
+	return entry
+}
+
+func recordResponse(trail *audit.Trail, resp interface{}) {
+	switch r := resp.(type) {
+	case *grpc_gen.Operation:
+		trail.SetOperationID(r.Id)
+	case *grpc_gen.SSHCertificateResponse:
+		trail.SetSerialNumber(fmt.Sprintf("%x", r.Serial))
+	case *grpc_gen.InventoryCertificate:
+		trail.SetNames(requestedNames(r.CommonName, r.SANs))
+	case *grpc_gen.ReloadResponse:
+		trail.SetDetail(fmt.Sprintf("added: %v, updated: %v, removed: %v", r.AddedIssuers, r.UpdatedIssuers, r.RemovedIssuers))
+	}
+}
+
+func requestedNames(commonName string, sans []string) []string {
+	names := []string{}
+	if commonName != "" {
+		names = append(names, commonName)
+	}
+
+	return append(names, sans...)
+}
+
+// errorKind is the error kind of typed errors, same with issuance outcomes of metrics
+func errorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return "error"
+	}
+
+	for _, detail := range st.Details() {
+		if errorInfo, ok := detail.(*errdetails.ErrorInfo); ok {
+			return errorInfo.Reason
+		}
+	}
+
+	switch st.Code() {
+	case codes.Canceled:
+		return "canceled"
+	case codes.DeadlineExceeded:
+		return "deadline-exceeded"
+	case codes.PermissionDenied:
+		return "permission-denied"
+	}
+
+	return "error"
+}
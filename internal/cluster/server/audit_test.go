@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/audit"
+	certificate_service "bilalekrem.com/certstore/internal/certificate/service"
+	grpc_gen "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/identity"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuditInterceptor(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	server := &Server{auditLogger: audit.NewWriterLogger(buffer)}
+
+	req := &grpc_gen.CertificateRequestV2{
+		Issuer:  "issuer",
+		Subject: &grpc_gen.Subject{CommonName: "certstore.com"},
+		SANs:    []string{"www.certstore.com"},
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.CertificateService/SubmitCertificateRequestV2"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		audit.Decide(ctx, audit.POLICY_CAA, audit.DECISION_ALLOWED, "certstore.com")
+		return &grpc_gen.Operation{Id: "operation"}, nil
+	}
+
+	_, err := server.unaryAuditInterceptor(agentContext(), req, info, handler)
+	assert.NotError(t, err, "intercepting request failed")
+
+	// ----
+
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	assert.Equal(t, 1, len(entries))
+
+	entry := entries[0]
+	assert.Equal(t, "SubmitCertificateRequestV2", entry.Action)
+	assert.Equal(t, "agent-1", entry.Identity)
+	assert.Equal(t, "2a", entry.IdentitySerial)
+	assert.Equal(t, "10.0.0.1:50000", entry.RemoteAddress)
+	assert.Equal(t, "issuer", entry.Issuer)
+	assert.DeepEqual(t, []string{"certstore.com", "www.certstore.com"}, entry.Names)
+	assert.Equal(t, "operation", entry.OperationID)
+	assert.Equal(t, 1, len(entry.Decisions))
+}
+
+func TestAuditInterceptorFailed(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	server := &Server{auditLogger: audit.NewWriterLogger(buffer)}
+
+	req := &grpc_gen.RevokeCertificateRequest{Issuer: "issuer", SerialNumber: "01", Reason: grpc_gen.RevocationReason_KEY_COMPROMISE}
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.CertificateService/RevokeCertificate"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		st, _ := status.New(codes.NotFound, "Certificate not found").WithDetails(&errdetails.ErrorInfo{
+			Reason: string(certificate_service.NotFoundErrorKind),
+		})
+		return nil, st.Err()
+	}
+
+	_, err := server.unaryAuditInterceptor(agentContext(), req, info, handler)
+	assert.Error(t, err, "revoking certificate should fail")
+
+	// ----
+
+	entries, readErr := audit.ReadEntries(buffer)
+	assert.NotError(t, readErr, "reading audit entries failed")
+	entry := entries[0]
+	assert.Equal(t, "RevokeCertificate", entry.Action)
+	assert.Equal(t, "01", entry.SerialNumber)
+	assert.Equal(t, "reason: KEY_COMPROMISE", entry.Detail)
+	assert.Equal(t, string(certificate_service.NotFoundErrorKind), entry.ErrorKind)
+	assert.ErrorContains(t, err, entry.Error)
+}
+
+func TestAuditInterceptorSkipsReadOnly(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	server := &Server{auditLogger: audit.NewWriterLogger(buffer)}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.CertificateService/ListCertificates"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Nil(t, audit.FromContext(ctx))
+		return &grpc_gen.ListCertificatesResponse{}, nil
+	}
+
+	_, err := server.unaryAuditInterceptor(agentContext(), &grpc_gen.ListCertificatesRequest{}, info, handler)
+	assert.NotError(t, err, "intercepting request failed")
+	assert.Equal(t, 0, buffer.Len())
+
+	// admin rpcs are audited
+	info = &grpc.UnaryServerInfo{FullMethod: "/proto.AdminService/Reload"}
+	handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &grpc_gen.ReloadResponse{AddedIssuers: []string{"new-issuer"}}, nil
+	}
+
+	_, err = server.unaryAuditInterceptor(agentContext(), &grpc_gen.ReloadRequest{}, info, handler)
+	assert.NotError(t, err, "intercepting request failed")
+
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	assert.Equal(t, "added: [new-issuer], updated: [], removed: []", entries[0].Detail)
+}
+
+func TestInterceptorApprovalDecision(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	server := &Server{auditLogger: audit.NewWriterLogger(buffer)}
+	server.setAdminIdentities([]string{"agent-1"})
+
+	req := &grpc_gen.ApprovalDecisionRequest{OperationId: "operation", Reason: "not expected"}
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.AdminService/DenyRequest"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		// approvers are identified by their client certificate
+		caller, _ := identity.FromContext(ctx)
+		assert.Equal(t, "agent-1", caller.Name)
+		assert.True(t, caller.Admin)
+		audit.Decide(ctx, audit.POLICY_APPROVAL, audit.DECISION_DENIED, "not expected")
+		return &grpc_gen.Operation{Id: "operation", State: grpc_gen.OperationState_FAILED}, nil
+	}
+
+	_, err := intercept(server, agentContext(), req, info, handler)
+	assert.NotError(t, err, "intercepting request failed")
+
+	// ----
+
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	entry := entries[0]
+	assert.Equal(t, "DenyRequest", entry.Action)
+	assert.Equal(t, "operation", entry.OperationID)
+	assert.Equal(t, "reason: not expected", entry.Detail)
+	assert.Equal(t, audit.DECISION_DENIED, entry.Decisions[0].Result)
+}
+
+func TestErrorKind(t *testing.T) {
+	assert.Equal(t, "", errorKind(nil))
+	assert.Equal(t, "canceled", errorKind(status.Error(codes.Canceled, "canceled")))
+	assert.Equal(t, "deadline-exceeded", errorKind(status.Error(codes.DeadlineExceeded, "deadline exceeded")))
+	assert.Equal(t, "error", errorKind(status.Error(codes.Internal, "failed")))
+}
+
+// ----
+
+func TestInterceptorAdminRefused(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	server := &Server{auditLogger: audit.NewWriterLogger(buffer)}
+	server.setAdminIdentities([]string{"admin"})
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.AdminService/Reload"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Fatal("admin rpc of a caller not admin should not be handled")
+		return nil, nil
+	}
+
+	_, err := intercept(server, agentContext(), &grpc_gen.ReloadRequest{}, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// refused admin rpcs are audited
+	entries, err := audit.ReadEntries(buffer)
+	assert.NotError(t, err, "reading audit entries failed")
+	entry := entries[0]
+	assert.Equal(t, "Reload", entry.Action)
+	assert.Equal(t, "agent-1", entry.Identity)
+	assert.Equal(t, "permission-denied", entry.ErrorKind)
+}
+
+// intercept runs handler through the interceptors of the server in order, as grpc.ChainUnaryInterceptor does
+func intercept(server *Server, ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+
+	interceptors := server.unaryInterceptors()
+	for index := len(interceptors) - 1; index >= 0; index-- {
+		interceptor, next := interceptors[index], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+func agentContext() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{SerialNumber: big.NewInt(42), Subject: pkix.Name{CommonName: "agent-1"}}},
+		}},
+	})
+}
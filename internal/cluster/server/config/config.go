@@ -1,6 +1,9 @@
 package config
 
 import (
+	"time"
+
+	"bilalekrem.com/certstore/internal/audit"
 	certstore_config "bilalekrem.com/certstore/internal/certstore/config"
 	"gopkg.in/yaml.v3"
 )
@@ -11,6 +14,59 @@ type Config struct {
 	TlsServerCert    string                  `yaml:"tls-server-cert"`
 	TlsServerCertKey string                  `yaml:"tls-server-cert-key"`
 	CertStore        certstore_config.Config `yaml:"certstore"`
+
+	// addresses grpc is served on instead of 0.0.0.0:<listen-port>, e.g. "10.0.0.1:10000", "[::1]:10000" or
+	// "unix:///run/certstore.sock". Unix sockets are served with the same tls material
+	ListenAddresses []string `yaml:"listen-addresses"`
+
+	// go defaults are used if empty. Version is one of 1.0, 1.1, 1.2 or 1.3, cipher suites are the names in
+	// crypto/tls, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, and apply only up to tls 1.2. Curves are
+	// X25519, P256, P384 or P521
+	TlsMinVersion       string   `yaml:"tls-min-version"`
+	TlsCipherSuites     []string `yaml:"tls-cipher-suites"`
+	TlsCurvePreferences []string `yaml:"tls-curve-preferences"`
+
+	Grpc GrpcConfig `yaml:"grpc"`
+
+	// interval of issuer self tests reported through grpc health service, defaults to a minute
+	HealthCheckInterval time.Duration `yaml:"health-check-interval"`
+
+	// https port of json gateway, authenticated with the same tls material of grpc, disabled if zero
+	RestListenPort int `yaml:"rest-listen-port"`
+
+	// common names of client certificates allowed to call admin rpcs, e.g. reload and approvals. Admin rpcs are
+	// refused if empty
+	AdminIdentities []string `yaml:"admin-identities"`
+
+	// append-only json lines of issuances, revocations, reloads and admin rpcs, disabled if sink is empty
+	Audit audit.Config `yaml:"audit"`
+
+	// address of the http listener serving prometheus metrics at /metrics, e.g. "127.0.0.1:9090", disabled if empty
+	MetricsListenAddress string `yaml:"metrics-listen-address"`
+
+	// time given to requests in progress on SIGINT or SIGTERM, defaults to 30 seconds
+	ShutdownTimeout time.Duration `yaml:"shutdown-timeout"`
+}
+
+// zero values are grpc defaults
+type GrpcConfig struct {
+	// server pings a client silent for keepalive-time, and closes the connection if the ping is not answered in
+	// keepalive-timeout
+	KeepaliveTime    time.Duration `yaml:"keepalive-time"`
+	KeepaliveTimeout time.Duration `yaml:"keepalive-timeout"`
+
+	// clients pinging more often than keepalive-min-time are disconnected, pings without an active stream are
+	// allowed only if permitted
+	KeepaliveMinTime             time.Duration `yaml:"keepalive-min-time"`
+	KeepalivePermitWithoutStream bool          `yaml:"keepalive-permit-without-stream"`
+
+	MaxConnectionIdle     time.Duration `yaml:"max-connection-idle"`
+	MaxConnectionAge      time.Duration `yaml:"max-connection-age"`
+	MaxConnectionAgeGrace time.Duration `yaml:"max-connection-age-grace"`
+
+	// in bytes
+	MaxRecvMessageSize int `yaml:"max-recv-message-size"`
+	MaxSendMessageSize int `yaml:"max-send-message-size"`
 }
 
 func Parse(configYaml string) (*Config, error) {
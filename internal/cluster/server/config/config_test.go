@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 	"bilalekrem.com/certstore/internal/certificate/service/factory"
@@ -12,6 +13,7 @@ func TestParse(t *testing.T) {
 tls-ca-cert: "ca-cert-path"
 tls-server-cert: "server-cert-path"
 tls-server-cert-key: "server-cert-key-path"
+admin-identities: [admin]
 certstore:
   services:
   - name: test-cert-service
@@ -26,6 +28,7 @@ certstore:
 	assert.Equal(t, "ca-cert-path", config.TlsCACert)
 	assert.Equal(t, "server-cert-path", config.TlsServerCert)
 	assert.Equal(t, "server-cert-key-path", config.TlsServerCertKey)
+	assert.DeepEqual(t, []string{"admin"}, config.AdminIdentities)
 
 	// ----
 
@@ -36,3 +39,27 @@ certstore:
 	assert.Equal(t, "test-cert-service", issuerConfigs[0].Name)
 	assert.Equal(t, string(factory.Simple), string(issuerConfigs[0].Type))
 }
+
+func TestParseListenerSettings(t *testing.T) {
+	configYaml := `listen-addresses:
+- "[::1]:10000"
+- unix:///run/certstore.sock
+tls-min-version: "1.3"
+tls-curve-preferences: [X25519, P256]
+grpc:
+  keepalive-time: 1m
+  keepalive-permit-without-stream: true
+  max-recv-message-size: 8388608`
+
+	config, err := Parse(configYaml)
+	assert.NotError(t, err, "parsing failed")
+
+	// ----
+
+	assert.DeepEqual(t, []string{"[::1]:10000", "unix:///run/certstore.sock"}, config.ListenAddresses)
+	assert.Equal(t, "1.3", config.TlsMinVersion)
+	assert.DeepEqual(t, []string{"X25519", "P256"}, config.TlsCurvePreferences)
+	assert.Equal(t, time.Minute, config.Grpc.KeepaliveTime)
+	assert.True(t, config.Grpc.KeepalivePermitWithoutStream)
+	assert.Equal(t, 8388608, config.Grpc.MaxRecvMessageSize)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service/factory"
+	certstore_pkg "bilalekrem.com/certstore/internal/certstore"
+	"bilalekrem.com/certstore/internal/cluster/healthservice"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	DEFAULT_HEALTH_CHECK_INTERVAL = time.Minute
+	HEALTH_CHECK_TIMEOUT          = 10 * time.Second
+)
+
+// healthChecker periodically runs self tests of server components and reports them through grpc health service
+type healthChecker struct {
+	certstore    certstore_pkg.CertStore
+	healthServer *health.Server
+	interval     time.Duration
+}
+
+func newHealthChecker(certstore certstore_pkg.CertStore, healthServer *health.Server,
+	interval time.Duration) *healthChecker {
+
+	if interval == 0 {
+		interval = DEFAULT_HEALTH_CHECK_INTERVAL
+	}
+
+	// unknown until first check
+	healthServer.SetServingStatus(healthservice.STORAGE, healthpb.HealthCheckResponse_UNKNOWN)
+	for _, issuer := range certstore.ListIssuers() {
+		healthServer.SetServingStatus(healthservice.Issuer(issuer.Name), healthpb.HealthCheckResponse_UNKNOWN)
+	}
+
+	return &healthChecker{
+		certstore:    certstore,
+		healthServer: healthServer,
+		interval:     interval,
+	}
+}
+
+// run checks components until stop is closed, first check is run immediately
+func (h *healthChecker) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.check()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthChecker) check() {
+	h.checkStorage()
+	h.checkIssuers()
+}
+
+func (h *healthChecker) checkStorage() {
+	status := healthpb.HealthCheckResponse_SERVING
+	err := h.certstore.CheckStorageHealth()
+	if err != nil {
+		logging.GetLogger().Warnf("health check of storage failed, %v", err)
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	h.healthServer.SetServingStatus(healthservice.STORAGE, status)
+	h.healthServer.SetServingStatus(healthservice.SERVER, status)
+}
+
+func (h *healthChecker) checkIssuers() {
+	acmeIssuers := 0
+	acmeStatus := healthpb.HealthCheckResponse_SERVING
+	for _, issuer := range h.certstore.ListIssuers() {
+		ctx, cancel := context.WithTimeout(context.Background(), HEALTH_CHECK_TIMEOUT)
+		err := h.certstore.CheckIssuerHealth(ctx, issuer.Name)
+		cancel()
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			logging.GetLogger().Warnf("health check of issuer [%s] failed, %v", issuer.Name, err)
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+
+		h.healthServer.SetServingStatus(healthservice.Issuer(issuer.Name), status)
+
+		if issuer.Type == factory.LetsEncrypt {
+			acmeIssuers++
+			if status != healthpb.HealthCheckResponse_SERVING {
+				acmeStatus = status
+			}
+		}
+	}
+
+	if acmeIssuers == 0 {
+		acmeStatus = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+	}
+	h.healthServer.SetServingStatus(healthservice.ACME, acmeStatus)
+}
+
+// reloaded updates health of the issuers added or removed by a reload, added ones are unknown until next check
+func (h *healthChecker) reloaded(result *certstore_pkg.ReloadResult) {
+	for _, issuer := range result.Added {
+		h.healthServer.SetServingStatus(healthservice.Issuer(issuer), healthpb.HealthCheckResponse_UNKNOWN)
+	}
+
+	for _, issuer := range result.Removed {
+		h.healthServer.SetServingStatus(healthservice.Issuer(issuer), healthpb.HealthCheckResponse_SERVICE_UNKNOWN)
+	}
+}
+
+// shutdown reports every component not serving while the server is stopping, later updates are ignored
+func (h *healthChecker) shutdown() {
+	h.healthServer.Shutdown()
+}
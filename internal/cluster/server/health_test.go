@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	certstore_pkg "bilalekrem.com/certstore/internal/certstore"
+	"bilalekrem.com/certstore/internal/cluster/healthservice"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestCheckIssuers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{{Name: "healthy"}, {Name: "unhealthy"}}).
+		AnyTimes()
+	certstore.
+		EXPECT().
+		CheckIssuerHealth(gomock.Any(), gomock.Eq("healthy")).
+		Return(nil)
+	certstore.
+		EXPECT().
+		CheckIssuerHealth(gomock.Any(), gomock.Eq("unhealthy")).
+		Return(errors.New("ca expires soon"))
+
+	certstore.
+		EXPECT().
+		CheckStorageHealth().
+		Return(nil)
+
+	healthServer := health.NewServer()
+	checker := newHealthChecker(certstore, healthServer, 0)
+	assert.Equal(t, DEFAULT_HEALTH_CHECK_INTERVAL, checker.interval)
+	assert.Equal(t, healthpb.HealthCheckResponse_UNKNOWN, checkHealth(t, healthServer, "issuer/healthy"))
+	assert.Equal(t, healthpb.HealthCheckResponse_UNKNOWN, checkHealth(t, healthServer, healthservice.STORAGE))
+
+	// ----
+
+	checker.check()
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkHealth(t, healthServer, "issuer/healthy"))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkHealth(t, healthServer, "issuer/unhealthy"))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkHealth(t, healthServer, healthservice.STORAGE))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVICE_UNKNOWN, checkHealth(t, healthServer, healthservice.ACME))
+
+	// server itself is serving, unhealthy issuers do not affect other issuers
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkHealth(t, healthServer, healthservice.SERVER))
+}
+
+func TestCheckStorageFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{}).
+		AnyTimes()
+	certstore.
+		EXPECT().
+		CheckStorageHealth().
+		Return(errors.New("disk is full"))
+
+	healthServer := health.NewServer()
+	checker := newHealthChecker(certstore, healthServer, 0)
+
+	// ----
+
+	checker.check()
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkHealth(t, healthServer, healthservice.STORAGE))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkHealth(t, healthServer, healthservice.SERVER))
+}
+
+func TestCheckACMEIssuers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{
+			{Name: "first", Type: "LetsEncrypt"},
+			{Name: "second", Type: "LetsEncrypt"},
+			{Name: "internal", Type: "Simple"},
+		}).
+		AnyTimes()
+	certstore.
+		EXPECT().
+		CheckIssuerHealth(gomock.Any(), gomock.Not("second")).
+		Return(nil).
+		Times(4)
+	gomock.InOrder(
+		certstore.
+			EXPECT().
+			CheckIssuerHealth(gomock.Any(), gomock.Eq("second")).
+			Return(nil),
+		certstore.
+			EXPECT().
+			CheckIssuerHealth(gomock.Any(), gomock.Eq("second")).
+			Return(errors.New("acme directory is not reachable")),
+	)
+
+	healthServer := health.NewServer()
+	checker := newHealthChecker(certstore, healthServer, 0)
+
+	// ----
+
+	checker.checkIssuers()
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkHealth(t, healthServer, healthservice.ACME))
+
+	checker.checkIssuers()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkHealth(t, healthServer, healthservice.ACME))
+}
+
+func TestHealthShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{})
+
+	healthServer := health.NewServer()
+	checker := newHealthChecker(certstore, healthServer, 0)
+
+	// ----
+
+	checker.shutdown()
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkHealth(t, healthServer, healthservice.SERVER))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkHealth(t, healthServer, healthservice.STORAGE))
+}
+
+func TestReloadedIssuers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{{Name: "removed"}})
+
+	healthServer := health.NewServer()
+	checker := newHealthChecker(certstore, healthServer, 0)
+
+	// ----
+
+	checker.reloaded(&certstore_pkg.ReloadResult{Added: []string{"added"}, Removed: []string{"removed"}})
+
+	assert.Equal(t, healthpb.HealthCheckResponse_UNKNOWN, checkHealth(t, healthServer, "issuer/added"))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVICE_UNKNOWN, checkHealth(t, healthServer, "issuer/removed"))
+}
+
+// -----
+
+func checkHealth(t *testing.T, healthServer *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	assert.NotError(t, err, "checking health failed")
+
+	return resp.Status
+}
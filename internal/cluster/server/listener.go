@@ -0,0 +1,212 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"bilalekrem.com/certstore/internal/cluster/server/config"
+	"bilalekrem.com/certstore/internal/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	UNIX_ADDRESS_PREFIX = "unix:"
+
+	// an existing socket is dialed before listening, so that a running server is not taken over
+	UNIX_SOCKET_DIAL_TIMEOUT = time.Second
+)
+
+// listenAddresses returns configured addresses, or all interfaces on listen port if there is none
+func listenAddresses(conf *config.Config) []string {
+	if len(conf.ListenAddresses) > 0 {
+		return conf.ListenAddresses
+	}
+
+	return []string{fmt.Sprintf("0.0.0.0:%d", conf.ListenPort)}
+}
+
+// restListenAddresses binds json gateway to the hosts grpc listens on, unix sockets are skipped. Empty if rest
+// listen port is not configured
+func restListenAddresses(conf *config.Config) []string {
+	addresses := []string{}
+	if conf.RestListenPort == 0 {
+		return addresses
+	}
+
+	for _, address := range listenAddresses(conf) {
+		if _, ok := unixSocketPath(address); ok {
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			continue
+		}
+
+		restAddress := net.JoinHostPort(host, strconv.Itoa(conf.RestListenPort))
+		if !contains(addresses, restAddress) {
+			addresses = append(addresses, restAddress)
+		}
+	}
+
+	return addresses
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateListenAddress(address string) error {
+	if path, ok := unixSocketPath(address); ok {
+		if path == "" {
+			return errors.New(fmt.Sprintf("unix socket path is missing: [%s]", address))
+		}
+
+		return nil
+	}
+
+	_, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.New(fmt.Sprintf("listen address is not valid: [%s], %v", address, err))
+	}
+
+	return nil
+}
+
+// listen opens a tcp listener, or a unix socket if address starts with unix:, e.g. unix:///run/certstore.sock
+func listen(address string) (net.Listener, error) {
+	path, ok := unixSocketPath(address)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	// socket is left behind if the server is not stopped gracefully, it is removed only if nothing accepts on it
+	info, err := os.Stat(path)
+	if err == nil && info.Mode()&os.ModeSocket != 0 {
+		conn, err := net.DialTimeout("unix", path, UNIX_SOCKET_DIAL_TIMEOUT)
+		if err == nil {
+			conn.Close()
+			return nil, errors.New(fmt.Sprintf("unix socket is in use by another process: [%s]", path))
+		} else if !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, errors.New(fmt.Sprintf("checking unix socket failed: [%s], %v", path, err))
+		}
+
+		logging.GetLogger().Infof("Removing stale unix socket [%s]", path)
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+func unixSocketPath(address string) (string, bool) {
+	if !strings.HasPrefix(address, UNIX_ADDRESS_PREFIX) {
+		return "", false
+	}
+
+	path := strings.TrimPrefix(address, UNIX_ADDRESS_PREFIX)
+	return strings.TrimPrefix(path, "//"), true
+}
+
+// ----
+
+func validateGrpcConfig(conf *config.GrpcConfig) error {
+	if conf.KeepaliveTime < 0 || conf.KeepaliveTimeout < 0 || conf.KeepaliveMinTime < 0 ||
+		conf.MaxConnectionIdle < 0 || conf.MaxConnectionAge < 0 || conf.MaxConnectionAgeGrace < 0 {
+		return errors.New("grpc keepalive and connection durations can not be negative")
+	} else if conf.MaxRecvMessageSize < 0 || conf.MaxSendMessageSize < 0 {
+		return errors.New("grpc max message sizes can not be negative")
+	}
+
+	return nil
+}
+
+// grpcOptions returns keepalive options and configured message sizes, grpc defaults zero keepalive values but takes
+// a zero message size literally
+func grpcOptions(conf *config.GrpcConfig) []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     conf.MaxConnectionIdle,
+			MaxConnectionAge:      conf.MaxConnectionAge,
+			MaxConnectionAgeGrace: conf.MaxConnectionAgeGrace,
+			Time:                  conf.KeepaliveTime,
+			Timeout:               conf.KeepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             conf.KeepaliveMinTime,
+			PermitWithoutStream: conf.KeepalivePermitWithoutStream,
+		}),
+	}
+
+	if conf.MaxRecvMessageSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(conf.MaxRecvMessageSize))
+	}
+	if conf.MaxSendMessageSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(conf.MaxSendMessageSize))
+	}
+
+	return opts
+}
+
+// ----
+
+// listen opens every listen address, none of them is left open if one fails
+func (s *Server) listen() ([]net.Listener, error) {
+	listeners := []net.Listener{}
+	for _, address := range s.listenAddresses {
+		logging.GetLogger().Debugf("Starting to listening on [%s]", address)
+		l, err := listen(address)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// serveGrpc serves on every listener until the server is stopped. If serving on one of them fails, the others are
+// stopped too and the failure is returned
+func (s *Server) serveGrpc(listeners []net.Listener) error {
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- s.grpcServer.Serve(l)
+		}(l)
+	}
+
+	var err error
+	for range listeners {
+		serveErr := <-errs
+		if serveErr != nil && err == nil {
+			logging.GetLogger().Errorf("Serving grpc failed, stopping other listeners, %v", serveErr)
+			err = serveErr
+			s.grpcServer.Stop()
+		}
+	}
+
+	return err
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/cluster/server/config"
+)
+
+func TestListenAddresses(t *testing.T) {
+	conf := getConfig()
+	assert.DeepEqual(t, []string{"0.0.0.0:10000"}, listenAddresses(conf))
+
+	conf.ListenAddresses = []string{"[::1]:10000", "unix:///run/certstore.sock"}
+	assert.DeepEqual(t, []string{"[::1]:10000", "unix:///run/certstore.sock"}, listenAddresses(conf))
+}
+
+func TestRestListenAddresses(t *testing.T) {
+	conf := getConfig()
+	assert.DeepEqual(t, []string{}, restListenAddresses(conf))
+
+	conf.RestListenPort = 10001
+	assert.DeepEqual(t, []string{"0.0.0.0:10001"}, restListenAddresses(conf))
+
+	conf.ListenAddresses = []string{"127.0.0.1:10000", "[::1]:10000", "127.0.0.1:10002", "unix:///run/certstore.sock"}
+	assert.DeepEqual(t, []string{"127.0.0.1:10001", "[::1]:10001"}, restListenAddresses(conf))
+}
+
+func TestValidateListenAddress(t *testing.T) {
+	assert.NotError(t, validateListenAddress("10.0.0.1:10000"), "validating ipv4 address failed")
+	assert.NotError(t, validateListenAddress("[::]:10000"), "validating ipv6 address failed")
+	assert.NotError(t, validateListenAddress("unix:///run/certstore.sock"), "validating unix socket failed")
+
+	assert.Error(t, validateListenAddress("10.0.0.1"), "validation failed: missing port")
+	assert.Error(t, validateListenAddress("unix://"), "validation failed: missing socket path")
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	path, ok := unixSocketPath("unix:///run/certstore.sock")
+	assert.True(t, ok)
+	assert.Equal(t, "/run/certstore.sock", path)
+
+	path, ok = unixSocketPath("unix:certstore.sock")
+	assert.True(t, ok)
+	assert.Equal(t, "certstore.sock", path)
+
+	_, ok = unixSocketPath("127.0.0.1:10000")
+	assert.False(t, ok)
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_server_listen")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	path := fmt.Sprintf("%s/certstore.sock", dir)
+
+	// socket left behind by a server not stopped gracefully
+	stale, err := net.Listen("unix", path)
+	assert.NotError(t, err, "listening unix socket failed")
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := listen("unix://" + path)
+	assert.NotError(t, err, "listening stale unix socket failed")
+	defer l.Close()
+
+	conn, err := net.Dial("unix", path)
+	assert.NotError(t, err, "connecting unix socket failed")
+	conn.Close()
+}
+
+func TestListenUnixSocketInUse(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_server_listen")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	path := fmt.Sprintf("%s/certstore.sock", dir)
+	running, err := net.Listen("unix", path)
+	assert.NotError(t, err, "listening unix socket failed")
+	defer running.Close()
+
+	_, err = listen("unix://" + path)
+	assert.ErrorContains(t, err, "in use by another process")
+
+	// socket of the running server is kept
+	conn, err := net.Dial("unix", path)
+	assert.NotError(t, err, "socket of running server is removed")
+	conn.Close()
+}
+
+func TestListenClosesListenersOnFailure(t *testing.T) {
+	port := freePort(t)
+	taken, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	assert.NotError(t, err, "listening port failed")
+	defer taken.Close()
+
+	first := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	server := &Server{listenAddresses: []string{first, fmt.Sprintf("127.0.0.1:%d", port)}}
+	_, err = server.listen()
+	assert.Error(t, err, "listening taken port should fail")
+
+	// first one is closed, it can be listened again
+	l, err := net.Listen("tcp", first)
+	assert.NotError(t, err, "first address is left open")
+	l.Close()
+}
+
+func TestValidateGrpcConfig(t *testing.T) {
+	assert.NotError(t, validateGrpcConfig(&config.GrpcConfig{}), "validating default grpc config failed")
+	assert.Error(t, validateGrpcConfig(&config.GrpcConfig{KeepaliveTime: -1}), "validation failed: negative keepalive")
+	assert.Error(t, validateGrpcConfig(&config.GrpcConfig{MaxRecvMessageSize: -1}), "validation failed: negative message size")
+}
+
+func TestGrpcOptions(t *testing.T) {
+	assert.Equal(t, 2, len(grpcOptions(&config.GrpcConfig{})))
+	assert.Equal(t, 4, len(grpcOptions(&config.GrpcConfig{MaxRecvMessageSize: 1024, MaxSendMessageSize: 1024})))
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"time"
+
+	certstore_pkg "bilalekrem.com/certstore/internal/certstore"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/logging"
+	"bilalekrem.com/certstore/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	METRICS_PATH = "/metrics"
+
+	// agents are named by the common name of their client certificate
+	UNKNOWN_AGENT = "unknown"
+)
+
+var (
+	caDaysUntilExpiryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.NAMESPACE, "", "ca_days_until_expiry"),
+		"Days until the CA certificate of the issuer expires.",
+		[]string{"issuer"}, nil)
+
+	inventoryDaysUntilExpiryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.NAMESPACE, "", "inventory_days_until_earliest_expiry"),
+		"Days until the first certificate in inventory expires, among the ones not expired yet.",
+		nil, nil)
+
+	keyPoolAvailableDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.NAMESPACE, "", "key_pool_available"),
+		"Keys ready in the key pool.",
+		[]string{"key_type"}, nil)
+
+	keyPoolDrawsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.NAMESPACE, "", "key_pool_draws_total"),
+		"Keys requested from the key pool, hit if served from the pool, miss if generated inline.",
+		[]string{"key_type", "result"}, nil)
+)
+
+// certstoreCollector reads the state of certstore on each scrape
+type certstoreCollector struct {
+	certstore    certstore_pkg.CertStore
+	timeProvider func() time.Time
+}
+
+func newCertstoreCollector(certstore certstore_pkg.CertStore) *certstoreCollector {
+	return &certstoreCollector{certstore: certstore, timeProvider: time.Now}
+}
+
+func (c *certstoreCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- caDaysUntilExpiryDesc
+	ch <- inventoryDaysUntilExpiryDesc
+	ch <- keyPoolAvailableDesc
+	ch <- keyPoolDrawsDesc
+}
+
+func (c *certstoreCollector) Collect(ch chan<- prometheus.Metric) {
+	now := c.timeProvider()
+
+	for _, issuer := range c.certstore.ListIssuers() {
+		if issuer.CAExpiry.IsZero() {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(caDaysUntilExpiryDesc, prometheus.GaugeValue,
+			daysBetween(now, issuer.CAExpiry), issuer.Name)
+	}
+
+	earliest, err := c.certstore.EarliestExpiry()
+	if err == nil {
+		ch <- prometheus.MustNewConstMetric(inventoryDaysUntilExpiryDesc, prometheus.GaugeValue,
+			daysBetween(now, earliest.NotAfter))
+	} else if !inventory.IsNotFound(err) {
+		logging.GetLogger().Warnf("Reading earliest expiry of inventory failed, %v", err)
+	}
+
+	for _, stats := range c.certstore.KeyPoolStats() {
+		ch <- prometheus.MustNewConstMetric(keyPoolAvailableDesc, prometheus.GaugeValue,
+			float64(stats.Available), stats.KeyType)
+		ch <- prometheus.MustNewConstMetric(keyPoolDrawsDesc, prometheus.CounterValue,
+			float64(stats.Hits), stats.KeyType, "hit")
+		ch <- prometheus.MustNewConstMetric(keyPoolDrawsDesc, prometheus.CounterValue,
+			float64(stats.Misses), stats.KeyType, "miss")
+	}
+}
+
+func daysBetween(from time.Time, to time.Time) float64 {
+	return to.Sub(from).Hours() / 24
+}
+
+// ----
+
+func unaryMetricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+
+	metrics.InFlightRequests.Inc()
+	defer metrics.InFlightRequests.Dec()
+	metrics.AgentRequests.WithLabelValues(agentName(ctx), info.FullMethod).Inc()
+
+	return handler(ctx, req)
+}
+
+func streamMetricsInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+
+	metrics.InFlightRequests.Inc()
+	defer metrics.InFlightRequests.Dec()
+	metrics.AgentRequests.WithLabelValues(agentName(stream.Context()), info.FullMethod).Inc()
+
+	return handler(srv, stream)
+}
+
+func agentName(ctx context.Context) string {
+	certificate := peerCertificate(ctx)
+	if certificate == nil || certificate.Subject.CommonName == "" {
+		return UNKNOWN_AGENT
+	}
+
+	return certificate.Subject.CommonName
+}
+
+// peerCertificate returns nil if the peer is not authenticated with a certificate
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil
+	}
+
+	return tlsInfo.State.PeerCertificates[0]
+}
+
+// ----
+
+func (s *Server) newMetricsHandler() http.Handler {
+	registry := metrics.NewRegistry()
+	registry.MustRegister(newCertstoreCollector(s.certstore))
+
+	mux := http.NewServeMux()
+	mux.Handle(METRICS_PATH, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// serveMetrics serves metrics until the returned server is closed
+func (s *Server) serveMetrics(address string) (*http.Server, error) {
+	logging.GetLogger().Debugf("Starting to serve metrics on [%s]", address)
+	listen, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	metricsServer := &http.Server{Handler: s.newMetricsHandler()}
+	go func() {
+		err := metricsServer.Serve(listen)
+		if err != nil && err != http.ErrServerClosed {
+			logging.GetLogger().Errorf("Serving metrics failed, %v", err)
+		}
+	}()
+
+	return metricsServer, nil
+}
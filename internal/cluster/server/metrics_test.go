@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/keypool"
+	certstore_pkg "bilalekrem.com/certstore/internal/certstore"
+	"bilalekrem.com/certstore/internal/certstore/inventory"
+	"bilalekrem.com/certstore/internal/certstore/storage"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestCertstoreCollector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2022, 01, 01, 12, 0, 0, 0, time.UTC)
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.
+		EXPECT().
+		ListIssuers().
+		Return([]certstore_pkg.IssuerInfo{
+			{Name: "ca", CAExpiry: now.AddDate(0, 0, 365)},
+			{Name: "lets-encrypt"},
+		})
+	certstore.
+		EXPECT().
+		EarliestExpiry().
+		Return(&inventory.Certificate{NotAfter: now.Add(36 * time.Hour)}, nil)
+	certstore.
+		EXPECT().
+		KeyPoolStats().
+		Return([]keypool.Stats{{KeyType: "RSA-2048", Available: 3, Hits: 10, Misses: 2}})
+
+	collector := newCertstoreCollector(certstore)
+	collector.timeProvider = func() time.Time { return now }
+
+	// ----
+
+	expected := `
+# HELP certstore_ca_days_until_expiry Days until the CA certificate of the issuer expires.
+# TYPE certstore_ca_days_until_expiry gauge
+certstore_ca_days_until_expiry{issuer="ca"} 365
+# HELP certstore_inventory_days_until_earliest_expiry Days until the first certificate in inventory expires, among the ones not expired yet.
+# TYPE certstore_inventory_days_until_earliest_expiry gauge
+certstore_inventory_days_until_earliest_expiry 1.5
+# HELP certstore_key_pool_available Keys ready in the key pool.
+# TYPE certstore_key_pool_available gauge
+certstore_key_pool_available{key_type="RSA-2048"} 3
+# HELP certstore_key_pool_draws_total Keys requested from the key pool, hit if served from the pool, miss if generated inline.
+# TYPE certstore_key_pool_draws_total counter
+certstore_key_pool_draws_total{key_type="RSA-2048",result="hit"} 10
+certstore_key_pool_draws_total{key_type="RSA-2048",result="miss"} 2
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected))
+	assert.NotError(t, err, "collected metrics are not as expected")
+}
+
+func TestCertstoreCollectorEmptyInventory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.EXPECT().ListIssuers().Return([]certstore_pkg.IssuerInfo{})
+	certstore.EXPECT().EarliestExpiry().Return(nil, storage.ErrNotFound)
+	certstore.EXPECT().KeyPoolStats().Return([]keypool.Stats{})
+
+	assert.Equal(t, 0, testutil.CollectAndCount(newCertstoreCollector(certstore)))
+}
+
+func TestAgentName(t *testing.T) {
+	assert.Equal(t, UNKNOWN_AGENT, agentName(context.Background()))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "agent-1"}}},
+		}},
+	})
+	assert.Equal(t, "agent-1", agentName(ctx))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	certstore := certstore_pkg.NewMockCertStore(ctrl)
+	certstore.EXPECT().ListIssuers().Return([]certstore_pkg.IssuerInfo{}).AnyTimes()
+	certstore.EXPECT().EarliestExpiry().Return(nil, storage.ErrNotFound).AnyTimes()
+	certstore.EXPECT().KeyPoolStats().Return([]keypool.Stats{}).AnyTimes()
+
+	server := &Server{certstore: certstore}
+	httpServer := httptest.NewServer(server.newMetricsHandler())
+	defer httpServer.Close()
+
+	// ----
+
+	response, err := httpServer.Client().Get(httpServer.URL + METRICS_PATH)
+	assert.NotError(t, err, "requesting metrics failed")
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NotError(t, err, "reading metrics failed")
+	assert.Equal(t, 200, response.StatusCode)
+	assert.True(t, strings.Contains(string(body), "certstore_requests_in_flight"))
+	assert.True(t, strings.Contains(string(body), "go_goroutines"))
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	grpc_service "bilalekrem.com/certstore/internal/certstore/grpc/service"
+	"bilalekrem.com/certstore/internal/certstore/rest"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+func (s *Server) createRestServer() *http.Server {
+	// same tls material and client verification with grpc, cloned to offer http/1.1 besides h2
+	tlsConfig := &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			conf := s.tlsConfig.Load().(*tls.Config).Clone()
+			conf.NextProtos = []string{"h2", "http/1.1"}
+			return conf, nil
+		},
+	}
+
+	return &http.Server{
+		Handler:   rest.NewHandler(grpc_service.NewCertificateService(s.certstore), s.unaryInterceptors()...),
+		TLSConfig: tlsConfig,
+	}
+}
+
+// serveRest serves json gateway on the rest listen addresses until rest server is shut down, none of them is left
+// open if one fails
+func (s *Server) serveRest() error {
+	listeners := []net.Listener{}
+	for _, address := range s.restListenAddresses {
+		logging.GetLogger().Debugf("Starting to serve json gateway on [%s]", address)
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			closeListeners(listeners)
+			return err
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			err := s.restServer.ServeTLS(l, "", "")
+			if err != nil && err != http.ErrServerClosed {
+				logging.GetLogger().Errorf("Serving json gateway failed, %v", err)
+			}
+		}(l)
+	}
+
+	return nil
+}
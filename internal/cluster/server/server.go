@@ -1,40 +1,88 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"bilalekrem.com/certstore/internal/audit"
 	certstore_pkg "bilalekrem.com/certstore/internal/certstore"
 	grpc_gen "bilalekrem.com/certstore/internal/certstore/grpc/gen"
 	grpc_service "bilalekrem.com/certstore/internal/certstore/grpc/service"
+	"bilalekrem.com/certstore/internal/certstore/identity"
 	"bilalekrem.com/certstore/internal/cluster/server/config"
+	"bilalekrem.com/certstore/internal/lifecycle"
 	"bilalekrem.com/certstore/internal/logging"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// minimum time given to certstore on stop, after requests in progress are completed or closed
+	CERTSTORE_STOP_TIMEOUT = 10 * time.Second
 )
 
 type Server struct {
-	certstore  certstore_pkg.CertStore
-	grpcServer *grpc.Server
-	listenPort int
+	certstore       certstore_pkg.CertStore
+	grpcServer      *grpc.Server
+	listenAddresses []string
+	healthChecker   *healthChecker
+
+	// nil if audit is not enabled
+	auditLogger *audit.Logger
+
+	// json gateway is not served if empty
+	restListenAddresses []string
+	restServer          *http.Server
+
+	// metrics are not served if empty
+	metricsListenAddress string
+
+	// zero if not configured, default timeout of lifecycle is used
+	shutdownTimeout time.Duration
+
+	// config is re-read from the file on reload, server can not be reloaded if empty
+	configPath  string
+	conf        *config.Config
+	reloadMutex sync.Mutex
+
+	// *tls.Config of new connections, replaced on reload
+	tlsConfig atomic.Value
+
+	// map[string]bool of admin common names, replaced on reload
+	adminIdentities atomic.Value
 }
 
 func NewFromFile(path string) (*Server, error) {
-	bytes, err := ioutil.ReadFile(path)
+	config, err := readConfig(path)
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := config.Parse(string(bytes))
+	server, err := NewFromConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewFromConfig(config)
+	server.configPath = path
+	return server, nil
 }
 
 func NewFromConfig(conf *config.Config) (*Server, error) {
@@ -43,33 +91,193 @@ func NewFromConfig(conf *config.Config) (*Server, error) {
 		return nil, err
 	}
 
-	certstore, err := certstore_pkg.NewFromConfig(&conf.CertStore)
+	tlsConfig, err := createTlsConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogger, err := audit.New(&conf.Audit)
 	if err != nil {
 		return nil, err
 	}
 
-	grpcServer, err := createAndSetupGrpcServer(conf, certstore)
+	certstore, err := certstore_pkg.NewFromConfig(&conf.CertStore)
 	if err != nil {
+		auditLogger.Close()
 		return nil, err
 	}
 
+	healthServer := health.NewServer()
 	server := &Server{
-		certstore:  certstore,
-		grpcServer: grpcServer,
-		listenPort: conf.ListenPort,
+		certstore:       certstore,
+		listenAddresses: listenAddresses(conf),
+		healthChecker:   newHealthChecker(certstore, healthServer, conf.HealthCheckInterval),
+		auditLogger:     auditLogger,
+		conf:            conf,
+
+		restListenAddresses:  restListenAddresses(conf),
+		metricsListenAddress: conf.MetricsListenAddress,
+		shutdownTimeout:      conf.ShutdownTimeout,
 	}
+	server.tlsConfig.Store(tlsConfig)
+	server.setAdminIdentities(conf.AdminIdentities)
+	server.grpcServer = server.createAndSetupGrpcServer(healthServer)
+	server.restServer = server.createRestServer()
 
 	return server, nil
 }
 
 func (s *Server) Serve() error {
-	logging.GetLogger().Debugf("Starting to listening on 0.0.0.0:%d", s.listenPort)
-	listen, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", s.listenPort))
+	listeners, err := s.listen()
 	if err != nil {
 		return fmt.Errorf("error occurred while listening port, %v", err)
 	}
 
-	return s.grpcServer.Serve(listen)
+	if s.metricsListenAddress != "" {
+		metricsServer, err := s.serveMetrics(s.metricsListenAddress)
+		if err != nil {
+			closeListeners(listeners)
+			return fmt.Errorf("error occurred while listening metrics address, %v", err)
+		}
+		defer metricsServer.Close()
+	}
+
+	if len(s.restListenAddresses) > 0 {
+		err = s.serveRest()
+		if err != nil {
+			closeListeners(listeners)
+			return fmt.Errorf("error occurred while listening rest port, %v", err)
+		}
+		defer s.restServer.Close()
+	}
+
+	stopHealthChecks := make(chan struct{})
+	defer close(stopHealthChecks)
+	go s.healthChecker.run(stopHealthChecks)
+
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	defer signal.Stop(reloadSignals)
+	go s.reloadOnSignal(reloadSignals, stopHealthChecks)
+
+	return s.serveGrpc(listeners)
+}
+
+// Stop waits for requests in progress until ctx is done, then closes remaining connections. Serve returns once
+// the server is stopped.
+func (s *Server) Stop(ctx context.Context) error {
+	logging.GetLogger().Info("Stopping server, waiting for requests in progress")
+	s.healthChecker.shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	restErr := s.restServer.Shutdown(ctx)
+	if restErr != nil {
+		logging.GetLogger().Warnf("Requests of json gateway are not completed in time, %v", restErr)
+	}
+
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logging.GetLogger().Warnf("Requests in progress are not completed in time, closing connections")
+		s.grpcServer.Stop()
+		err = ctx.Err()
+	}
+
+	// submitted requests keep running meanwhile, certstore is given time to complete them and deliver their events
+	// even if requests in progress consumed ctx
+	certstoreCtx, cancel := lifecycle.StopContext(ctx, CERTSTORE_STOP_TIMEOUT)
+	defer cancel()
+
+	certstoreErr := s.certstore.Stop(certstoreCtx)
+	if err == nil {
+		err = certstoreErr
+	}
+
+	// closed last, submitted certificate requests are audited once they are completed
+	auditErr := s.auditLogger.Close()
+	if auditErr != nil {
+		logging.GetLogger().Errorf("Closing audit log failed, %v", auditErr)
+	}
+
+	return err
+}
+
+// ShutdownTimeout is the time given to requests in progress when the server is stopped by a signal
+func (s *Server) ShutdownTimeout() time.Duration {
+	return s.shutdownTimeout
+}
+
+// Reload re-reads config file, replaces changed issuers and tls material of the server. Nothing is changed if
+// the config is not valid. Connections established already keep their tls material.
+func (s *Server) Reload() (*certstore_pkg.ReloadResult, error) {
+	if s.configPath == "" {
+		return nil, errors.New("server is not created from a config file, nothing to reload")
+	}
+
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	logging.GetLogger().Infof("Reloading server config [%s]", s.configPath)
+	conf, err := readConfig(s.configPath)
+	if err != nil {
+		logging.GetLogger().Errorf("Reading server config failed, reload is refused, %v", err)
+		return nil, err
+	}
+
+	err = validateConfig(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("Validating server config failed, reload is refused, %v", err)
+		return nil, err
+	}
+
+	tlsConfig, err := createTlsConfig(conf)
+	if err != nil {
+		logging.GetLogger().Errorf("Loading tls material failed, reload is refused, %v", err)
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(listenAddresses(conf), s.listenAddresses) {
+		logging.GetLogger().Warnf("listen-port and listen-addresses changes require a restart, keeping %v", s.listenAddresses)
+	}
+	if conf.Grpc != s.conf.Grpc {
+		logging.GetLogger().Warnf("grpc settings change requires a restart, keeping the previous ones")
+	}
+	if conf.HealthCheckInterval != s.conf.HealthCheckInterval {
+		logging.GetLogger().Warnf("health-check-interval change requires a restart, keeping [%v]", s.conf.HealthCheckInterval)
+	}
+	if conf.ShutdownTimeout != s.conf.ShutdownTimeout {
+		logging.GetLogger().Warnf("shutdown-timeout change requires a restart, keeping [%v]", s.shutdownTimeout)
+	}
+	if conf.RestListenPort != s.conf.RestListenPort {
+		logging.GetLogger().Warnf("rest-listen-port change requires a restart, keeping [%d]", s.conf.RestListenPort)
+	}
+	if !reflect.DeepEqual(conf.Audit, s.conf.Audit) {
+		logging.GetLogger().Warnf("audit change requires a restart, keeping the running audit log")
+	}
+	if conf.MetricsListenAddress != s.conf.MetricsListenAddress {
+		logging.GetLogger().Warnf("metrics-listen-address change requires a restart, keeping [%s]", s.conf.MetricsListenAddress)
+	}
+
+	// ----
+
+	result, err := s.certstore.Reload(&conf.CertStore)
+	if err != nil {
+		logging.GetLogger().Errorf("Reloading issuers failed, reload is refused, %v", err)
+		return nil, err
+	}
+
+	s.tlsConfig.Store(tlsConfig)
+	s.setAdminIdentities(conf.AdminIdentities)
+	s.conf = conf
+	s.healthChecker.reloaded(result)
+
+	return result, nil
 }
 
 // -----
@@ -81,31 +289,166 @@ func validateConfig(conf *config.Config) error {
 		return fmt.Errorf("tls-server-cert is required argument")
 	} else if conf.TlsServerCertKey == "" {
 		return fmt.Errorf("tls-server-cert-key is required argument")
-	} else if conf.ListenPort == 0 {
+	} else if conf.ListenPort == 0 && len(conf.ListenAddresses) == 0 {
 		return fmt.Errorf("port is required argument, missing or provided zero")
+	} else if conf.RestListenPort < 0 {
+		return fmt.Errorf("rest-listen-port can not be negative")
+	} else if conf.HealthCheckInterval < 0 {
+		return fmt.Errorf("health-check-interval can not be negative")
+	}
+
+	for _, address := range conf.ListenAddresses {
+		err := validateListenAddress(address)
+		if err != nil {
+			return err
+		}
+	}
+
+	if conf.RestListenPort != 0 && len(restListenAddresses(conf)) == 0 {
+		return fmt.Errorf("rest-listen-port requires a tcp listen address, json gateway is not served on unix sockets")
+	}
+
+	for _, address := range restListenAddresses(conf) {
+		if contains(listenAddresses(conf), address) {
+			return fmt.Errorf("rest-listen-port can not be the same with listen-port, both listen on [%s]", address)
+		}
+	}
+
+	err := validateGrpcConfig(&conf.Grpc)
+	if err != nil {
+		return err
 	}
 
 	// should we also validate cerstore config in here ?
 	return nil
 }
 
-func createAndSetupGrpcServer(conf *config.Config, certstore certstore_pkg.CertStore) (*grpc.Server, error) {
-	tlsConfig, err := createTlsConfig(conf)
-	if err != nil {
-		return nil, err
-	}
+func (s *Server) createAndSetupGrpcServer(healthServer *health.Server) *grpc.Server {
+	// tls material is looked up for each connection, so that it can be reloaded
+	creds := credentials.NewTLS(&tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.tlsConfig.Load().(*tls.Config), nil
+		},
+	})
 
-	creds := credentials.NewTLS(tlsConfig)
-	if creds == nil {
-		return nil, err
+	opts := []grpc.ServerOption{
+		grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(s.unaryInterceptors()...),
+		grpc.ChainStreamInterceptor(s.streamInterceptors()...),
 	}
-
-	opts := []grpc.ServerOption{grpc.Creds(creds)}
+	opts = append(opts, grpcOptions(&s.conf.Grpc)...)
 	grpcServer := grpc.NewServer(opts...)
-	grpc_gen.RegisterCertificateServiceServer(grpcServer, grpc_service.NewCertificateService(certstore))
+	grpc_gen.RegisterCertificateServiceServer(grpcServer, grpc_service.NewCertificateService(s.certstore))
+	grpc_gen.RegisterAdminServiceServer(grpcServer, grpc_service.NewAdminService(s.certstore, s.Reload))
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
 	reflection.Register(grpcServer)
 
-	return grpcServer, nil
+	return grpcServer
+}
+
+// unaryInterceptors are run in order for grpc and json gateway requests: callers are identified by their client
+// certificate, e.g. requesters and approvers of approval requests, requests are counted in metrics and audited,
+// then admin rpcs are authorized, so that refused ones are audited too
+func (s *Server) unaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		s.unaryCallerInterceptor,
+		unaryMetricsInterceptor,
+		s.unaryAuditInterceptor,
+		authorizeAdmin,
+	}
+}
+
+// there is no admin rpc streaming
+func (s *Server) streamInterceptors() []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		s.streamCallerInterceptor,
+		streamMetricsInterceptor,
+	}
+}
+
+func (s *Server) unaryCallerInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+
+	return handler(identity.NewContext(ctx, s.caller(ctx)), req)
+}
+
+func (s *Server) streamCallerInterceptor(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+
+	ctx := identity.NewContext(stream.Context(), s.caller(stream.Context()))
+	return handler(srv, &callerStream{ServerStream: stream, ctx: ctx})
+}
+
+// caller has no name if the peer is not authenticated with a certificate
+func (s *Server) caller(ctx context.Context) identity.Caller {
+	certificate := peerCertificate(ctx)
+	if certificate == nil {
+		return identity.Caller{}
+	}
+
+	name := certificate.Subject.CommonName
+	admins, _ := s.adminIdentities.Load().(map[string]bool)
+	return identity.Caller{Name: name, Admin: name != "" && admins[name]}
+}
+
+func (s *Server) setAdminIdentities(adminIdentities []string) {
+	admins := make(map[string]bool)
+	for _, admin := range adminIdentities {
+		admins[admin] = true
+	}
+
+	if len(admins) == 0 {
+		logging.GetLogger().Warnf("admin-identities is empty, admin rpcs are refused")
+	}
+	s.adminIdentities.Store(admins)
+}
+
+func authorizeAdmin(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+
+	if !strings.HasPrefix(info.FullMethod, ADMIN_SERVICE_PREFIX) {
+		return handler(ctx, req)
+	}
+
+	caller, _ := identity.FromContext(ctx)
+	if !caller.Admin {
+		logging.GetLogger().Warnf("Admin rpc [%s] is refused, caller is not an admin: [%s]", info.FullMethod, caller.Name)
+		return nil, status.Errorf(codes.PermissionDenied, "caller is not an admin: [%s]", caller.Name)
+	}
+
+	return handler(ctx, req)
+}
+
+// callerStream carries the caller in the context of a stream
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Server) reloadOnSignal(signals <-chan os.Signal, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-signals:
+		}
+
+		// failures are logged by reload, server keeps running with the previous config
+		s.auditedReload()
+	}
+}
+
+func readConfig(path string) (*config.Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.Parse(string(bytes))
 }
 
 func createTlsConfig(conf *config.Config) (*tls.Config, error) {
@@ -124,9 +467,18 @@ func createTlsConfig(conf *config.Config) (*tls.Config, error) {
 		return nil, fmt.Errorf("Loading server certification failed: %v", err)
 	}
 
-	return &tls.Config{
+	// returned for each connection as is, grpc credentials do not add http2 protocol to it
+	tlsConfig := &tls.Config{
 		ClientAuth:   tls.RequireAndVerifyClientCert,
 		Certificates: []tls.Certificate{serverCertificate},
 		ClientCAs:    caPool,
-	}, nil
+		NextProtos:   []string{"h2"},
+	}
+
+	err = applyTlsParameters(conf, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return tlsConfig, nil
 }
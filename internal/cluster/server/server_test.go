@@ -1,10 +1,23 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/certificate/service"
+	grpc_gen "bilalekrem.com/certstore/internal/certstore/grpc/gen"
 	"bilalekrem.com/certstore/internal/cluster/server/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func TestValidateConfig(t *testing.T) {
@@ -20,6 +33,21 @@ func TestValidateConfigMissingListenPort(t *testing.T) {
 	assert.Error(t, err, "validation failed: missing listen port")
 }
 
+func TestValidateConfigListenAddressesWithoutPort(t *testing.T) {
+	conf := getConfig()
+	conf.ListenPort = 0
+	conf.ListenAddresses = []string{"[::1]:10000", "unix:///run/certstore.sock"}
+	err := validateConfig(conf)
+	assert.NotError(t, err, "validation failed")
+}
+
+func TestValidateConfigNotValidListenAddress(t *testing.T) {
+	conf := getConfig()
+	conf.ListenAddresses = []string{"10.0.0.1"}
+	err := validateConfig(conf)
+	assert.Error(t, err, "validation failed: listen address without port")
+}
+
 func TestValidateConfigMissingTlsCACert(t *testing.T) {
 	conf := getConfig()
 	conf.TlsCACert = ""
@@ -41,6 +69,188 @@ func TestValidateConfigMissingTlsServerCertKey(t *testing.T) {
 	assert.Error(t, err, "validation failed: missing server cert key")
 }
 
+func TestValidateConfigNegativeHealthCheckInterval(t *testing.T) {
+	conf := getConfig()
+	conf.HealthCheckInterval = -time.Minute
+	err := validateConfig(conf)
+	assert.Error(t, err, "validation failed: negative health check interval")
+}
+
+func TestValidateConfigSameRestListenPort(t *testing.T) {
+	conf := getConfig()
+	conf.RestListenPort = conf.ListenPort
+	err := validateConfig(conf)
+	assert.Error(t, err, "validation failed: rest listen port same with listen port")
+
+	conf.ListenAddresses = []string{"127.0.0.1:10000", "10.0.0.1:10001"}
+	conf.RestListenPort = 10001
+	err = validateConfig(conf)
+	assert.Error(t, err, "validation failed: rest listen port same with port of a listen address")
+}
+
+func TestValidateConfigRestListenPortOnUnixSocketOnly(t *testing.T) {
+	conf := getConfig()
+	conf.ListenAddresses = []string{"unix:///run/certstore.sock"}
+	conf.RestListenPort = 10001
+	err := validateConfig(conf)
+	assert.ErrorContains(t, err, "requires a tcp listen address")
+}
+
+func TestServeRest(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_server_rest")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	server, err := NewFromFile(writeTestConfig(t, dir, "first-issuer"))
+	assert.NotError(t, err, "creating server failed")
+
+	port := freePort(t)
+	server.restListenAddresses = []string{fmt.Sprintf("127.0.0.1:%d", port)}
+	err = server.serveRest()
+	assert.NotError(t, err, "serving json gateway failed")
+	defer server.restServer.Close()
+
+	// ----
+
+	clientCertificate, err := tls.LoadX509KeyPair(fmt.Sprintf("%s/server.crt", dir), fmt.Sprintf("%s/server.key", dir))
+	assert.NotError(t, err, "loading client certificate failed")
+
+	url := fmt.Sprintf("https://127.0.0.1:%d/v1/issuers", port)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		Certificates:       []tls.Certificate{clientCertificate},
+		InsecureSkipVerify: true,
+	}}}
+
+	resp, err := client.Get(url)
+	assert.NotError(t, err, "requesting issuers failed")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.True(t, strings.Contains(string(body), "first-issuer"))
+
+	// ----
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	_, err = anonymous.Get(url)
+	assert.Error(t, err, "request without client certificate should be refused")
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_server_unix")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	server, err := NewFromFile(writeTestConfig(t, dir, "first-issuer"))
+	assert.NotError(t, err, "creating server failed")
+
+	socketPath := fmt.Sprintf("%s/certstore.sock", dir)
+	server.listenAddresses = []string{"unix://" + socketPath}
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve() }()
+
+	// ----
+
+	clientCertificate, err := tls.LoadX509KeyPair(fmt.Sprintf("%s/server.crt", dir), fmt.Sprintf("%s/server.key", dir))
+	assert.NotError(t, err, "loading client certificate failed")
+
+	creds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{clientCertificate}, InsecureSkipVerify: true})
+	conn, err := grpc.Dial("unix://"+socketPath, grpc.WithTransportCredentials(creds))
+	assert.NotError(t, err, "dialing unix socket failed")
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := grpc_gen.NewCertificateServiceClient(conn).ListIssuers(ctx, &grpc_gen.ListIssuersRequest{}, grpc.WaitForReady(true))
+	assert.NotError(t, err, "listing issuers over unix socket failed")
+	assert.Equal(t, "first-issuer", resp.Issuers[0].Name)
+
+	// ----
+
+	err = server.Stop(ctx)
+	assert.NotError(t, err, "stopping server failed")
+	assert.NotError(t, <-served, "serving failed")
+
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_server_reload")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	configPath := writeTestConfig(t, dir, "first-issuer")
+	server, err := NewFromFile(configPath)
+	assert.NotError(t, err, "creating server failed")
+
+	// ----
+
+	writeTestConfig(t, dir, "second-issuer")
+	result, err := server.Reload()
+	assert.NotError(t, err, "reloading server failed")
+	assert.DeepEqual(t, []string{"second-issuer"}, result.Added)
+	assert.DeepEqual(t, []string{"first-issuer"}, result.Removed)
+
+	issuers := server.certstore.ListIssuers()
+	assert.Equal(t, 1, len(issuers))
+	assert.Equal(t, "second-issuer", issuers[0].Name)
+}
+
+func TestReloadRefusedWithNotValidConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_server_reload")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	configPath := writeTestConfig(t, dir, "first-issuer")
+	server, err := NewFromFile(configPath)
+	assert.NotError(t, err, "creating server failed")
+
+	// ----
+
+	err = ioutil.WriteFile(fmt.Sprintf("%s/server.key", dir), []byte("not a key"), 0666)
+	assert.NotError(t, err, "writing server key failed")
+	writeTestConfig(t, dir, "second-issuer")
+
+	_, err = server.Reload()
+	assert.ErrorContains(t, err, "Loading server certification failed")
+
+	issuers := server.certstore.ListIssuers()
+	assert.Equal(t, 1, len(issuers))
+	assert.Equal(t, "first-issuer", issuers[0].Name)
+}
+
+func TestReloadWithoutConfigFile(t *testing.T) {
+	server := &Server{}
+	_, err := server.Reload()
+	assert.ErrorContains(t, err, "not created from a config file")
+}
+
+func TestStop(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_server_stop")
+	assert.NotError(t, err, "creating temp dir failed")
+	defer os.RemoveAll(dir)
+
+	server, err := NewFromFile(writeTestConfig(t, dir, "first-issuer"))
+	assert.NotError(t, err, "creating server failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = server.Stop(ctx)
+	assert.NotError(t, err, "stopping server failed")
+}
+
+func freePort(t *testing.T) int {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NotError(t, err, "finding free port failed")
+	defer listen.Close()
+
+	return listen.Addr().(*net.TCPAddr).Port
+}
+
 func getConfig() *config.Config {
 	conf := &config.Config{}
 	conf.ListenPort = 10000
@@ -48,4 +258,38 @@ func getConfig() *config.Config {
 	conf.TlsServerCert = "tls-server-cert"
 	conf.TlsServerCertKey = "tls"
 	return conf
-}
\ No newline at end of file
+}
+
+// writeTestConfig writes a server config with a single issuer, tls material is created only once
+func writeTestConfig(t *testing.T, dir string, issuer string) string {
+	certPath := fmt.Sprintf("%s/server.crt", dir)
+	keyPath := fmt.Sprintf("%s/server.key", dir)
+	if _, err := os.Stat(certPath); os.IsNotExist(err) {
+		caService := &service.CACertificateService{KeyGenerator: service.NewRSAKeyGenerator(2048)}
+		ca, err := caService.CreateCertificate(context.Background(), &service.NewCertificateRequest{
+			CommonName:     "certstore.com",
+			ExpirationDays: 1,
+		})
+		assert.NotError(t, err, "creating tls certificate failed")
+
+		err = ioutil.WriteFile(certPath, ca.Certificate, 0666)
+		assert.NotError(t, err, "writing server certificate failed")
+		err = ioutil.WriteFile(keyPath, ca.PrivateKey, 0666)
+		assert.NotError(t, err, "writing server key failed")
+	}
+
+	configPath := fmt.Sprintf("%s/server.yaml", dir)
+	configYaml := fmt.Sprintf(`listen-port: 10000
+tls-ca-cert: %s
+tls-server-cert: %s
+tls-server-cert-key: %s
+certstore:
+  services:
+    - name: %s
+      type: CertificateAuthority`, certPath, certPath, keyPath, issuer)
+
+	err := ioutil.WriteFile(configPath, []byte(configYaml), 0666)
+	assert.NotError(t, err, "writing server config failed")
+
+	return configPath
+}
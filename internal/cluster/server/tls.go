@@ -0,0 +1,75 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"bilalekrem.com/certstore/internal/cluster/server/config"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsCurves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+}
+
+// applyTlsParameters sets minimum version, cipher suites and curves of the config, go defaults are kept for the
+// ones not configured
+func applyTlsParameters(conf *config.Config, tlsConfig *tls.Config) error {
+	if conf.TlsMinVersion != "" {
+		version, exist := tlsVersions[conf.TlsMinVersion]
+		if !exist {
+			return errors.New(fmt.Sprintf("unknown tls-min-version: [%s], one of 1.0, 1.1, 1.2 or 1.3 expected", conf.TlsMinVersion))
+		}
+
+		tlsConfig.MinVersion = version
+	}
+
+	for _, name := range conf.TlsCipherSuites {
+		id, err := cipherSuite(name)
+		if err != nil {
+			return err
+		}
+
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+	}
+
+	for _, name := range conf.TlsCurvePreferences {
+		curve, exist := tlsCurves[name]
+		if !exist {
+			return errors.New(fmt.Sprintf("unknown tls curve: [%s], one of X25519, P256, P384 or P521 expected", name))
+		}
+
+		tlsConfig.CurvePreferences = append(tlsConfig.CurvePreferences, curve)
+	}
+
+	return nil
+}
+
+// cipherSuite refuses insecure suites, and tls 1.3 suites which are not configurable
+func cipherSuite(name string) (uint16, error) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name != name {
+			continue
+		}
+
+		for _, version := range suite.SupportedVersions {
+			if version <= tls.VersionTLS12 {
+				return suite.ID, nil
+			}
+		}
+
+		return 0, errors.New(fmt.Sprintf("tls 1.3 cipher suites are not configurable: [%s]", name))
+	}
+
+	return 0, errors.New(fmt.Sprintf("unknown or insecure tls cipher suite: [%s]", name))
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestApplyTlsParameters(t *testing.T) {
+	conf := getConfig()
+	conf.TlsMinVersion = "1.2"
+	conf.TlsCipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}
+	conf.TlsCurvePreferences = []string{"X25519", "P256"}
+
+	tlsConfig := &tls.Config{}
+	err := applyTlsParameters(conf, tlsConfig)
+	assert.NotError(t, err, "applying tls parameters failed")
+
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.DeepEqual(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+		tlsConfig.CipherSuites)
+	assert.DeepEqual(t, []tls.CurveID{tls.X25519, tls.CurveP256}, tlsConfig.CurvePreferences)
+}
+
+func TestApplyTlsParametersDefaults(t *testing.T) {
+	tlsConfig := &tls.Config{}
+	err := applyTlsParameters(getConfig(), tlsConfig)
+	assert.NotError(t, err, "applying tls parameters failed")
+
+	assert.Equal(t, uint16(0), tlsConfig.MinVersion)
+	assert.Nil(t, tlsConfig.CipherSuites)
+	assert.Nil(t, tlsConfig.CurvePreferences)
+}
+
+func TestApplyTlsParametersNotValid(t *testing.T) {
+	conf := getConfig()
+	conf.TlsMinVersion = "1.4"
+	assert.ErrorContains(t, applyTlsParameters(conf, &tls.Config{}), "unknown tls-min-version")
+
+	conf = getConfig()
+	conf.TlsCipherSuites = []string{"TLS_AES_128_GCM_SHA256"}
+	assert.ErrorContains(t, applyTlsParameters(conf, &tls.Config{}), "not configurable")
+
+	conf = getConfig()
+	conf.TlsCipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+	assert.ErrorContains(t, applyTlsParameters(conf, &tls.Config{}), "unknown or insecure")
+
+	conf = getConfig()
+	conf.TlsCurvePreferences = []string{"P224"}
+	assert.ErrorContains(t, applyTlsParameters(conf, &tls.Config{}), "unknown tls curve")
+}
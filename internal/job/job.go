@@ -1,5 +1,12 @@
 package job
 
+import (
+	"context"
+)
+
 type Job interface {
 	Execute() error
+
+	// Stop stops scheduling the job and waits for the run in progress until ctx is done, then cancels it
+	Stop(ctx context.Context) error
 }
@@ -1,6 +1,9 @@
 package job
 
 import (
+	"context"
+	"sync"
+
 	"bilalekrem.com/certstore/internal/logging"
 	"bilalekrem.com/certstore/internal/pipeline"
 	"bilalekrem.com/certstore/internal/scheduler"
@@ -10,13 +13,24 @@ type pipelineJob struct {
 	name      string
 	scheduler scheduler.Scheduler
 	pipeline  pipeline.Pipeline
+
+	// context of pipeline runs, canceled if a run is not completed until stop deadline
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex   sync.Mutex
+	stopped bool
+	running sync.WaitGroup
 }
 
 func NewPipelineJob(name string, sched scheduler.Scheduler, pip pipeline.Pipeline) *pipelineJob {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &pipelineJob{
 		name:      name,
 		scheduler: sched,
 		pipeline:  pip,
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
@@ -24,9 +38,45 @@ func (j *pipelineJob) Execute() error {
 	logging.GetLogger().Infof("Scheduling job [%s]", j.name)
 
 	j.scheduler.Schedule(func() {
-		err := j.pipeline.Run()
-		logging.GetLogger().Errorf("Running job [%s] pipeline failed, %v", j.name, err)
+		j.mutex.Lock()
+		if j.stopped {
+			j.mutex.Unlock()
+			return
+		}
+		j.running.Add(1)
+		j.mutex.Unlock()
+		defer j.running.Done()
+
+		err := j.pipeline.Run(j.ctx)
+		if err != nil {
+			logging.GetLogger().Errorf("Running job [%s] pipeline failed, %v", j.name, err)
+		}
 	})
 
 	return nil
 }
+
+func (j *pipelineJob) Stop(ctx context.Context) error {
+	logging.GetLogger().Infof("Stopping job [%s]", j.name)
+	j.scheduler.Stop()
+
+	j.mutex.Lock()
+	j.stopped = true
+	j.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		j.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		j.cancel()
+		return nil
+	case <-ctx.Done():
+		logging.GetLogger().Warnf("Job [%s] pipeline is not completed in time, canceling it", j.name)
+		j.cancel()
+		return ctx.Err()
+	}
+}
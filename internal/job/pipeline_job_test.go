@@ -1,12 +1,14 @@
 package job
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 	"bilalekrem.com/certstore/internal/pipeline"
 	"bilalekrem.com/certstore/internal/pipeline/action"
+	pipeline_context "bilalekrem.com/certstore/internal/pipeline/context"
 	"bilalekrem.com/certstore/internal/scheduler"
 	"github.com/golang/mock/gomock"
 )
@@ -41,3 +43,77 @@ func TestExecute(t *testing.T) {
 	// wait for scheduler to run pipeline, and pipeline will run mockAction
 	time.Sleep(2 * time.Second)
 }
+
+func TestStopWaitsRunningPipeline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan struct{})
+	mockAction := action.NewMockAction(ctrl)
+	mockAction.
+		EXPECT().
+		Run(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx *pipeline_context.Context, args map[string]string) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}).
+		Times(1)
+
+	pipeline := pipeline.New("test-pipeline")
+	pipeline.RegisterAction(mockAction, map[string]string{})
+
+	pipelineJob := NewPipelineJob("test job", scheduler.NewIntervalScheduler(10*time.Millisecond), pipeline)
+	err := pipelineJob.Execute()
+	assert.NotError(t, err, "while executing pipeline job")
+
+	// ----
+
+	<-started
+	err = pipelineJob.Stop(context.Background())
+	assert.NotError(t, err, "stopping job failed")
+
+	// no more runs after stop
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestStopCancelsPipelineAfterDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	mockAction := action.NewMockAction(ctrl)
+	mockAction.
+		EXPECT().
+		Run(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx *pipeline_context.Context, args map[string]string) error {
+			close(started)
+			<-ctx.Context().Done()
+			close(canceled)
+			return ctx.Context().Err()
+		}).
+		Times(1)
+
+	pipeline := pipeline.New("test-pipeline")
+	pipeline.RegisterAction(mockAction, map[string]string{})
+
+	pipelineJob := NewPipelineJob("test job", scheduler.NewIntervalScheduler(10*time.Millisecond), pipeline)
+	err := pipelineJob.Execute()
+	assert.NotError(t, err, "while executing pipeline job")
+
+	// ----
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = pipelineJob.Stop(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("running pipeline is not canceled")
+	}
+}
@@ -1,9 +1,14 @@
 package lego
 
 import (
+	"context"
+
 	"github.com/go-acme/lego/v4/certificate"
 )
 
 type LegoAdapter interface {
-	Obtain(req certificate.ObtainRequest) (*certificate.Resource, error)
+	Obtain(ctx context.Context, req certificate.ObtainRequest) (*certificate.Resource, error)
+
+	// cert is PEM encoded, reason is the reason code of RFC 5280
+	Revoke(ctx context.Context, cert []byte, reason uint) error
 }
@@ -1,9 +1,11 @@
 package lego
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"io/ioutil"
+	"net/http"
 
 	"bilalekrem.com/certstore/internal/certificate/x509utils"
 	"bilalekrem.com/certstore/internal/logging"
@@ -16,31 +18,25 @@ import (
 )
 
 type legoAdapterImpl struct {
-	legoClient *real_lego.Client
+	user     *AcmeUser
+	provider challenge.Provider
+	caDirUrl string
 }
 
 func NewAdapter(user *AcmeUser, provider challenge.Provider, caDirUrl string) (*legoAdapterImpl, error) {
-	config := real_lego.NewConfig(user)
-	config.CADirURL = caDirUrl
-	config.Certificate.KeyType = certcrypto.RSA2048
-
-	client, err := real_lego.NewClient(config)
-	if err != nil {
-		logging.GetLogger().Errorf("creating lego client failed %v", err)
-		return nil, err
+	adapter := &legoAdapterImpl{
+		user:     user,
+		provider: provider,
+		caDirUrl: caDirUrl,
 	}
 
-	// -----
-
-	err = client.Challenge.SetDNS01Provider(provider, dns01.DisableCompletePropagationRequirement())
+	// a client is created for each request, this one only tells the config is valid and the CA is reachable
+	_, err := adapter.newClient(context.Background())
 	if err != nil {
-		logging.GetLogger().Errorf("setting new dns 01 provider failed %v", err)
 		return nil, err
 	}
 
-	// -----
-
-	return &legoAdapterImpl{legoClient: client}, nil
+	return adapter, nil
 }
 
 // this function will generate a new lets encrypt user and will save private key to 'userPrivateKeyPath'
@@ -53,9 +49,17 @@ func NewAdapterWithNewUserRegistration(userEmail string, userPrivateKeyPath stri
 	return NewAdapter(user, provider, caDirUrl)
 }
 
-func (c *legoAdapterImpl) Obtain(req certificate.ObtainRequest) (*certificate.Resource, error) {
-	certificates, err := c.legoClient.Certificate.Obtain(req)
+// Obtain stops once ctx is done, requests to the CA are canceled and no challenge is presented or waited anymore.
+// Challenges presented already are cleaned up
+func (c *legoAdapterImpl) Obtain(ctx context.Context, req certificate.ObtainRequest) (*certificate.Resource, error) {
+	client, err := c.newClient(ctx)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	certificates, err := client.Certificate.Obtain(req)
 	if err != nil {
+		err = contextError(ctx, err)
 		logging.GetLogger().Errorf("Obtaining certificate failed request:%v, %v", req, err)
 		return nil, err
 	}
@@ -63,6 +67,105 @@ func (c *legoAdapterImpl) Obtain(req certificate.ObtainRequest) (*certificate.Re
 	return certificates, nil
 }
 
+func (c *legoAdapterImpl) Revoke(ctx context.Context, cert []byte, reason uint) error {
+	client, err := c.newClient(ctx)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	err = client.Certificate.RevokeWithReason(cert, &reason)
+	if err != nil {
+		err = contextError(ctx, err)
+		logging.GetLogger().Errorf("Revoking certificate failed, %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// newClient creates a lego client bound to ctx, lego does not accept a context so it is passed through the http
+// client and the challenge provider
+func (c *legoAdapterImpl) newClient(ctx context.Context) (*real_lego.Client, error) {
+	config := real_lego.NewConfig(c.user)
+	config.CADirURL = c.caDirUrl
+	config.Certificate.KeyType = certcrypto.RSA2048
+	config.HTTPClient = newContextHTTPClient(ctx, config.HTTPClient)
+
+	client, err := real_lego.NewClient(config)
+	if err != nil {
+		logging.GetLogger().Errorf("creating lego client failed %v", err)
+		return nil, err
+	}
+
+	// -----
+
+	err = client.Challenge.SetDNS01Provider(&contextProvider{ctx: ctx, provider: c.provider},
+		dns01.DisableCompletePropagationRequirement(),
+		dns01.WrapPreCheck(func(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+			// stops waiting for propagation, the next request to the CA fails with the context error
+			if ctx.Err() != nil {
+				return true, ctx.Err()
+			}
+			return check(fqdn, value)
+		}))
+	if err != nil {
+		logging.GetLogger().Errorf("setting new dns 01 provider failed %v", err)
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// contextError returns the error of ctx if it is done, lego does not wrap the errors of http requests
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// ----
+
+// contextProvider does not present challenges once ctx is done, cleaning up is not affected
+type contextProvider struct {
+	ctx      context.Context
+	provider challenge.Provider
+}
+
+func (p *contextProvider) Present(domain, token, keyAuth string) error {
+	if p.ctx.Err() != nil {
+		return p.ctx.Err()
+	}
+
+	return p.provider.Present(domain, token, keyAuth)
+}
+
+func (p *contextProvider) CleanUp(domain, token, keyAuth string) error {
+	return p.provider.CleanUp(domain, token, keyAuth)
+}
+
+// contextTransport sends every request with ctx, so that requests in progress are canceled with it
+type contextTransport struct {
+	ctx       context.Context
+	transport http.RoundTripper
+}
+
+func newContextHTTPClient(ctx context.Context, client *http.Client) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	bound := *client
+	bound.Transport = &contextTransport{ctx: ctx, transport: transport}
+	return &bound
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport.RoundTrip(req.WithContext(t.ctx))
+}
+
 // --------
 
 func createAndRegisterNewUser(email string, userPrivateKeyPath string, caDirUrl string) (*AcmeUser, error) {
@@ -1,15 +1,22 @@
 package lego
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 	"bilalekrem.com/certstore/internal/certificate/x509utils"
+	"bilalekrem.com/certstore/internal/lego/providers/mock"
+	"github.com/go-acme/lego/v4/certificate"
 	real_lego "github.com/go-acme/lego/v4/lego"
 )
 
@@ -72,3 +79,39 @@ func TestNewAdapter(t *testing.T) {
 	_, err = NewAdapter(acmeUser, nil, real_lego.LEDirectoryStaging)
 	assert.NotError(t, err, "creating new lego adapter failed")
 }
+
+func TestObtainCanceled(t *testing.T) {
+	// directory never responds, request is canceled with the context
+	directory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer directory.Close()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NotError(t, err, "generating private key failed")
+	user, err := NewAcmeUser("certstore@certstore.com", privateKey)
+	assert.NotError(t, err, "creating acme user failed")
+
+	adapter := &legoAdapterImpl{user: user, provider: mock.NewMockDNSProvider(), caDirUrl: directory.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = adapter.Obtain(ctx, certificate.ObtainRequest{Domains: []string{"certstore.com"}})
+	assert.TrueM(t, errors.Is(err, context.DeadlineExceeded), "obtaining should stop with the context")
+}
+
+func TestContextProviderDoesNotPresentAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	provider := &contextProvider{ctx: ctx, provider: mock.NewMockDNSProvider()}
+
+	err := provider.Present("certstore.com", "token", "key-auth")
+	assert.NotError(t, err, "presenting challenge failed")
+
+	cancel()
+	err = provider.Present("certstore.com", "token", "key-auth")
+	assert.TrueM(t, errors.Is(err, context.Canceled), "challenge should not be presented after cancel")
+
+	err = provider.CleanUp("certstore.com", "token", "key-auth")
+	assert.NotError(t, err, "cleaning up should not be affected by cancel")
+}
@@ -5,6 +5,7 @@
 package lego
 
 import (
+	context "context"
 	reflect "reflect"
 
 	certificate "github.com/go-acme/lego/v4/certificate"
@@ -35,16 +36,30 @@ func (m *MockLegoAdapter) EXPECT() *MockLegoAdapterMockRecorder {
 }
 
 // Obtain mocks base method.
-func (m *MockLegoAdapter) Obtain(req certificate.ObtainRequest) (*certificate.Resource, error) {
+func (m *MockLegoAdapter) Obtain(ctx context.Context, req certificate.ObtainRequest) (*certificate.Resource, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Obtain", req)
+	ret := m.ctrl.Call(m, "Obtain", ctx, req)
 	ret0, _ := ret[0].(*certificate.Resource)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Obtain indicates an expected call of Obtain.
-func (mr *MockLegoAdapterMockRecorder) Obtain(req interface{}) *gomock.Call {
+func (mr *MockLegoAdapterMockRecorder) Obtain(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Obtain", reflect.TypeOf((*MockLegoAdapter)(nil).Obtain), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Obtain", reflect.TypeOf((*MockLegoAdapter)(nil).Obtain), ctx, req)
+}
+
+// Revoke mocks base method.
+func (m *MockLegoAdapter) Revoke(ctx context.Context, cert []byte, reason uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, cert, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockLegoAdapterMockRecorder) Revoke(ctx, cert, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockLegoAdapter)(nil).Revoke), ctx, cert, reason)
 }
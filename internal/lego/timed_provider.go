@@ -0,0 +1,52 @@
+package lego
+
+import (
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/metrics"
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+// timedProvider reports how long challenges take, from presenting them until they are cleaned up
+type timedProvider struct {
+	name     string
+	provider challenge.Provider
+
+	mutex     sync.Mutex
+	presented map[string]time.Time
+}
+
+func NewTimedProvider(name string, provider challenge.Provider) challenge.Provider {
+	return &timedProvider{
+		name:      name,
+		provider:  provider,
+		presented: make(map[string]time.Time),
+	}
+}
+
+func (p *timedProvider) Present(domain, token, keyAuth string) error {
+	err := p.provider.Present(domain, token, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	p.presented[domain+"/"+token] = time.Now()
+	p.mutex.Unlock()
+
+	return nil
+}
+
+func (p *timedProvider) CleanUp(domain, token, keyAuth string) error {
+	p.mutex.Lock()
+	presentedAt, exist := p.presented[domain+"/"+token]
+	delete(p.presented, domain+"/"+token)
+	p.mutex.Unlock()
+
+	if exist {
+		metrics.ACMEChallengeDuration.WithLabelValues(p.name).Observe(time.Since(presentedAt).Seconds())
+	}
+
+	return p.provider.CleanUp(domain, token, keyAuth)
+}
@@ -0,0 +1,44 @@
+package lego
+
+import (
+	"errors"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/lego/providers/mock"
+	"bilalekrem.com/certstore/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTimedProvider(t *testing.T) {
+	provider := NewTimedProvider("timed-test", mock.NewMockDNSProvider())
+	before := testutil.CollectAndCount(metrics.ACMEChallengeDuration)
+
+	err := provider.Present("certstore.com", "token", "key-auth")
+	assert.NotError(t, err, "presenting challenge failed")
+	err = provider.CleanUp("certstore.com", "token", "key-auth")
+	assert.NotError(t, err, "cleaning up challenge failed")
+
+	assert.Equal(t, before+1, testutil.CollectAndCount(metrics.ACMEChallengeDuration))
+	assert.Equal(t, 0, len(provider.(*timedProvider).presented))
+}
+
+func TestTimedProviderPresentFailed(t *testing.T) {
+	provider := NewTimedProvider("timed-test-failed", failingProvider{})
+
+	err := provider.Present("certstore.com", "token", "key-auth")
+	assert.ErrorContains(t, err, "present failed")
+	assert.Equal(t, 0, len(provider.(*timedProvider).presented))
+}
+
+// ----
+
+type failingProvider struct{}
+
+func (failingProvider) Present(domain, token, keyAuth string) error {
+	return errors.New("present failed")
+}
+
+func (failingProvider) CleanUp(domain, token, keyAuth string) error {
+	return nil
+}
@@ -0,0 +1,97 @@
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+const (
+	DEFAULT_SHUTDOWN_TIMEOUT = 30 * time.Second
+)
+
+// ServeFunc blocks until the component is stopped, or fails
+type ServeFunc func() error
+
+// StopFunc stops the component gracefully, work in progress is abandoned once ctx is done
+type StopFunc func(ctx context.Context) error
+
+// Run serves until serve returns, or SIGINT or SIGTERM is received. On a signal, stop is called with a context done
+// after the timeout, or right away if the signal is received again. serve is nil for components running in
+// background, e.g. scheduled jobs. Returned error tells whether the component failed or did not stop in time.
+func Run(serve ServeFunc, stop StopFunc, timeout time.Duration) error {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	return run(signals, serve, stop, timeout)
+}
+
+func run(signals <-chan os.Signal, serve ServeFunc, stop StopFunc, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DEFAULT_SHUTDOWN_TIMEOUT
+	}
+
+	served := make(chan error, 1)
+	if serve != nil {
+		go func() {
+			served <- serve()
+		}()
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			logging.GetLogger().Errorf("Serving failed, %v", err)
+		}
+		return err
+	case sig := <-signals:
+		logging.GetLogger().Infof("Received [%v], stopping gracefully in %v", sig, timeout)
+	}
+
+	// ----
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case sig := <-signals:
+			logging.GetLogger().Warnf("Received [%v] again, stopping immediately", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := stop(ctx)
+	if err != nil {
+		logging.GetLogger().Errorf("Stopping gracefully failed, %v", err)
+		return err
+	}
+
+	if serve != nil {
+		err = <-served
+		if err != nil {
+			logging.GetLogger().Errorf("Serving failed while stopping, %v", err)
+			return err
+		}
+	}
+
+	logging.GetLogger().Info("Stopped gracefully")
+	return nil
+}
+
+// StopContext gives a step of stopping its own deadline, ctx may be consumed by the steps before already. It is done
+// once the time left of ctx passes, but not before minimum
+func StopContext(ctx context.Context, minimum time.Duration) (context.Context, context.CancelFunc) {
+	timeout := minimum
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) > timeout {
+		timeout = time.Until(deadline)
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
@@ -0,0 +1,95 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestRunStopsOnSignal(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+	stopped := make(chan struct{})
+
+	serve := func() error {
+		<-stopped
+		return nil
+	}
+	stop := func(ctx context.Context) error {
+		close(stopped)
+		return nil
+	}
+
+	signals <- syscall.SIGTERM
+	err := run(signals, serve, stop, time.Second)
+	assert.NotError(t, err, "run should stop gracefully")
+}
+
+func TestRunServeFails(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+
+	serve := func() error {
+		return errors.New("listening failed")
+	}
+	stop := func(ctx context.Context) error {
+		t.Fatal("stop should not be called")
+		return nil
+	}
+
+	err := run(signals, serve, stop, time.Second)
+	assert.ErrorContains(t, err, "listening failed")
+}
+
+func TestRunStopTimeout(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+
+	stop := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	signals <- syscall.SIGINT
+	err := run(signals, nil, stop, 50*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestRunStopsImmediatelyOnSecondSignal(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+
+	stop := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	signals <- syscall.SIGTERM
+	signals <- syscall.SIGTERM
+	err := run(signals, nil, stop, time.Hour)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestStopContext(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-expired.Done()
+
+	// step is given time even if the steps before consumed the whole timeout
+	ctx, cancelStop := StopContext(expired, 10*time.Second)
+	defer cancelStop()
+	assert.NotError(t, ctx.Err(), "stop context should not be done")
+	deadline, _ := ctx.Deadline()
+	assert.TrueM(t, time.Until(deadline) > 9*time.Second, "step should be given minimum timeout")
+
+	// ----
+
+	long, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ctx, cancelStop = StopContext(long, 10*time.Second)
+	defer cancelStop()
+	deadline, _ = ctx.Deadline()
+	assert.TrueM(t, time.Until(deadline) > 50*time.Second, "step should be given time left")
+}
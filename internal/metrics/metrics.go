@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	NAMESPACE = "certstore"
+)
+
+var (
+	// outcome is issued, reused or the error kind of the failure, e.g. rate-limited
+	Issuances = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "issuances_total",
+		Help:      "Certificate requests by issuer and outcome.",
+	}, []string{"issuer", "outcome"})
+
+	IssuanceDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "issuance_duration_seconds",
+		Help:      "Duration of certificate requests by issuer and outcome, including the time waiting in issuer queue.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"issuer", "outcome"})
+
+	InFlightRequests = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "requests_in_flight",
+		Help:      "Grpc requests being served.",
+	})
+
+	// agent is the common name of the client certificate
+	AgentRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "agent_requests_total",
+		Help:      "Grpc requests by agent and method.",
+	}, []string{"agent", "method"})
+
+	ACMEChallengeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "acme_challenge_duration_seconds",
+		Help:      "Duration from presenting an ACME challenge until it is cleaned up, by challenge provider.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"provider"})
+
+	KeyGenerationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "key_generation_duration_seconds",
+		Help:      "Duration of generating private keys, by key type.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"key_type"})
+)
+
+// NewRegistry returns a registry with metrics of this package, and go runtime and process metrics
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		Issuances,
+		IssuanceDuration,
+		InFlightRequests,
+		AgentRequests,
+		ACMEChallengeDuration,
+		KeyGenerationDuration,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return registry
+}
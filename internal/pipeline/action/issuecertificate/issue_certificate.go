@@ -2,34 +2,78 @@ package issuecertificate
 
 import (
 	go_ctx "context"
+	"crypto/sha256"
 	b64 "encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
 	"strings"
+	"time"
 
 	"bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/storage"
 	"bilalekrem.com/certstore/internal/logging"
 	"bilalekrem.com/certstore/internal/pipeline/action"
 	"bilalekrem.com/certstore/internal/pipeline/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const (
 	ISSUED_CERTIFICATE_CTX_KEY context.Key = "issued-certificated"
 	ISSUED_PRIVATE_KEY_CTX_KEY context.Key = "issued-certificated-private-key"
 
-	ARGS_ISSUER          string = "issuer"
-	ARGS_COMMON_NAME     string = "common-name"
-	ARGS_EMAIL           string = "email"
-	ARGS_ORGANIZATION    string = "organization"
-	ARGS_EXPIRATION_DAYS string = "expiration-days"
-	ARGS_SANS            string = "sans"
+	ARGS_ISSUER           string = "issuer"
+	ARGS_COMMON_NAME      string = "common-name"
+	ARGS_EMAIL            string = "email"
+	ARGS_ORGANIZATION     string = "organization"
+	ARGS_EXPIRATION_DAYS  string = "expiration-days"
+	ARGS_VALIDITY         string = "validity"
+	ARGS_SANS             string = "sans"
+	ARGS_FORCE_NEW        string = "force-new"
+	ARGS_MAX_RETRIES      string = "max-retries"
+	ARGS_PRIORITY         string = "priority"
+	ARGS_APPROVAL_TIMEOUT string = "approval-timeout"
+
+	// pending operations are kept in this bucket of agent state, to resume after a restart
+	STATE_BUCKET string = "issue-certificate-operations"
+
+	DEFAULT_POLL_INTERVAL = 5 * time.Second
+
+	// retryable errors are retried with exponential backoff, starting from default delay
+	DEFAULT_MAX_RETRIES = 3
+	DEFAULT_RETRY_DELAY = 5 * time.Second
+
+	// server asking to retry later than max delay, e.g. rate limits, fails the action instead
+	MAX_RETRY_DELAY = 5 * time.Minute
+
+	// operation awaiting approval longer than the timeout fails the action, it is resumed in next run
+	DEFAULT_APPROVAL_TIMEOUT = 10 * time.Minute
 )
 
 type IssueCertificateAction struct {
-	client gen.CertificateServiceClient
+	client       gen.CertificateServiceClient
+	state        storage.Storage
+	pollInterval time.Duration
+	retryDelay   time.Duration
 }
 
 func NewIssueCertificateAction(client gen.CertificateServiceClient) IssueCertificateAction {
-	return IssueCertificateAction{client: client}
+	return NewIssueCertificateActionWithState(client, storage.NewMemoryStorage())
+}
+
+func NewIssueCertificateActionWithState(client gen.CertificateServiceClient, state storage.Storage) IssueCertificateAction {
+	return IssueCertificateAction{
+		client:       client,
+		state:        state,
+		pollInterval: DEFAULT_POLL_INTERVAL,
+		retryDelay:   DEFAULT_RETRY_DELAY,
+	}
 }
 
 func (a IssueCertificateAction) Run(ctx *context.Context, args map[string]string) error {
@@ -48,10 +92,22 @@ func (a IssueCertificateAction) Run(ctx *context.Context, args map[string]string
 		return err
 	}
 
+	maxRetries, err := maxRetries(args)
+	if err != nil {
+		logging.GetLogger().Errorf("parsing action arg failed: max-retries, %v", err)
+		return err
+	}
+
+	approvalTimeout, err := approvalTimeout(args)
+	if err != nil {
+		logging.GetLogger().Errorf("parsing duration failed for action arg: approval-timeout, %v", err)
+		return err
+	}
+
 	// -----
 
 	logging.GetLogger().Debugf("Issuing certificate for issuer: [%s]", issuer)
-	response, err := a.client.IssueCertificate(go_ctx.TODO(), request)
+	response, err := a.issueCertificateWithRetries(ctx.Context(), request, maxRetries, approvalTimeout)
 	if err != nil {
 		logging.GetLogger().Errorf("issuing certificate for issuer: [%s], failed, %v", issuer, err)
 		return err
@@ -70,6 +126,14 @@ func (a IssueCertificateAction) Run(ctx *context.Context, args map[string]string
 		return err
 	}
 
+	// e.g. private key of a resumed operation is expired or claimed by another client, a certificate is not
+	// saved without its key. Operation is not resumed again, a new certificate is requested in next run
+	if len(privateKey) == 0 {
+		logging.GetLogger().Errorf("issued certificate of issuer: [%s] has no private key", issuer)
+		return errors.New(fmt.Sprintf("issued certificate of issuer [%s] has no private key, it is expired or claimed by another client, a new certificate is requested in next run",
+			issuer))
+	}
+
 	// ----
 
 	logging.GetLogger().Debugf("Storing issued certificate into context - [%s]", issuer)
@@ -79,6 +143,244 @@ func (a IssueCertificateAction) Run(ctx *context.Context, args map[string]string
 	return nil
 }
 
+// issueCertificateWithRetries retries issuance while the server reports a temporary failure
+func (a IssueCertificateAction) issueCertificateWithRetries(ctx go_ctx.Context, request *gen.CertificateRequest,
+	maxRetries int, approvalTimeout time.Duration) (*gen.CertificateResponse, error) {
+
+	delay := a.retryDelay
+	for attempt := 0; ; attempt++ {
+		response, err := a.issueCertificate(ctx, request, approvalTimeout)
+		if err == nil {
+			return response, nil
+		}
+
+		retryAfter, retryable := retryDelay(ctx, err, delay)
+		if !retryable || attempt >= maxRetries {
+			return nil, err
+		}
+
+		logging.GetLogger().Warnf("issuing certificate failed, retrying in %v, attempt: [%d/%d], %v",
+			retryAfter, attempt+1, maxRetries, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryAfter):
+		}
+
+		delay *= 2
+	}
+}
+
+// issueCertificate submits the request and waits for its operation, resuming the operation of an earlier
+// run for the same request if any. Falls back to unary issuance if server does not support operations
+func (a IssueCertificateAction) issueCertificate(ctx go_ctx.Context, request *gen.CertificateRequest,
+	approvalTimeout time.Duration) (*gen.CertificateResponse, error) {
+	key, err := stateKey(request)
+	if err != nil {
+		return nil, err
+	}
+
+	operationId, err := a.pendingOperation(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if operationId == "" {
+		operation, err := a.client.SubmitCertificateRequest(ctx, request)
+		if status.Code(err) == codes.Unimplemented {
+			logging.GetLogger().Warnf("server does not support operations, issuing certificate synchronously")
+			return a.client.IssueCertificate(ctx, request)
+		} else if err != nil {
+			return nil, err
+		}
+
+		operationId = operation.Id
+		err = a.state.Put(STATE_BUCKET, key, []byte(operationId))
+		if err != nil {
+			logging.GetLogger().Warnf("saving pending operation [%s] failed, it can not be resumed, %v", operationId, err)
+		}
+	} else {
+		logging.GetLogger().Infof("Resuming pending operation [%s]", operationId)
+	}
+
+	// ----
+
+	operation, err := a.waitOperation(ctx, operationId, approvalTimeout)
+	if status.Code(err) == codes.NotFound {
+		logging.GetLogger().Warnf("operation [%s] not found in server, it will be submitted again in next run", operationId)
+		a.state.Delete(STATE_BUCKET, key)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	a.state.Delete(STATE_BUCKET, key)
+	if operation.State == gen.OperationState_FAILED {
+		return nil, operationError(operation)
+	}
+
+	return operation.Result, nil
+}
+
+func (a IssueCertificateAction) pendingOperation(key string) (string, error) {
+	operationId, err := a.state.Get(STATE_BUCKET, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", nil
+	} else if err != nil {
+		logging.GetLogger().Errorf("loading pending operation failed, %v", err)
+		return "", err
+	}
+
+	return string(operationId), nil
+}
+
+// waitOperation watches operation until it is done, polls the operation if watching is interrupted. Waiting
+// stops once the operation awaits approval longer than the timeout, so that it is resumed in next run
+func (a IssueCertificateAction) waitOperation(ctx go_ctx.Context, operationId string,
+	approvalTimeout time.Duration) (*gen.Operation, error) {
+
+	waitCtx, cancel := go_ctx.WithCancel(ctx)
+	defer cancel()
+
+	// timer is stopped once the operation is approved, issuance itself is waited without a timeout
+	var approvalTimer *time.Timer
+	observe := func(operation *gen.Operation) {
+		awaiting := operation.State == gen.OperationState_AWAITING_APPROVAL
+		if awaiting && approvalTimer == nil {
+			logging.GetLogger().Infof("operation [%s] is awaiting approval, waiting at most %v", operationId, approvalTimeout)
+			approvalTimer = time.AfterFunc(approvalTimeout, cancel)
+		} else if !awaiting && approvalTimer != nil {
+			approvalTimer.Stop()
+		}
+	}
+	defer func() {
+		if approvalTimer != nil {
+			approvalTimer.Stop()
+		}
+	}()
+
+	request := &gen.OperationRequest{OperationId: operationId}
+	for {
+		operation, err := a.watchOperation(waitCtx, request, observe)
+		if err == nil && isDone(operation) {
+			return operation, nil
+		} else if status.Code(err) == codes.NotFound {
+			return nil, err
+		}
+
+		logging.GetLogger().Debugf("watching operation [%s] interrupted, polling, %v", operationId, err)
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() == nil {
+				return nil, errors.New(fmt.Sprintf("operation [%s] is not approved in %v, it is resumed in next run",
+					operationId, approvalTimeout))
+			}
+			return nil, ctx.Err()
+		case <-time.After(a.pollInterval):
+		}
+
+		operation, err = a.client.GetOperation(waitCtx, request)
+		if err == nil && isDone(operation) {
+			return operation, nil
+		} else if status.Code(err) == codes.NotFound {
+			return nil, err
+		} else if err == nil {
+			observe(operation)
+		}
+	}
+}
+
+// watchOperation returns the last received update of the operation, observe is called with every update
+func (a IssueCertificateAction) watchOperation(ctx go_ctx.Context, request *gen.OperationRequest,
+	observe func(*gen.Operation)) (*gen.Operation, error) {
+
+	stream, err := a.client.WatchOperation(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var operation *gen.Operation
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return operation, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		operation = update
+		logging.GetLogger().Debugf("operation [%s] is %s", operation.Id, operation.State)
+		observe(operation)
+		if isDone(operation) {
+			return operation, nil
+		}
+	}
+}
+
+// operationError converts failure of the operation to a status error, as if it was returned by a unary call
+func operationError(operation *gen.Operation) error {
+	code := codes.Code(operation.ErrorCode)
+	if code == codes.OK {
+		code = codes.Unknown
+	}
+
+	st := status.New(code, operation.Error)
+	if operation.RetryAfterSeconds > 0 {
+		retryInfo := &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(operation.RetryAfterSeconds) * time.Second)}
+		detailed, err := st.WithDetails(retryInfo)
+		if err == nil {
+			st = detailed
+		}
+	}
+
+	return st.Err()
+}
+
+// retryDelay tells if the error is temporary, and how long to wait before retrying. Backoff delay is used
+// unless the server asks for a longer one
+func retryDelay(ctx go_ctx.Context, err error, backoff time.Duration) (time.Duration, bool) {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.Unavailable:
+	case codes.DeadlineExceeded, codes.Canceled:
+		// only the call timed out or canceled, not the action itself
+		if ctx.Err() != nil {
+			return 0, false
+		}
+	case codes.ResourceExhausted:
+		for _, detail := range st.Details() {
+			retryInfo, ok := detail.(*errdetails.RetryInfo)
+			if ok && retryInfo.RetryDelay.AsDuration() > backoff {
+				backoff = retryInfo.RetryDelay.AsDuration()
+			}
+		}
+	default:
+		return 0, false
+	}
+
+	if backoff > MAX_RETRY_DELAY {
+		return 0, false
+	}
+
+	return backoff, true
+}
+
+func isDone(operation *gen.Operation) bool {
+	return operation != nil &&
+		(operation.State == gen.OperationState_ISSUED || operation.State == gen.OperationState_FAILED)
+}
+
+// stateKey identifies the request in agent state
+func stateKey(request *gen.CertificateRequest) (string, error) {
+	requestBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(requestBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
 func validate(args map[string]string) error {
 	err := action.ValidateRequiredArgs(args, ARGS_ISSUER, ARGS_COMMON_NAME)
 	if err != nil {
@@ -88,6 +390,46 @@ func validate(args map[string]string) error {
 	return nil
 }
 
+func maxRetries(args map[string]string) (int, error) {
+	maxRetriesStr, exists := args[ARGS_MAX_RETRIES]
+	if !exists {
+		return DEFAULT_MAX_RETRIES, nil
+	}
+
+	maxRetries, err := strconv.Atoi(maxRetriesStr)
+	if err != nil {
+		return 0, err
+	} else if maxRetries < 0 {
+		return 0, errors.New(fmt.Sprintf("max-retries can not be negative: [%d]", maxRetries))
+	}
+
+	return maxRetries, nil
+}
+
+func approvalTimeout(args map[string]string) (time.Duration, error) {
+	approvalTimeoutStr, exists := args[ARGS_APPROVAL_TIMEOUT]
+	if !exists {
+		return DEFAULT_APPROVAL_TIMEOUT, nil
+	}
+
+	return time.ParseDuration(approvalTimeoutStr)
+}
+
+// validityArg parses validity arg, e.g. 6h or 90m
+func validityArg(args map[string]string) (time.Duration, bool, error) {
+	validityStr, exists := args[ARGS_VALIDITY]
+	if !exists {
+		return 0, false, nil
+	}
+
+	validity, err := time.ParseDuration(validityStr)
+	if err != nil {
+		return 0, true, err
+	}
+
+	return validity, true, nil
+}
+
 func createCertificateRequest(args map[string]string) (*gen.CertificateRequest, error) {
 	issuer := args[ARGS_ISSUER]
 
@@ -107,6 +449,14 @@ func createCertificateRequest(args map[string]string) (*gen.CertificateRequest,
 		request.ExpirationDays = int32(expirationDays)
 	}
 
+	validity, exists, err := validityArg(args)
+	if err != nil {
+		logging.GetLogger().Errorf("parsing duration failed for action arg: validity, %v", err)
+		return nil, err
+	} else if exists {
+		request.ValiditySeconds = int64(validity / time.Second)
+	}
+
 	email, exists := args[ARGS_EMAIL]
 	if exists {
 		request.Email = email
@@ -117,6 +467,28 @@ func createCertificateRequest(args map[string]string) (*gen.CertificateRequest,
 		request.Organization = organization
 	}
 
+	forceNewStr, exists := args[ARGS_FORCE_NEW]
+	if exists {
+		forceNew, err := strconv.ParseBool(forceNewStr)
+		if err != nil {
+			logging.GetLogger().Errorf("str to bool conversion failed for action arg: force-new, %v", err)
+			return nil, err
+		}
+
+		request.ForceNew = forceNew
+	}
+
+	switch args[ARGS_PRIORITY] {
+	case "", "normal":
+		request.Priority = gen.Priority_NORMAL
+	case "high":
+		request.Priority = gen.Priority_HIGH
+	case "low":
+		request.Priority = gen.Priority_LOW
+	default:
+		return nil, errors.New(fmt.Sprintf("priority must be high, normal or low: [%s]", args[ARGS_PRIORITY]))
+	}
+
 	sansStr, exists := args[ARGS_SANS]
 	if exists && sansStr != "" {
 		request.SANs = strings.Split(args[ARGS_SANS], ";")
@@ -3,12 +3,17 @@ package issuecertificate
 import (
 	go_ctx "context"
 	b64 "encoding/base64"
+	"errors"
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/certstore/storage"
 	"bilalekrem.com/certstore/internal/pipeline/context"
 	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestRun(t *testing.T) {
@@ -17,6 +22,7 @@ func TestRun(t *testing.T) {
 
 	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
 	action := NewIssueCertificateAction(mockClient)
+	expectSubmitUnimplemented(mockClient)
 
 	expectedCertificate := "cert payload"
 	expectedPrivateKey := "cert key payload"
@@ -63,6 +69,7 @@ func TestExpirationDaysConvertableInt(t *testing.T) {
 
 	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
 	action := NewIssueCertificateAction(mockClient)
+	expectSubmitUnimplemented(mockClient)
 
 	mockClient.
 		EXPECT().
@@ -72,7 +79,7 @@ func TestExpirationDaysConvertableInt(t *testing.T) {
 
 			return &grpc.CertificateResponse{
 				Certificate: "",
-				PrivateKey:  "",
+				PrivateKey:  b64.StdEncoding.EncodeToString([]byte("cert key payload")),
 			}, nil
 		})
 
@@ -93,12 +100,42 @@ func TestExpirationDaysNotConvertableInt(t *testing.T) {
 	assert.ErrorContains(t, err, "invalid syntax")
 }
 
+func TestValidity(t *testing.T) {
+	args := getValidArgs()
+	args[ARGS_VALIDITY] = "6h"
+
+	request, err := createCertificateRequest(args)
+	assert.NotError(t, err, "creating certificate request failed")
+	assert.Equal(t, int64(6*60*60), request.ValiditySeconds)
+
+	args[ARGS_VALIDITY] = "six hours"
+	_, err = createCertificateRequest(args)
+	assert.ErrorContains(t, err, "invalid duration")
+}
+
+func TestPriority(t *testing.T) {
+	args := getValidArgs()
+	request, err := createCertificateRequest(args)
+	assert.NotError(t, err, "creating certificate request failed")
+	assert.Equal(t, grpc.Priority_NORMAL, request.Priority)
+
+	args[ARGS_PRIORITY] = "high"
+	request, err = createCertificateRequest(args)
+	assert.NotError(t, err, "creating certificate request failed")
+	assert.Equal(t, grpc.Priority_HIGH, request.Priority)
+
+	args[ARGS_PRIORITY] = "urgent"
+	_, err = createCertificateRequest(args)
+	assert.ErrorContains(t, err, "priority must be high, normal or low")
+}
+
 func TestMultipleSANs(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
 	action := NewIssueCertificateAction(mockClient)
+	expectSubmitUnimplemented(mockClient)
 
 	mockClient.
 		EXPECT().
@@ -109,7 +146,7 @@ func TestMultipleSANs(t *testing.T) {
 
 			return &grpc.CertificateResponse{
 				Certificate: "",
-				PrivateKey:  "",
+				PrivateKey:  b64.StdEncoding.EncodeToString([]byte("cert key payload")),
 			}, nil
 		})
 
@@ -126,6 +163,7 @@ func TestSingleSAN(t *testing.T) {
 
 	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
 	action := NewIssueCertificateAction(mockClient)
+	expectSubmitUnimplemented(mockClient)
 
 	mockClient.
 		EXPECT().
@@ -136,7 +174,7 @@ func TestSingleSAN(t *testing.T) {
 
 			return &grpc.CertificateResponse{
 				Certificate: "",
-				PrivateKey:  "",
+				PrivateKey:  b64.StdEncoding.EncodeToString([]byte("cert key payload")),
 			}, nil
 		})
 
@@ -154,6 +192,7 @@ func TestEmptySAN(t *testing.T) {
 
 	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
 	action := NewIssueCertificateAction(mockClient)
+	expectSubmitUnimplemented(mockClient)
 
 	mockClient.
 		EXPECT().
@@ -163,7 +202,7 @@ func TestEmptySAN(t *testing.T) {
 
 			return &grpc.CertificateResponse{
 				Certificate: "",
-				PrivateKey:  "",
+				PrivateKey:  b64.StdEncoding.EncodeToString([]byte("cert key payload")),
 			}, nil
 		})
 
@@ -175,8 +214,483 @@ func TestEmptySAN(t *testing.T) {
 
 }
 
+func TestForceNew(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	action := NewIssueCertificateAction(mockClient)
+	expectSubmitUnimplemented(mockClient)
+
+	mockClient.
+		EXPECT().
+		IssueCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ go_ctx.Context, req *grpc.CertificateRequest, opts ...interface{}) (*grpc.CertificateResponse, error) {
+			assert.True(t, req.ForceNew)
+
+			return &grpc.CertificateResponse{
+				Certificate: "",
+				PrivateKey:  b64.StdEncoding.EncodeToString([]byte("cert key payload")),
+			}, nil
+		})
+
+	args := getValidArgs()
+	args[ARGS_FORCE_NEW] = "true"
+
+	err := action.Run(context.New(), args)
+	assert.NotError(t, err, "running action")
+}
+
+func TestForceNewNotConvertableBool(t *testing.T) {
+	action := NewIssueCertificateAction(nil)
+
+	args := getValidArgs()
+	args[ARGS_FORCE_NEW] = "always"
+
+	err := action.Run(context.New(), args)
+	assert.ErrorContains(t, err, "invalid syntax")
+}
+
+func TestRunAsync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	state := storage.NewMemoryStorage()
+	action := NewIssueCertificateActionWithState(mockClient, state)
+
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_PENDING}, nil)
+
+	stream := grpc.NewMockCertificateService_WatchOperationClient(ctrl)
+	gomock.InOrder(
+		stream.EXPECT().Recv().Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_VALIDATING}, nil),
+		stream.EXPECT().Recv().Return(issuedOperation("cert payload", "cert key payload"), nil),
+	)
+
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Eq(&grpc.OperationRequest{OperationId: "operation-id"})).
+		Return(stream, nil)
+
+	// ----
+
+	ctx := context.New()
+	err := action.Run(ctx, getValidArgs())
+	assert.NotError(t, err, "running action")
+
+	certificate := ctx.GetValue(ISSUED_CERTIFICATE_CTX_KEY).([]byte)
+	assert.Equal(t, "cert payload", string(certificate))
+
+	// operation is done, nothing to resume
+	pending, _ := state.List(STATE_BUCKET)
+	assert.Equal(t, 0, len(pending))
+}
+
+func TestRunAsyncFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	action := NewIssueCertificateAction(mockClient)
+
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_PENDING}, nil)
+
+	stream := grpc.NewMockCertificateService_WatchOperationClient(ctrl)
+	stream.
+		EXPECT().
+		Recv().
+		Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_FAILED, Error: "dns challenge failed"}, nil)
+
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Any()).
+		Return(stream, nil)
+
+	// ----
+
+	err := action.Run(context.New(), getValidArgs())
+	assert.ErrorContains(t, err, "dns challenge failed")
+}
+
+func TestRunResumesPendingOperation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	state := storage.NewMemoryStorage()
+	action := NewIssueCertificateActionWithState(mockClient, state)
+	action.pollInterval = time.Millisecond
+
+	key, err := stateKey(createValidRequest(t))
+	assert.NotError(t, err, "creating state key failed")
+	state.Put(STATE_BUCKET, key, []byte("operation-id"))
+
+	// no submission, watching is interrupted and operation is polled
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Eq(&grpc.OperationRequest{OperationId: "operation-id"})).
+		Return(nil, errors.New("connection reset"))
+
+	mockClient.
+		EXPECT().
+		GetOperation(gomock.Any(), gomock.Eq(&grpc.OperationRequest{OperationId: "operation-id"})).
+		Return(issuedOperation("cert payload", "cert key payload"), nil)
+
+	// ----
+
+	err = action.Run(context.New(), getValidArgs())
+	assert.NotError(t, err, "running action")
+
+	_, err = state.Get(STATE_BUCKET, key)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestRunResumedOperationWithoutPrivateKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	state := storage.NewMemoryStorage()
+	action := NewIssueCertificateActionWithState(mockClient, state)
+	action.pollInterval = time.Millisecond
+
+	key, err := stateKey(createValidRequest(t))
+	assert.NotError(t, err, "creating state key failed")
+	state.Put(STATE_BUCKET, key, []byte("operation-id"))
+
+	// private key is not returned anymore, e.g. it is expired after it was claimed before a restart
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Eq(&grpc.OperationRequest{OperationId: "operation-id"})).
+		Return(nil, errors.New("connection reset"))
+
+	mockClient.
+		EXPECT().
+		GetOperation(gomock.Any(), gomock.Eq(&grpc.OperationRequest{OperationId: "operation-id"})).
+		Return(issuedOperation("cert payload", ""), nil)
+
+	// ----
+
+	ctx := context.New()
+	err = action.Run(ctx, getValidArgs())
+	assert.ErrorContains(t, err, "has no private key")
+	assert.Nil(t, ctx.GetValue(ISSUED_CERTIFICATE_CTX_KEY))
+
+	// a new certificate is requested in next run
+	_, err = state.Get(STATE_BUCKET, key)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestRunPendingOperationNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	state := storage.NewMemoryStorage()
+	action := NewIssueCertificateActionWithState(mockClient, state)
+
+	key, err := stateKey(createValidRequest(t))
+	assert.NotError(t, err, "creating state key failed")
+	state.Put(STATE_BUCKET, key, []byte("operation-id"))
+
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.NotFound, "Operation not found"))
+
+	// ----
+
+	err = action.Run(context.New(), getValidArgs())
+	assert.ErrorContains(t, err, "Operation not found")
+
+	// operation is submitted again in next run
+	_, err = state.Get(STATE_BUCKET, key)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestRunAwaitingApproval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	action := NewIssueCertificateAction(mockClient)
+
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_AWAITING_APPROVAL}, nil)
+
+	stream := grpc.NewMockCertificateService_WatchOperationClient(ctrl)
+	gomock.InOrder(
+		stream.EXPECT().Recv().Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_AWAITING_APPROVAL}, nil),
+		stream.EXPECT().Recv().Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_PENDING}, nil),
+		stream.EXPECT().Recv().Return(issuedOperation("cert payload", "cert key payload"), nil),
+	)
+
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Any()).
+		Return(stream, nil)
+
+	// ----
+
+	ctx := context.New()
+	err := action.Run(ctx, getValidArgs())
+	assert.NotError(t, err, "running action")
+
+	certificate := ctx.GetValue(ISSUED_CERTIFICATE_CTX_KEY).([]byte)
+	assert.Equal(t, "cert payload", string(certificate))
+}
+
+func TestRunApprovalTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	state := storage.NewMemoryStorage()
+	action := NewIssueCertificateActionWithState(mockClient, state)
+
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_AWAITING_APPROVAL}, nil)
+
+	// stream is kept open until the approval timeout, as no approver decides
+	var watchCtx go_ctx.Context
+	stream := grpc.NewMockCertificateService_WatchOperationClient(ctrl)
+	gomock.InOrder(
+		stream.EXPECT().Recv().Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_AWAITING_APPROVAL}, nil),
+		stream.EXPECT().Recv().DoAndReturn(func() (*grpc.Operation, error) {
+			<-watchCtx.Done()
+			return nil, status.Error(codes.Canceled, watchCtx.Err().Error())
+		}),
+	)
+
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx go_ctx.Context, _ *grpc.OperationRequest, _ ...interface{}) (grpc.CertificateService_WatchOperationClient, error) {
+			watchCtx = ctx
+			return stream, nil
+		})
+
+	// ----
+
+	args := getValidArgs()
+	args[ARGS_APPROVAL_TIMEOUT] = "10ms"
+
+	err := action.Run(context.New(), args)
+	assert.ErrorContains(t, err, "operation [operation-id] is not approved in 10ms")
+
+	// not retried, operation is resumed in next run
+	key, err := stateKey(createValidRequest(t))
+	assert.NotError(t, err, "creating state key failed")
+	operationId, err := state.Get(STATE_BUCKET, key)
+	assert.NotError(t, err, "getting pending operation failed")
+	assert.Equal(t, "operation-id", string(operationId))
+}
+
+func TestApprovalTimeoutNotValid(t *testing.T) {
+	action := NewIssueCertificateAction(nil)
+
+	args := getValidArgs()
+	args[ARGS_APPROVAL_TIMEOUT] = "ten minutes"
+
+	err := action.Run(context.New(), args)
+	assert.Error(t, err, "approval timeout should be a duration")
+}
+
+func TestMaxRetriesNotValid(t *testing.T) {
+	action := NewIssueCertificateAction(nil)
+
+	args := getValidArgs()
+	args[ARGS_MAX_RETRIES] = "-1"
+
+	err := action.Run(context.New(), args)
+	assert.ErrorContains(t, err, "max-retries can not be negative")
+}
+
+func TestRunRetriesUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	action := NewIssueCertificateAction(mockClient)
+	action.retryDelay = time.Millisecond
+
+	gomock.InOrder(
+		mockClient.
+			EXPECT().
+			SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+			Return(nil, status.Error(codes.Unavailable, "backend unavailable")),
+		mockClient.
+			EXPECT().
+			SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+			Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_PENDING}, nil),
+	)
+
+	stream := grpc.NewMockCertificateService_WatchOperationClient(ctrl)
+	stream.EXPECT().Recv().Return(issuedOperation("cert payload", "cert key payload"), nil)
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Any()).
+		Return(stream, nil)
+
+	// ----
+
+	err := action.Run(context.New(), getValidArgs())
+	assert.NotError(t, err, "running action")
+}
+
+func TestRunRetriesAtMostMaxRetries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	action := NewIssueCertificateAction(mockClient)
+	action.retryDelay = time.Millisecond
+
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.Unavailable, "backend unavailable")).
+		Times(3)
+
+	// ----
+
+	args := getValidArgs()
+	args[ARGS_MAX_RETRIES] = "2"
+
+	err := action.Run(context.New(), args)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestRunDoesNotRetryInvalidArgument(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	action := NewIssueCertificateAction(mockClient)
+
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.InvalidArgument, "Validation error: common name is required")).
+		Times(1)
+
+	// ----
+
+	err := action.Run(context.New(), getValidArgs())
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRunFailedOperationRateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	action := NewIssueCertificateAction(mockClient)
+
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(&grpc.Operation{Id: "operation-id", State: grpc.OperationState_PENDING}, nil).
+		Times(1)
+
+	// retry after is longer than max retry delay, action fails without retrying
+	stream := grpc.NewMockCertificateService_WatchOperationClient(ctrl)
+	stream.
+		EXPECT().
+		Recv().
+		Return(&grpc.Operation{
+			Id:                "operation-id",
+			State:             grpc.OperationState_FAILED,
+			Error:             "Rate limit exceeded",
+			ErrorCode:         int32(codes.ResourceExhausted),
+			RetryAfterSeconds: 3600,
+		}, nil)
+
+	mockClient.
+		EXPECT().
+		WatchOperation(gomock.Any(), gomock.Any()).
+		Return(stream, nil)
+
+	// ----
+
+	err := action.Run(context.New(), getValidArgs())
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.ErrorContains(t, err, "Rate limit exceeded")
+}
+
+func TestMaxRetriesNotConvertableInt(t *testing.T) {
+	action := NewIssueCertificateAction(nil)
+
+	args := getValidArgs()
+	args[ARGS_MAX_RETRIES] = "three"
+
+	err := action.Run(context.New(), args)
+	assert.Error(t, err, "max retries should be int")
+}
+
+func TestRetryDelay(t *testing.T) {
+	ctx := go_ctx.Background()
+
+	delay, retryable := retryDelay(ctx, status.Error(codes.Unavailable, ""), time.Second)
+	assert.True(t, retryable)
+	assert.Equal(t, time.Second, delay)
+
+	_, retryable = retryDelay(ctx, status.Error(codes.FailedPrecondition, ""), time.Second)
+	assert.False(t, retryable)
+
+	_, retryable = retryDelay(ctx, errors.New("untyped error"), time.Second)
+	assert.False(t, retryable)
+
+	// server asks for a longer delay than backoff
+	err := operationError(&grpc.Operation{ErrorCode: int32(codes.ResourceExhausted), RetryAfterSeconds: 60})
+	delay, retryable = retryDelay(ctx, err, time.Second)
+	assert.True(t, retryable)
+	assert.Equal(t, time.Minute, delay)
+
+	// action itself is canceled
+	canceledCtx, cancel := go_ctx.WithCancel(ctx)
+	cancel()
+	_, retryable = retryDelay(canceledCtx, status.Error(codes.Canceled, ""), time.Second)
+	assert.False(t, retryable)
+}
+
 // -----
 
+func expectSubmitUnimplemented(mockClient *grpc.MockCertificateServiceClient) {
+	mockClient.
+		EXPECT().
+		SubmitCertificateRequest(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.Unimplemented, "method SubmitCertificateRequest not implemented"))
+}
+
+func issuedOperation(certificate string, privateKey string) *grpc.Operation {
+	return &grpc.Operation{
+		Id:    "operation-id",
+		State: grpc.OperationState_ISSUED,
+		Result: &grpc.CertificateResponse{
+			Certificate: b64.StdEncoding.EncodeToString([]byte(certificate)),
+			PrivateKey:  b64.StdEncoding.EncodeToString([]byte(privateKey)),
+		},
+	}
+}
+
+func createValidRequest(t *testing.T) *grpc.CertificateRequest {
+	request, err := createCertificateRequest(getValidArgs())
+	assert.NotError(t, err, "creating certificate request failed")
+
+	return request
+}
+
 func testRequiredArgument(t *testing.T, arg string) {
 	action := NewIssueCertificateAction(nil)
 
@@ -0,0 +1,87 @@
+package issuecertificate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certificate/service"
+	"bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+// action args and the request fields they set
+var argFields = map[string]string{
+	ARGS_COMMON_NAME:     service.FIELD_COMMON_NAME,
+	ARGS_EMAIL:           service.FIELD_EMAIL,
+	ARGS_ORGANIZATION:    service.FIELD_ORGANIZATION,
+	ARGS_EXPIRATION_DAYS: service.FIELD_EXPIRATION_DAYS,
+	ARGS_VALIDITY:        service.FIELD_VALIDITY,
+	ARGS_SANS:            service.FIELD_SANS,
+}
+
+// ValidateArgsAgainstIssuers checks the action args against issuers reported by the server. Unknown issuers
+// and validity out of issuer limits are errors, args ignored by the issuer are only logged
+func ValidateArgsAgainstIssuers(args map[string]string, issuers []*gen.Issuer) error {
+	issuerName := args[ARGS_ISSUER]
+
+	var issuer *gen.Issuer
+	for _, candidate := range issuers {
+		if candidate.Name == issuerName {
+			issuer = candidate
+			break
+		}
+	}
+
+	if issuer == nil {
+		return errors.New(fmt.Sprintf("issuer not found in server: [%s]", issuerName))
+	}
+
+	// ----
+
+	supported := make(map[string]bool)
+	for _, field := range issuer.SupportedFields {
+		supported[field] = true
+	}
+
+	for arg, field := range argFields {
+		value, exists := args[arg]
+		if exists && value != "" && !supported[field] {
+			logging.GetLogger().Warnf("issuer [%s] ignores action arg: [%s]", issuerName, arg)
+		}
+	}
+
+	validity, exists, err := validityArg(args)
+	if err != nil {
+		return errors.New(fmt.Sprintf("validity is not a duration: [%s]", args[ARGS_VALIDITY]))
+	}
+
+	maxValidity := time.Duration(issuer.MaxValidityDays) * 24 * time.Hour
+	if exists && supported[argFields[ARGS_VALIDITY]] && issuer.MaxValidityDays > 0 && validity > maxValidity {
+		return errors.New(fmt.Sprintf("validity [%v] is more than maximum of issuer [%s]: [%d days]",
+			validity, issuerName, issuer.MaxValidityDays))
+	}
+
+	// ----
+
+	expirationDaysStr, exists := args[ARGS_EXPIRATION_DAYS]
+	if !exists || !supported[argFields[ARGS_EXPIRATION_DAYS]] {
+		return nil
+	}
+
+	expirationDays, err := strconv.Atoi(expirationDaysStr)
+	if err != nil {
+		return errors.New(fmt.Sprintf("expiration-days is not a number: [%s]", expirationDaysStr))
+	}
+
+	if issuer.MinValidityDays > 0 && int32(expirationDays) < issuer.MinValidityDays {
+		return errors.New(fmt.Sprintf("expiration-days [%d] is less than minimum of issuer [%s]: [%d]",
+			expirationDays, issuerName, issuer.MinValidityDays))
+	} else if issuer.MaxValidityDays > 0 && int32(expirationDays) > issuer.MaxValidityDays {
+		return errors.New(fmt.Sprintf("expiration-days [%d] is more than maximum of issuer [%s]: [%d]",
+			expirationDays, issuerName, issuer.MaxValidityDays))
+	}
+
+	return nil
+}
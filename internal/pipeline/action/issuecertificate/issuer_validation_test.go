@@ -0,0 +1,80 @@
+package issuecertificate
+
+import (
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+)
+
+func TestValidateArgsAgainstIssuers(t *testing.T) {
+	err := ValidateArgsAgainstIssuers(getValidArgs(), getTestIssuers())
+	assert.NotError(t, err, "args should be valid")
+}
+
+func TestValidateArgsUnknownIssuer(t *testing.T) {
+	args := getValidArgs()
+	args[ARGS_ISSUER] = "unknown issuer"
+
+	err := ValidateArgsAgainstIssuers(args, getTestIssuers())
+	assert.ErrorContains(t, err, "issuer not found in server: [unknown issuer]")
+}
+
+func TestValidateArgsExpirationDaysOutOfLimits(t *testing.T) {
+	args := getValidArgs()
+
+	args[ARGS_EXPIRATION_DAYS] = "400"
+	err := ValidateArgsAgainstIssuers(args, getTestIssuers())
+	assert.ErrorContains(t, err, "more than maximum")
+
+	args[ARGS_EXPIRATION_DAYS] = "0"
+	err = ValidateArgsAgainstIssuers(args, getTestIssuers())
+	assert.ErrorContains(t, err, "less than minimum")
+}
+
+func TestValidateArgsIgnoredExpirationDays(t *testing.T) {
+	args := getValidArgs()
+	args[ARGS_ISSUER] = "lets-encrypt"
+	args[ARGS_EXPIRATION_DAYS] = "400"
+
+	// issuer ignores expiration days, limits are not checked
+	err := ValidateArgsAgainstIssuers(args, getTestIssuers())
+	assert.NotError(t, err, "ignored args should not fail validation")
+}
+
+func TestValidateArgsValidityOutOfLimits(t *testing.T) {
+	args := getValidArgs()
+
+	args[ARGS_VALIDITY] = "6h"
+	err := ValidateArgsAgainstIssuers(args, getTestIssuers())
+	assert.NotError(t, err, "short lived certificates should be valid")
+
+	args[ARGS_VALIDITY] = "9000h"
+	err = ValidateArgsAgainstIssuers(args, getTestIssuers())
+	assert.ErrorContains(t, err, "more than maximum")
+
+	args[ARGS_VALIDITY] = "six hours"
+	err = ValidateArgsAgainstIssuers(args, getTestIssuers())
+	assert.ErrorContains(t, err, "validity is not a duration")
+}
+
+// -----
+
+func getTestIssuers() []*grpc.Issuer {
+	return []*grpc.Issuer{
+		{
+			Name:            "issuer",
+			Type:            "Simple",
+			MinValidityDays: 1,
+			MaxValidityDays: 365,
+			SupportedFields: []string{"commonName", "email", "organization", "expirationDays", "validity", "sans"},
+		},
+		{
+			Name:            "lets-encrypt",
+			Type:            "LetsEncrypt",
+			MinValidityDays: 90,
+			MaxValidityDays: 90,
+			SupportedFields: []string{"commonName", "sans"},
+		},
+	}
+}
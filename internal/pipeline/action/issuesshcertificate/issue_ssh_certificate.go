@@ -0,0 +1,134 @@
+package issuesshcertificate
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/logging"
+	"bilalekrem.com/certstore/internal/pipeline/action"
+	"bilalekrem.com/certstore/internal/pipeline/context"
+)
+
+const (
+	ISSUED_SSH_CERTIFICATE_CTX_KEY context.Key = "issued-ssh-certificate"
+	SSH_PUBLIC_KEY_PATH_CTX_KEY    context.Key = "ssh-public-key-path"
+
+	ARGS_ISSUER           string = "issuer"
+	ARGS_PUBLIC_KEY_PATH  string = "public-key-path"
+	ARGS_CERTIFICATE_TYPE string = "certificate-type"
+	ARGS_KEY_ID           string = "key-id"
+	ARGS_PRINCIPALS       string = "principals"
+	ARGS_VALIDITY         string = "validity"
+	ARGS_CRITICAL_OPTIONS string = "critical-options"
+	ARGS_EXTENSIONS       string = "extensions"
+)
+
+type IssueSSHCertificateAction struct {
+	client gen.CertificateServiceClient
+}
+
+func NewIssueSSHCertificateAction(client gen.CertificateServiceClient) IssueSSHCertificateAction {
+	return IssueSSHCertificateAction{
+		client: client,
+	}
+}
+
+func (a IssueSSHCertificateAction) Run(ctx *context.Context, args map[string]string) error {
+	err := action.ValidateRequiredArgs(args, ARGS_ISSUER, ARGS_PUBLIC_KEY_PATH, ARGS_PRINCIPALS)
+	if err != nil {
+		logging.GetLogger().Errorf("validation args failed, %v", err)
+		return err
+	}
+
+	// --
+
+	publicKeyPath := args[ARGS_PUBLIC_KEY_PATH]
+	publicKey, err := ioutil.ReadFile(publicKeyPath)
+	if err != nil {
+		logging.GetLogger().Errorf("reading ssh public key failed, %v", err)
+		return err
+	}
+
+	request, err := createSSHCertificateRequest(args, publicKey)
+	if err != nil {
+		logging.GetLogger().Errorf("creating ssh certificate request %v", err)
+		return err
+	}
+
+	// --
+
+	issuer := args[ARGS_ISSUER]
+	logging.GetLogger().Debugf("Issuing ssh certificate for issuer: [%s]", issuer)
+	response, err := a.client.IssueSSHCertificate(ctx.Context(), request)
+	if err != nil {
+		logging.GetLogger().Errorf("issuing ssh certificate for issuer: [%s], failed, %v", issuer, err)
+		return err
+	}
+
+	logging.GetLogger().Debugf("Storing issued ssh certificate into context - [%s]", issuer)
+	ctx.StoreValue(ISSUED_SSH_CERTIFICATE_CTX_KEY, response.Certificate)
+	ctx.StoreValue(SSH_PUBLIC_KEY_PATH_CTX_KEY, publicKeyPath)
+
+	return nil
+}
+
+func createSSHCertificateRequest(args map[string]string, publicKey []byte) (*gen.SSHCertificateRequest, error) {
+	request := &gen.SSHCertificateRequest{
+		Issuer:     args[ARGS_ISSUER],
+		PublicKey:  publicKey,
+		KeyId:      args[ARGS_KEY_ID],
+		Principals: splitList(args[ARGS_PRINCIPALS]),
+	}
+
+	switch args[ARGS_CERTIFICATE_TYPE] {
+	case "", "user":
+		request.Type = gen.SSHCertificateType_USER
+	case "host":
+		request.Type = gen.SSHCertificateType_HOST
+	default:
+		return nil, errors.New(fmt.Sprintf("certificate-type must be user or host: [%s]", args[ARGS_CERTIFICATE_TYPE]))
+	}
+
+	validityStr, exists := args[ARGS_VALIDITY]
+	if exists {
+		validity, err := time.ParseDuration(validityStr)
+		if err != nil {
+			logging.GetLogger().Errorf("parsing duration failed for action arg: validity, %v", err)
+			return nil, err
+		}
+
+		request.ValiditySeconds = int64(validity / time.Second)
+	}
+
+	request.CriticalOptions = parseOptions(args[ARGS_CRITICAL_OPTIONS])
+	request.Extensions = parseOptions(args[ARGS_EXTENSIONS])
+
+	return request, nil
+}
+
+// parseOptions parses options separated by ";", in name=value or name form
+func parseOptions(optionsStr string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range splitList(optionsStr) {
+		nameValue := strings.SplitN(option, "=", 2)
+		if len(nameValue) == 2 {
+			options[nameValue[0]] = nameValue[1]
+		} else {
+			options[nameValue[0]] = ""
+		}
+	}
+
+	return options
+}
+
+func splitList(listStr string) []string {
+	if listStr == "" {
+		return nil
+	}
+
+	return strings.Split(listStr, ";")
+}
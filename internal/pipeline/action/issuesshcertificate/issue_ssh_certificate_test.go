@@ -0,0 +1,86 @@
+package issuesshcertificate
+
+import (
+	go_ctx "context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+	grpc "bilalekrem.com/certstore/internal/certstore/grpc/gen"
+	"bilalekrem.com/certstore/internal/pipeline/context"
+	"github.com/golang/mock/gomock"
+)
+
+func TestRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dir, err := ioutil.TempDir("/tmp", "test_issue_ssh_certificate_action")
+	assert.NotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+
+	publicKeyPath := fmt.Sprintf("%s/id_ed25519.pub", dir)
+	err = ioutil.WriteFile(publicKeyPath, []byte("ssh-ed25519 AAAA alice@laptop\n"), 0666)
+	assert.NotError(t, err, "writing public key")
+
+	// ----
+
+	mockClient := grpc.NewMockCertificateServiceClient(ctrl)
+	mockClient.
+		EXPECT().
+		IssueSSHCertificate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ go_ctx.Context, request *grpc.SSHCertificateRequest, _ ...interface{}) (*grpc.SSHCertificateResponse, error) {
+			assert.Equal(t, "ssh-ca", request.Issuer)
+			assert.Equal(t, "ssh-ed25519 AAAA alice@laptop\n", string(request.PublicKey))
+			assert.Equal(t, grpc.SSHCertificateType_USER, request.Type)
+			assert.DeepEqual(t, []string{"alice", "deploy"}, request.Principals)
+			assert.Equal(t, int64((8 * time.Hour).Seconds()), request.ValiditySeconds)
+			assert.DeepEqual(t, map[string]string{"force-command": "/usr/bin/deploy"}, request.CriticalOptions)
+			assert.DeepEqual(t, map[string]string{"permit-pty": "", "permit-port-forwarding": ""}, request.Extensions)
+
+			return &grpc.SSHCertificateResponse{Certificate: []byte("ssh-ed25519-cert-v01@openssh.com AAAA")}, nil
+		})
+
+	args := map[string]string{
+		ARGS_ISSUER:           "ssh-ca",
+		ARGS_PUBLIC_KEY_PATH:  publicKeyPath,
+		ARGS_KEY_ID:           "alice@laptop",
+		ARGS_PRINCIPALS:       "alice;deploy",
+		ARGS_VALIDITY:         "8h",
+		ARGS_CRITICAL_OPTIONS: "force-command=/usr/bin/deploy",
+		ARGS_EXTENSIONS:       "permit-pty;permit-port-forwarding",
+	}
+
+	ctx := context.New()
+	err = NewIssueSSHCertificateAction(mockClient).Run(ctx, args)
+	assert.NotError(t, err, "running action")
+
+	// ----
+
+	certificate := ctx.GetValue(ISSUED_SSH_CERTIFICATE_CTX_KEY).([]byte)
+	assert.Equal(t, "ssh-ed25519-cert-v01@openssh.com AAAA", string(certificate))
+	assert.Equal(t, publicKeyPath, ctx.GetValue(SSH_PUBLIC_KEY_PATH_CTX_KEY).(string))
+}
+
+func TestRequiredArgumentPrincipals(t *testing.T) {
+	args := map[string]string{
+		ARGS_ISSUER:          "ssh-ca",
+		ARGS_PUBLIC_KEY_PATH: "/tmp/id_ed25519.pub",
+	}
+
+	err := NewIssueSSHCertificateAction(nil).Run(context.New(), args)
+	assert.ErrorContains(t, err, "required argument: principals")
+}
+
+func TestCertificateTypeNotValid(t *testing.T) {
+	args := map[string]string{
+		ARGS_ISSUER:           "ssh-ca",
+		ARGS_CERTIFICATE_TYPE: "server",
+	}
+
+	_, err := createSSHCertificateRequest(args, []byte("ssh-ed25519 AAAA"))
+	assert.ErrorContains(t, err, "certificate-type must be user or host")
+}
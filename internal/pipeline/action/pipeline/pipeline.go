@@ -41,7 +41,7 @@ func (a pipelineAction) Run(ctx *context.Context, args map[string]string) error
 
 	// ----
 
-	err = pipeline.Run()
+	err = pipeline.Run(ctx.Context())
 	if err != nil {
 		logging.GetLogger().Errorf("Running pipeline failed in pipeline action, %v", err)
 		return err
@@ -0,0 +1,56 @@
+package savesshcertificate
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"bilalekrem.com/certstore/internal/logging"
+	"bilalekrem.com/certstore/internal/pipeline/action"
+	"bilalekrem.com/certstore/internal/pipeline/action/issuesshcertificate"
+	"bilalekrem.com/certstore/internal/pipeline/context"
+)
+
+const (
+	// optional, certificate is saved next to the public key as OpenSSH expects, e.g. id_ed25519-cert.pub
+	ARGS_CERTIFICATE_TARGET_PATH string = "certificate-target-path"
+)
+
+type SaveSSHCertificateAction struct {
+}
+
+func NewSaveSSHCertificateAction() SaveSSHCertificateAction {
+	return SaveSSHCertificateAction{}
+}
+
+func (a SaveSSHCertificateAction) Run(ctx *context.Context, args map[string]string) error {
+	err := action.ValidateContextObjectExists(ctx, issuesshcertificate.ISSUED_SSH_CERTIFICATE_CTX_KEY,
+		issuesshcertificate.SSH_PUBLIC_KEY_PATH_CTX_KEY)
+	if err != nil {
+		logging.GetLogger().Errorf("validation args failed, %v", err)
+		return err
+	}
+
+	// --
+
+	certificate := ctx.GetValue(issuesshcertificate.ISSUED_SSH_CERTIFICATE_CTX_KEY).([]byte)
+
+	targetCertificatePath, exists := args[ARGS_CERTIFICATE_TARGET_PATH]
+	if !exists {
+		publicKeyPath := ctx.GetValue(issuesshcertificate.SSH_PUBLIC_KEY_PATH_CTX_KEY).(string)
+		targetCertificatePath = CertificatePath(publicKeyPath)
+	}
+
+	logging.GetLogger().Debugf("saving ssh certificate to target path: [%s]", targetCertificatePath)
+	err = ioutil.WriteFile(targetCertificatePath, certificate, 0666)
+	if err != nil {
+		logging.GetLogger().Errorf("writing ssh certificate to file failed, %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// CertificatePath returns the path OpenSSH looks up the certificate of a key, e.g. id_ed25519-cert.pub
+func CertificatePath(publicKeyPath string) string {
+	return strings.TrimSuffix(publicKeyPath, ".pub") + "-cert.pub"
+}
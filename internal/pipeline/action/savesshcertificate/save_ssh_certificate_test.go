@@ -0,0 +1,57 @@
+package savesshcertificate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"bilalekrem.com/certstore/internal/assert"
+	"bilalekrem.com/certstore/internal/pipeline/action/issuesshcertificate"
+	"bilalekrem.com/certstore/internal/pipeline/context"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "test_save_ssh_certificate_action")
+	assert.NotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+
+	// ----
+
+	certificateContent := []byte("ssh-ed25519-cert-v01@openssh.com AAAA\n")
+
+	ctx := context.New()
+	ctx.StoreValue(issuesshcertificate.ISSUED_SSH_CERTIFICATE_CTX_KEY, certificateContent)
+	ctx.StoreValue(issuesshcertificate.SSH_PUBLIC_KEY_PATH_CTX_KEY, fmt.Sprintf("%s/id_ed25519.pub", dir))
+
+	err = NewSaveSSHCertificateAction().Run(ctx, map[string]string{})
+	assert.NotError(t, err, "running action")
+
+	// ----
+
+	actualCertificateContent, err := ioutil.ReadFile(fmt.Sprintf("%s/id_ed25519-cert.pub", dir))
+	assert.NotError(t, err, "reading file")
+	assert.Equal(t, string(certificateContent), string(actualCertificateContent))
+
+	// ----
+
+	targetPath := fmt.Sprintf("%s/host-cert.pub", dir)
+	err = NewSaveSSHCertificateAction().Run(ctx, map[string]string{ARGS_CERTIFICATE_TARGET_PATH: targetPath})
+	assert.NotError(t, err, "running action")
+
+	_, err = os.Stat(targetPath)
+	assert.NotError(t, err, "certificate is not saved to target path")
+}
+
+func TestIssuedCertificateIsNotInContext(t *testing.T) {
+	ctx := context.New()
+	ctx.StoreValue(issuesshcertificate.SSH_PUBLIC_KEY_PATH_CTX_KEY, "/tmp/id_ed25519.pub")
+
+	err := NewSaveSSHCertificateAction().Run(ctx, map[string]string{})
+	assert.ErrorContains(t, err, "required context object")
+}
+
+func TestCertificatePath(t *testing.T) {
+	assert.Equal(t, "/home/alice/.ssh/id_ed25519-cert.pub", CertificatePath("/home/alice/.ssh/id_ed25519.pub"))
+	assert.Equal(t, "/etc/ssh/ssh_host_key-cert.pub", CertificatePath("/etc/ssh/ssh_host_key"))
+}
@@ -17,6 +17,9 @@ const (
 	ARGS_CERTIFICATE_PATH           string = "certificate-path"
 	ARGS_RENEW_X_DAYS_BEFORE_EXPIRE string = "renew-before-expire-days"
 
+	// duration, e.g. 2h for short lived certificates. Takes precedence over renew-before-expire-days
+	ARGS_RENEW_BEFORE_EXPIRE string = "renew-before-expire"
+
 	// will be used to decide renew certificate, if certificate will expire in {ACCEPTABLE_NUM_OF_DAYS_TO_EXPIRE} days
 	DEFAULT_ACCEPTABLE_NUM_OF_DAYS_TO_EXPIRE = 25
 )
@@ -45,12 +48,12 @@ func (a shouldRenewCertificateAction) Run(ctx *context.Context, args map[string]
 	}
 
 	// ----
-	daysBeforeExpire, err := getNumOfDaysBeforeExpire(args)
+	renewBefore, err := getRenewBeforeExpire(args)
 	if err != nil {
-		logging.GetLogger().Infof("extracting num of days to expire failed, %v", err)
+		logging.GetLogger().Infof("extracting duration before expire failed, %v", err)
 		return nil
 	}
-	shouldRenew := shouldRenewCertificate(certificate, daysBeforeExpire)
+	shouldRenew := shouldRenewCertificateBefore(certificate, renewBefore)
 	if shouldRenew {
 		logging.GetLogger().Error("certificate should be renewed")
 		return nil
@@ -96,11 +99,29 @@ func getNumOfDaysBeforeExpire(args map[string]string) (int, error) {
 	return DEFAULT_ACCEPTABLE_NUM_OF_DAYS_TO_EXPIRE, nil
 }
 
+func getRenewBeforeExpire(args map[string]string) (time.Duration, error) {
+	renewBeforeStr, exist := args[ARGS_RENEW_BEFORE_EXPIRE]
+	if exist {
+		return time.ParseDuration(renewBeforeStr)
+	}
+
+	daysBeforeExpire, err := getNumOfDaysBeforeExpire(args)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(daysBeforeExpire) * 24 * time.Hour, nil
+}
+
 func shouldRenewCertificate(cert *x509.Certificate, acceptableDaysBeforeExpire int) bool {
+	return shouldRenewCertificateBefore(cert, time.Duration(acceptableDaysBeforeExpire)*24*time.Hour)
+}
+
+func shouldRenewCertificateBefore(cert *x509.Certificate, renewBefore time.Duration) bool {
 	now := time.Now()
 	certificateExpireDate := cert.NotAfter
 
-	daysBetween := certificateExpireDate.Sub(now).Hours() / 24
-	logging.GetLogger().Infof("days between expiration date and now: [%f]", daysBetween)
-	return (daysBetween <= float64(acceptableDaysBeforeExpire))
+	remaining := certificateExpireDate.Sub(now)
+	logging.GetLogger().Infof("remaining until expiration date: [%v]", remaining)
+	return remaining <= renewBefore
 }
@@ -47,6 +47,35 @@ func TestShouldRenewCertificate(t *testing.T) {
 	assert.True(t, shouldRenew)
 }
 
+func TestShouldRenewShortLivedCertificate(t *testing.T) {
+	cert := &x509.Certificate{
+		NotBefore: time.Now(),
+		NotAfter:  time.Now().Add(90 * time.Minute),
+	}
+
+	assert.False(t, shouldRenewCertificateBefore(cert, time.Hour))
+	assert.True(t, shouldRenewCertificateBefore(cert, 2*time.Hour))
+}
+
+func TestRenewBeforeExpire(t *testing.T) {
+	args := make(map[string]string)
+	args[ARGS_RENEW_X_DAYS_BEFORE_EXPIRE] = "10"
+
+	renewBefore, err := getRenewBeforeExpire(args)
+	assert.NotError(t, err, "getting duration before expire failed")
+	assert.Equal(t, 10*24*time.Hour, renewBefore)
+
+	// duration takes precedence over days
+	args[ARGS_RENEW_BEFORE_EXPIRE] = "2h"
+	renewBefore, err = getRenewBeforeExpire(args)
+	assert.NotError(t, err, "getting duration before expire failed")
+	assert.Equal(t, 2*time.Hour, renewBefore)
+
+	args[ARGS_RENEW_BEFORE_EXPIRE] = "two hours"
+	_, err = getRenewBeforeExpire(args)
+	assert.Error(t, err, "expected duration, not string")
+}
+
 func TestRequiredArgumentCertificatePath(t *testing.T) {
 	args := make(map[string]string)
 
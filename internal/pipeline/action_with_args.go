@@ -1,8 +1,15 @@
 package pipeline
 
-import "bilalekrem.com/certstore/internal/pipeline/action"
+import (
+	"time"
+
+	"bilalekrem.com/certstore/internal/pipeline/action"
+)
 
 type ActionWithArgs struct {
 	action action.Action
 	args   map[string]string
+
+	// zero if action does not have a deadline
+	timeout time.Duration
 }
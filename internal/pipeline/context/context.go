@@ -1,13 +1,22 @@
 package context
 
+import go_ctx "context"
+
 type Key string
 
+// Context keeps values shared between actions of a pipeline run, and the go context to
+// cancel the run
 type Context struct {
 	values map[Key]interface{}
+	ctx    go_ctx.Context
 }
 
 func New() *Context {
-	return &Context{values: make(map[Key]interface{})}
+	return NewWithContext(go_ctx.Background())
+}
+
+func NewWithContext(ctx go_ctx.Context) *Context {
+	return &Context{values: make(map[Key]interface{}), ctx: ctx}
 }
 
 func (c *Context) GetValue(key Key) interface{} {
@@ -22,3 +31,12 @@ func (c *Context) GetValue(key Key) interface{} {
 func (c *Context) StoreValue(key Key, value interface{}) {
 	c.values[key] = value
 }
+
+func (c *Context) Context() go_ctx.Context {
+	return c.ctx
+}
+
+// WithContext returns a context sharing values with c, but cancelled with the given go context
+func (c *Context) WithContext(ctx go_ctx.Context) *Context {
+	return &Context{values: c.values, ctx: ctx}
+}
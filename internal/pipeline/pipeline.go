@@ -1,6 +1,11 @@
 package pipeline
 
 import (
+	go_ctx "context"
+	"errors"
+	"fmt"
+	"time"
+
 	"bilalekrem.com/certstore/internal/logging"
 	"bilalekrem.com/certstore/internal/pipeline/action"
 	"bilalekrem.com/certstore/internal/pipeline/context"
@@ -8,7 +13,7 @@ import (
 
 type Pipeline interface {
 	Name() string
-	Run() error
+	Run(go_ctx.Context) error
 }
 
 // ----
@@ -32,17 +37,25 @@ func NewFromConfig(config *PipelineConfig, actionStore *action.ActionStore) (*pi
 			return nil, err
 		}
 
+		if configAction.Timeout < 0 {
+			return nil, errors.New(fmt.Sprintf("action timeout can not be negative, %s", configAction.Name))
+		}
+
 		args := configAction.Args
 		logging.GetLogger().Infof("[%s] args [%s]", configAction.Name, args)
-		pipeline.RegisterAction(*_action, args)
+		pipeline.RegisterActionWithTimeout(*_action, args, configAction.Timeout)
 	}
 
 	return pipeline, nil
 }
 
 func (p *pipelineImpl) RegisterAction(action action.Action, args map[string]string) {
+	p.RegisterActionWithTimeout(action, args, 0)
+}
+
+func (p *pipelineImpl) RegisterActionWithTimeout(action action.Action, args map[string]string, timeout time.Duration) {
 	logging.GetLogger().Infof("Adding a new action to pipeline [%s]", p.Name())
-	actionWithArgs := ActionWithArgs{action: action, args: args}
+	actionWithArgs := ActionWithArgs{action: action, args: args, timeout: timeout}
 	p.actions = append(p.actions, &actionWithArgs)
 }
 
@@ -50,14 +63,18 @@ func (p *pipelineImpl) Name() string {
 	return p.name
 }
 
-func (p *pipelineImpl) Run() error {
+func (p *pipelineImpl) Run(goCtx go_ctx.Context) error {
 	logging.GetLogger().Infof("Starting to run pipeline [%s]", p.Name())
 
-	ctx := context.New()
+	ctx := context.NewWithContext(goCtx)
 	for _, actionWithArgs := range p.actions {
-		action := actionWithArgs.action
-		args := actionWithArgs.args
-		err := action.Run(ctx, args)
+		err := goCtx.Err()
+		if err != nil {
+			logging.GetLogger().Errorf("pipeline [%s] cancelled, %v", p.Name(), err)
+			return err
+		}
+
+		err = runAction(ctx, actionWithArgs)
 		if err != nil {
 			logging.GetLogger().Debugf("error occurred while running an action in pipeline %s, err: %v", p.Name(), err)
 			return err
@@ -65,3 +82,14 @@ func (p *pipelineImpl) Run() error {
 	}
 	return nil
 }
+
+func runAction(ctx *context.Context, actionWithArgs *ActionWithArgs) error {
+	if actionWithArgs.timeout <= 0 {
+		return actionWithArgs.action.Run(ctx, actionWithArgs.args)
+	}
+
+	goCtx, cancel := go_ctx.WithTimeout(ctx.Context(), actionWithArgs.timeout)
+	defer cancel()
+
+	return actionWithArgs.action.Run(ctx.WithContext(goCtx), actionWithArgs.args)
+}
@@ -1,6 +1,8 @@
 package pipeline
 
 import (
+	"time"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,6 +15,7 @@ actions:
     args:
 	  command: "echo hello world"
   - name: mock-action
+    timeout: 30s
 */
 
 type PipelineConfig struct {
@@ -23,6 +26,8 @@ type PipelineConfig struct {
 type PipelineActionConfig struct {
 	Name string            `yaml:"name"`
 	Args map[string]string `yaml:"args"`
+
+	Timeout time.Duration `yaml:"timeout"`
 }
 
 func ParsePipelineConfig(configYaml string) (*PipelineConfig, error) {
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 )
@@ -37,3 +38,15 @@ actions:
 	// should be a nil map
 	assert.Nil(t, secondAction.Args)
 }
+
+func TestParseActionTimeout(t *testing.T) {
+	pipelineYaml := `name: my-pipeline
+actions:
+  - name: issue-certificate
+    timeout: 5m`
+
+	config, err := ParsePipelineConfig(pipelineYaml)
+	assert.NotError(t, err, "parsing pipeline config")
+
+	assert.Equal(t, 5*time.Minute, config.Actions[0].Timeout)
+}
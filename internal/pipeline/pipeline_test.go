@@ -1,8 +1,10 @@
 package pipeline
 
 import (
+	go_ctx "context"
 	"reflect"
 	"testing"
+	"time"
 
 	"bilalekrem.com/certstore/internal/assert"
 	"bilalekrem.com/certstore/internal/pipeline/action"
@@ -36,7 +38,7 @@ func TestPipelineRunAction(t *testing.T) {
 
 	// ----
 
-	err := pipeline.Run()
+	err := pipeline.Run(go_ctx.Background())
 	assert.NotError(t, err, "running pipeline")
 }
 
@@ -61,7 +63,7 @@ func TestPipelineRunActionWithConfig(t *testing.T) {
 
 	// ----
 
-	err := pipeline.Run()
+	err := pipeline.Run(go_ctx.Background())
 	assert.NotError(t, err, "running pipeline")
 }
 
@@ -91,7 +93,7 @@ func TestPipelineRunMultipleAction(t *testing.T) {
 
 	// ----
 
-	err := pipeline.Run()
+	err := pipeline.Run(go_ctx.Background())
 	assert.NotError(t, err, "running pipeline")
 }
 
@@ -122,7 +124,7 @@ func TestNewPipelineFromConfig(t *testing.T) {
 
 	// ----
 
-	err = pipeline.Run()
+	err = pipeline.Run(go_ctx.Background())
 	assert.NotError(t, err, "running pipeline")
 }
 
@@ -180,7 +182,7 @@ actions:
 
 	// ----
 
-	err = pipeline.Run()
+	err = pipeline.Run(go_ctx.Background())
 	assert.NotError(t, err, "running pipeline")
 }
 
@@ -233,6 +235,59 @@ func TestPipelineContextStoreAndGetCustomValue(t *testing.T) {
 	pipeline.RegisterAction(first, nil)
 	pipeline.RegisterAction(second, nil)
 
-	err := pipeline.Run()
+	err := pipeline.Run(go_ctx.Background())
 	assert.NotError(t, err, "running pipeline")
 }
+
+func TestPipelineActionTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAction := action.NewMockAction(ctrl)
+	mockAction.
+		EXPECT().
+		Run(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx *context.Context, args map[string]string) error {
+			<-ctx.Context().Done()
+			return ctx.Context().Err()
+		})
+
+	pipeline := New("test-pipeline")
+	pipeline.RegisterActionWithTimeout(mockAction, nil, 10*time.Millisecond)
+
+	err := pipeline.Run(go_ctx.Background())
+	assert.Equal(t, go_ctx.DeadlineExceeded, err)
+}
+
+func TestPipelineCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAction := action.NewMockAction(ctrl)
+	mockAction.
+		EXPECT().
+		Run(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	pipeline := New("test-pipeline")
+	pipeline.RegisterAction(mockAction, nil)
+
+	ctx, cancel := go_ctx.WithCancel(go_ctx.Background())
+	cancel()
+
+	err := pipeline.Run(ctx)
+	assert.Equal(t, go_ctx.Canceled, err)
+}
+
+func TestNewPipelineFromConfigNegativeTimeout(t *testing.T) {
+	actionsConfig := []PipelineActionConfig{
+		{Name: "my-action", Timeout: -time.Second},
+	}
+	pipelineConfig := &PipelineConfig{Name: "my-pipeline", Actions: actionsConfig}
+
+	actionStore := action.NewActionStore()
+	actionStore.Put("my-action", action.NewMockAction(gomock.NewController(t)))
+
+	_, err := NewFromConfig(pipelineConfig, actionStore)
+	assert.ErrorContains(t, err, "timeout can not be negative")
+}
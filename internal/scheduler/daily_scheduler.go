@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"bilalekrem.com/certstore/internal/logging"
@@ -19,6 +20,9 @@ type dailyScheduler struct {
 	scheduled bool
 
 	timeProvider TimeProvider
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 func NewDailyScheduler() *dailyScheduler {
@@ -26,7 +30,7 @@ func NewDailyScheduler() *dailyScheduler {
 }
 
 func NewDailySchedulerWithTimeProvider(timeProvider TimeProvider) *dailyScheduler {
-	return &dailyScheduler{timeProvider: timeProvider}
+	return &dailyScheduler{timeProvider: timeProvider, stop: make(chan struct{})}
 }
 
 func (s *dailyScheduler) Schedule(fn func()) error {
@@ -47,16 +51,26 @@ func (s *dailyScheduler) Schedule(fn func()) error {
 
 	go func() {
 		logging.GetLogger().Infof("sleeping for %d seconds", timeForNextHourInSeconds)
-		time.Sleep(time.Duration(timeForNextHourInSeconds) * time.Second)
+		if !sleep(time.Duration(timeForNextHourInSeconds)*time.Second, s.stop) {
+			return
+		}
 
 		for {
 			fn()
 
 			logging.GetLogger().Info("will sleep for a day, until for next iteration")
-			time.Sleep(24 * time.Hour)
+			if !sleep(24*time.Hour, s.stop) {
+				return
+			}
 		}
 	}()
 	s.scheduled = true
 
 	return nil
 }
+
+func (s *dailyScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
@@ -60,3 +60,23 @@ func TestScheduleValidateNotRunBeforeTimeIsUp(t *testing.T) {
 	time.Sleep(2 * time.Second)
 	assert.True(t, called)
 }
+
+func TestScheduleStop(t *testing.T) {
+	// fn would run after 2 seconds
+	mockTimeProvider := func() time.Time {
+		return time.Date(2022, 01, 01, 01, 59, 58, 0, time.Local)
+	}
+
+	scheduler := NewDailySchedulerWithTimeProvider(mockTimeProvider)
+
+	called := false
+	err := scheduler.Schedule(func() {
+		called = true
+	})
+	assert.NotError(t, err, "scheduling failed")
+
+	scheduler.Stop()
+
+	time.Sleep(3 * time.Second)
+	assert.False(t, called)
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"bilalekrem.com/certstore/internal/logging"
+)
+
+// intervalScheduler runs the function in fixed intervals, e.g. to renew short lived certificates
+type intervalScheduler struct {
+	scheduled bool
+
+	interval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewIntervalScheduler(interval time.Duration) *intervalScheduler {
+	return &intervalScheduler{interval: interval, stop: make(chan struct{})}
+}
+
+func (s *intervalScheduler) Schedule(fn func()) error {
+	if s.scheduled {
+		return errors.New("scheduler is already scheduled..")
+	}
+
+	if s.interval <= 0 {
+		return errors.New("scheduler interval must be positive")
+	}
+
+	// ----
+
+	go func() {
+		for {
+			logging.GetLogger().Infof("will sleep for %v, until for next iteration", s.interval)
+			if !sleep(s.interval, s.stop) {
+				return
+			}
+
+			fn()
+		}
+	}()
+	s.scheduled = true
+
+	return nil
+}
+
+func (s *intervalScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
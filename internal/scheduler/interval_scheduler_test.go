@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bilalekrem.com/certstore/internal/assert"
+)
+
+func TestIntervalSchedule(t *testing.T) {
+	scheduler := NewIntervalScheduler(100 * time.Millisecond)
+
+	var called int32
+	err := scheduler.Schedule(func() {
+		atomic.AddInt32(&called, 1)
+	})
+	assert.NotError(t, err, "scheduling failed")
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&called))
+
+	time.Sleep(300 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&called) >= 2)
+
+	err = scheduler.Schedule(func() {})
+	assert.ErrorContains(t, err, "already scheduled")
+}
+
+func TestIntervalScheduleNotPositive(t *testing.T) {
+	err := NewIntervalScheduler(0).Schedule(func() {})
+	assert.ErrorContains(t, err, "interval must be positive")
+}
+
+func TestIntervalScheduleStop(t *testing.T) {
+	scheduler := NewIntervalScheduler(100 * time.Millisecond)
+
+	var called int32
+	err := scheduler.Schedule(func() {
+		atomic.AddInt32(&called, 1)
+	})
+	assert.NotError(t, err, "scheduling failed")
+
+	time.Sleep(150 * time.Millisecond)
+	scheduler.Stop()
+	scheduler.Stop()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&called))
+}
@@ -1,5 +1,25 @@
 package scheduler
 
+import (
+	"time"
+)
+
 type Scheduler interface {
 	Schedule(fn func()) error
+
+	// Stop stops scheduling further runs, a run in progress is not interrupted
+	Stop()
+}
+
+// sleep returns false if stop is closed before the duration passes
+func sleep(duration time.Duration, stop <-chan struct{}) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
 }